@@ -7,91 +7,284 @@ import (
 	"strings"
 )
 
-type valueDecoder func(dec *json.Decoder, key string, keyOffset int64) error
+// decoder wraps a json.Decoder with the state needed to decode a
+// Smithy JSON AST: the options in effect, the nesting depth of the
+// most recently read token and, in recover mode, the errors recorded
+// so far.
+//
+// All reads made while decoding must go through the decoder, and not
+// the wrapped json.Decoder, so that the nesting depth stays accurate.
+type decoder struct {
+	*json.Decoder
+	opts    DecodeOptions
+	depth   int
+	path    []string // object keys and array indices leading to the current value
+	shapes  int      // number of shapes seen so far
+	members int      // number of members seen so far
+	lines   *lineReader
+	errs    JSONErrors
+}
+
+func newDecoder(dec *json.Decoder, opts DecodeOptions) *decoder {
+	return &decoder{Decoder: dec, opts: opts}
+}
+
+// Token returns the next JSON token in the input stream, keeping
+// track of the nesting depth and enforcing the MaxDepth and MaxBytes
+// limits.
+func (d *decoder) Token() (json.Token, error) {
+	offset := d.InputOffset()
+	tok, err := d.Decoder.Token()
+	if err == errInputTooLarge {
+		return nil, limitError(d, "MaxBytes", d.opts.MaxBytes, d.opts.MaxBytes)
+	}
+	if delim, ok := tok.(json.Delim); ok {
+		switch delim {
+		case '{', '[':
+			d.depth++
+			if d.opts.MaxDepth > 0 && d.depth > d.opts.MaxDepth {
+				return nil, limitError(d, "MaxDepth", int64(d.opts.MaxDepth), offset)
+			}
+		case '}', ']':
+			d.depth--
+		}
+	}
+	return tok, err
+}
+
+// countShape counts a shape toward the MaxShapes limit.
+func (d *decoder) countShape() error {
+	d.shapes++
+	if d.opts.MaxShapes > 0 && d.shapes > d.opts.MaxShapes {
+		return limitError(d, "MaxShapes", int64(d.opts.MaxShapes), d.InputOffset())
+	}
+	return nil
+}
+
+// countMember counts a member toward the MaxMembers limit.
+func (d *decoder) countMember() error {
+	d.members++
+	if d.opts.MaxMembers > 0 && d.members > d.opts.MaxMembers {
+		return limitError(d, "MaxMembers", int64(d.opts.MaxMembers), d.InputOffset())
+	}
+	return nil
+}
+
+// unknownKey handles an object key which the decoder does not
+// recognize. If the AllowUnknownKeys option is set, the key's value is
+// skipped. Otherwise, unknownKey returns an unsupported key error.
+func (d *decoder) unknownKey(name, key string, keyOffset int64) error {
+	if d.opts.AllowUnknownKeys {
+		return d.skip(d.depth, d.Decoder.InputOffset())
+	}
+	return unsupportedKeyError(d, name, key, keyOffset)
+}
+
+// jsonError returns a *JSONError whose Path points to the value
+// currently being decoded.
+func (d *decoder) jsonError(msg string, offset int64) error {
+	return &JSONError{msg: prefix + msg, Offset: offset, Path: pointer(d.path)}
+}
+
+// keyError returns a *JSONError about the key of the object member
+// currently being decoded. Its Path points to the object containing
+// the key.
+func (d *decoder) keyError(msg string, offset int64) error {
+	return &JSONError{msg: prefix + msg, Offset: offset, Path: pointer(d.path[:len(d.path)-1])}
+}
+
+// record records err in recover mode, if it is a *JSONError, and
+// returns nil. Otherwise it returns err.
+func (d *decoder) record(err error) error {
+	if jsonErr, ok := err.(*JSONError); ok && d.opts.Recover {
+		d.errs = append(d.errs, *jsonErr)
+		return nil
+	}
+	return err
+}
+
+// recoverable calls decodeFunc to decode a value which can be left out
+// of the decoded model without spoiling the rest of it, such as a
+// shape, member, or trait. It returns true if the value was decoded
+// successfully.
+//
+// In recover mode, if decodeFunc fails with a *JSONError, the error is
+// recorded, the rest of the value is skipped, and recoverable returns
+// false with a nil error so the caller can carry on decoding.
+func (d *decoder) recoverable(decodeFunc func() error) (bool, error) {
+	depth := d.depth
+	offset := d.Decoder.InputOffset()
+	err := decodeFunc()
+	if err == nil {
+		return true, nil
+	}
+	err = d.record(err)
+	if err != nil {
+		return false, err
+	}
+	return false, d.skip(depth, offset)
+}
+
+// skip skips the remainder of a JSON value which began at the given
+// depth and offset. If the value has not been started, it is skipped
+// entirely.
+func (d *decoder) skip(depth int, offset int64) error {
+	if d.depth == depth && d.Decoder.InputOffset() == offset {
+		_, err := d.Token()
+		if err != nil {
+			return err
+		}
+	}
+	for d.depth > depth {
+		_, err := d.Token()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeDecoder is implemented by the Node types in this package. They
+// decode from a decoder, sharing its state, rather than a bare
+// json.Decoder.
+type nodeDecoder interface {
+	decode(d *decoder) error
+}
+
+// decodeNode decodes the JSON value at the current position in the
+// decoder into a Node. If the Path option is set, it also records the
+// node's Location.
+func decodeNode(d *decoder, n Node) error {
+	var loc Location
+	if d.opts.Path != "" {
+		loc = d.location()
+	}
+	var err error
+	if nd, ok := n.(nodeDecoder); ok {
+		err = nd.decode(d)
+	} else {
+		err = n.Decode(d.Decoder)
+	}
+	if err == nil && d.opts.Path != "" {
+		n.SetLocation(loc)
+	}
+	return err
+}
+
+// location returns the Location of the next JSON value in the input.
+func (d *decoder) location() Location {
+	// The decoder's input offset is at the end of the previous token,
+	// which may be followed by whitespace and a separator before the
+	// next value begins.
+	offset := d.InputOffset()
+	r := d.Buffered()
+	var b [1]byte
+	for {
+		n, _ := r.Read(b[:])
+		if n == 0 || !strings.ContainsRune(" \t\r\n:,", rune(b[0])) {
+			break
+		}
+		offset++
+	}
+	row, col := d.lines.position(offset)
+	return Location{
+		Path:   d.opts.Path,
+		Offset: int(offset),
+		Row:    row,
+		Col:    col,
+	}
+}
+
+type valueDecoder func(d *decoder, key string, keyOffset int64) error
 
 // decodeObject decodes the JSON object at the current position in
 // the decoder. It extracts keys and then calls a callback function
 // to decode the value. If the key and value need to be persisted, this
 // is the responsibility of the callback.
-func decodeObject(dec *json.Decoder, name string, valDec valueDecoder) error {
+func decodeObject(d *decoder, name string, valDec valueDecoder) error {
 	var offset int64
 	var tok json.Token
 	var err error
 
 	// Expect an open brace starting a JSON object.
-	offset = dec.InputOffset()
-	tok, err = dec.Token()
-	if isNonSyntaxError(err) {
+	offset = d.InputOffset()
+	tok, err = d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	var delim json.Delim
 	var ok bool
 	if delim, ok = tok.(json.Delim); !ok || delim != '{' {
-		return jsonError("expected '{' to start "+name, offset)
+		return d.jsonError("expected '{' to start "+name, offset)
 	}
 
 	// Record keys already seen.
 	seen := make(map[string]bool)
 
 	// Find all key, value pairs in the object.
-	for dec.More() {
+	for d.More() {
 		// Get the key.
-		offset = dec.InputOffset()
-		tok, err = dec.Token()
-		if isNonSyntaxError(err) {
+		offset = d.InputOffset()
+		tok, err = d.Token()
+		if isFatalError(err) {
 			return err
 		}
 		var key string
 		if key, ok = tok.(string); !ok {
-			return jsonError("expected string key within "+name, offset)
+			return d.jsonError("expected string key within "+name, offset)
 		}
 
 		// Check for duplication.
 		if seen[key] {
-			return jsonError("duplicate key "+strconv.Quote(key)+" within "+name, offset)
+			return d.jsonError("duplicate key "+strconv.Quote(key)+" within "+name, offset)
 		}
+		seen[key] = true
 
 		// Decode the value.
-		err = valDec(dec, key, offset)
+		d.path = append(d.path, key)
+		err = valDec(d, key, offset)
+		d.path = d.path[:len(d.path)-1]
 		if err != nil {
 			return err
 		}
 	}
 
 	// Expect a closing brace ending the JSON object.
-	tok, err = dec.Token()
-	if isNonSyntaxError(err) {
+	tok, err = d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	if delim, ok = tok.(json.Delim); !ok || delim != '}' {
-		return jsonError("expected '}' to end "+name, dec.InputOffset())
+		return d.jsonError("expected '}' to end "+name, d.InputOffset())
 	}
 
 	// Object parsed successfully.
 	return nil
 }
 
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
 // decodeToMap decodes a JSON object into a map. The map key type must
 // be a kind of string, and the value type must be type whose pointer
 // type implements Node. The map must be non-nil.
-func decodeToMap(dec *json.Decoder, name string, target interface{}) error {
+func decodeToMap(d *decoder, name string, target interface{}) error {
 	v := reflect.ValueOf(target)
 	t := v.Type()
+	if t.Kind() != reflect.Map {
+		panic(newErrorf("map required within %s but %s is not a map", name, t))
+	}
 	kt := t.Key()
 	if kt.Kind() != reflect.String {
 		panic(newErrorf("map key type must be a kind of string within %s map but %s is not", name, kt.Name()))
 	}
 	vt := t.Elem()
-	var n Node
-	nt := reflect.TypeOf(&n).Elem()
-	if !reflect.PtrTo(vt).Implements(nt) {
+	if !reflect.PtrTo(vt).Implements(nodeType) {
 		panic(newErrorf("map value type must implement Node within %s map but %s does not", name, vt.Name()))
 	}
 
-	return decodeObject(dec, name, func(dec2 *json.Decoder, key string, _ int64) error {
+	return decodeObject(d, name, func(d *decoder, key string, _ int64) error {
 		vv := reflect.New(vt)
-		n2 := vv.Interface().(Node)
-		err := n2.Decode(dec2)
+		err := decodeNode(d, vv.Interface().(Node))
 		if err != nil {
 			return err
 		}
@@ -103,9 +296,10 @@ func decodeToMap(dec *json.Decoder, name string, target interface{}) error {
 // decodeToStructPtr decodes a JSON object into a pointer to a struct.
 // Each struct field must have a "json" tags to specify the JSON key
 // corresponding to the field. Each struct field must either implement
-// Node or be a map whose keys are strings and whose values implement
-// Node.
-func decodeToStructPtr(dec *json.Decoder, name string, target interface{}) error {
+// Node, be a struct whose pointer type implements Node, be a map
+// whose keys are strings and whose values implement Node, or be a
+// slice whose elements implement Node.
+func decodeToStructPtr(d *decoder, name string, target interface{}) error {
 	v := reflect.ValueOf(target)
 	t := v.Type()
 
@@ -135,86 +329,88 @@ func decodeToStructPtr(dec *json.Decoder, name string, target interface{}) error
 			key = key[0:x]
 		}
 		if key == "" {
-			panic(newErrorf("field %s [%i] in struct %s has no usable json tag", f.Name, i, t.Name()))
+			panic(newErrorf("field %s [%d] in struct %s has no usable json tag", f.Name, i, t.Name()))
 		}
 		if _, ok := fields[key]; ok {
-			panic(newErrorf("field %s [%i] in struct %s duplicates JSON key %q", f.Name, i, t.Name(), key))
+			panic(newErrorf("field %s [%d] in struct %s duplicates JSON key %q", f.Name, i, t.Name(), key))
 		}
 		fields[key] = i
 	}
 
-	var n Node
-	nt := reflect.TypeOf(&n).Elem()
-
-	return decodeObject(dec, name, func(dec2 *json.Decoder, key string, keyOffset int64) error {
+	return decodeObject(d, name, func(d *decoder, key string, keyOffset int64) error {
 		i, ok := fields[key]
 		if !ok {
-			return unsupportedKeyError(name, key, keyOffset)
+			return d.unknownKey(name, key, keyOffset)
 		}
 
 		fv := v.Field(i)
 		ft := fv.Type()
-		if ft.Kind() == reflect.Pointer && ft.Implements(nt) {
+		if ft.Kind() == reflect.Pointer && ft.Implements(nodeType) {
 			fv.Set(reflect.New(ft.Elem()))
-			return fv.Interface().(Node).Decode(dec2)
+			return decodeNode(d, fv.Interface().(Node))
+		} else if ft.Kind() == reflect.Struct && reflect.PtrTo(ft).Implements(nodeType) {
+			return decodeNode(d, fv.Addr().Interface().(Node))
 		} else if ft.Kind() == reflect.Map {
 			fv.Set(reflect.MakeMap(ft))
-			return decodeToMap(dec2, name+`["`+key+`"]`, fv.Interface())
+			return decodeToMap(d, name+`["`+key+`"]`, fv.Interface())
 		} else if ft.Kind() == reflect.Slice {
-			return decodeToSlicePtr(dec2, name+`["`+key+`"]`, fv.Addr().Interface())
+			return decodeToSlicePtr(d, name+`["`+key+`"]`, fv.Addr().Interface())
 		} else {
 			panic(newErrorf("field %s in struct %s has invalid type", t.Field(i).Name, t.Name()))
 		}
 	})
 }
 
-type elementDecoder func(dec *json.Decoder, index int) error
+type elementDecoder func(d *decoder, index int) error
 
 // decodeArray decodes the JSON array at the current position in the
 // decoder. For each element in the array, it calls a callback function
 // to decode that element. If the decoded element needs to be persisted,
 // this is the responsibility of the callback.
-func decodeArray(dec *json.Decoder, name string, elemDec elementDecoder) error {
+func decodeArray(d *decoder, name string, elemDec elementDecoder) error {
 	var offset int64
 	var err error
 	var tok json.Token
 
 	// Expect an open bracket starting a JSON object.
-	offset = dec.InputOffset()
-	tok, err = dec.Token()
-	if isNonSyntaxError(err) {
+	offset = d.InputOffset()
+	tok, err = d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	var delim json.Delim
 	var ok bool
 	if delim, ok = tok.(json.Delim); !ok || delim != '[' {
-		return jsonError("expected '[' to start "+name, offset)
+		return d.jsonError("expected '[' to start "+name, offset)
 	}
 
 	// Decode each element in the array.
-	for index := 0; dec.More(); index++ {
-		err := elemDec(dec, index)
+	for index := 0; d.More(); index++ {
+		d.path = append(d.path, strconv.Itoa(index))
+		err := elemDec(d, index)
+		d.path = d.path[:len(d.path)-1]
 		if err != nil {
 			return err
 		}
 	}
 
 	// Expect a closing bracket ending the JSON array.
-	tok, err = dec.Token()
-	if isNonSyntaxError(err) {
+	tok, err = d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	if delim, ok = tok.(json.Delim); !ok || delim != ']' {
-		return jsonError("expected ']' to end "+name, dec.InputOffset())
+		return d.jsonError("expected ']' to end "+name, d.InputOffset())
 	}
 
 	// Array parsed successfully.
 	return nil
 }
 
-// decodeToSlice decodes a JSON array into a pointer to a slice. The
-// slice element type must implement Node.
-func decodeToSlicePtr(dec *json.Decoder, name string, target interface{}) error {
+// decodeToSlicePtr decodes a JSON array into a pointer to a slice.
+// The slice element type must be a type whose pointer type implements
+// Node.
+func decodeToSlicePtr(d *decoder, name string, target interface{}) error {
 	v := reflect.ValueOf(target)
 	t := v.Type()
 
@@ -222,27 +418,26 @@ func decodeToSlicePtr(dec *json.Decoder, name string, target interface{}) error
 		panic(newError("pointer to slice required"))
 	}
 
-	v2 := v.Elem()
-	t2 := v.Type()
+	v = v.Elem()
+	t = v.Type()
 
-	if t2.Kind() != reflect.Slice {
+	if t.Kind() != reflect.Slice {
 		panic(newError("pointer to slice required"))
 	}
 
-	var n Node
-	nt := reflect.TypeOf(&n)
-	et := t2.Elem()
-	if !et.Implements(nt) {
+	et := t.Elem()
+	if !reflect.PtrTo(et).Implements(nodeType) {
 		panic(newError("slice element type must implement Node"))
 	}
 
-	return decodeArray(dec, name, func(dec2 *json.Decoder, index int) error {
+	v.Set(reflect.MakeSlice(t, 0, 0))
+	return decodeArray(d, name, func(d *decoder, _ int) error {
 		ev := reflect.New(et)
-		err := ev.Interface().(Node).Decode(dec2)
+		err := decodeNode(d, ev.Interface().(Node))
 		if err != nil {
 			return err
 		}
-		v.Set(reflect.Append(v2, ev))
+		v.Set(reflect.Append(v, ev.Elem()))
 		return nil
 	})
 }
@@ -254,17 +449,21 @@ type numberDecoder func(s string) error
 // the decoder as a string, then passes the string to a callback
 // function to decode it. If the decoded number needs to be persisted,
 // this is the responsibility of the callback.
-func decodeNumber(dec *json.Decoder, numDec numberDecoder) error {
-	offset := dec.InputOffset()
-	dec.UseNumber()
-	t, err := dec.Token()
-	if isNonSyntaxError(err) {
+func decodeNumber(d *decoder, numDec numberDecoder) error {
+	offset := d.InputOffset()
+	d.UseNumber()
+	t, err := d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	var n json.Number
 	var ok bool
 	if n, ok = t.(json.Number); !ok {
-		return jsonError("expected number", offset)
+		return d.jsonError("expected number", offset)
+	}
+	err = numDec(string(n))
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return d.jsonError("invalid number "+string(n)+": "+numErr.Err.Error(), offset)
 	}
-	return numDec(string(n))
+	return err
 }
@@ -0,0 +1,313 @@
+package ast
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadModelWithOptions(t *testing.T) {
+	t.Run("Recover", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			json  string
+			model Model
+			errs  JSONErrors
+		}{
+			{
+				name:  "no errors",
+				json:  `{"version":"1.0","shapes":{"foo#Bar":{"type":"string"}}}`,
+				model: Model{Version: StringNode{Value: "1.0"}, Shapes: map[AbsShapeID]Shape{"foo#Bar": {Type: StringType}}},
+			},
+			{
+				name:  "missing version",
+				json:  `{"shapes":{}}`,
+				model: Model{Shapes: map[AbsShapeID]Shape{}},
+				errs:  JSONErrors{jsonErrorAt("missing version", 0, "")},
+			},
+			{
+				name:  "unsupported key",
+				json:  `{"foo":{"bar":[1,2]},"version":"1.0"}`,
+				model: Model{Version: StringNode{Value: "1.0"}},
+				errs:  JSONErrors{jsonErrorAt(`unsupported key "foo" in model`, 1, "")},
+			},
+			{
+				name: "bad shapes",
+				json: `{"version":"1.0","shapes":{"foo#A":{"type":"wrong"},"foo#B":{"type":"blob"},"foo#C":{"traits":{}}}}`,
+				model: Model{
+					Version: StringNode{Value: "1.0"},
+					Shapes:  map[AbsShapeID]Shape{"foo#B": {Type: BlobType}},
+				},
+				errs: JSONErrors{
+					jsonErrorAt(`unrecognized shape type: "wrong"`, 42, "/shapes/foo#A/type"),
+					jsonErrorAt("shape is missing type field", 83, "/shapes/foo#C"),
+				},
+			},
+			{
+				name: "bad members",
+				json: `{"version":"1.0","shapes":{"foo#S":{"type":"structure","members":{"a":{"target":"foo#T"},"b":{"target":5},"c":{"target":"foo#T","extra":{}}}}}}`,
+				model: Model{
+					Version: StringNode{Value: "1.0"},
+					Shapes: map[AbsShapeID]Shape{
+						"foo#S": {
+							Type:    StructureType,
+							Members: map[string]Member{"a": {Target: AbsShapeIDNode{Value: "foo#T"}}},
+						},
+					},
+				},
+				errs: JSONErrors{
+					jsonErrorAt("expected string [absolute shape ID]", 102, "/shapes/foo#S/members/b/target"),
+					jsonErrorAt(`unsupported key "extra" in member`, 127, "/shapes/foo#S/members/c"),
+				},
+			},
+			{
+				name: "bad traits",
+				json: `{"version":"1.0","shapes":{"foo#L":{"type":"list","traits":{"smithy.api#length":{"min":"x"},"smithy.api#sensitive":{},"smithy.api#required":{"a":1}},"member":{"target":"foo#T"}}}}`,
+				model: Model{
+					Version: StringNode{Value: "1.0"},
+					Shapes: map[AbsShapeID]Shape{
+						"foo#L": {
+							Type:   ListType,
+							Traits: Traits{SensitiveTraitID: &AnnotationTrait{}},
+							Value:  &Member{Target: AbsShapeIDNode{Value: "foo#T"}},
+						},
+					},
+				},
+				errs: JSONErrors{
+					jsonErrorAt("expected number", 86, "/shapes/foo#L/traits/smithy.api#length/min"),
+					jsonErrorAt("annotation trait must be an empty object", 139, "/shapes/foo#L/traits/smithy.api#required"),
+				},
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				m, err := ReadModelWithOptions(strings.NewReader(testCase.json), DecodeOptions{Recover: true})
+
+				if testCase.errs == nil {
+					assert.NoError(t, err)
+				} else {
+					require.IsType(t, JSONErrors{}, err)
+					assert.Equal(t, testCase.errs, err)
+				}
+				assert.Equal(t, testCase.model, m)
+			})
+		}
+	})
+
+	t.Run("Recover.Syntax", func(t *testing.T) {
+		_, err := ReadModelWithOptions(strings.NewReader(`{"version":"1.0","shapes":{"foo#A":{"type":]}}`), DecodeOptions{Recover: true})
+
+		assert.IsType(t, &json.SyntaxError{}, err)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		_, err := ReadModelWithOptions(strings.NewReader(`{"version":"1.0","shapes":{"foo#A":{"type":"wrong"},"foo#B":{}}}`), DecodeOptions{})
+
+		assert.EqualError(t, err, `ast: unrecognized shape type: "wrong" at offset 42 in /shapes/foo#A/type`)
+	})
+}
+
+func TestJSONErrorPath(t *testing.T) {
+	testCases := []struct {
+		name string
+		json string
+		path string
+	}{
+		{
+			name: "root",
+			json: `[]`,
+			path: "",
+		},
+		{
+			name: "model key",
+			json: `{"version":"1.0","bad":1}`,
+			path: "",
+		},
+		{
+			name: "version",
+			json: `{"version":1}`,
+			path: "/version",
+		},
+		{
+			name: "shape operations index",
+			json: `{"version":"1.0","shapes":{"foo#Svc":{"type":"service","operations":["foo#A",7]}}}`,
+			path: "/shapes/foo#Svc/operations/1",
+		},
+		{
+			name: "member trait",
+			json: `{"version":"1.0","shapes":{"foo#S":{"type":"structure","members":{"baz":{"target":"foo#T","traits":{"smithy.api#length":{"min":true}}}}}}}`,
+			path: "/shapes/foo#S/members/baz/traits/smithy.api#length/min",
+		},
+		{
+			name: "escaped",
+			json: `{"version":"1.0","metadata":{"a/b~c":{}},"shapes":{"foo#S":{"type":"structure","traits":{"foo#x/y":{}},"members":{"m":{"target":"foo#T","traits":{"smithy.api#enum":[{"value":"a"},{"value":"b","name":3}]}}}}}}`,
+			path: "/shapes/foo#S/members/m/traits/smithy.api#enum/1/name",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ReadModel(strings.NewReader(testCase.json))
+
+			var jsonErr *JSONError
+			require.ErrorAs(t, err, &jsonErr)
+			assert.Equal(t, testCase.path, jsonErr.Path)
+		})
+	}
+
+	t.Run("pointer", func(t *testing.T) {
+		assert.Equal(t, "", pointer(nil))
+		assert.Equal(t, "/shapes/a~1b~0c/0", pointer([]string{"shapes", "a/b~c", "0"}))
+	})
+
+	t.Run("JSONErrors", func(t *testing.T) {
+		_, err := ReadModelWithOptions(strings.NewReader(`{"version":"1.0","shapes":{"foo#A":{"type":"string","traits":{"smithy.api#pattern":1}}}}`), DecodeOptions{Recover: true})
+
+		var jsonErr *JSONError
+		require.ErrorAs(t, err, &jsonErr)
+		assert.Equal(t, "/shapes/foo#A/traits/smithy.api#pattern", jsonErr.Path)
+	})
+}
+
+func jsonErrorAt(msg string, offset int64, path string) JSONError {
+	return JSONError{msg: prefix + msg, Offset: offset, Path: path}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	const model = `{"version":"1.0","metadata":{"a":[[1]]},"shapes":{"foo#L":{"type":"list","member":{"target":"foo#S"}},"foo#S":{"type":"structure","members":{"a":{"target":"foo#T"},"b":{"target":"foo#T"}}}}}`
+
+	t.Run("Limits", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			opts   DecodeOptions
+			limit  string
+			offset int64
+			path   string
+		}{
+			{
+				name:   "MaxBytes",
+				opts:   DecodeOptions{MaxBytes: 100},
+				limit:  "MaxBytes",
+				offset: 100,
+				path:   "/shapes/foo#L",
+			},
+			{
+				name:   "MaxDepth",
+				opts:   DecodeOptions{MaxDepth: 3},
+				limit:  "MaxDepth",
+				offset: 34,
+				path:   "/metadata/a/0",
+			},
+			{
+				name:   "MaxShapes",
+				opts:   DecodeOptions{MaxShapes: 1},
+				limit:  "MaxShapes",
+				offset: 109,
+				path:   "/shapes/foo#S",
+			},
+			{
+				name:   "MaxMembers",
+				opts:   DecodeOptions{MaxMembers: 2, Recover: true},
+				limit:  "MaxMembers",
+				offset: 167,
+				path:   "/shapes/foo#S/members/b",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				_, err := ReadModelWithOptions(strings.NewReader(model), testCase.opts)
+
+				var limitErr *LimitError
+				require.ErrorAs(t, err, &limitErr)
+				assert.Equal(t, testCase.limit, limitErr.Limit)
+				assert.Equal(t, testCase.offset, limitErr.Offset)
+				assert.Equal(t, testCase.path, limitErr.Path)
+
+				var jsonErr *JSONError
+				assert.ErrorAs(t, err, &jsonErr)
+			})
+		}
+
+		t.Run("Within", func(t *testing.T) {
+			opts := DecodeOptions{MaxBytes: int64(len(model)), MaxDepth: 5, MaxShapes: 2, MaxMembers: 3}
+
+			_, err := ReadModelWithOptions(strings.NewReader(model), opts)
+
+			assert.NoError(t, err)
+		})
+	})
+
+	t.Run("AllowUnknownKeys", func(t *testing.T) {
+		json := `{"version":"1.0","x":[{"y":1}],"shapes":{"foo#S":{"type":"structure","mixins":[{"target":"foo#M"}],"traits":{"smithy.api#required":{"z":{}},"smithy.api#length":{"min":1,"q":2}},"members":{"a":{"target":"foo#T","w":null}}}}}`
+
+		_, err := ReadModel(strings.NewReader(json))
+		require.Error(t, err)
+
+		m, err := ReadModelWithOptions(strings.NewReader(json), DecodeOptions{AllowUnknownKeys: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, Model{
+			Version: StringNode{Value: "1.0"},
+			Shapes: map[AbsShapeID]Shape{
+				"foo#S": {
+					Type: StructureType,
+					Traits: Traits{
+						RequiredTraitID: &AnnotationTrait{},
+						LengthTraitID:   &LengthTrait{Min: &Int64Node{Value: 1}},
+					},
+					Members: map[string]Member{"a": {Target: AbsShapeIDNode{Value: "foo#T"}}},
+				},
+			},
+		}, m)
+	})
+
+	t.Run("AllowUnknownShapeTypes", func(t *testing.T) {
+		json := `{"version":"1.0","shapes":{"foo#E":{"type":"enum","traits":{"smithy.api#private":{}},"members":{"A":{"target":"smithy.api#Unit"}}}}}`
+
+		_, err := ReadModel(strings.NewReader(json))
+		require.Error(t, err)
+
+		m, err := ReadModelWithOptions(strings.NewReader(json), DecodeOptions{AllowUnknownShapeTypes: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, Model{
+			Version: StringNode{Value: "1.0"},
+			Shapes: map[AbsShapeID]Shape{
+				"foo#E": {
+					Type:    ShapeType("enum"),
+					Traits:  Traits{PrivateTraitID: &AnnotationTrait{}},
+					Members: map[string]Member{"A": {Target: AbsShapeIDNode{Value: "smithy.api#Unit"}}},
+				},
+			},
+		}, m)
+	})
+}
+
+func TestDecodeOptions_Path(t *testing.T) {
+	json := "{\n  \"version\": \"1.0\",\n  \"shapes\": {\n    \"foo#S\": {\n      \"type\": \"structure\",\n      \"members\": {\n        \"m\": { \"target\": \"foo#T\" }\n      }\n    }\n  }\n}\n"
+
+	t.Run("set", func(t *testing.T) {
+		m, err := ReadModelWithOptions(strings.NewReader(json), DecodeOptions{Path: "model.json"})
+
+		require.NoError(t, err)
+		assert.Equal(t, Location{Path: "model.json", Offset: 0, Row: 1, Col: 1}, m.Location())
+		assert.Equal(t, Location{Path: "model.json", Offset: 15, Row: 2, Col: 14}, m.Version.Location())
+		s := m.Shapes["foo#S"]
+		assert.Equal(t, Location{Path: "model.json", Offset: 49, Row: 4, Col: 14}, s.Location())
+		member := s.Members["m"]
+		assert.Equal(t, Location{Path: "model.json", Offset: 110, Row: 7, Col: 14}, member.Location())
+		assert.Equal(t, Location{Path: "model.json", Offset: 122, Row: 7, Col: 26}, member.Target.Location())
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		m, err := ReadModelWithOptions(strings.NewReader(json), DecodeOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, Location{}, m.Location())
+	})
+}
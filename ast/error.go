@@ -1,10 +1,11 @@
 package ast
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 )
 
 // JSONError describes an error in the JSON representation of the Smithy
@@ -12,23 +13,33 @@ import (
 type JSONError struct {
 	msg    string // description of error
 	Offset int64  // error occurred after reading Offset bytes
+	// Path is a JSON Pointer (RFC 6901) to the JSON value in which the
+	// error occurred, for example
+	// "/shapes/com.foo#Bar/members/baz/traits/smithy.api#length/min".
+	// For errors concerning an object key, Path points to the object
+	// containing the key. The empty string points to the whole model.
+	Path string
 }
 
-func isNonSyntaxError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	_, ok := err.(*json.SyntaxError)
-	return ok
+// isFatalError reports whether an error returned while reading a JSON
+// token must stop decoding. A premature end of input is not fatal,
+// since the caller can describe what it expected to find with a
+// *JSONError, but any other error, such as a JSON syntax error, a read
+// error, or a *LimitError, is.
+func isFatalError(err error) bool {
+	return err != nil && err != io.EOF && err != io.ErrUnexpectedEOF
 }
 
 func jsonError(msg string, offset int64) error {
-	return &JSONError{prefix + msg, offset}
+	return &JSONError{msg: prefix + msg, Offset: offset}
 }
 
 func (err *JSONError) Error() string {
-	return err.msg + " at offset " + strconv.FormatInt(err.Offset, 10)
+	s := err.msg + " at offset " + strconv.FormatInt(err.Offset, 10)
+	if err.Path != "" {
+		s += " in " + err.Path
+	}
+	return s
 }
 
 func (err *JSONError) Is(other error) bool {
@@ -39,10 +50,75 @@ func (err *JSONError) Is(other error) bool {
 	return false
 }
 
-func unsupportedKeyError(name, key string, offset int64) error {
-	return jsonError("unsupported key "+strconv.Quote(key)+" in "+name, offset)
+// LimitError indicates that decoding stopped because the JSON AST
+// exceeded one of the resource limits set in DecodeOptions. Decoding
+// never recovers from a LimitError, even in recover mode.
+type LimitError struct {
+	JSONError
+	Limit string // name of the DecodeOptions field setting the limit
+}
+
+func limitError(d *decoder, limit string, max int64, offset int64) error {
+	return &LimitError{
+		JSONError: JSONError{
+			msg:    prefix + "exceeded " + limit + " limit of " + strconv.FormatInt(max, 10),
+			Offset: offset,
+			Path:   pointer(d.path),
+		},
+		Limit: limit,
+	}
+}
+
+// Unwrap returns the underlying *JSONError.
+func (err *LimitError) Unwrap() error {
+	return &err.JSONError
+}
+
+// errInputTooLarge is returned by a limitReader when its input exceeds
+// the limit.
+var errInputTooLarge = errors.New(prefix + "input too large")
+
+// JSONErrors is returned when decoding in recover mode encounters one
+// or more errors in the JSON AST. It contains each error encountered,
+// in the order they occurred in the input.
+type JSONErrors []JSONError
+
+func (err JSONErrors) Error() string {
+	if len(err) == 1 {
+		return err[0].Error()
+	}
+
+	return prefix + strconv.Itoa(len(err)) + " JSON errors"
+}
+
+// Unwrap returns each error contained in err, so that errors.Is and
+// errors.As can match individual errors.
+func (err JSONErrors) Unwrap() []error {
+	errs := make([]error, len(err))
+	for i := range err {
+		errs[i] = &err[i]
+	}
+	return errs
+}
+
+func unsupportedKeyError(d *decoder, name, key string, offset int64) error {
+	return d.keyError("unsupported key "+strconv.Quote(key)+" in "+name, offset)
 }
 
+// pointer returns a JSON Pointer (RFC 6901) which refers to the JSON
+// value reached by following the given object keys and array indices
+// from the root of the document.
+func pointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		_ = b.WriteByte('/')
+		_, _ = pointerEscaper.WriteString(&b, token)
+	}
+	return b.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
 type MergeConflictError struct {
 	msg           string
 	First, Second Node
@@ -62,6 +138,16 @@ func (err MergeConflictsError) Error() string {
 	return prefix + strconv.Itoa(len(err)) + " merge conflicts"
 }
 
+// Unwrap returns each conflict contained in err, so that errors.Is and
+// errors.As can match individual conflicts.
+func (err MergeConflictsError) Unwrap() []error {
+	errs := make([]error, len(err))
+	for i := range err {
+		errs[i] = &err[i]
+	}
+	return errs
+}
+
 func newError(text string) error {
 	return errors.New(prefix + text)
 }
@@ -9,14 +9,18 @@ type Member struct {
 }
 
 func (m *Member) Decode(dec *json.Decoder) error {
-	return decodeObject(dec, "member", func(dec2 *json.Decoder, key string, keyOffset int64) error {
+	return m.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (m *Member) decode(d *decoder) error {
+	return decodeObject(d, "member", func(d *decoder, key string, keyOffset int64) error {
 		switch key {
 		case "target":
-			return m.Target.Decode(dec2)
+			return decodeNode(d, &m.Target)
 		case "traits":
-			return m.Traits.decode(dec2)
+			return m.Traits.decode(d)
 		default:
-			return unsupportedKeyError("member", key, keyOffset)
+			return d.unknownKey("member", key, keyOffset)
 		}
 	})
 }
@@ -3,6 +3,12 @@ package ast
 import (
 	"encoding/json"
 	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogama/smithy-ast/internal/jsonequal"
 )
 
 // Model represents the root of a Smithy model's abstract syntax tree in
@@ -15,23 +21,30 @@ type Model struct {
 }
 
 func (m *Model) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
+	return m.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (m *Model) decode(d *decoder) error {
+	offset := d.InputOffset()
 	version := false
 
-	err := decodeObject(dec, "model", func(dec2 *json.Decoder, key string, keyOffset int64) error {
-		switch key {
-		case "version":
-			version = true
-			return m.Version.Decode(dec2)
-		case "metadata":
-			m.Metadata = make(map[string]InterfaceNode)
-			return decodeToMap(dec2, "metadata", m.Metadata)
-		case "shapes":
-			m.Shapes = make(map[AbsShapeID]Shape)
-			return decodeToMap(dec2, "shapes", m.Shapes)
-		default:
-			return unsupportedKeyError("model", key, keyOffset)
-		}
+	err := decodeObject(d, "model", func(d *decoder, key string, keyOffset int64) error {
+		_, err2 := d.recoverable(func() error {
+			switch key {
+			case "version":
+				version = true
+				return decodeNode(d, &m.Version)
+			case "metadata":
+				m.Metadata = make(map[string]InterfaceNode)
+				return decodeToMap(d, "metadata", m.Metadata)
+			case "shapes":
+				m.Shapes = make(map[AbsShapeID]Shape)
+				return m.decodeShapes(d)
+			default:
+				return d.unknownKey("model", key, keyOffset)
+			}
+		})
+		return err2
 	})
 
 	if err != nil {
@@ -39,12 +52,29 @@ func (m *Model) Decode(dec *json.Decoder) error {
 	}
 
 	if !version {
-		return jsonError("missing version", offset)
+		return d.record(d.jsonError("missing version", offset))
 	}
 
 	return nil
 }
 
+func (m *Model) decodeShapes(d *decoder) error {
+	return decodeObject(d, "shapes", func(d *decoder, key string, _ int64) error {
+		err := d.countShape()
+		if err != nil {
+			return err
+		}
+		var s Shape
+		ok, err := d.recoverable(func() error {
+			return decodeNode(d, &s)
+		})
+		if ok {
+			m.Shapes[AbsShapeID(key)] = s
+		}
+		return err
+	})
+}
+
 func (m *Model) UnmarshalJSON(data []byte) error {
 	return unmarshalJSON(data, m)
 }
@@ -56,8 +86,85 @@ func (m *Model) UnmarshalJSON(data []byte) error {
 // returned error has type *JSONError. Other errors may also be
 // returned, e.g. for input/output errors with the reader.
 func ReadModel(r io.Reader) (m Model, err error) {
-	dec := json.NewDecoder(r)
-	err = m.Decode(dec)
+	return ReadModelWithOptions(r, DecodeOptions{})
+}
+
+// DecodeOptions control how a Model is decoded from its JSON AST. The
+// zero value gives the same strict decoding as ReadModel, with no
+// resource limits.
+//
+// When decoding a JSON AST from an untrusted source, set the resource
+// limits MaxBytes, MaxDepth, MaxShapes, and MaxMembers. If decoding
+// exceeds any of them, it stops with a *LimitError.
+type DecodeOptions struct {
+	// Recover enables recover mode. In recover mode, decoding does not
+	// stop at the first error in a shape, member, or trait. Instead,
+	// the error is recorded, the offending shape, member, or trait is
+	// left out of the decoded model, and decoding carries on.
+	//
+	// Only errors of type *JSONError can be recovered from. Other
+	// errors, such as JSON syntax errors or input/output errors with
+	// the reader, always stop decoding.
+	Recover bool
+
+	// MaxBytes, if positive, is the maximum number of bytes of input
+	// that may be read.
+	MaxBytes int64
+
+	// MaxDepth, if positive, is the maximum nesting depth of JSON
+	// objects and arrays, including within metadata and trait values.
+	// The model object itself is at depth 1.
+	MaxDepth int
+
+	// MaxShapes, if positive, is the maximum number of shapes the
+	// model may contain.
+	MaxShapes int
+
+	// MaxMembers, if positive, is the maximum number of members the
+	// model may contain, counted across all shapes. The member of a
+	// list or set and the key and value of a map count as members.
+	MaxMembers int
+
+	// AllowUnknownKeys, if true, causes object keys which are not part
+	// of the JSON AST to be ignored. By default they are an error.
+	AllowUnknownKeys bool
+
+	// Path, if not empty, names the file or other source from which
+	// the JSON AST is read. When Path is set, the Location of each
+	// decoded node records Path and the position of the node's JSON
+	// value within the input.
+	Path string
+
+	// AllowUnknownShapeTypes, if true, allows shapes whose type is not
+	// one of the ShapeTypes. Such shapes are decoded with their type,
+	// traits and members, and any other shape fields are ignored. By
+	// default they are an error.
+	AllowUnknownShapeTypes bool
+}
+
+// ReadModelWithOptions reads a Model from an io.Reader using the given
+// decoding options.
+//
+// Without recover mode, ReadModelWithOptions returns errors in the
+// same way as ReadModel. In recover mode, if any errors were recorded,
+// the returned error has type JSONErrors and contains every error
+// recorded, and the returned model contains everything that was
+// decoded successfully.
+func ReadModelWithOptions(r io.Reader, opts DecodeOptions) (m Model, err error) {
+	if opts.MaxBytes > 0 {
+		r = &limitReader{r: r, n: opts.MaxBytes}
+	}
+	var lr *lineReader
+	if opts.Path != "" {
+		lr = &lineReader{r: r}
+		r = lr
+	}
+	d := newDecoder(json.NewDecoder(r), opts)
+	d.lines = lr
+	err = decodeNode(d, &m)
+	if err == nil && len(d.errs) > 0 {
+		err = d.errs
+	}
 	return
 }
 
@@ -89,6 +196,7 @@ func MergeModels(m ...Model) (Model, error) {
 		err = append(err, mergeMetadata(&r, &m[i])...)
 		err = append(err, mergeShapes(&r, &m[i])...)
 	}
+	err = append(err, applyMemberTraits(&r)...)
 
 	if len(err) > 0 {
 		return r, err
@@ -97,14 +205,288 @@ func MergeModels(m ...Model) (Model, error) {
 	return r, nil
 }
 
+// mergeVersions keeps the first version seen. Models with different
+// major versions cannot be merged.
 func mergeVersions(dst, src *Model) []MergeConflictError {
-	return nil // TODO
+	if dst.Version.Value == "" {
+		dst.Version = src.Version
+		return nil
+	}
+
+	if src.Version.Value == "" || majorVersion(dst.Version.Value) == majorVersion(src.Version.Value) {
+		return nil
+	}
+
+	first, second := dst.Version, src.Version
+	return []MergeConflictError{{
+		msg:    "incompatible versions " + strconv.Quote(first.Value) + " and " + strconv.Quote(second.Value),
+		First:  &first,
+		Second: &second,
+	}}
 }
 
+func majorVersion(version string) string {
+	i := strings.IndexByte(version, '.')
+	if i < 0 {
+		return version
+	}
+	return version[0:i]
+}
+
+// mergeMetadata adds the metadata from src to dst. If both models have
+// the same metadata key, the values are concatenated if they are both
+// arrays, and otherwise must be equal.
 func mergeMetadata(dst, src *Model) []MergeConflictError {
-	return nil // TODO
+	if len(src.Metadata) == 0 {
+		return nil
+	}
+
+	if dst.Metadata == nil {
+		dst.Metadata = make(map[string]InterfaceNode, len(src.Metadata))
+	}
+
+	keys := make([]string, 0, len(src.Metadata))
+	for key := range src.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conflicts []MergeConflictError
+	for _, key := range keys {
+		second := src.Metadata[key]
+		first, ok := dst.Metadata[key]
+		if !ok {
+			dst.Metadata[key] = second
+			continue
+		}
+
+		merged, ok := mergeLists(&first, &second)
+		if ok {
+			dst.Metadata[key] = *merged.(*InterfaceNode)
+		} else if !jsonequal.Equal(&first, &second) {
+			conflicts = append(conflicts, MergeConflictError{
+				msg:    "conflicting values for metadata key " + strconv.Quote(key),
+				First:  &first,
+				Second: &second,
+			})
+		}
+	}
+
+	return conflicts
 }
 
+// mergeShapes adds the shapes from src to dst. If both models have a
+// shape with the same ID, the shapes must be equal, unless one or both
+// of them has type ApplyType, in which case the applied traits are
+// merged onto the other shape.
 func mergeShapes(dst, src *Model) []MergeConflictError {
-	return nil // TODO
+	if len(src.Shapes) == 0 {
+		return nil
+	}
+
+	if dst.Shapes == nil {
+		dst.Shapes = make(map[AbsShapeID]Shape, len(src.Shapes))
+	}
+
+	var conflicts []MergeConflictError
+	for _, id := range sortedShapeIDs(src.Shapes) {
+		second := src.Shapes[id]
+		first, ok := dst.Shapes[id]
+		if !ok {
+			dst.Shapes[id] = second
+			continue
+		}
+
+		if first.Type == ApplyType || second.Type == ApplyType {
+			target := first
+			if first.Type == ApplyType {
+				target = second
+			}
+			var c []MergeConflictError
+			target.Traits, c = mergeTraits(id, first.Traits, second.Traits)
+			conflicts = append(conflicts, c...)
+			dst.Shapes[id] = target
+		} else if !jsonequal.Equal(&first, &second) {
+			conflicts = append(conflicts, MergeConflictError{
+				msg:    "conflicting definitions for shape " + string(id),
+				First:  &first,
+				Second: &second,
+			})
+		}
+	}
+
+	return conflicts
+}
+
+// applyMemberTraits merges the traits of shapes with type ApplyType
+// whose shape ID refers to a member onto the member, if the member
+// exists, and removes the applied shape from the model.
+func applyMemberTraits(m *Model) []MergeConflictError {
+	var conflicts []MergeConflictError
+	for _, id := range sortedShapeIDs(m.Shapes) {
+		applied := m.Shapes[id]
+		i := strings.IndexByte(string(id), '$')
+		if applied.Type != ApplyType || i < 0 {
+			continue
+		}
+
+		containerID := id[0:i]
+		container, ok := m.Shapes[containerID]
+		if !ok {
+			continue
+		}
+
+		name := string(id[i+1:])
+		var member *Member
+		switch {
+		case container.Members != nil:
+			if x, ok := container.Members[name]; ok {
+				member = &x
+			}
+		case name == "member" && (container.Type == ListType || container.Type == SetType),
+			name == "value" && container.Type == MapType:
+			member = container.Value
+		case name == "key" && container.Type == MapType:
+			member = container.Key
+		}
+		if member == nil {
+			continue
+		}
+
+		merged := *member
+		var c []MergeConflictError
+		merged.Traits, c = mergeTraits(id, member.Traits, applied.Traits)
+		conflicts = append(conflicts, c...)
+
+		switch {
+		case container.Members != nil:
+			members := make(map[string]Member, len(container.Members))
+			for k, v := range container.Members {
+				members[k] = v
+			}
+			members[name] = merged
+			container.Members = members
+		case name == "key":
+			container.Key = &merged
+		default:
+			container.Value = &merged
+		}
+		m.Shapes[containerID] = container
+		delete(m.Shapes, id)
+	}
+
+	return conflicts
+}
+
+// mergeTraits returns a new traits map containing the traits from both
+// first and second. If both contain the same trait, the values are
+// concatenated if they are both lists, and otherwise must be equal.
+func mergeTraits(id AbsShapeID, first, second Traits) (Traits, []MergeConflictError) {
+	if len(first) == 0 {
+		return second, nil
+	} else if len(second) == 0 {
+		return first, nil
+	}
+
+	merged := make(Traits, len(first)+len(second))
+	for k, v := range first {
+		merged[k] = v
+	}
+
+	traitIDs := make([]AbsShapeID, 0, len(second))
+	for traitID := range second {
+		traitIDs = append(traitIDs, traitID)
+	}
+	sort.Slice(traitIDs, func(i, j int) bool { return traitIDs[i] < traitIDs[j] })
+
+	var conflicts []MergeConflictError
+	for _, traitID := range traitIDs {
+		v := second[traitID]
+		existing, ok := merged[traitID]
+		if !ok {
+			merged[traitID] = v
+		} else if list, ok := mergeLists(existing, v); ok {
+			merged[traitID] = list
+		} else if !jsonequal.Equal(existing, v) {
+			conflicts = append(conflicts, MergeConflictError{
+				msg:    "conflicting values for trait " + string(traitID) + " on " + string(id),
+				First:  existing,
+				Second: v,
+			})
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeLists concatenates two list-valued nodes of the same type. A
+// list-valued node is either an InterfaceNode holding a JSON array or
+// a trait, such as TagsTrait, whose value is held in a slice field
+// named Items.
+func mergeLists(first, second Node) (Node, bool) {
+	if x, ok := first.(*InterfaceNode); ok {
+		y, ok := second.(*InterfaceNode)
+		if !ok {
+			return nil, false
+		}
+		a, ok1 := x.Value.([]interface{})
+		b, ok2 := y.Value.([]interface{})
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		list := make([]interface{}, 0, len(a)+len(b))
+		list = append(list, a...)
+		list = append(list, b...)
+		return &InterfaceNode{node: x.node, Value: list}, true
+	}
+
+	v1, v2 := reflect.ValueOf(first), reflect.ValueOf(second)
+	if v1.Type() != v2.Type() || v1.Kind() != reflect.Pointer || v1.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	items1, items2 := v1.Elem().FieldByName("Items"), v2.Elem().FieldByName("Items")
+	if !items1.IsValid() || items1.Kind() != reflect.Slice {
+		return nil, false
+	}
+	v := reflect.New(v1.Type().Elem())
+	v.Elem().Set(v1.Elem())
+	items := reflect.MakeSlice(items1.Type(), 0, items1.Len()+items2.Len())
+	items = reflect.AppendSlice(items, items1)
+	items = reflect.AppendSlice(items, items2)
+	v.Elem().FieldByName("Items").Set(items)
+	return v.Interface().(Node), true
+}
+
+func sortedShapeIDs(shapes map[AbsShapeID]Shape) []AbsShapeID {
+	ids := make([]AbsShapeID, 0, len(shapes))
+	for id := range shapes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// limitReader reads from an underlying reader, failing with
+// errInputTooLarge if the underlying reader has more than n bytes.
+type limitReader struct {
+	r io.Reader
+	n int64 // bytes remaining before the limit is reached
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		// At the limit, so any further input is too much.
+		var b [1]byte
+		n, err := lr.r.Read(b[:])
+		if n > 0 {
+			return 0, errInputTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > lr.n {
+		p = p[0:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestModel(t *testing.T) {
@@ -75,6 +76,38 @@ func TestModel(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "protocol test traits",
+			json: `{"version":"1.0","shapes":{"foo#Op":{"type":"operation","traits":{"smithy.test#httpResponseTests":[{"id":"A","protocol":"foo#proto","code":200,"headers":{"X-A":"1"},"body":"{}","params":{"a":1}}],"smithy.test#smokeTests":[{"id":"B","expect":{"failure":{"errorId":"foo#Err"}}}]}}}}`,
+			model: Model{
+				Version: StringNode{Value: "1.0"},
+				Shapes: map[AbsShapeID]Shape{
+					"foo#Op": {
+						Type: OperationType,
+						Traits: Traits{
+							HTTPResponseTestsTraitID: &HTTPResponseTestsTrait{
+								Items: []HTTPResponseTestsTraitItem{{
+									ID:       StringNode{Value: "A"},
+									Protocol: AbsShapeIDNode{Value: "foo#proto"},
+									Code:     Int32Node{Value: 200},
+									Headers:  map[string]StringNode{"X-A": {Value: "1"}},
+									Body:     &StringNode{Value: "{}"},
+									Params:   map[string]InterfaceNode{"a": {Value: float64(1)}},
+								}},
+							},
+							SmokeTestsTraitID: &SmokeTestsTrait{
+								Items: []SmokeTestsTraitItem{{
+									ID: StringNode{Value: "B"},
+									Expect: SmokeTestsTraitExpectation{
+										Failure: &SmokeTestsTraitFailure{ErrorID: &AbsShapeIDNode{Value: "foo#Err"}},
+									},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "error/unsupported key",
 			json: `{"foo":"bar"}`,
@@ -145,3 +178,95 @@ func TestModel(t *testing.T) {
 		// TODO. Cursory test case using a mock writer that errors out.
 	})
 }
+
+func TestMergeModels(t *testing.T) {
+	read := func(t *testing.T, s string) Model {
+		m, err := ReadModel(strings.NewReader(s))
+		require.NoError(t, err)
+		return m
+	}
+	write := func(t *testing.T, m Model) string {
+		w := bytes.Buffer{}
+		require.NoError(t, WriteModel(m, &w))
+		return strings.TrimRight(w.String(), "\n")
+	}
+
+	testCases := []struct {
+		name      string
+		json      []string
+		merged    string
+		conflicts []string
+	}{
+		{
+			name:   "one model",
+			json:   []string{`{"version":"1.0","shapes":{"foo#A":{"type":"string"}}}`},
+			merged: `{"version":"1.0","shapes":{"foo#A":{"type":"string"}}}`,
+		},
+		{
+			name: "disjoint",
+			json: []string{
+				`{"version":"1.0","metadata":{"a":1},"shapes":{"foo#A":{"type":"string"}}}`,
+				`{"version":"1.1","metadata":{"b":2},"shapes":{"foo#B":{"type":"blob"}}}`,
+			},
+			merged: `{"version":"1.0","metadata":{"a":1,"b":2},"shapes":{"foo#A":{"type":"string"},"foo#B":{"type":"blob"}}}`,
+		},
+		{
+			name: "compatible duplicates",
+			json: []string{
+				`{"version":"1.0","metadata":{"a":[1],"b":{"c":true}},"shapes":{"foo#A":{"type":"string","traits":{"smithy.api#pattern":"x"}}}}`,
+				`{"version":"1.0","metadata":{"a":[2,3],"b":{"c":true}},"shapes":{"foo#A":{"type":"string","traits":{"smithy.api#pattern":"x"}}}}`,
+			},
+			merged: `{"version":"1.0","metadata":{"a":[1,2,3],"b":{"c":true}},"shapes":{"foo#A":{"type":"string","traits":{"smithy.api#pattern":"x"}}}}`,
+		},
+		{
+			name: "apply",
+			json: []string{
+				`{"version":"1.0","shapes":{"foo#A":{"type":"apply","traits":{"smithy.api#tags":["b"]}},"foo#S$m":{"type":"apply","traits":{"smithy.api#required":{}}}}}`,
+				`{"version":"1.0","shapes":{"foo#A":{"type":"string","traits":{"smithy.api#tags":["a"]}},"foo#S":{"type":"structure","members":{"m":{"target":"foo#A"}}}}}`,
+			},
+			merged: `{"version":"1.0","shapes":{"foo#A":{"type":"string","traits":{"smithy.api#tags":["b","a"]}},"foo#S":{"type":"structure","members":{"m":{"target":"foo#A","traits":{"smithy.api#required":{}}}}}}}`,
+		},
+		{
+			name: "conflicts",
+			json: []string{
+				`{"version":"1.0","metadata":{"a":1},"shapes":{"foo#A":{"type":"string"},"foo#B":{"type":"apply","traits":{"smithy.api#pattern":"x"}}}}`,
+				`{"version":"2.0","metadata":{"a":2},"shapes":{"foo#A":{"type":"blob"},"foo#B":{"type":"string","traits":{"smithy.api#pattern":"y"}}}}`,
+			},
+			merged: `{"version":"1.0","metadata":{"a":1},"shapes":{"foo#A":{"type":"string"},"foo#B":{"type":"string","traits":{"smithy.api#pattern":"x"}}}}`,
+			conflicts: []string{
+				`ast: merge conflict: incompatible versions "1.0" and "2.0"`,
+				`ast: merge conflict: conflicting values for metadata key "a"`,
+				`ast: merge conflict: conflicting definitions for shape foo#A`,
+				`ast: merge conflict: conflicting values for trait smithy.api#pattern on foo#B`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			models := make([]Model, len(testCase.json))
+			for i := range testCase.json {
+				models[i] = read(t, testCase.json[i])
+			}
+
+			m, err := MergeModels(models...)
+
+			assert.Equal(t, testCase.merged, write(t, m))
+			if testCase.conflicts == nil {
+				assert.NoError(t, err)
+			} else {
+				require.IsType(t, MergeConflictsError{}, err)
+				conflicts := err.(MergeConflictsError)
+				actual := make([]string, len(conflicts))
+				for i := range conflicts {
+					actual[i] = conflicts[i].Error()
+				}
+				assert.Equal(t, testCase.conflicts, actual)
+			}
+
+			for i := range testCase.json {
+				assert.Equal(t, testCase.json[i], write(t, models[i]), "input model %d must not be modified", i)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"math/big"
+	"sort"
 	"strconv"
 )
 
@@ -18,6 +20,38 @@ func (loc *Location) IsEmpty() bool {
 	return *loc == Location{}
 }
 
+// lineReader reads from an underlying reader, recording the offset of
+// each line break so offsets can be converted to rows and columns.
+type lineReader struct {
+	r      io.Reader
+	n      int64   // bytes read so far
+	breaks []int64 // offset of each '\n' read so far
+}
+
+func (lr *lineReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			lr.breaks = append(lr.breaks, lr.n+int64(i))
+		}
+	}
+	lr.n += int64(n)
+	return n, err
+}
+
+// position returns the one-based row and column of an offset which
+// has already been read.
+func (lr *lineReader) position(offset int64) (row, col int) {
+	i := sort.Search(len(lr.breaks), func(i int) bool {
+		return lr.breaks[i] >= offset
+	})
+	start := int64(0)
+	if i > 0 {
+		start = lr.breaks[i-1] + 1
+	}
+	return i + 1, int(offset-start) + 1
+}
+
 type Node interface {
 	Location() Location
 	SetLocation(loc Location)
@@ -47,7 +81,78 @@ type InterfaceNode struct {
 }
 
 func (n *InterfaceNode) Decode(dec *json.Decoder) error {
-	return dec.Decode(&n.Value)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *InterfaceNode) decode(d *decoder) error {
+	v, err := decodeInterface(d)
+	if err != nil {
+		return err
+	}
+	n.Value = v
+	return nil
+}
+
+// decodeInterface decodes an arbitrary JSON value into the same Go
+// types json.Unmarshal would produce for an interface{} value.
+func decodeInterface(d *decoder) (interface{}, error) {
+	offset := d.InputOffset()
+	t, err := d.Token()
+	if isFatalError(err) {
+		return nil, err
+	}
+	switch x := t.(type) {
+	case json.Delim:
+		if x == '{' {
+			obj := make(map[string]interface{})
+			for d.More() {
+				t, err = d.Token()
+				if isFatalError(err) {
+					return nil, err
+				}
+				key, _ := t.(string)
+				d.path = append(d.path, key)
+				obj[key], err = decodeInterface(d)
+				d.path = d.path[:len(d.path)-1]
+				if err != nil {
+					return nil, err
+				}
+			}
+			_, err = d.Token()
+			if isFatalError(err) {
+				return nil, err
+			}
+			return obj, nil
+		} else if x == '[' {
+			arr := make([]interface{}, 0)
+			for i := 0; d.More(); i++ {
+				d.path = append(d.path, strconv.Itoa(i))
+				var v interface{}
+				v, err = decodeInterface(d)
+				d.path = d.path[:len(d.path)-1]
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+			_, err = d.Token()
+			if isFatalError(err) {
+				return nil, err
+			}
+			return arr, nil
+		}
+	case json.Number:
+		f, err2 := strconv.ParseFloat(string(x), 64)
+		if err2 != nil {
+			return nil, d.jsonError("invalid number "+string(x), offset)
+		}
+		return f, nil
+	case string, bool, float64, nil:
+		if t != nil || err == nil {
+			return t, nil
+		}
+	}
+	return nil, d.jsonError("expected value", offset)
 }
 
 func (n *InterfaceNode) UnmarshalJSON(data []byte) error {
@@ -64,16 +169,20 @@ type StringNode struct {
 }
 
 func (n *StringNode) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
-	t, err := dec.Token()
-	if isNonSyntaxError(err) {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *StringNode) decode(d *decoder) error {
+	offset := d.InputOffset()
+	t, err := d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	if s, ok := t.(string); ok {
 		n.Value = s
 		return nil
 	}
-	return jsonError("expected string", offset)
+	return d.jsonError("expected string", offset)
 }
 
 func (n *StringNode) UnmarshalJSON(data []byte) error {
@@ -90,16 +199,20 @@ type BoolNode struct {
 }
 
 func (n *BoolNode) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
-	t, err := dec.Token()
-	if isNonSyntaxError(err) {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *BoolNode) decode(d *decoder) error {
+	offset := d.InputOffset()
+	t, err := d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	if b, ok := t.(bool); ok {
 		n.Value = b
 		return nil
 	}
-	return jsonError("expected boolean", offset)
+	return d.jsonError("expected boolean", offset)
 }
 
 func (n *BoolNode) UnmarshalJSON(data []byte) error {
@@ -116,7 +229,11 @@ type Int32Node struct {
 }
 
 func (n *Int32Node) Decode(dec *json.Decoder) error {
-	return decodeNumber(dec, func(s string) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *Int32Node) decode(d *decoder) error {
+	return decodeNumber(d, func(s string) error {
 		i, err := strconv.ParseInt(s, 10, 32)
 		if err != nil {
 			return err
@@ -140,7 +257,11 @@ type Int64Node struct {
 }
 
 func (n *Int64Node) Decode(dec *json.Decoder) error {
-	return decodeNumber(dec, func(s string) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *Int64Node) decode(d *decoder) error {
+	return decodeNumber(d, func(s string) error {
 		i, err := strconv.ParseInt(s, 10, 64)
 		if err != nil {
 			return err
@@ -164,7 +285,11 @@ type BigFloatNode struct {
 }
 
 func (n *BigFloatNode) Decode(dec *json.Decoder) error {
-	return decodeNumber(dec, func(s string) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *BigFloatNode) decode(d *decoder) error {
+	return decodeNumber(d, func(s string) error {
 		return n.Value.UnmarshalText([]byte(s))
 	})
 }
@@ -174,5 +299,5 @@ func (n *BigFloatNode) UnmarshalJSON(data []byte) error {
 }
 
 func (n BigFloatNode) MarshalJSON() ([]byte, error) {
-	return json.Marshal(n.Value)
+	return []byte(n.Value.Text('g', -1)), nil
 }
@@ -3,7 +3,6 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
-	"strconv"
 	"strings"
 )
 
@@ -24,16 +23,17 @@ func (id *AbsShapeID) Name() string {
 	s = s[i+1:]
 	i = strings.IndexByte(s, '$')
 	if i < 0 {
-		return ""
+		return s
 	}
 	return s[0:i]
 }
 
 func (id *AbsShapeID) Member() string {
 	s := string(*id)
-	i := strings.IndexByte(s, '#')
-	s = s[i+1:]
-	i = strings.IndexByte(s, '$')
+	i := strings.IndexByte(s, '$')
+	if i < 0 {
+		return ""
+	}
 	return s[i+1:]
 }
 
@@ -43,16 +43,20 @@ type AbsShapeIDNode struct {
 }
 
 func (n *AbsShapeIDNode) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
-	t, err := dec.Token()
-	if isNonSyntaxError(err) {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *AbsShapeIDNode) decode(d *decoder) error {
+	offset := d.InputOffset()
+	t, err := d.Token()
+	if isFatalError(err) {
 		return err
 	}
 	if s, ok := t.(string); ok {
 		n.Value = AbsShapeID(s)
 		return nil
 	}
-	return jsonError("expected string [absolute shape ID]", offset)
+	return d.jsonError("expected string [absolute shape ID]", offset)
 }
 
 func (n AbsShapeIDNode) MarshalJSON() ([]byte, error) {
@@ -69,9 +73,9 @@ func (n *AbsShapeIDNode) UnmarshalJSON(data []byte) error {
 	return unmarshalJSON(data, n)
 }
 
-func decodeAbsShapeIDNodeTo(dec *json.Decoder, dst **AbsShapeIDNode) error {
+func decodeAbsShapeIDNodeTo(d *decoder, dst **AbsShapeIDNode) error {
 	var id AbsShapeIDNode
-	err := id.Decode(dec)
+	err := decodeNode(d, &id)
 	if err != nil {
 		return err
 	}
@@ -79,19 +83,16 @@ func decodeAbsShapeIDNodeTo(dec *json.Decoder, dst **AbsShapeIDNode) error {
 	return nil
 }
 
-func decodeAbsShapeIDSliceTo(dec *json.Decoder, name string, dst *[]AbsShapeIDNode) error {
+func decodeAbsShapeIDSliceTo(d *decoder, name string, dst *[]AbsShapeIDNode) error {
 	ids := make([]AbsShapeIDNode, 0)
-	err := decodeArray(dec, name, func(dec2 *json.Decoder, index int) error {
+	err := decodeArray(d, name, func(d *decoder, _ int) error {
 		var id AbsShapeIDNode
-		err2 := id.Decode(dec2)
-		if err2 == nil {
-			ids = append(ids, id)
-			return nil
+		err2 := decodeNode(d, &id)
+		if err2 != nil {
+			return err2
 		}
-		if modelErr, ok := err2.(*JSONError); ok {
-			modelErr.msg += " in " + name + " [index " + strconv.Itoa(index) + "]"
-		}
-		return err2
+		ids = append(ids, id)
+		return nil
 	})
 	if err != nil {
 		return err
@@ -1 +1,28 @@
 package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsShapeID(t *testing.T) {
+	testCases := []struct {
+		id        AbsShapeID
+		namespace string
+		name      string
+		member    string
+	}{
+		{id: "foo#A", namespace: "foo", name: "A"},
+		{id: "foo.bar#A$b", namespace: "foo.bar", name: "A", member: "b"},
+		{id: "A", name: "A"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(string(testCase.id), func(t *testing.T) {
+			assert.Equal(t, testCase.namespace, testCase.id.Namespace())
+			assert.Equal(t, testCase.name, testCase.id.Name())
+			assert.Equal(t, testCase.member, testCase.id.Member())
+		})
+	}
+}
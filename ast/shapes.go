@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"sort"
 	"strconv"
 )
 
@@ -19,7 +20,11 @@ type Shape struct {
 }
 
 func (s *Shape) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
+	return s.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (s *Shape) decode(d *decoder) error {
+	offset := d.InputOffset()
 
 	var t *ShapeType
 	var traits Traits
@@ -28,33 +33,37 @@ func (s *Shape) Decode(dec *json.Decoder) error {
 	// Field decode phase. We have to buffer the decoded members
 	// because ordering of object keys is not guaranteed in JSON, and
 	// therefore the "type" member might be at the end.
-	err := decodeObject(dec, "shape", func(dec2 *json.Decoder, key string, keyOffset int64) error {
+	err := decodeObject(d, "shape", func(d *decoder, key string, keyOffset int64) error {
 		switch key {
 		case "type":
-			tok, err2 := dec2.Token()
-			if isNonSyntaxError(err2) {
+			typeOffset := d.InputOffset()
+			tok, err2 := d.Token()
+			if isFatalError(err2) {
 				return err2
 			}
 			if s2, ok := tok.(string); ok {
-				if !ShapeTypes[ShapeType(s2)] {
-					return jsonError("unrecognized shape type: "+strconv.Quote(s2), offset)
+				if !ShapeTypes[ShapeType(s2)] && !d.opts.AllowUnknownShapeTypes {
+					return d.jsonError("unrecognized shape type: "+strconv.Quote(s2), typeOffset)
 				}
 				x := ShapeType(s2)
 				t = &x
 				return nil
 			}
-			return jsonError("expected string [shape type]", offset)
+			return d.jsonError("expected string [shape type]", typeOffset)
 		case "traits":
-			err2 := traits.decode(dec2)
+			err2 := traits.decode(d)
 			if err2 != nil {
 				return err2
 			}
 		default:
 			f, ok := shapeFields[key]
 			if !ok {
-				return jsonError("unrecognized shape key: "+strconv.Quote(key), keyOffset)
+				if d.opts.AllowUnknownKeys {
+					return d.unknownKey("shape", key, keyOffset)
+				}
+				return d.keyError("unrecognized shape key: "+strconv.Quote(key), keyOffset)
 			}
-			err2 := f.decodeFunc(dec2, &buf)
+			err2 := f.decodeFunc(d, &buf)
 			if err2 != nil {
 				return err2
 			}
@@ -70,12 +79,25 @@ func (s *Shape) Decode(dec *json.Decoder) error {
 
 	// Validate that a shape type was received.
 	if t == nil {
-		return jsonError("shape is missing type field", offset)
+		return d.jsonError("shape is missing type field", offset)
+	}
+
+	// Shapes of unknown type, if allowed, only keep the fields which
+	// hold members.
+	known := ShapeTypes[*t]
+	if !known {
+		fields := buf.fields[:0]
+		for i := range buf.fields {
+			if buf.fields[i].members {
+				fields = append(fields, buf.fields[i])
+			}
+		}
+		buf.fields = fields
 	}
 
 	// Validate that all shape fields decoded are valid members of the
 	// shape type specified.
-	for i := range buf.fields {
+	for i := 0; known && i < len(buf.fields); i++ {
 		found := false
 		for j := range buf.fields[i].types {
 			if buf.fields[i].types[j] == *t {
@@ -84,7 +106,7 @@ func (s *Shape) Decode(dec *json.Decoder) error {
 			}
 		}
 		if !found {
-			return jsonError("shape of type "+string(*t)+" contains unsupported field "+strconv.Quote(buf.fields[i].name), offset)
+			return d.jsonError("shape of type "+string(*t)+" contains unsupported field "+strconv.Quote(buf.fields[i].name), offset)
 		}
 	}
 
@@ -172,6 +194,37 @@ func (s *Shape) UnmarshalJSON(data []byte) error {
 	return unmarshalJSON(data, s)
 }
 
+// MemberNames returns the names of the members of a structure or union
+// in sorted order.
+func (s Shape) MemberNames() []string {
+	names := make([]string, 0, len(s.Members))
+	for name := range s.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MemberOrder returns the names of the members of a structure or union
+// in the order they appear in the model. Members whose location is known
+// come first, ordered by path and offset; the others, and any at the same
+// position, follow in sorted order.
+func (s Shape) MemberOrder() []string {
+	names := s.MemberNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		mi, mj := s.Members[names[i]], s.Members[names[j]]
+		a, b := mi.Location(), mj.Location()
+		if a.IsEmpty() || b.IsEmpty() {
+			return !a.IsEmpty() && b.IsEmpty()
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Offset < b.Offset
+	})
+	return names
+}
+
 func (s *Shape) service() *Service {
 	if s.Service == nil {
 		s.Service = &Service{}
@@ -220,7 +273,7 @@ type Resource struct {
 	List                 *AbsShapeIDNode           `json:"list,omitempty"`
 	Operations           []AbsShapeIDNode          `json:"operations,omitempty"`
 	CollectionOperations []AbsShapeIDNode          `json:"collectionOperations,omitempty"`
-	Resources            []AbsShapeIDNode          `json:"resources ,omitempty"`
+	Resources            []AbsShapeIDNode          `json:"resources,omitempty"`
 }
 
 type Operation struct {
@@ -232,60 +285,61 @@ type Operation struct {
 type shapeField struct {
 	name       string
 	types      []ShapeType
+	members    bool // whether the field holds members
 	storeFunc  func(t ShapeType, src *shapeBuffer, dst *Shape)
-	decodeFunc func(dec *json.Decoder, dst *shapeBuffer) error
+	decodeFunc func(d *decoder, dst *shapeBuffer) error
 }
 
 var shapeFields = map[string]shapeField{
 	"member": {
-		name:  "member",
-		types: []ShapeType{ListType, SetType},
+		name:    "member",
+		types:   []ShapeType{ListType, SetType},
+		members: true,
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.Value = src.value
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			dst.value = &Member{}
-			return dst.value.Decode(dec)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeMemberTo(d, &dst.value)
 		},
 	},
 	"key": {
-		name:  "key",
-		types: []ShapeType{MapType},
+		name:    "key",
+		types:   []ShapeType{MapType},
+		members: true,
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.Key = src.key
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			dst.key = &Member{}
-			return dst.key.Decode(dec)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeMemberTo(d, &dst.key)
 		},
 	},
 	"value": {
-		name:  "value",
-		types: []ShapeType{MapType},
+		name:    "value",
+		types:   []ShapeType{MapType},
+		members: true,
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.Value = src.value
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			dst.value = &Member{}
-			return dst.value.Decode(dec)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeMemberTo(d, &dst.value)
 		},
 	},
 	"members": {
-		name:  "members",
-		types: []ShapeType{StructureType, UnionType},
+		name:    "members",
+		types:   []ShapeType{StructureType, UnionType},
+		members: true,
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.Members = src.members
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
 			dst.members = make(map[string]Member)
-			return decodeObject(dec, "structure/union members", func(dec2 *json.Decoder, key string, offset int64) error {
-				var m Member
-				err := m.Decode(dec2)
-				if err != nil {
-					return err
+			return decodeObject(d, "structure/union members", func(d *decoder, key string, _ int64) error {
+				var m *Member
+				err := decodeMemberTo(d, &m)
+				if m != nil {
+					dst.members[key] = *m
 				}
-				dst.members[key] = m
-				return nil
+				return err
 			})
 		},
 	},
@@ -295,8 +349,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.service().Version = src.version
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return dst.version.Decode(dec)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeNode(d, &dst.version)
 		},
 	},
 	"operations": {
@@ -312,8 +366,8 @@ var shapeFields = map[string]shapeField{
 			}
 			*o = src.operations
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDSliceTo(dec, "operations", &dst.operations)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDSliceTo(d, "operations", &dst.operations)
 		},
 	},
 	"resources": {
@@ -329,8 +383,8 @@ var shapeFields = map[string]shapeField{
 			}
 			*o = src.resources
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDSliceTo(dec, "resources", &dst.resources)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDSliceTo(d, "resources", &dst.resources)
 		},
 	},
 	"errors": {
@@ -346,8 +400,8 @@ var shapeFields = map[string]shapeField{
 			}
 			*o = src.errors
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDSliceTo(dec, "errors", &dst.errors)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDSliceTo(d, "errors", &dst.errors)
 		},
 	},
 	"rename": {
@@ -356,8 +410,9 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.service().Rename = src.rename
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeToMap(dec, "rename", &dst.rename)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			dst.rename = make(map[AbsShapeID]StringNode)
+			return decodeToMap(d, "rename", dst.rename)
 		},
 	},
 	"identifiers": {
@@ -366,8 +421,9 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Identifiers = src.identifiers
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeToMap(dec, "identifiers", &dst.identifiers)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			dst.identifiers = make(map[string]AbsShapeIDNode)
+			return decodeToMap(d, "identifiers", dst.identifiers)
 		},
 	},
 	"create": {
@@ -376,8 +432,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Create = src.create
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.create)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.create)
 		},
 	},
 	"put": {
@@ -386,8 +442,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Put = src.put
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.put)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.put)
 		},
 	},
 	"read": {
@@ -396,8 +452,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Read = src.read
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.read)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.read)
 		},
 	},
 	"update": {
@@ -406,8 +462,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Update = src.update
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.update)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.update)
 		},
 	},
 	"delete": {
@@ -416,8 +472,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().Delete = src.delete
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.delete)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.delete)
 		},
 	},
 	"list": {
@@ -426,8 +482,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().List = src.list
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.list)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.list)
 		},
 	},
 	"collectionOperations": {
@@ -436,8 +492,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.resource().CollectionOperations = src.collectionOperations
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDSliceTo(dec, "collection operations", &dst.collectionOperations)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDSliceTo(d, "collection operations", &dst.collectionOperations)
 		},
 	},
 	"input": {
@@ -446,8 +502,8 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.operation().Input = src.input
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.input)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.input)
 		},
 	},
 	"output": {
@@ -456,12 +512,31 @@ var shapeFields = map[string]shapeField{
 		storeFunc: func(_ ShapeType, src *shapeBuffer, dst *Shape) {
 			dst.operation().Output = src.output
 		},
-		decodeFunc: func(dec *json.Decoder, dst *shapeBuffer) error {
-			return decodeAbsShapeIDNodeTo(dec, &dst.output)
+		decodeFunc: func(d *decoder, dst *shapeBuffer) error {
+			return decodeAbsShapeIDNodeTo(d, &dst.output)
 		},
 	},
 }
 
+// decodeMemberTo decodes a member. If the member is decoded
+// successfully, it is stored in dst. Members are recoverable: in
+// recover mode, a member which fails to decode is left out of its
+// shape.
+func decodeMemberTo(d *decoder, dst **Member) error {
+	err := d.countMember()
+	if err != nil {
+		return err
+	}
+	var m Member
+	ok, err := d.recoverable(func() error {
+		return decodeNode(d, &m)
+	})
+	if ok {
+		*dst = &m
+	}
+	return err
+}
+
 type shapeBuffer struct {
 	// Some fields can be multiple shape types.
 	// Want a map shapetype[bool], because then when we find out the
@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShape_MemberNames(t *testing.T) {
+	s := Shape{Type: StructureType, Members: map[string]Member{"b": {}, "c": {}, "a": {}}}
+
+	assert.Equal(t, []string{"a", "b", "c"}, s.MemberNames())
+	assert.Empty(t, Shape{Type: StringType}.MemberNames())
+}
+
+func TestShape_MemberOrder(t *testing.T) {
+	at := func(path string, offset int) Member {
+		var m Member
+		if path != "" {
+			m.SetLocation(Location{Path: path, Offset: offset})
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name    string
+		members map[string]Member
+		order   []string
+	}{
+		{
+			name:    "no locations",
+			members: map[string]Member{"b": {}, "c": {}, "a": {}},
+			order:   []string{"a", "b", "c"},
+		},
+		{
+			name:    "offsets",
+			members: map[string]Member{"a": at("x.json", 30), "b": at("x.json", 10), "c": at("x.json", 20)},
+			order:   []string{"b", "c", "a"},
+		},
+		{
+			name:    "some locations",
+			members: map[string]Member{"a": at("x.json", 10), "b": at("", 0), "c": at("x.json", 5), "d": at("", 0)},
+			order:   []string{"c", "a", "b", "d"},
+		},
+		{
+			name:    "paths",
+			members: map[string]Member{"a": at("y.json", 1), "b": at("x.json", 9), "c": at("x.json", 9)},
+			order:   []string{"b", "c", "a"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := Shape{Type: StructureType, Members: testCase.members}
+
+			assert.Equal(t, testCase.order, s.MemberOrder())
+		})
+	}
+}
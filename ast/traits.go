@@ -83,13 +83,17 @@ const (
 
 	EndpointTraitID  AbsShapeID = "smithy.api#endpoint"
 	HostLabelTraitID AbsShapeID = "smithy.api#hostLabel"
+
+	HTTPRequestTestsTraitID  AbsShapeID = "smithy.test#httpRequestTests"
+	HTTPResponseTestsTraitID AbsShapeID = "smithy.test#httpResponseTests"
+	SmokeTestsTraitID        AbsShapeID = "smithy.test#smokeTests"
 )
 
 type Traits map[AbsShapeID]Node
 
-func (t *Traits) decode(dec *json.Decoder) error {
+func (t *Traits) decode(d *decoder) error {
 	t2 := make(Traits)
-	err := decodeObject(dec, "traits map", func(dec2 *json.Decoder, key string, keyOffset int64) error {
+	err := decodeObject(d, "traits map", func(d *decoder, key string, keyOffset int64) error {
 		// Determine the type of the value to decode.
 		var v reflect.Value
 		if tp, ok := builtinTraits[AbsShapeID(key)]; ok {
@@ -98,14 +102,16 @@ func (t *Traits) decode(dec *json.Decoder) error {
 			v = reflect.New(reflect.TypeOf(InterfaceNode{}))
 		}
 
-		// Decode the value.
+		// Decode the value. Traits are recoverable: in recover mode, a
+		// trait which fails to decode is left out of the traits map.
 		n := v.Interface().(Node)
-		err2 := dec.Decode(n)
-		if err2 != nil {
-			return err2
+		ok, err2 := d.recoverable(func() error {
+			return decodeNode(d, n)
+		})
+		if ok {
+			t2[AbsShapeID(key)] = n
 		}
-		t2[AbsShapeID(key)] = n
-		return nil
+		return err2
 	})
 	if err != nil {
 		return err
@@ -115,8 +121,40 @@ func (t *Traits) decode(dec *json.Decoder) error {
 }
 
 func (t *Traits) UnmarshalJSON(data []byte) error {
-	dec := json.NewDecoder(bytes.NewReader(data))
-	return t.decode(dec)
+	d := newDecoder(json.NewDecoder(bytes.NewReader(data)), DecodeOptions{})
+	return t.decode(d)
+}
+
+// HasTrait reports whether t contains the trait with the given ID.
+func (t Traits) HasTrait(id AbsShapeID) bool {
+	_, ok := t[id]
+	return ok
+}
+
+// StringTrait returns the value of the trait with the given ID if it is
+// a string trait, such as the documentation trait, and "" otherwise.
+func (t Traits) StringTrait(id AbsShapeID) string {
+	if s, ok := t[id].(*StringNode); ok {
+		return s.Value
+	}
+	return ""
+}
+
+// Merge returns the traits of t overlaid with the traits of other, such
+// as the traits of a shape overlaid with those of a member targeting it.
+// It returns t itself if other is empty, and a new map otherwise.
+func (t Traits) Merge(other Traits) Traits {
+	if len(other) == 0 {
+		return t
+	}
+	merged := make(Traits, len(t)+len(other))
+	for id, trait := range t {
+		merged[id] = trait
+	}
+	for id, trait := range other {
+		merged[id] = trait
+	}
+	return merged
 }
 
 type AnnotationTrait struct {
@@ -124,9 +162,16 @@ type AnnotationTrait struct {
 }
 
 func (n *AnnotationTrait) Decode(dec *json.Decoder) error {
-	offset := dec.InputOffset()
-	return decodeObject(dec, "annotation trait", func(_ *json.Decoder, _ string, _ int64) error {
-		return jsonError("annotation trait must be an empty object", offset)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *AnnotationTrait) decode(d *decoder) error {
+	offset := d.InputOffset()
+	return decodeObject(d, "annotation trait", func(d *decoder, key string, keyOffset int64) error {
+		if d.opts.AllowUnknownKeys {
+			return d.unknownKey("annotation trait", key, keyOffset)
+		}
+		return d.keyError("annotation trait must be an empty object", offset)
 	})
 }
 
@@ -143,7 +188,11 @@ type TraitTrait struct {
 }
 
 func (n *TraitTrait) Decode(dec *json.Decoder) error {
-	return decodeToStructPtr(dec, "trait trait", n)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *TraitTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "trait trait", n)
 }
 
 func (n *TraitTrait) UnmarshalJSON(data []byte) error {
@@ -156,7 +205,11 @@ type SuppressionTrait struct {
 }
 
 func (n *SuppressionTrait) Decode(dec *json.Decoder) error {
-	return decodeToSlicePtr(dec, "suppression trait", &n.Items)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *SuppressionTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "suppression trait", &n.Items)
 }
 
 func (n *SuppressionTrait) UnmarshalJSON(data []byte) error {
@@ -177,7 +230,11 @@ type EnumTraitItem struct {
 }
 
 func (n *EnumTraitItem) Decode(dec *json.Decoder) error {
-	return decodeToStructPtr(dec, "enum trait item", n)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *EnumTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "enum trait item", n)
 }
 
 func (n *EnumTraitItem) UnmarshalJSON(data []byte) error {
@@ -190,7 +247,11 @@ type EnumTrait struct {
 }
 
 func (n *EnumTrait) Decode(dec *json.Decoder) error {
-	return decodeToSlicePtr(dec, "enum trait", &n.Items)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *EnumTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "enum trait", &n.Items)
 }
 
 func (n *EnumTrait) UnmarshalJSON(data []byte) error {
@@ -203,13 +264,17 @@ func (n EnumTrait) MarshalJSON() ([]byte, error) {
 
 type IDRefTrait struct {
 	node
-	FailWhenMissing BoolNode   `json:"failWhenMissing"`
-	Selector        StringNode `json:"selector"`
-	ErrorMessage    StringNode `json:"errorMessage"`
+	FailWhenMissing *BoolNode   `json:"failWhenMissing,omitempty"`
+	Selector        *StringNode `json:"selector,omitempty"`
+	ErrorMessage    *StringNode `json:"errorMessage,omitempty"`
 }
 
 func (n *IDRefTrait) Decode(dec *json.Decoder) error {
-	return decodeToStructPtr(dec, "idRef trait", n)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *IDRefTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "idRef trait", n)
 }
 
 func (n *IDRefTrait) UnmarshalJSON(data []byte) error {
@@ -223,7 +288,11 @@ type LengthTrait struct {
 }
 
 func (n *LengthTrait) Decode(dec *json.Decoder) error {
-	return decodeToStructPtr(dec, "length trait", n)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *LengthTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "length trait", n)
 }
 
 func (n *LengthTrait) UnmarshalJSON(data []byte) error {
@@ -232,12 +301,16 @@ func (n *LengthTrait) UnmarshalJSON(data []byte) error {
 
 type RangeTrait struct {
 	node
-	Min BigFloatNode `json:"min,omitempty"`
-	Max BigFloatNode `json:"max,omitempty"`
+	Min *BigFloatNode `json:"min,omitempty"`
+	Max *BigFloatNode `json:"max,omitempty"`
 }
 
 func (n *RangeTrait) Decode(dec *json.Decoder) error {
-	return decodeToStructPtr(dec, "range trait", n)
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *RangeTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "range trait", n)
 }
 
 func (n *RangeTrait) UnmarshalJSON(data []byte) error {
@@ -246,8 +319,20 @@ func (n *RangeTrait) UnmarshalJSON(data []byte) error {
 
 type DeprecatedTrait struct {
 	node
-	Message StringNode
-	Since   StringNode
+	Message *StringNode `json:"message,omitempty"`
+	Since   *StringNode `json:"since,omitempty"`
+}
+
+func (n *DeprecatedTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *DeprecatedTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "deprecated trait", n)
+}
+
+func (n *DeprecatedTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type ExamplesTrait struct {
@@ -255,70 +340,329 @@ type ExamplesTrait struct {
 	Items []ExamplesTraitItem
 }
 
+func (n *ExamplesTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ExamplesTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "examples trait", &n.Items)
+}
+
+func (n *ExamplesTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n ExamplesTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
 type ExamplesTraitItem struct {
-	Title         StringNode
-	Documentation StringNode
-	Input         map[string]InterfaceNode
-	Output        map[string]InterfaceNode
-	Error         *ExamplesTraitError
+	node
+	Title         StringNode               `json:"title"`
+	Documentation *StringNode              `json:"documentation,omitempty"`
+	Input         map[string]InterfaceNode `json:"input,omitempty"`
+	Output        map[string]InterfaceNode `json:"output,omitempty"`
+	Error         *ExamplesTraitError      `json:"error,omitempty"`
+}
+
+func (n *ExamplesTraitItem) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ExamplesTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "examples trait item", n)
+}
+
+func (n *ExamplesTraitItem) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type ExamplesTraitError struct {
-	ShapeID AbsShapeIDNode
-	Content map[string]InterfaceNode
+	node
+	ShapeID AbsShapeIDNode           `json:"shapeId"`
+	Content map[string]InterfaceNode `json:"content,omitempty"`
+}
+
+func (n *ExamplesTraitError) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ExamplesTraitError) decode(d *decoder) error {
+	return decodeToStructPtr(d, "examples trait error", n)
+}
+
+func (n *ExamplesTraitError) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type ExternalDocumentationTrait struct {
+	node
+	Items map[string]StringNode
+}
+
+func (n *ExternalDocumentationTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ExternalDocumentationTrait) decode(d *decoder) error {
+	n.Items = make(map[string]StringNode)
+	return decodeToMap(d, "externalDocumentation trait", n.Items)
+}
+
+func (n *ExternalDocumentationTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n ExternalDocumentationTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
 }
 
 type RecommendedTrait struct {
 	node
-	Reason StringNode
+	Reason *StringNode `json:"reason,omitempty"`
+}
+
+func (n *RecommendedTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *RecommendedTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "recommended trait", n)
+}
+
+func (n *RecommendedTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type TagsTrait struct {
+	node
+	Items []StringNode
+}
+
+func (n *TagsTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *TagsTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "tags trait", &n.Items)
+}
+
+func (n *TagsTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n TagsTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
 }
 
 type ProtocolDefinitionTrait struct {
 	node
-	Traits                  []AbsShapeIDNode `json:"traits"`
-	NoInlineDocumentSupport BoolNode         `json:"noInlineDocumentSupport"`
+	Traits                  []AbsShapeIDNode `json:"traits,omitempty"`
+	NoInlineDocumentSupport *BoolNode        `json:"noInlineDocumentSupport,omitempty"`
+}
+
+func (n *ProtocolDefinitionTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ProtocolDefinitionTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "protocolDefinition trait", n)
+}
+
+func (n *ProtocolDefinitionTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type AuthDefinitionTrait struct {
 	node
-	Traits []AbsShapeIDNode `json:"traits"`
+	Traits []AbsShapeIDNode `json:"traits,omitempty"`
+}
+
+func (n *AuthDefinitionTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *AuthDefinitionTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "authDefinition trait", n)
+}
+
+func (n *AuthDefinitionTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type AuthTrait struct {
+	node
+	Items []AbsShapeIDNode
+}
+
+func (n *AuthTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *AuthTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "auth trait", &n.Items)
+}
+
+func (n *AuthTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n AuthTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
+type HTTPAPIKeyAuthTrait struct {
+	node
+	Name   StringNode  `json:"name"`
+	In     StringNode  `json:"in"`
+	Scheme *StringNode `json:"scheme,omitempty"`
+}
+
+func (n *HTTPAPIKeyAuthTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPAPIKeyAuthTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "httpApiKeyAuth trait", n)
+}
+
+func (n *HTTPAPIKeyAuthTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type RetryableTrait struct {
+	node
+	Throttling *BoolNode `json:"throttling,omitempty"`
+}
+
+func (n *RetryableTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *RetryableTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "retryable trait", n)
+}
+
+func (n *RetryableTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type PaginatedTrait struct {
 	node
-	InputToken  StringNode `json:"inputToken"`
-	OutputToken StringNode `json:"outputToken"`
-	Items       StringNode `json:"items"`
-	PageSize    StringNode `json:"pageSize"`
+	InputToken  *StringNode `json:"inputToken,omitempty"`
+	OutputToken *StringNode `json:"outputToken,omitempty"`
+	Items       *StringNode `json:"items,omitempty"`
+	PageSize    *StringNode `json:"pageSize,omitempty"`
+}
+
+func (n *PaginatedTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *PaginatedTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "paginated trait", n)
+}
+
+func (n *PaginatedTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type ReferencesTrait struct {
 	node
-	Service  AbsShapeIDNode        `json:"service"`
+	Items []ReferencesTraitItem
+}
+
+func (n *ReferencesTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ReferencesTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "references trait", &n.Items)
+}
+
+func (n *ReferencesTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n ReferencesTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
+type ReferencesTraitItem struct {
+	node
+	Service  *AbsShapeIDNode       `json:"service,omitempty"`
 	Resource AbsShapeIDNode        `json:"resource"`
-	IDs      map[string]StringNode `json:"ids"`
-	Rel      StringNode            `json:"rel"`
+	IDs      map[string]StringNode `json:"ids,omitempty"`
+	Rel      *StringNode           `json:"rel,omitempty"`
+}
+
+func (n *ReferencesTraitItem) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *ReferencesTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "references trait item", n)
+}
+
+func (n *ReferencesTraitItem) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type HTTPTrait struct {
 	node
 	Method StringNode `json:"method"`
 	URI    StringNode `json:"uri"`
-	Code   Int32Node  `json:"code"` // TODO: Nodify and check node types in Smithy, it is integer so int32 https://awslabs.github.io/smithy/1.0/spec/core/model.html#trait-node-values
+	Code   *Int32Node `json:"code,omitempty"`
+}
+
+func (n *HTTPTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "http trait", n)
+}
+
+func (n *HTTPTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type CORSTrait struct {
 	node
-	Origin                   StringNode   `json:"origin"`
-	MaxAge                   Int32Node    `json:"maxAge"` // TODO: nodify
-	AdditionalAllowedHeaders []StringNode `json:"additionalAllowedHeaders"`
-	AdditionalExposedHeaders []StringNode `json:"additionalExposedHeaders"`
+	Origin                   *StringNode  `json:"origin,omitempty"`
+	MaxAge                   *Int32Node   `json:"maxAge,omitempty"`
+	AdditionalAllowedHeaders []StringNode `json:"additionalAllowedHeaders,omitempty"`
+	AdditionalExposedHeaders []StringNode `json:"additionalExposedHeaders,omitempty"`
+}
+
+func (n *CORSTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *CORSTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "cors trait", n)
+}
+
+func (n *CORSTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type XMLNamespaceTrait struct {
 	node
-	URI    StringNode `json:"uri"`
-	Prefix StringNode `json:"prefix"`
+	URI    StringNode  `json:"uri"`
+	Prefix *StringNode `json:"prefix,omitempty"`
+}
+
+func (n *XMLNamespaceTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *XMLNamespaceTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "xmlNamespace trait", n)
+}
+
+func (n *XMLNamespaceTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
 }
 
 type EndpointTrait struct {
@@ -326,6 +670,206 @@ type EndpointTrait struct {
 	HostPrefix StringNode `json:"hostPrefix"`
 }
 
+func (n *EndpointTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *EndpointTrait) decode(d *decoder) error {
+	return decodeToStructPtr(d, "endpoint trait", n)
+}
+
+func (n *EndpointTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type HTTPRequestTestsTrait struct {
+	node
+	Items []HTTPRequestTestsTraitItem
+}
+
+func (n *HTTPRequestTestsTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPRequestTestsTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "httpRequestTests trait", &n.Items)
+}
+
+func (n *HTTPRequestTestsTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n HTTPRequestTestsTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
+type HTTPRequestTestsTraitItem struct {
+	node
+	ID                 StringNode               `json:"id"`
+	Protocol           AbsShapeIDNode           `json:"protocol"`
+	Method             StringNode               `json:"method"`
+	URI                StringNode               `json:"uri"`
+	Host               *StringNode              `json:"host,omitempty"`
+	ResolvedHost       *StringNode              `json:"resolvedHost,omitempty"`
+	AuthScheme         *AbsShapeIDNode          `json:"authScheme,omitempty"`
+	QueryParams        []StringNode             `json:"queryParams,omitempty"`
+	ForbidQueryParams  []StringNode             `json:"forbidQueryParams,omitempty"`
+	RequireQueryParams []StringNode             `json:"requireQueryParams,omitempty"`
+	Headers            map[string]StringNode    `json:"headers,omitempty"`
+	ForbidHeaders      []StringNode             `json:"forbidHeaders,omitempty"`
+	RequireHeaders     []StringNode             `json:"requireHeaders,omitempty"`
+	Body               *StringNode              `json:"body,omitempty"`
+	BodyMediaType      *StringNode              `json:"bodyMediaType,omitempty"`
+	Params             map[string]InterfaceNode `json:"params,omitempty"`
+	VendorParams       map[string]InterfaceNode `json:"vendorParams,omitempty"`
+	VendorParamsShape  *AbsShapeIDNode          `json:"vendorParamsShape,omitempty"`
+	Documentation      *StringNode              `json:"documentation,omitempty"`
+	Tags               []StringNode             `json:"tags,omitempty"`
+	AppliesTo          *StringNode              `json:"appliesTo,omitempty"`
+}
+
+func (n *HTTPRequestTestsTraitItem) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPRequestTestsTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "httpRequestTests trait item", n)
+}
+
+func (n *HTTPRequestTestsTraitItem) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type HTTPResponseTestsTrait struct {
+	node
+	Items []HTTPResponseTestsTraitItem
+}
+
+func (n *HTTPResponseTestsTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPResponseTestsTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "httpResponseTests trait", &n.Items)
+}
+
+func (n *HTTPResponseTestsTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n HTTPResponseTestsTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
+type HTTPResponseTestsTraitItem struct {
+	node
+	ID                StringNode               `json:"id"`
+	Protocol          AbsShapeIDNode           `json:"protocol"`
+	Code              Int32Node                `json:"code"`
+	AuthScheme        *AbsShapeIDNode          `json:"authScheme,omitempty"`
+	Headers           map[string]StringNode    `json:"headers,omitempty"`
+	ForbidHeaders     []StringNode             `json:"forbidHeaders,omitempty"`
+	RequireHeaders    []StringNode             `json:"requireHeaders,omitempty"`
+	Body              *StringNode              `json:"body,omitempty"`
+	BodyMediaType     *StringNode              `json:"bodyMediaType,omitempty"`
+	Params            map[string]InterfaceNode `json:"params,omitempty"`
+	VendorParams      map[string]InterfaceNode `json:"vendorParams,omitempty"`
+	VendorParamsShape *AbsShapeIDNode          `json:"vendorParamsShape,omitempty"`
+	Documentation     *StringNode              `json:"documentation,omitempty"`
+	Tags              []StringNode             `json:"tags,omitempty"`
+	AppliesTo         *StringNode              `json:"appliesTo,omitempty"`
+}
+
+func (n *HTTPResponseTestsTraitItem) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *HTTPResponseTestsTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "httpResponseTests trait item", n)
+}
+
+func (n *HTTPResponseTestsTraitItem) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type SmokeTestsTrait struct {
+	node
+	Items []SmokeTestsTraitItem
+}
+
+func (n *SmokeTestsTrait) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *SmokeTestsTrait) decode(d *decoder) error {
+	return decodeToSlicePtr(d, "smokeTests trait", &n.Items)
+}
+
+func (n *SmokeTestsTrait) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+func (n SmokeTestsTrait) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Items)
+}
+
+type SmokeTestsTraitItem struct {
+	node
+	ID                StringNode                 `json:"id"`
+	Params            map[string]InterfaceNode   `json:"params,omitempty"`
+	VendorParams      map[string]InterfaceNode   `json:"vendorParams,omitempty"`
+	VendorParamsShape *AbsShapeIDNode            `json:"vendorParamsShape,omitempty"`
+	Expect            SmokeTestsTraitExpectation `json:"expect"`
+	Tags              []StringNode               `json:"tags,omitempty"`
+}
+
+func (n *SmokeTestsTraitItem) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *SmokeTestsTraitItem) decode(d *decoder) error {
+	return decodeToStructPtr(d, "smokeTests trait item", n)
+}
+
+func (n *SmokeTestsTraitItem) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type SmokeTestsTraitExpectation struct {
+	node
+	Success *AnnotationTrait        `json:"success,omitempty"`
+	Failure *SmokeTestsTraitFailure `json:"failure,omitempty"`
+}
+
+func (n *SmokeTestsTraitExpectation) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *SmokeTestsTraitExpectation) decode(d *decoder) error {
+	return decodeToStructPtr(d, "smokeTests trait expectation", n)
+}
+
+func (n *SmokeTestsTraitExpectation) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+type SmokeTestsTraitFailure struct {
+	node
+	ErrorID *AbsShapeIDNode `json:"errorId,omitempty"`
+}
+
+func (n *SmokeTestsTraitFailure) Decode(dec *json.Decoder) error {
+	return n.decode(newDecoder(dec, DecodeOptions{}))
+}
+
+func (n *SmokeTestsTraitFailure) decode(d *decoder) error {
+	return decodeToStructPtr(d, "smokeTests trait failure", n)
+}
+
+func (n *SmokeTestsTraitFailure) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
 var builtinTraits = map[AbsShapeID]reflect.Type{
 	TraitTraitID:       reflect.TypeOf(TraitTrait{}),
 	UnitTypeTraitID:    reflect.TypeOf(AnnotationTrait{}),
@@ -343,17 +887,17 @@ var builtinTraits = map[AbsShapeID]reflect.Type{
 	DeprecatedTraitID:            reflect.TypeOf(DeprecatedTrait{}),
 	DocumentationTraitID:         reflect.TypeOf(StringNode{}),
 	ExamplesTraitID:              reflect.TypeOf(ExamplesTrait{}),
-	ExternalDocumentationTraitID: reflect.TypeOf(map[string]string{}),
+	ExternalDocumentationTraitID: reflect.TypeOf(ExternalDocumentationTrait{}),
 	InternalTraitID:              reflect.TypeOf(AnnotationTrait{}),
 	RecommendedTraitID:           reflect.TypeOf(RecommendedTrait{}),
 	SensitiveTraitID:             reflect.TypeOf(AnnotationTrait{}),
 	SinceTraitID:                 reflect.TypeOf(StringNode{}),
-	TagsTraitID:                  reflect.TypeOf([]string{}),
+	TagsTraitID:                  reflect.TypeOf(TagsTrait{}),
 	TitleTraitID:                 reflect.TypeOf(StringNode{}),
 	UnstableTraitID:              reflect.TypeOf(AnnotationTrait{}),
 
 	BoxTraitID:    reflect.TypeOf(AnnotationTrait{}),
-	ErrorTraitID:  reflect.TypeOf(AnnotationTrait{}),
+	ErrorTraitID:  reflect.TypeOf(StringNode{}),
 	InputTraitID:  reflect.TypeOf(AnnotationTrait{}),
 	OutputTraitID: reflect.TypeOf(AnnotationTrait{}),
 	SparseTraitID: reflect.TypeOf(AnnotationTrait{}),
@@ -367,14 +911,14 @@ var builtinTraits = map[AbsShapeID]reflect.Type{
 	HTTPBasicAuthTraitID:  reflect.TypeOf(AnnotationTrait{}),
 	HTTPDigestAuthTraitID: reflect.TypeOf(AnnotationTrait{}),
 	HTTPBearerAuthTraitID: reflect.TypeOf(AnnotationTrait{}),
-	HTTPAPIKeyAuthTraitID: reflect.TypeOf(AnnotationTrait{}),
+	HTTPAPIKeyAuthTraitID: reflect.TypeOf(HTTPAPIKeyAuthTrait{}),
 	OptionalAuthTraitID:   reflect.TypeOf(AnnotationTrait{}),
-	AuthTraitID:           reflect.TypeOf([]string{}),
+	AuthTraitID:           reflect.TypeOf(AuthTrait{}),
 
 	IdempotencyTokenTraitID:     reflect.TypeOf(AnnotationTrait{}),
 	IdempotentTraitID:           reflect.TypeOf(AnnotationTrait{}),
 	ReadOnlyTraitID:             reflect.TypeOf(AnnotationTrait{}),
-	RetryableTraitID:            reflect.TypeOf(AnnotationTrait{}),
+	RetryableTraitID:            reflect.TypeOf(RetryableTrait{}),
 	PaginatedTraitID:            reflect.TypeOf(PaginatedTrait{}),
 	HTTPChecksumRequiredTraitID: reflect.TypeOf(AnnotationTrait{}),
 
@@ -386,7 +930,7 @@ var builtinTraits = map[AbsShapeID]reflect.Type{
 	RequiresLengthTraitID: reflect.TypeOf(AnnotationTrait{}),
 
 	HTTPTraitID:                reflect.TypeOf(HTTPTrait{}),
-	HTTPErrorTraitID:           reflect.TypeOf(uint16(0)),
+	HTTPErrorTraitID:           reflect.TypeOf(Int32Node{}),
 	HTTPHeaderTraitID:          reflect.TypeOf(StringNode{}),
 	HTTPLabelTraitID:           reflect.TypeOf(AnnotationTrait{}),
 	HTTPPayloadTraitID:         reflect.TypeOf(AnnotationTrait{}),
@@ -403,4 +947,8 @@ var builtinTraits = map[AbsShapeID]reflect.Type{
 
 	EndpointTraitID:  reflect.TypeOf(EndpointTrait{}),
 	HostLabelTraitID: reflect.TypeOf(AnnotationTrait{}),
+
+	HTTPRequestTestsTraitID:  reflect.TypeOf(HTTPRequestTestsTrait{}),
+	HTTPResponseTestsTraitID: reflect.TypeOf(HTTPResponseTestsTrait{}),
+	SmokeTestsTraitID:        reflect.TypeOf(SmokeTestsTrait{}),
 }
@@ -0,0 +1,182 @@
+package ast
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraits_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name  string
+		json  string
+		id    AbsShapeID
+		trait Node
+	}{
+		{
+			name:  "idRef",
+			json:  `{"selector":"structure"}`,
+			id:    IDRefTraitID,
+			trait: &IDRefTrait{Selector: &StringNode{Value: "structure"}},
+		},
+		{
+			name:  "range",
+			json:  `{}`,
+			id:    RangeTraitID,
+			trait: &RangeTrait{},
+		},
+		{
+			name:  "deprecated",
+			json:  `{"message":"use Bar","since":"2.0"}`,
+			id:    DeprecatedTraitID,
+			trait: &DeprecatedTrait{Message: &StringNode{Value: "use Bar"}, Since: &StringNode{Value: "2.0"}},
+		},
+		{
+			name: "examples",
+			json: `[{"title":"Get","input":{"id":"a"},"error":{"shapeId":"foo#NotFound","content":{"message":"b"}}}]`,
+			id:   ExamplesTraitID,
+			trait: &ExamplesTrait{Items: []ExamplesTraitItem{
+				{
+					Title: StringNode{Value: "Get"},
+					Input: map[string]InterfaceNode{"id": {Value: "a"}},
+					Error: &ExamplesTraitError{
+						ShapeID: AbsShapeIDNode{Value: "foo#NotFound"},
+						Content: map[string]InterfaceNode{"message": {Value: "b"}},
+					},
+				},
+			}},
+		},
+		{
+			name:  "externalDocumentation",
+			json:  `{"Homepage":"https://example.com"}`,
+			id:    ExternalDocumentationTraitID,
+			trait: &ExternalDocumentationTrait{Items: map[string]StringNode{"Homepage": {Value: "https://example.com"}}},
+		},
+		{
+			name:  "recommended",
+			json:  `{"reason":"faster"}`,
+			id:    RecommendedTraitID,
+			trait: &RecommendedTrait{Reason: &StringNode{Value: "faster"}},
+		},
+		{
+			name:  "tags",
+			json:  `["a","b"]`,
+			id:    TagsTraitID,
+			trait: &TagsTrait{Items: []StringNode{{Value: "a"}, {Value: "b"}}},
+		},
+		{
+			name:  "error",
+			json:  `"client"`,
+			id:    ErrorTraitID,
+			trait: &StringNode{Value: "client"},
+		},
+		{
+			name:  "protocolDefinition",
+			json:  `{"traits":["smithy.api#jsonName"]}`,
+			id:    ProtocolDefinitionTraitID,
+			trait: &ProtocolDefinitionTrait{Traits: []AbsShapeIDNode{{Value: JSONNameTraitID}}},
+		},
+		{
+			name:  "authDefinition",
+			json:  `{}`,
+			id:    AuthDefinitionTraitID,
+			trait: &AuthDefinitionTrait{},
+		},
+		{
+			name:  "auth",
+			json:  `["smithy.api#httpBasicAuth"]`,
+			id:    AuthTraitID,
+			trait: &AuthTrait{Items: []AbsShapeIDNode{{Value: HTTPBasicAuthTraitID}}},
+		},
+		{
+			name:  "retryable",
+			json:  `{"throttling":true}`,
+			id:    RetryableTraitID,
+			trait: &RetryableTrait{Throttling: &BoolNode{Value: true}},
+		},
+		{
+			name:  "paginated",
+			json:  `{"inputToken":"next"}`,
+			id:    PaginatedTraitID,
+			trait: &PaginatedTrait{InputToken: &StringNode{Value: "next"}},
+		},
+		{
+			name:  "references",
+			json:  `[{"resource":"foo#Thing","ids":{"id":"thingId"}}]`,
+			id:    ReferencesTraitID,
+			trait: &ReferencesTrait{Items: []ReferencesTraitItem{{Resource: AbsShapeIDNode{Value: "foo#Thing"}, IDs: map[string]StringNode{"id": {Value: "thingId"}}}}},
+		},
+		{
+			name:  "http",
+			json:  `{"method":"GET","uri":"/things"}`,
+			id:    HTTPTraitID,
+			trait: &HTTPTrait{Method: StringNode{Value: "GET"}, URI: StringNode{Value: "/things"}},
+		},
+		{
+			name:  "httpError",
+			json:  `404`,
+			id:    HTTPErrorTraitID,
+			trait: &Int32Node{Value: 404},
+		},
+		{
+			name:  "cors",
+			json:  `{"origin":"*"}`,
+			id:    CORSTraitID,
+			trait: &CORSTrait{Origin: &StringNode{Value: "*"}},
+		},
+		{
+			name:  "xmlNamespace",
+			json:  `{"uri":"https://example.com"}`,
+			id:    XMLNamespaceTraitID,
+			trait: &XMLNamespaceTrait{URI: StringNode{Value: "https://example.com"}},
+		},
+		{
+			name:  "endpoint",
+			json:  `{"hostPrefix":"{foo}."}`,
+			id:    EndpointTraitID,
+			trait: &EndpointTrait{HostPrefix: StringNode{Value: "{foo}."}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var traits Traits
+			err := json.Unmarshal([]byte(`{"`+string(testCase.id)+`":`+testCase.json+`}`), &traits)
+
+			assert.NoError(t, err)
+			assert.Equal(t, Traits{testCase.id: testCase.trait}, traits)
+		})
+	}
+}
+
+func TestTraits(t *testing.T) {
+	doc := &StringNode{Value: "docs"}
+	traits := Traits{
+		DocumentationTraitID: doc,
+		RequiredTraitID:      &AnnotationTrait{},
+	}
+
+	t.Run("HasTrait", func(t *testing.T) {
+		assert.True(t, traits.HasTrait(RequiredTraitID))
+		assert.False(t, traits.HasTrait(SensitiveTraitID))
+		assert.False(t, Traits(nil).HasTrait(RequiredTraitID))
+	})
+
+	t.Run("StringTrait", func(t *testing.T) {
+		assert.Equal(t, "docs", traits.StringTrait(DocumentationTraitID))
+		assert.Equal(t, "", traits.StringTrait(RequiredTraitID))
+		assert.Equal(t, "", traits.StringTrait(SensitiveTraitID))
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		sensitive := &AnnotationTrait{}
+		other := &StringNode{Value: "member docs"}
+
+		merged := traits.Merge(Traits{DocumentationTraitID: other, SensitiveTraitID: sensitive})
+
+		assert.Equal(t, Traits{DocumentationTraitID: other, RequiredTraitID: traits[RequiredTraitID], SensitiveTraitID: sensitive}, merged)
+		assert.Same(t, doc, traits[DocumentationTraitID])
+		assert.Len(t, traits, 2)
+		assert.Equal(t, traits, traits.Merge(nil))
+	})
+}
@@ -0,0 +1,829 @@
+// Package cborcodec serializes values to and from CBOR (RFC 8949)
+// according to the shapes of a Smithy model, following the Smithy RPC
+// v2 CBOR protocol, rpcv2Cbor.
+//
+// A Codec converts between CBOR bytes and the same generic value tree
+// as package jsoncodec. Structures and unions are maps keyed by member
+// name, blobs are byte strings, timestamps are epoch seconds tagged
+// with tag 1, bigIntegers are bignums (tags 2 and 3), bigDecimals are
+// decimal fractions (tag 4), and only sparse lists and maps may hold
+// nulls.
+package cborcodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// CBOR major types.
+const (
+	majorUnsigned byte = iota
+	majorNegative
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+// CBOR tags used by the rpcv2Cbor protocol.
+const (
+	tagEpoch           = 1
+	tagPositiveBignum  = 2
+	tagNegativeBignum  = 3
+	tagDecimalFraction = 4
+)
+
+// CBOR simple values and special additional information.
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	indefinite      = 31
+)
+
+// maxDepth limits the nesting of data items Unmarshal accepts.
+const maxDepth = 1000
+
+// A Codec serializes values of the shapes of a model to and from CBOR.
+//
+// Values have the types documented by jsoncodec.Codec, except that
+// documents cannot be serialized, since rpcv2Cbor does not support
+// them.
+type Codec struct {
+	shapes map[ast.AbsShapeID]ast.Shape
+}
+
+// New returns a Codec for the shapes of m. Shapes not in m are looked up
+// in the prelude.
+func New(m ast.Model) *Codec {
+	shapes := prelude.Shapes(m)
+	return &Codec{shapes: shapes}
+}
+
+// Marshal returns the CBOR encoding of the value v of the shape with the
+// given ID. Collections are encoded with definite lengths, and map keys
+// in sorted order. Marshal returns an error, naming the JSON Pointer
+// path of the offending value, if v does not fit the shape.
+func (c *Codec) Marshal(id ast.AbsShapeID, v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.encode(&buf, "", id, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the CBOR data into a value of the shape with the
+// given ID. It returns nil if data is the CBOR null or undefined value.
+// It returns an error, naming the JSON Pointer path of the offending
+// value, if the data is not well-formed or does not fit the shape.
+//
+// Definite and indefinite length items are accepted, as are floats of
+// any width, and integers where bigIntegers are expected. Unknown
+// structure members are ignored.
+func (c *Codec) Unmarshal(id ast.AbsShapeID, data []byte) (interface{}, error) {
+	d := decoder{data: data}
+	item, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, newErrorf("invalid data after top-level value")
+	}
+	if item == nil {
+		return nil, nil
+	}
+	return c.decode("", id, item)
+}
+
+func (c *Codec) shape(path string, target ast.AbsShapeID) (ast.Shape, error) {
+	s, ok := c.shapes[target]
+	if !ok {
+		return s, errorAt(path, "shape %s not found", target)
+	}
+	if (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value == nil ||
+		s.Type == ast.MapType && (s.Key == nil || s.Value == nil) {
+		return s, errorAt(path, "%s %s has no members", s.Type, target)
+	}
+	return s, nil
+}
+
+// encode writes the value v at path, of the shape with ID target.
+func (c *Codec) encode(buf *bytes.Buffer, path string, target ast.AbsShapeID, v interface{}) error {
+	s, err := c.shape(path, target)
+	if err != nil {
+		return err
+	}
+	v = valuetree.Unwrap(v)
+	if v == nil {
+		return errorAt(path, "expected %s, got null", s.Type)
+	}
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", s.Type, valuetree.TypeName(v))
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := valuetree.Object(v)
+		if !ok {
+			return mismatch()
+		}
+		names := make([]string, 0, len(obj))
+		for name, value := range obj {
+			if _, ok := s.Members[name]; !ok {
+				return errorAt(path, "unknown member %q of %s", name, target)
+			}
+			if valuetree.Unwrap(value) != nil {
+				names = append(names, name)
+			}
+		}
+		if s.Type == ast.UnionType && len(names) != 1 {
+			return errorAt(path, "union must have exactly one member set, got %d", len(names))
+		}
+		sort.Strings(names)
+		writeHead(buf, majorMap, uint64(len(names)))
+		for _, name := range names {
+			writeText(buf, name)
+			if err := c.encode(buf, path+"/"+valuetree.Escape(name), s.Members[name].Target.Value, obj[name]); err != nil {
+				return err
+			}
+		}
+
+	case ast.ListType, ast.SetType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || valuetree.IsBytes(v) {
+			return mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		writeHead(buf, majorArray, uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			item := valuetree.Unwrap(rv.Index(i).Interface())
+			itemPath := path + "/" + strconv.Itoa(i)
+			if item == nil && sparse {
+				buf.WriteByte(majorSimple<<5 | simpleNull)
+				continue
+			} else if item == nil {
+				return errorAt(itemPath, "null item in list which is not sparse")
+			}
+			if err := c.encode(buf, itemPath, s.Value.Target.Value, item); err != nil {
+				return err
+			}
+		}
+
+	case ast.MapType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		writeHead(buf, majorMap, uint64(len(keys)))
+		for _, key := range keys {
+			valuePath := path + "/" + valuetree.Escape(key.String())
+			if err := c.encode(buf, valuePath, s.Key.Target.Value, key.String()); err != nil {
+				return err
+			}
+			value := valuetree.Unwrap(rv.MapIndex(key).Interface())
+			if value == nil && sparse {
+				buf.WriteByte(majorSimple<<5 | simpleNull)
+				continue
+			} else if value == nil {
+				return errorAt(valuePath, "null value in map which is not sparse")
+			}
+			if err := c.encode(buf, valuePath, s.Value.Target.Value, value); err != nil {
+				return err
+			}
+		}
+
+	case ast.StringType:
+		str, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+		writeText(buf, str)
+
+	case ast.BlobType:
+		b, ok := v.([]byte)
+		if !ok {
+			return mismatch()
+		}
+		writeHead(buf, majorBytes, uint64(len(b)))
+		buf.Write(b)
+
+	case ast.BooleanType:
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+		if b {
+			buf.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			buf.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return mismatch()
+		}
+		if !f.IsInt() {
+			return errorAt(path, "expected %s, got non-integer %s", s.Type, f.Text('g', -1))
+		}
+		i, _ := f.Int(nil)
+		if bits := valuetree.IntegerBits[s.Type]; i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits) {
+			return errorAt(path, "value %s is out of range for %s", i, s.Type)
+		}
+		writeInt(buf, i)
+
+	case ast.BigIntegerType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return mismatch()
+		}
+		if !f.IsInt() {
+			return errorAt(path, "expected %s, got non-integer %s", s.Type, f.Text('g', -1))
+		}
+		i, _ := f.Int(nil)
+		writeBignum(buf, i)
+
+	case ast.FloatType, ast.DoubleType:
+		var f float64
+		switch n := v.(type) {
+		case float32:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			g, ok := valuetree.Number(v)
+			if !ok {
+				return mismatch()
+			}
+			f, _ = g.Float64()
+		}
+		if s.Type == ast.FloatType {
+			buf.WriteByte(majorSimple<<5 | 26)
+			_ = binary.Write(buf, binary.BigEndian, math.Float32bits(float32(f)))
+		} else {
+			writeFloat64(buf, f)
+		}
+
+	case ast.BigDecimalType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return mismatch()
+		}
+		mant, exp := decimal(f)
+		writeHead(buf, majorTag, tagDecimalFraction)
+		writeHead(buf, majorArray, 2)
+		writeInt(buf, big.NewInt(exp))
+		if mant.IsInt64() {
+			writeInt(buf, mant)
+		} else {
+			writeBignum(buf, mant)
+		}
+
+	case ast.TimestampType:
+		t, ok := v.(time.Time)
+		if !ok {
+			return mismatch()
+		}
+		ms := t.Unix()*1000 + int64(t.Nanosecond()/int(time.Millisecond))
+		writeHead(buf, majorTag, tagEpoch)
+		writeFloat64(buf, float64(ms)/1000)
+
+	case ast.DocumentType:
+		return errorAt(path, "document values are not supported by rpcv2Cbor")
+
+	default:
+		return errorAt(path, "shape %s is a %s, which has no values", target, s.Type)
+	}
+	return nil
+}
+
+// decode converts the data item v at path, parsed by a decoder, to a
+// value of the shape with ID target.
+func (c *Codec) decode(path string, target ast.AbsShapeID, v interface{}) (interface{}, error) {
+	s, err := c.shape(path, target)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errorAt(path, "expected %s, got null", s.Type)
+	}
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", s.Type, itemName(v))
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		values := make(map[string]interface{}, len(obj))
+		for name, value := range obj {
+			member, ok := s.Members[name]
+			if !ok || value == nil {
+				continue
+			}
+			if values[name], err = c.decode(path+"/"+valuetree.Escape(name), member.Target.Value, value); err != nil {
+				return nil, err
+			}
+		}
+		if s.Type == ast.UnionType && len(values) != 1 {
+			return nil, errorAt(path, "union must have exactly one member set, got %d", len(values))
+		}
+		return values, nil
+
+	case ast.ListType, ast.SetType:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			itemPath := path + "/" + strconv.Itoa(i)
+			if item == nil && sparse {
+				continue
+			} else if item == nil {
+				return nil, errorAt(itemPath, "null item in list which is not sparse")
+			}
+			if values[i], err = c.decode(itemPath, s.Value.Target.Value, item); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.MapType:
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		values := make(map[string]interface{}, len(entries))
+		for key, value := range entries {
+			valuePath := path + "/" + valuetree.Escape(key)
+			if value == nil && sparse {
+				values[key] = nil
+				continue
+			} else if value == nil {
+				return nil, errorAt(valuePath, "null value in map which is not sparse")
+			}
+			if values[key], err = c.decode(valuePath, s.Value.Target.Value, value); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.StringType:
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+
+	case ast.BlobType:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+
+	case ast.BooleanType:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType:
+		i, ok := v.(*big.Int)
+		if !ok {
+			return nil, mismatch()
+		}
+		if bits := valuetree.IntegerBits[s.Type]; i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits) {
+			return nil, errorAt(path, "value %s is out of range for %s", i, s.Type)
+		}
+		return i.Int64(), nil
+
+	case ast.BigIntegerType:
+		if i, ok := bignum(v); ok {
+			return i, nil
+		}
+
+	case ast.FloatType, ast.DoubleType:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case *big.Int:
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f, nil
+		}
+
+	case ast.BigDecimalType:
+		switch n := v.(type) {
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, errorAt(path, "value %v is out of range for %s", n, s.Type)
+			}
+			return new(big.Float).SetFloat64(n), nil
+		case tagged:
+			if n.number == tagDecimalFraction {
+				return decimalFraction(path, n.content)
+			}
+		}
+		if i, ok := bignum(v); ok {
+			return new(big.Float).SetInt(i), nil
+		}
+
+	case ast.TimestampType:
+		n, ok := v.(tagged)
+		if !ok || n.number != tagEpoch {
+			return nil, mismatch()
+		}
+		switch sec := n.content.(type) {
+		case *big.Int:
+			if !sec.IsInt64() {
+				return nil, errorAt(path, "timestamp %s is out of range", sec)
+			}
+			return time.Unix(sec.Int64(), 0).UTC(), nil
+		case float64:
+			if math.IsNaN(sec) || math.IsInf(sec, 0) {
+				return nil, errorAt(path, "timestamp %v is out of range", sec)
+			}
+			whole := math.Floor(sec)
+			ms := math.Round((sec - whole) * 1000)
+			return time.Unix(int64(whole), int64(ms)*int64(time.Millisecond)).UTC(), nil
+		}
+		return nil, errorAt(path, "expected epoch seconds in timestamp, got %s", itemName(n.content))
+
+	case ast.DocumentType:
+		return nil, errorAt(path, "document values are not supported by rpcv2Cbor")
+
+	default:
+		return nil, errorAt(path, "shape %s is a %s, which has no values", target, s.Type)
+	}
+	return nil, mismatch()
+}
+
+// bignum returns an integer or bignum data item as an integer.
+func bignum(v interface{}) (*big.Int, bool) {
+	switch n := v.(type) {
+	case *big.Int:
+		return n, true
+	case tagged:
+		b, ok := n.content.([]byte)
+		if !ok || n.number != tagPositiveBignum && n.number != tagNegativeBignum {
+			return nil, false
+		}
+		i := new(big.Int).SetBytes(b)
+		if n.number == tagNegativeBignum {
+			i.Neg(i).Sub(i, big.NewInt(1))
+		}
+		return i, true
+	}
+	return nil, false
+}
+
+// decimalFraction converts the content of a decimal fraction tag to a
+// big.Float.
+func decimalFraction(path string, content interface{}) (*big.Float, error) {
+	parts, ok := content.([]interface{})
+	if !ok || len(parts) != 2 {
+		return nil, errorAt(path, "decimal fraction must be an array of exponent and mantissa")
+	}
+	exp, ok := parts[0].(*big.Int)
+	if !ok || !exp.IsInt64() {
+		return nil, errorAt(path, "decimal fraction exponent must be an integer")
+	}
+	mant, ok := bignum(parts[1])
+	if !ok {
+		return nil, errorAt(path, "decimal fraction mantissa must be an integer")
+	}
+	text := mant.String() + "e" + exp.String()
+	f, _, err := big.ParseFloat(text, 10, uint(4*len(text)+64), big.ToNearestEven)
+	if err != nil {
+		return nil, errorAt(path, "decimal fraction %s is out of range", text)
+	}
+	return f, nil
+}
+
+// decimal returns the mantissa and base 10 exponent of the shortest
+// decimal representation of f.
+func decimal(f *big.Float) (*big.Int, int64) {
+	text := f.Text('e', -1)
+	i := strings.IndexByte(text, 'e')
+	exp, _ := strconv.ParseInt(text[i+1:], 10, 64)
+	digits := text[:i]
+	if j := strings.IndexByte(digits, '.'); j >= 0 {
+		exp -= int64(len(digits) - j - 1)
+		digits = digits[:j] + digits[j+1:]
+	}
+	mant, _ := new(big.Int).SetString(digits, 10)
+	return mant, exp
+}
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeText(buf *bytes.Buffer, s string) {
+	writeHead(buf, majorText, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// writeInt writes an integer which fits in 64 bits.
+func writeInt(buf *bytes.Buffer, i *big.Int) {
+	if i.Sign() >= 0 {
+		writeHead(buf, majorUnsigned, i.Uint64())
+	} else {
+		n := new(big.Int).Neg(i)
+		writeHead(buf, majorNegative, n.Sub(n, big.NewInt(1)).Uint64())
+	}
+}
+
+func writeBignum(buf *bytes.Buffer, i *big.Int) {
+	if i.Sign() >= 0 {
+		writeHead(buf, majorTag, tagPositiveBignum)
+		b := i.Bytes()
+		writeHead(buf, majorBytes, uint64(len(b)))
+		buf.Write(b)
+	} else {
+		writeHead(buf, majorTag, tagNegativeBignum)
+		n := new(big.Int).Neg(i)
+		b := n.Sub(n, big.NewInt(1)).Bytes()
+		writeHead(buf, majorBytes, uint64(len(b)))
+		buf.Write(b)
+	}
+}
+
+func writeFloat64(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(majorSimple<<5 | 27)
+	_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+// A tagged is a tagged data item.
+type tagged struct {
+	number  uint64
+	content interface{}
+}
+
+// A decoder parses CBOR data items into a tree of nil, bool, *big.Int,
+// float64, []byte, string, []interface{}, map[string]interface{} and
+// tagged values. Integers are *big.Int so that the full range of
+// negative integers is represented.
+type decoder struct {
+	data []byte
+	off  int
+}
+
+// errBreak is returned by item when it reads a break stop code.
+var errBreak = newErrorf("unexpected break")
+
+func (d *decoder) item(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, newErrorf("data items nested more than %d deep", maxDepth)
+	}
+	start := d.off
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUnsigned:
+		return new(big.Int).SetUint64(n), nil
+
+	case majorNegative:
+		i := new(big.Int).SetUint64(n)
+		return i.Neg(i).Sub(i, big.NewInt(1)), nil
+
+	case majorBytes, majorText:
+		var b []byte
+		if info == indefinite {
+			for {
+				chunk, err := d.item(depth + 1)
+				if err == errBreak {
+					break
+				} else if err != nil {
+					return nil, err
+				}
+				switch c := chunk.(type) {
+				case []byte:
+					if major != majorBytes {
+						return nil, newErrorf("invalid chunk in indefinite length text string at offset %d", start)
+					}
+					b = append(b, c...)
+				case string:
+					if major != majorText {
+						return nil, newErrorf("invalid chunk in indefinite length byte string at offset %d", start)
+					}
+					b = append(b, c...)
+				default:
+					return nil, newErrorf("invalid chunk in indefinite length string at offset %d", start)
+				}
+			}
+			if b == nil {
+				b = []byte{}
+			}
+		} else {
+			if n > uint64(len(d.data)-d.off) {
+				return nil, newErrorf("unexpected end of data")
+			}
+			b = append([]byte{}, d.data[d.off:d.off+int(n)]...)
+			d.off += int(n)
+		}
+		if major == majorBytes {
+			return b, nil
+		}
+		if !utf8.Valid(b) {
+			return nil, newErrorf("invalid UTF-8 in text string at offset %d", start)
+		}
+		return string(b), nil
+
+	case majorArray:
+		var items []interface{}
+		if info != indefinite && n > uint64(len(d.data)-d.off) {
+			return nil, newErrorf("unexpected end of data")
+		}
+		for i := uint64(0); info == indefinite || i < n; i++ {
+			item, err := d.item(depth + 1)
+			if err == errBreak && info == indefinite {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		return items, nil
+
+	case majorMap:
+		if info != indefinite && n > uint64(len(d.data)-d.off)/2 {
+			return nil, newErrorf("unexpected end of data")
+		}
+		entries := make(map[string]interface{})
+		for i := uint64(0); info == indefinite || i < n; i++ {
+			keyOff := d.off
+			key, err := d.item(depth + 1)
+			if err == errBreak && info == indefinite {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			str, ok := key.(string)
+			if !ok {
+				return nil, newErrorf("map key at offset %d is not a text string", keyOff)
+			}
+			if entries[str], err = d.item(depth + 1); err == errBreak {
+				return nil, newErrorf("map at offset %d has a key without a value", start)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+
+	case majorTag:
+		content, err := d.item(depth + 1)
+		if err == errBreak {
+			return nil, newErrorf("tag at offset %d has no content", start)
+		} else if err != nil {
+			return nil, err
+		}
+		return tagged{number: n, content: content}, nil
+	}
+
+	switch info {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull, simpleUndefined:
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	case indefinite:
+		return nil, errBreak
+	}
+	return nil, newErrorf("unsupported simple value %d at offset %d", n, start)
+}
+
+// head reads the initial byte and argument of a data item.
+func (d *decoder) head() (major, info byte, n uint64, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, 0, newErrorf("unexpected end of data")
+	}
+	b := d.data[d.off]
+	d.off++
+	major, info = b>>5, b&31
+	var size int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size = 1 << (info - 24)
+	case info == indefinite && (major >= majorBytes && major <= majorMap || major == majorSimple):
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, newErrorf("invalid additional information %d at offset %d", info, d.off-1)
+	}
+	if len(d.data)-d.off < size {
+		return 0, 0, 0, newErrorf("unexpected end of data")
+	}
+	for _, c := range d.data[d.off : d.off+size] {
+		n = n<<8 | uint64(c)
+	}
+	d.off += size
+	return major, info, n, nil
+}
+
+// halfFloat converts an IEEE 754 half-precision float to a float64.
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// itemName describes the type of a data item in an error message.
+func itemName(v interface{}) string {
+	switch n := v.(type) {
+	case bool:
+		return "boolean"
+	case *big.Int:
+		return "integer"
+	case float64:
+		return "float"
+	case []byte:
+		return "byte string"
+	case string:
+		return "text string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "map"
+	case tagged:
+		return fmt.Sprintf("tag %d", n.number)
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// errorAt returns an error about the value at the JSON Pointer path.
+func errorAt(path, format string, a ...interface{}) error {
+	if path == "" {
+		return newErrorf(format, a...)
+	}
+	return newErrorf("%s: "+format, append([]interface{}{path}, a...)...)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "cborcodec: "
@@ -0,0 +1,192 @@
+package cborcodec
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Thing": {
+			"type": "structure",
+			"members": {
+				"a": {"target": "smithy.api#String", "traits": {"smithy.api#jsonName": "A"}},
+				"b": {"target": "foo#Notes"}
+			}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#Notes": {
+			"type": "list",
+			"member": {"target": "smithy.api#Integer"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "smithy.api#Boolean"}
+		},
+		"foo#Shape": {
+			"type": "union",
+			"members": {
+				"circle": {"target": "smithy.api#Float"},
+				"none": {"target": "smithy.api#Unit"}
+			}
+		},
+		"foo#Doc": {
+			"type": "structure",
+			"members": {"doc": {"target": "smithy.api#Document"}}
+		}
+	}
+}`
+
+func testCodec(t *testing.T) *Codec {
+	m := testmodel.Read(t, testModel)
+	return New(m)
+}
+
+func bigInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
+func TestCodec(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		value interface{}
+		cbor  string
+	}{
+		{name: "integer", id: "smithy.api#Integer", value: int64(1000), cbor: "1903e8"},
+		{name: "negative integer", id: "smithy.api#Long", value: int64(-1000), cbor: "3903e7"},
+		{name: "minimum long", id: "smithy.api#Long", value: int64(math.MinInt64), cbor: "3b7fffffffffffffff"},
+		{name: "bignum", id: "smithy.api#BigInteger", value: bigInt("18446744073709551616"), cbor: "c249010000000000000000"},
+		{name: "negative bignum", id: "smithy.api#BigInteger", value: bigInt("-18446744073709551617"), cbor: "c349010000000000000000"},
+		{name: "decimal fraction", id: "smithy.api#BigDecimal", value: big.NewFloat(273.15), cbor: "c48221196ab3"},
+		{name: "float", id: "smithy.api#Float", value: float64(1.5), cbor: "fa3fc00000"},
+		{name: "double", id: "smithy.api#Double", value: math.Inf(-1), cbor: "fbfff0000000000000"},
+		{name: "timestamp", id: "smithy.api#Timestamp", value: time.Unix(1363896240, 500000000).UTC(), cbor: "c1fb41d452d9ec200000"},
+		{name: "blob", id: "smithy.api#Blob", value: []byte{1, 2, 3, 4}, cbor: "4401020304"},
+		{name: "string", id: "smithy.api#String", value: "ü", cbor: "62c3bc"},
+		{name: "boolean", id: "smithy.api#Boolean", value: true, cbor: "f5"},
+		{name: "list", id: "foo#Tags", value: []interface{}{"a", "b"}, cbor: "826161" + "6162"},
+		{name: "sparse list", id: "foo#Notes", value: []interface{}{int64(1), nil}, cbor: "8201f6"},
+		{name: "map", id: "foo#Attrs", value: map[string]interface{}{"b": false, "a": true}, cbor: "a26161f56162f4"},
+		{name: "structure", id: "foo#Thing", value: map[string]interface{}{"a": "x", "b": []interface{}{}}, cbor: "a2616161786162" + "80"},
+		{name: "union", id: "foo#Shape", value: map[string]interface{}{"none": map[string]interface{}{}}, cbor: "a1646e6f6e65a0"},
+	}
+
+	c := testCodec(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p, err := c.Marshal(testCase.id, testCase.value)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.cbor, hex.EncodeToString(p))
+
+			v, err := c.Unmarshal(testCase.id, p)
+			require.NoError(t, err)
+			if f, ok := testCase.value.(*big.Float); ok {
+				require.IsType(t, f, v)
+				assert.Equal(t, f.Text('g', -1), v.(*big.Float).Text('g', -1))
+			} else {
+				assert.Equal(t, testCase.value, v)
+			}
+		})
+	}
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		cbor  string
+		value interface{}
+	}{
+		{name: "half float", id: "smithy.api#Double", cbor: "f93c00", value: float64(1)},
+		{name: "half float subnormal", id: "smithy.api#Double", cbor: "f90001", value: 5.960464477539063e-08},
+		{name: "half float infinity", id: "smithy.api#Float", cbor: "f9fc00", value: math.Inf(-1)},
+		{name: "single float as double", id: "smithy.api#Double", cbor: "fa47c35000", value: float64(100000)},
+		{name: "integer as double", id: "smithy.api#Double", cbor: "0a", value: float64(10)},
+		{name: "integer as bigInteger", id: "smithy.api#BigInteger", cbor: "0a", value: big.NewInt(10)},
+		{name: "integer timestamp", id: "smithy.api#Timestamp", cbor: "c11a514b67b0", value: time.Unix(1363896240, 0).UTC()},
+		{name: "indefinite byte string", id: "smithy.api#Blob", cbor: "5f42010243030405ff", value: []byte{1, 2, 3, 4, 5}},
+		{name: "indefinite text string", id: "smithy.api#String", cbor: "7f657374726561646d696e67ff", value: "streaming"},
+		{name: "indefinite array", id: "foo#Tags", cbor: "9f6161ff", value: []interface{}{"a"}},
+		{name: "empty indefinite array", id: "foo#Tags", cbor: "9fff", value: []interface{}{}},
+		{name: "indefinite map", id: "foo#Attrs", cbor: "bf6161f5ff", value: map[string]interface{}{"a": true}},
+		{name: "null members and unknown members", id: "foo#Thing", cbor: "a36161f66141016162f6", value: map[string]interface{}{}},
+		{name: "undefined", id: "foo#Thing", cbor: "f7", value: nil},
+	}
+
+	c := testCodec(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p, err := hex.DecodeString(testCase.cbor)
+			require.NoError(t, err)
+
+			v, err := c.Unmarshal(testCase.id, p)
+
+			require.NoError(t, err)
+			assert.Equal(t, testCase.value, v)
+		})
+	}
+}
+
+func TestCodec_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		value interface{}
+		cbor  string
+		err   string
+	}{
+		{name: "marshal/not found", id: "foo#Missing", value: 1, err: "cborcodec: shape foo#Missing not found"},
+		{name: "marshal/type", id: "foo#Thing", value: map[string]interface{}{"a": 1}, err: "cborcodec: /a: expected string, got number"},
+		{name: "marshal/unknown member", id: "foo#Thing", value: map[string]interface{}{"A": "x"}, err: `cborcodec: unknown member "A" of foo#Thing`},
+		{name: "marshal/range", id: "smithy.api#Short", value: 40000, err: "cborcodec: value 40000 is out of range for short"},
+		{name: "marshal/null item", id: "foo#Tags", value: []interface{}{nil}, err: "cborcodec: /0: null item in list which is not sparse"},
+		{name: "marshal/union", id: "foo#Shape", value: map[string]interface{}{}, err: "cborcodec: union must have exactly one member set, got 0"},
+		{name: "marshal/document", id: "foo#Doc", value: map[string]interface{}{"doc": 1}, err: "cborcodec: /doc: document values are not supported by rpcv2Cbor"},
+		{name: "unmarshal/truncated", id: "smithy.api#Blob", cbor: "4401", err: "cborcodec: unexpected end of data"},
+		{name: "unmarshal/trailing", id: "smithy.api#Integer", cbor: "0101", err: "cborcodec: invalid data after top-level value"},
+		{name: "unmarshal/break", id: "smithy.api#Integer", cbor: "ff", err: "cborcodec: unexpected break"},
+		{name: "unmarshal/reserved", id: "smithy.api#Integer", cbor: "1c", err: "cborcodec: invalid additional information 28 at offset 0"},
+		{name: "unmarshal/map key", id: "foo#Attrs", cbor: "a101f5", err: "cborcodec: map key at offset 1 is not a text string"},
+		{name: "unmarshal/utf-8", id: "smithy.api#String", cbor: "61ff", err: "cborcodec: invalid UTF-8 in text string at offset 0"},
+		{name: "unmarshal/type", id: "foo#Thing", cbor: "a1616101", err: "cborcodec: /a: expected string, got integer"},
+		{name: "unmarshal/range", id: "smithy.api#Byte", cbor: "3880", err: "cborcodec: value -129 is out of range for byte"},
+		{name: "unmarshal/timestamp", id: "smithy.api#Timestamp", cbor: "c06161", err: "cborcodec: expected timestamp, got tag 0"},
+		{name: "unmarshal/null item", id: "foo#Tags", cbor: "81f6", err: "cborcodec: /0: null item in list which is not sparse"},
+		{name: "unmarshal/union", id: "foo#Shape", cbor: "a266636972636c65fa3fc00000646e6f6e65a0", err: "cborcodec: union must have exactly one member set, got 2"},
+		{name: "unmarshal/depth", id: "foo#Tags", cbor: strings.Repeat("81", maxDepth+1) + "01", err: "cborcodec: data items nested more than 1000 deep"},
+	}
+
+	c := testCodec(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var err error
+			if testCase.cbor != "" {
+				p, decodeErr := hex.DecodeString(testCase.cbor)
+				require.NoError(t, decodeErr)
+				_, err = c.Unmarshal(testCase.id, p)
+			} else {
+				_, err = c.Marshal(testCase.id, testCase.value)
+			}
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// stdinPath is the Location path of a model read from standard input.
+const stdinPath = "<stdin>"
+
+func newFlagSet(e *env, name, args, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: smithy-ast %s %s\n\n%s\n", name, args, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func validate(e *env, args []string) int {
+	fs := newFlagSet(e, "validate", "[-recover] [file ...]",
+		"Validate decodes each model file strictly, reporting every error with\n"+
+			"its file, line, column, byte offset and JSON Pointer path.")
+	recoverAll := fs.Bool("recover", false, "report all errors in each file rather than only the first")
+	if fs.Parse(args) != nil {
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, name := range paths(fs) {
+		src, err := readFile(e, name)
+		if err == nil {
+			_, err = ast.ReadModelWithOptions(bytes.NewReader(src), ast.DecodeOptions{Path: name, Recover: *recoverAll})
+		}
+		if err != nil {
+			for _, err2 := range splitError(err) {
+				fmt.Fprintf(e.stderr, "smithy-ast: %s%s: %s\n", name, position(src, err2), err2)
+			}
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// position returns the ":line:col" position in src of the offset at
+// which a decoding error occurred, or the empty string if err does not
+// record an offset.
+func position(src []byte, err error) string {
+	var offset int64
+	var jsonErr *ast.JSONError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &jsonErr):
+		offset = jsonErr.Offset
+	case errors.As(err, &syntaxErr) && syntaxErr.Offset > 0:
+		offset = syntaxErr.Offset - 1 // Offset is just after the bad byte.
+	default:
+		return ""
+	}
+	if offset > int64(len(src)) {
+		offset = int64(len(src))
+	}
+	line := bytes.Count(src[:offset], []byte{'\n'}) + 1
+	col := offset - int64(bytes.LastIndexByte(src[:offset], '\n'))
+	return fmt.Sprintf(":%d:%d", line, col)
+}
+
+func format(e *env, args []string) int {
+	fs := newFlagSet(e, "fmt", "[-l] [-w] [file ...]",
+		"Fmt prints each model file as canonical, indented JSON AST, with\n"+
+			"object keys in a fixed order.")
+	list := fs.Bool("l", false, "list files whose formatting differs instead of printing them")
+	write := fs.Bool("w", false, "write the result to the file instead of standard output")
+	if fs.Parse(args) != nil {
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, name := range paths(fs) {
+		if err := formatFile(e, name, *list, *write); err != nil {
+			reportError(e, name, err)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+func formatFile(e *env, name string, list, write bool) error {
+	src, err := readFile(e, name)
+	if err != nil {
+		return err
+	}
+
+	m, err := ast.ReadModelWithOptions(bytes.NewReader(src), ast.DecodeOptions{Path: name})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = writeModel(&buf, m); err != nil {
+		return err
+	}
+
+	switch {
+	case list:
+		if !bytes.Equal(src, buf.Bytes()) {
+			fmt.Fprintln(e.stdout, name)
+		}
+		return nil
+	case write && name != stdinPath:
+		if bytes.Equal(src, buf.Bytes()) {
+			return nil
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, buf.Bytes(), info.Mode().Perm())
+	default:
+		_, err = e.stdout.Write(buf.Bytes())
+		return err
+	}
+}
+
+func merge(e *env, args []string) int {
+	fs := newFlagSet(e, "merge", "[-o file] [file ...]",
+		"Merge merges the model files following the Smithy model merging\n"+
+			"rules and prints the merged model.")
+	out := fs.String("o", "", "write the merged model to `file` instead of standard output")
+	if fs.Parse(args) != nil {
+		return exitUsage
+	}
+
+	m, ok := readModels(e, paths(fs))
+	if !ok {
+		return exitFailure
+	}
+
+	var buf bytes.Buffer
+	if err := writeModel(&buf, m); err != nil {
+		reportError(e, "", err)
+		return exitFailure
+	}
+	if *out == "" {
+		_, _ = e.stdout.Write(buf.Bytes())
+	} else if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		reportError(e, "", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func show(e *env, args []string) int {
+	fs := newFlagSet(e, "show", "[-no-prelude] shape-id [file ...]",
+		"Show prints a shape from the merged model files, and the traits of\n"+
+			"each of its members merged with the traits of the member's target.\n"+
+			"The prelude is merged into the model unless -no-prelude is given.")
+	noPrelude := fs.Bool("no-prelude", false, "do not merge the prelude into the model")
+	if fs.Parse(args) != nil {
+		return exitUsage
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	id := ast.AbsShapeID(fs.Arg(0))
+	var files []string
+	if fs.NArg() > 1 {
+		files = fs.Args()[1:]
+	} else {
+		files = []string{stdinPath}
+	}
+	m, ok := readModels(e, files)
+	if !ok {
+		return exitFailure
+	}
+	if !*noPrelude {
+		var err error
+		if m, err = ast.MergeModels(prelude.Model(), m); err != nil {
+			reportError(e, "", err)
+			return exitFailure
+		}
+	}
+
+	s, ok := m.Shapes[id]
+	if !ok {
+		reportError(e, "", fmt.Errorf("shape %s not found", id))
+		return exitFailure
+	}
+	members := make(map[string]ast.Member)
+	for name, member := range s.Members {
+		members[name] = member
+	}
+	if s.Key != nil {
+		members["key"] = *s.Key
+	}
+	if s.Value != nil && s.Type == ast.MapType {
+		members["value"] = *s.Value
+	} else if s.Value != nil {
+		members["member"] = *s.Value
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resolved := make(map[string]ast.Member, len(members))
+	for _, name := range names {
+		member := members[name]
+		traits, err := member.ResolveTraits(m)
+		if err != nil {
+			reportError(e, "", fmt.Errorf("member %s$%s: %w", id, name, err))
+			return exitFailure
+		}
+		resolved[name] = ast.Member{Target: member.Target, Traits: traits}
+	}
+
+	p, err := json.Marshal(struct {
+		ID      ast.AbsShapeID        `json:"id"`
+		Shape   ast.Shape             `json:"shape"`
+		Members map[string]ast.Member `json:"resolvedMembers,omitempty"`
+	}{id, s, resolved})
+	if err == nil {
+		err = writeIndented(e.stdout, p)
+	}
+	if err != nil {
+		reportError(e, "", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printPrelude(e *env, args []string) int {
+	fs := newFlagSet(e, "prelude", "", "Prelude prints the Smithy prelude model embedded in smithy-ast.")
+	if fs.Parse(args) != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err := writeModel(e.stdout, prelude.Model()); err != nil {
+		reportError(e, "", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// paths returns the file arguments of a command, or the standard input
+// path if there are none.
+func paths(fs *flag.FlagSet) []string {
+	if fs.NArg() == 0 {
+		return []string{stdinPath}
+	}
+	return fs.Args()
+}
+
+// readModel reads one model file, or standard input if name is
+// stdinPath.
+func readModel(e *env, name string, opts ast.DecodeOptions) (ast.Model, error) {
+	opts.Path = name
+	if name == stdinPath {
+		return ast.ReadModelWithOptions(e.stdin, opts)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return ast.Model{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ast.ReadModelWithOptions(f, opts)
+}
+
+// readModels reads and merges model files, reporting any errors. It
+// returns false if there were errors.
+func readModels(e *env, names []string) (ast.Model, bool) {
+	models := make([]ast.Model, 0, len(names))
+	ok := true
+	for _, name := range names {
+		m, err := readModel(e, name, ast.DecodeOptions{})
+		if err != nil {
+			reportError(e, name, err)
+			ok = false
+			continue
+		}
+		models = append(models, m)
+	}
+	if !ok {
+		return ast.Model{}, false
+	}
+
+	m, err := ast.MergeModels(models...)
+	if err != nil {
+		reportError(e, "", err)
+		return ast.Model{}, false
+	}
+	return m, true
+}
+
+// readFile reads a model file, or standard input if name is stdinPath.
+func readFile(e *env, name string) ([]byte, error) {
+	if name == stdinPath {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// reportError writes an error to standard error. Errors containing
+// several errors, such as decoding errors in recover mode and merge
+// conflicts, are written one per line.
+func reportError(e *env, name string, err error) {
+	prefix := "smithy-ast: "
+	if name != "" {
+		prefix += name + ": "
+	}
+	for _, err2 := range splitError(err) {
+		fmt.Fprintln(e.stderr, prefix+err2.Error())
+	}
+}
+
+// splitError returns the errors contained in an error which contains
+// several, or else the error itself.
+func splitError(err error) []error {
+	var multi interface{ Unwrap() []error }
+	if errors.As(err, &multi) {
+		if errs := multi.Unwrap(); len(errs) > 1 {
+			return errs
+		}
+	}
+	return []error{err}
+}
+
+// writeModel writes m as indented JSON AST.
+func writeModel(w io.Writer, m ast.Model) error {
+	var buf bytes.Buffer
+	if err := ast.WriteModel(m, &buf); err != nil {
+		return err
+	}
+	return writeIndented(w, buf.Bytes())
+}
+
+func writeIndented(w io.Writer, p []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(p), "", "  "); err != nil {
+		return err
+	}
+	_ = buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,89 @@
+// Command smithy-ast validates, formats, merges and inspects Smithy
+// models in JSON AST format.
+//
+// Usage:
+//
+//	smithy-ast <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	validate  decode model files strictly and report errors
+//	fmt       rewrite model files as canonical, indented JSON AST
+//	merge     merge model files into one model
+//	show      print a shape and the resolved traits of its members
+//	prelude   print the Smithy prelude model
+//
+// Run "smithy-ast <command> -h" for the flags of a command. Where a
+// command reads model files and none are given, it reads standard
+// input. The exit code is 0 on success, 1 if a model is invalid or
+// cannot be processed, and 2 if the command line is invalid.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+type command struct {
+	summary string
+	run     func(env *env, args []string) int
+}
+
+var commands = map[string]command{
+	"validate": {summary: "decode model files strictly and report errors", run: validate},
+	"fmt":      {summary: "rewrite model files as canonical, indented JSON AST", run: format},
+	"merge":    {summary: "merge model files into one model", run: merge},
+	"show":     {summary: "print a shape and the resolved traits of its members", run: show},
+	"prelude":  {summary: "print the Smithy prelude model", run: printPrelude},
+}
+
+// env is the environment a command runs in.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func main() {
+	e := &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(run(e, os.Args[1:]))
+}
+
+// run runs the command named by args[0] and returns the exit code.
+func run(e *env, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(e.stderr)
+		return exitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "smithy-ast: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return exitUsage
+	}
+
+	return cmd.run(e, args[1:])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: smithy-ast <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testModelA = `{"version":"1.0","shapes":{"foo#A":{"type":"structure","members":{"b":{"target":"foo#B","traits":{"smithy.api#required":{}}},"s":{"target":"smithy.api#String"}}}}}`
+	testModelB = `{"version":"1.0","shapes":{"foo#B":{"type":"string","traits":{"smithy.api#documentation":"B"}}}}`
+	testModelC = "{\n  \"version\": \"1.0\",\n  \"shapes\": {\n    \"foo#B\": {\"type\": \"blob\"},\n    \"foo#C\": {\"type\": \"nope\"}\n  }\n}\n"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func runCommand(stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	e := &env{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}
+	code = run(e, args)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.json": testModelA, "b.json": testModelB, "c.json": testModelC})
+	a, b, c := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"), filepath.Join(dir, "c.json")
+
+	testCases := []struct {
+		name   string
+		stdin  string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "no command",
+			code:   exitUsage,
+			stderr: "usage: smithy-ast <command> [flags] [arguments]\n",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"frob"},
+			code:   exitUsage,
+			stderr: "smithy-ast: unknown command \"frob\"\n",
+		},
+		{
+			name: "validate ok",
+			args: []string{"validate", a, b},
+		},
+		{
+			name:   "validate error",
+			args:   []string{"validate", a, c},
+			code:   exitFailure,
+			stderr: "smithy-ast: " + c + `:5:21: ast: unrecognized shape type: "nope" at offset 87 in /shapes/foo#C/type` + "\n",
+		},
+		{
+			name:  "validate stdin recover",
+			stdin: `{"version":"1.0","shapes":{"foo#A":{"type":"nope"},"foo#B":{"type":"nope"}}}`,
+			args:  []string{"validate", "-recover"},
+			code:  exitFailure,
+			stderr: `smithy-ast: <stdin>:1:43: ast: unrecognized shape type: "nope" at offset 42 in /shapes/foo#A/type` + "\n" +
+				`smithy-ast: <stdin>:1:67: ast: unrecognized shape type: "nope" at offset 66 in /shapes/foo#B/type` + "\n",
+		},
+		{
+			name:   "validate syntax error",
+			stdin:  "{\n\"version\" 1}",
+			args:   []string{"validate"},
+			code:   exitFailure,
+			stderr: "smithy-ast: <stdin>:2:11: invalid character '1' after object key\n",
+		},
+		{
+			name:   "validate bad flag",
+			args:   []string{"validate", "-x"},
+			code:   exitUsage,
+			stderr: "flag provided but not defined: -x\n",
+		},
+		{
+			name:  "fmt",
+			stdin: testModelB,
+			args:  []string{"fmt"},
+			stdout: `{
+  "version": "1.0",
+  "shapes": {
+    "foo#B": {
+      "type": "string",
+      "traits": {
+        "smithy.api#documentation": "B"
+      }
+    }
+  }
+}
+`,
+		},
+		{
+			name:   "fmt list",
+			args:   []string{"fmt", "-l", a},
+			stdout: a + "\n",
+		},
+		{
+			name: "merge",
+			args: []string{"merge", a, b},
+			stdout: `{
+  "version": "1.0",
+  "shapes": {
+    "foo#A": {
+      "type": "structure",
+      "members": {
+        "b": {
+          "target": "foo#B",
+          "traits": {
+            "smithy.api#required": {}
+          }
+        },
+        "s": {
+          "target": "smithy.api#String"
+        }
+      }
+    },
+    "foo#B": {
+      "type": "string",
+      "traits": {
+        "smithy.api#documentation": "B"
+      }
+    }
+  }
+}
+`,
+		},
+		{
+			name:   "merge conflict",
+			stdin:  `{"version":"1.0","shapes":{"foo#B":{"type":"blob"}}}`,
+			args:   []string{"merge", b, "<stdin>"},
+			code:   exitFailure,
+			stderr: "smithy-ast: ast: merge conflict: conflicting definitions for shape foo#B\n",
+		},
+		{
+			name: "show",
+			args: []string{"show", "foo#A", a, b},
+			stdout: `{
+  "id": "foo#A",
+  "shape": {
+    "type": "structure",
+    "members": {
+      "b": {
+        "target": "foo#B",
+        "traits": {
+          "smithy.api#required": {}
+        }
+      },
+      "s": {
+        "target": "smithy.api#String"
+      }
+    }
+  },
+  "resolvedMembers": {
+    "b": {
+      "target": "foo#B",
+      "traits": {
+        "smithy.api#documentation": "B",
+        "smithy.api#required": {}
+      }
+    },
+    "s": {
+      "target": "smithy.api#String"
+    }
+  }
+}
+`,
+		},
+		{
+			name:   "show without prelude",
+			args:   []string{"show", "-no-prelude", "foo#A", a, b},
+			code:   exitFailure,
+			stderr: "smithy-ast: member foo#A$s: ast: model does not contain member target shape smithy.api#String\n",
+		},
+		{
+			name:   "show not found",
+			args:   []string{"show", "foo#Z", a},
+			code:   exitFailure,
+			stderr: "smithy-ast: shape foo#Z not found\n",
+		},
+		{
+			name:   "prelude arguments",
+			args:   []string{"prelude", "x"},
+			code:   exitUsage,
+			stderr: "usage: smithy-ast prelude \n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			code, stdout, stderr := runCommand(testCase.stdin, testCase.args...)
+
+			assert.Equal(t, testCase.code, code)
+			assert.Equal(t, testCase.stdout, stdout)
+			if testCase.code == exitUsage {
+				assert.True(t, strings.HasPrefix(stderr, testCase.stderr), stderr)
+			} else {
+				assert.Equal(t, testCase.stderr, stderr)
+			}
+		})
+	}
+}
+
+func TestRun_FmtWrite(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"b.json": testModelB})
+	b := filepath.Join(dir, "b.json")
+
+	code, _, stderr := runCommand("", "fmt", "-w", b)
+
+	require.Equal(t, exitOK, code, stderr)
+	p, err := os.ReadFile(b)
+	require.NoError(t, err)
+	code, stdout, _ := runCommand("", "fmt", "-l", b)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+	code, stdout, _ = runCommand(string(p), "fmt")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, string(p), stdout)
+}
+
+func TestRun_Prelude(t *testing.T) {
+	code, stdout, _ := runCommand("", "prelude")
+
+	assert.Equal(t, exitOK, code)
+	code, _, stderr := runCommand(stdout, "validate")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, `"smithy.api#String": {`)
+}
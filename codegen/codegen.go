@@ -0,0 +1,533 @@
+// Package codegen generates Go types for the shapes of a Smithy
+// service.
+//
+// Generate produces one Go source file declaring a type for each
+// structure, union, list, set, map and enum string in the closure of a
+// service: the input, output and error structures of its operations,
+// the errors of the service, and every shape these refer to through
+// their members. Other simple shapes map to Go's built-in types, to
+// time.Time, or to *big.Int and *big.Float.
+//
+// Structures become Go structs whose fields follow the order of the
+// members in the model file, where the model was decoded with a path
+// in its ast.DecodeOptions, and otherwise the order of the member
+// names. A field is a pointer when its member may be absent and its Go
+// type has no nil value of its own: members targeting structures, and
+// members which are not required and target strings, timestamps, enums
+// or boxed booleans and numbers. Slices, maps, interfaces, []byte,
+// *big.Int and *big.Float fields use nil for absence instead.
+//
+// Unions become sealed interfaces, implemented by one struct type per
+// union member named after the union and the member, such as
+// ShapeMemberCircle. Lists and sets become slice types and maps become
+// map types with string keys; the elements of sparse lists and maps are
+// pointers. Enum strings become named string types with a typed
+// constant for each enum value. The documentation and deprecated traits
+// become doc comments.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// Options controls the generation of Go source.
+type Options struct {
+	// Package is the name of the generated Go package. The default is
+	// the last component of the service's namespace, in lower case.
+	Package string
+}
+
+// Generate returns formatted Go source declaring types for the shapes
+// in the closure of the service with the given ID in m. Shapes not in m
+// are looked up in the prelude. The names of the types follow the
+// service's rename property. The output depends only on m and opts.
+//
+// Generate returns an error if the service or a shape in its closure
+// cannot be found, if two shapes or members would produce the same Go
+// name, or if the package name is not a valid Go identifier.
+func Generate(m ast.Model, service ast.AbsShapeID, opts Options) ([]byte, error) {
+	shapes := prelude.Shapes(m)
+	s, ok := shapes[service]
+	if !ok {
+		return nil, newErrorf("service %s not found", service)
+	}
+	if s.Type != ast.ServiceType || s.Service == nil {
+		return nil, newErrorf("shape %s is a %s, not a service", service, s.Type)
+	}
+	if opts.Package == "" {
+		ns := service.Namespace()
+		opts.Package = strings.ToLower(ns[strings.LastIndexByte(ns, '.')+1:])
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return nil, newErrorf("invalid package name %q", opts.Package)
+	}
+
+	g := &generator{
+		shapes:  shapes,
+		rename:  s.Service.Rename,
+		types:   make(map[string]ast.AbsShapeID),
+		decls:   make(map[string]string),
+		imports: make(map[string]bool),
+	}
+	ids, err := g.closure(service)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err = g.declare(id); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(g.decls))
+	for name := range g.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by smithy-ast codegen from %s. DO NOT EDIT.\n\n", service)
+	fmt.Fprintf(&buf, "package %s\n", opts.Package)
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		buf.WriteString("\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		}
+		buf.WriteString(")\n")
+	}
+	for _, name := range names {
+		buf.WriteString("\n")
+		buf.WriteString(g.decls[name])
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, newErrorf("generated invalid Go source: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	shapes  map[ast.AbsShapeID]ast.Shape
+	rename  map[ast.AbsShapeID]ast.StringNode
+	types   map[string]ast.AbsShapeID // Shapes and members by Go identifier.
+	decls   map[string]string         // Declarations by Go type name.
+	imports map[string]bool
+}
+
+// closure returns the IDs of the shapes in the closure of a service
+// which are declared as Go types, in sorted order.
+func (g *generator) closure(service ast.AbsShapeID) ([]ast.AbsShapeID, error) {
+	seen := make(map[ast.AbsShapeID]bool)
+	var ids []ast.AbsShapeID
+	queue := []ast.AbsShapeID{service}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		s, ok := g.shapes[id]
+		if !ok {
+			return nil, newErrorf("shape %s not found", id)
+		}
+
+		var refs []*ast.AbsShapeIDNode
+		switch s.Type {
+		case ast.ServiceType:
+			for _, list := range [][]ast.AbsShapeIDNode{s.Service.Operations, s.Service.Resources, s.Service.Errors} {
+				for i := range list {
+					refs = append(refs, &list[i])
+				}
+			}
+		case ast.ResourceType:
+			if r := s.Resource; r != nil {
+				refs = append(refs, r.Create, r.Put, r.Read, r.Update, r.Delete, r.List)
+				for _, list := range [][]ast.AbsShapeIDNode{r.Operations, r.CollectionOperations, r.Resources} {
+					for i := range list {
+						refs = append(refs, &list[i])
+					}
+				}
+			}
+		case ast.OperationType:
+			if op := s.Operation; op != nil {
+				refs = append(refs, op.Input, op.Output)
+				for i := range op.Errors {
+					refs = append(refs, &op.Errors[i])
+				}
+			}
+		case ast.StructureType, ast.UnionType, ast.ListType, ast.SetType, ast.MapType:
+			ids = append(ids, id)
+			for _, member := range members(s) {
+				target := member.Target
+				refs = append(refs, &target)
+			}
+		case ast.StringType:
+			if _, ok := s.Traits[ast.EnumTraitID]; ok {
+				ids = append(ids, id)
+			}
+		}
+		for _, ref := range refs {
+			if ref != nil && ref.Value != unitID {
+				queue = append(queue, ref.Value)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// declare generates the declaration of the Go type for a shape.
+func (g *generator) declare(id ast.AbsShapeID) error {
+	s := g.shapes[id]
+	name, err := g.typeName(id)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeDoc(&buf, "", s.Traits)
+	switch s.Type {
+	case ast.StructureType:
+		fmt.Fprintf(&buf, "type %s struct {\n", name)
+		err = g.fields(&buf, id, s)
+		buf.WriteString("}\n")
+	case ast.UnionType:
+		err = g.union(&buf, id, name, s)
+	case ast.ListType, ast.SetType:
+		var elem string
+		elem, err = g.elemType(id+"$member", s.Value, s.Traits.HasTrait(ast.SparseTraitID))
+		fmt.Fprintf(&buf, "type %s []%s\n", name, elem)
+	case ast.MapType:
+		var elem string
+		elem, err = g.elemType(id+"$value", s.Value, s.Traits.HasTrait(ast.SparseTraitID))
+		fmt.Fprintf(&buf, "type %s map[string]%s\n", name, elem)
+	case ast.StringType:
+		err = g.enum(&buf, id, name, s.Traits[ast.EnumTraitID].(*ast.EnumTrait))
+	}
+	if err != nil {
+		return err
+	}
+
+	g.decls[name] = buf.String()
+	return nil
+}
+
+// fields writes the fields of the Go struct for a structure.
+func (g *generator) fields(buf *bytes.Buffer, id ast.AbsShapeID, s ast.Shape) error {
+	seen := make(map[string]string)
+	for i, name := range s.MemberOrder() {
+		member := s.Members[name]
+		memberID := id + "$" + ast.AbsShapeID(name)
+		field := exportedName(name)
+		if prev, ok := seen[field]; ok {
+			return newErrorf("members %s and %s of %s both generate field %s", prev, name, id, field)
+		}
+		seen[field] = name
+		t, err := g.fieldType(memberID, member)
+		if err != nil {
+			return err
+		}
+		if i > 0 && hasDoc(member.Traits) {
+			buf.WriteString("\n")
+		}
+		writeDoc(buf, "\t", member.Traits)
+		fmt.Fprintf(buf, "\t%s %s\n", field, t)
+	}
+	return nil
+}
+
+// union writes the sealed interface for a union and the struct types
+// of its members.
+func (g *generator) union(buf *bytes.Buffer, id ast.AbsShapeID, name string, s ast.Shape) error {
+	method := "is" + name
+	fmt.Fprintf(buf, "type %s interface {\n\t%s()\n}\n", name, method)
+	for _, memberName := range s.MemberOrder() {
+		member := s.Members[memberName]
+		memberID := id + "$" + ast.AbsShapeID(memberName)
+		variant := name + "Member" + exportedName(memberName)
+		if err := g.claim(variant, memberID); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		if hasDoc(member.Traits) {
+			writeDoc(buf, "", member.Traits)
+		} else {
+			fmt.Fprintf(buf, "// %s is the %s member of %s.\n", variant, memberName, name)
+		}
+		if member.Target.Value == unitID {
+			fmt.Fprintf(buf, "type %s struct{}\n", variant)
+		} else {
+			t, _, err := g.goType(memberID, member)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(buf, "type %s struct {\n\tValue %s\n}\n", variant, t)
+		}
+		fmt.Fprintf(buf, "\nfunc (*%s) %s() {}\n", variant, method)
+	}
+	return nil
+}
+
+// enum writes the named string type for an enum string and its
+// constants. The constants share the package scope with the types, so
+// they are claimed like them, as members of the enum shape.
+func (g *generator) enum(buf *bytes.Buffer, id ast.AbsShapeID, name string, e *ast.EnumTrait) error {
+	fmt.Fprintf(buf, "type %s string\n\n", name)
+	fmt.Fprintf(buf, "// Values of %s.\nconst (\n", name)
+	seen := make(map[string]string)
+	for i := range e.Items {
+		item := &e.Items[i]
+		suffix := item.Value.Value
+		if item.Name != nil {
+			suffix = item.Name.Value
+		}
+		constant := name + camelCase(suffix)
+		if prev, ok := seen[constant]; ok {
+			return newErrorf("enum values %q and %q of %s both generate constant %s", prev, item.Value.Value, name, constant)
+		}
+		seen[constant] = item.Value.Value
+		if err := g.claim(constant, id+"$"+ast.AbsShapeID(suffix)); err != nil {
+			return err
+		}
+		if item.Documentation != nil {
+			writeComment(buf, "\t", item.Documentation.Value)
+		}
+		fmt.Fprintf(buf, "\t%s %s = %q\n", constant, name, item.Value.Value)
+	}
+	buf.WriteString(")\n")
+	return nil
+}
+
+// fieldType returns the Go type of the field for a structure member.
+func (g *generator) fieldType(id ast.AbsShapeID, member ast.Member) (string, error) {
+	t, k, err := g.goType(id, member)
+	if err != nil {
+		return "", err
+	}
+	switch k {
+	case kindStruct:
+		return "*" + t, nil
+	case kindValue:
+		if !member.Traits.HasTrait(ast.RequiredTraitID) {
+			return "*" + t, nil
+		}
+	case kindPrimitive:
+		target := g.shapes[member.Target.Value]
+		boxed := member.Traits.HasTrait(ast.BoxTraitID) || target.Traits.HasTrait(ast.BoxTraitID)
+		if boxed && !member.Traits.HasTrait(ast.RequiredTraitID) {
+			return "*" + t, nil
+		}
+	}
+	return t, nil
+}
+
+// elemType returns the Go type of the elements of a list, set or map.
+func (g *generator) elemType(id ast.AbsShapeID, member *ast.Member, sparse bool) (string, error) {
+	if member == nil {
+		return "", newErrorf("shape %s not found", id)
+	}
+	t, k, err := g.goType(id, *member)
+	if err != nil {
+		return "", err
+	}
+	if sparse && k != kindNil {
+		return "*" + t, nil
+	}
+	return t, nil
+}
+
+// A kind classifies Go types by how a field of the type can be absent.
+type kind int
+
+const (
+	kindNil       kind = iota // Has a nil value: slices, maps, interfaces and pointers.
+	kindStruct                // A struct type.
+	kindValue                 // A string, enum or time.Time.
+	kindPrimitive             // A boolean or number, absent only if boxed.
+)
+
+// goType returns the Go type for the target of a member, and its kind.
+func (g *generator) goType(id ast.AbsShapeID, member ast.Member) (string, kind, error) {
+	target, ok := g.shapes[member.Target.Value]
+	if !ok {
+		return "", 0, newErrorf("target %s of member %s not found", member.Target.Value, id)
+	}
+	switch target.Type {
+	case ast.StructureType:
+		name, err := g.typeName(member.Target.Value)
+		return name, kindStruct, err
+	case ast.UnionType, ast.ListType, ast.SetType, ast.MapType:
+		name, err := g.typeName(member.Target.Value)
+		return name, kindNil, err
+	case ast.StringType:
+		if _, ok := target.Traits[ast.EnumTraitID]; ok {
+			name, err := g.typeName(member.Target.Value)
+			return name, kindValue, err
+		}
+		return "string", kindValue, nil
+	case ast.TimestampType:
+		g.imports["time"] = true
+		return "time.Time", kindValue, nil
+	case ast.BlobType:
+		return "[]byte", kindNil, nil
+	case ast.DocumentType:
+		return "interface{}", kindNil, nil
+	case ast.BigIntegerType:
+		g.imports["math/big"] = true
+		return "*big.Int", kindNil, nil
+	case ast.BigDecimalType:
+		g.imports["math/big"] = true
+		return "*big.Float", kindNil, nil
+	}
+	if t, ok := primitives[target.Type]; ok {
+		return t, kindPrimitive, nil
+	}
+	return "", 0, newErrorf("member %s targets %s %s, which has no Go type", id, target.Type, member.Target.Value)
+}
+
+var primitives = map[ast.ShapeType]string{
+	ast.BooleanType: "bool",
+	ast.ByteType:    "int8",
+	ast.ShortType:   "int16",
+	ast.IntegerType: "int32",
+	ast.LongType:    "int64",
+	ast.FloatType:   "float32",
+	ast.DoubleType:  "float64",
+}
+
+const unitID ast.AbsShapeID = "smithy.api#Unit"
+
+// typeName returns the Go type name for a shape.
+func (g *generator) typeName(id ast.AbsShapeID) (string, error) {
+	name := id.Name()
+	if r, ok := g.rename[id]; ok {
+		name = r.Value
+	}
+	name = exportedName(name)
+	return name, g.claim(name, id)
+}
+
+// claim records that the Go type or constant with the given name is
+// generated for the shape or member id, and returns an error if it is
+// generated for another one too.
+func (g *generator) claim(name string, id ast.AbsShapeID) error {
+	if prev, ok := g.types[name]; ok && prev != id {
+		return newErrorf("%s and %s both generate identifier %s", prev, id, name)
+	}
+	g.types[name] = id
+	return nil
+}
+
+// members returns the members of s, including the member of a list or
+// set and the key and value of a map.
+func members(s ast.Shape) []ast.Member {
+	ms := make([]ast.Member, 0, len(s.Members)+2)
+	for _, name := range s.MemberOrder() {
+		ms = append(ms, s.Members[name])
+	}
+	if s.Key != nil {
+		ms = append(ms, *s.Key)
+	}
+	if s.Value != nil {
+		ms = append(ms, *s.Value)
+	}
+	return ms
+}
+
+// exportedName returns an exported Go identifier for a Smithy
+// identifier.
+func exportedName(name string) string {
+	name = strings.TrimLeft(name, "_")
+	if name == "" {
+		return "X"
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// camelCase converts an enum name or value, such as "LIGHT_BLUE" or
+// "light-blue", to camel case, such as "LightBlue".
+func camelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		default:
+			upper = true
+		}
+	}
+	if b.Len() == 0 {
+		return "Empty"
+	}
+	return b.String()
+}
+
+// writeDoc writes the doc comment for a shape or member with the given
+// traits, if it has one.
+func writeDoc(buf *bytes.Buffer, indent string, traits ast.Traits) {
+	doc, _ := traits[ast.DocumentationTraitID].(*ast.StringNode)
+	dep, _ := traits[ast.DeprecatedTraitID].(*ast.DeprecatedTrait)
+	if doc != nil {
+		writeComment(buf, indent, doc.Value)
+	}
+	if dep != nil {
+		if doc != nil {
+			fmt.Fprintf(buf, "%s//\n", indent)
+		}
+		text := "Deprecated:"
+		if dep.Since != nil {
+			text += " since " + dep.Since.Value + "."
+		}
+		if dep.Message != nil {
+			text += " " + dep.Message.Value
+		}
+		if dep.Since == nil && dep.Message == nil {
+			text += " do not use."
+		}
+		writeComment(buf, indent, text)
+	}
+}
+
+func writeComment(buf *bytes.Buffer, indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			fmt.Fprintf(buf, "%s//\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%s// %s\n", indent, line)
+		}
+	}
+}
+
+func hasDoc(traits ast.Traits) bool {
+	return traits.HasTrait(ast.DocumentationTraitID) || traits.HasTrait(ast.DeprecatedTraitID)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "codegen: "
@@ -0,0 +1,287 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"example.weather#Weather": {
+			"type": "service",
+			"version": "2006-03-01",
+			"operations": ["example.weather#GetForecast"],
+			"errors": ["example.weather#ServiceError"],
+			"rename": {"example.weather#Sky": "SkyCondition"}
+		},
+		"example.weather#GetForecast": {
+			"type": "operation",
+			"input": "example.weather#GetForecastInput",
+			"output": "example.weather#GetForecastOutput"
+		},
+		"example.weather#GetForecastInput": {
+			"type": "structure",
+			"members": {
+				"cityId": {"target": "smithy.api#String", "traits": {"smithy.api#required": {}}},
+				"days": {"target": "smithy.api#Integer"},
+				"hourly": {"target": "smithy.api#PrimitiveBoolean"},
+				"units": {"target": "example.weather#Units"}
+			}
+		},
+		"example.weather#GetForecastOutput": {
+			"type": "structure",
+			"members": {
+				"sky": {"target": "example.weather#Sky", "traits": {"smithy.api#documentation": "The expected sky."}},
+				"temperatures": {"target": "example.weather#Temperatures"},
+				"updated": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#required": {}}},
+				"extra": {"target": "example.weather#Extra"},
+				"precipitation": {"target": "example.weather#Precipitation"},
+				"next": {"target": "example.weather#GetForecastOutput"},
+				"raw": {"target": "smithy.api#Blob"},
+				"total": {"target": "smithy.api#BigDecimal"}
+			},
+			"traits": {"smithy.api#documentation": "Forecast for a city.\n\nSee also GetCity."}
+		},
+		"example.weather#Units": {
+			"type": "string",
+			"traits": {
+				"smithy.api#enum": [
+					{"value": "metric", "documentation": "Degrees Celsius."},
+					{"value": "imperial", "name": "US_CUSTOMARY"}
+				]
+			}
+		},
+		"example.weather#Sky": {
+			"type": "union",
+			"members": {
+				"clear": {"target": "smithy.api#Unit"},
+				"cloudy": {"target": "smithy.api#Integer", "traits": {"smithy.api#documentation": "Cloud cover percentage."}}
+			}
+		},
+		"example.weather#Temperatures": {
+			"type": "list",
+			"member": {"target": "smithy.api#Float"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"example.weather#Extra": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "smithy.api#Document"}
+		},
+		"example.weather#Precipitation": {
+			"type": "structure",
+			"members": {
+				"chance": {"target": "smithy.api#PrimitiveDouble"}
+			},
+			"traits": {"smithy.api#deprecated": {"message": "Use sky instead.", "since": "2020"}}
+		},
+		"example.weather#ServiceError": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "server"}
+		}
+	}
+}`
+
+const testSource = `// Code generated by smithy-ast codegen from example.weather#Weather. DO NOT EDIT.
+
+package weather
+
+import (
+	"math/big"
+	"time"
+)
+
+type Extra map[string]interface{}
+
+type GetForecastInput struct {
+	CityId string
+	Days   *int32
+	Hourly bool
+	Units  *Units
+}
+
+// Forecast for a city.
+//
+// See also GetCity.
+type GetForecastOutput struct {
+	// The expected sky.
+	Sky           SkyCondition
+	Temperatures  Temperatures
+	Updated       time.Time
+	Extra         Extra
+	Precipitation *Precipitation
+	Next          *GetForecastOutput
+	Raw           []byte
+	Total         *big.Float
+}
+
+// Deprecated: since 2020. Use sky instead.
+type Precipitation struct {
+	Chance float64
+}
+
+type ServiceError struct {
+	Message *string
+}
+
+type SkyCondition interface {
+	isSkyCondition()
+}
+
+// SkyConditionMemberClear is the clear member of SkyCondition.
+type SkyConditionMemberClear struct{}
+
+func (*SkyConditionMemberClear) isSkyCondition() {}
+
+// Cloud cover percentage.
+type SkyConditionMemberCloudy struct {
+	Value int32
+}
+
+func (*SkyConditionMemberCloudy) isSkyCondition() {}
+
+type Temperatures []*float32
+
+type Units string
+
+// Values of Units.
+const (
+	// Degrees Celsius.
+	UnitsMetric      Units = "metric"
+	UnitsUsCustomary Units = "imperial"
+)
+`
+
+func TestGenerate(t *testing.T) {
+	m, err := ast.ReadModelWithOptions(strings.NewReader(testModel), ast.DecodeOptions{Path: "weather.json"})
+	require.NoError(t, err)
+
+	src, err := Generate(m, "example.weather#Weather", Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, testSource, string(src))
+
+	t.Run("deterministic", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			again, err := Generate(m, "example.weather#Weather", Options{})
+			require.NoError(t, err)
+			assert.Equal(t, string(src), string(again))
+		}
+	})
+}
+
+func TestGenerate_NoLocations(t *testing.T) {
+	m := testmodel.Read(t, `{"version":"1.0","shapes":{
+		"foo#S":{"type":"service","version":"1","operations":["foo#Op"]},
+		"foo#Op":{"type":"operation","input":"foo#In","output":"smithy.api#Unit"},
+		"foo#In":{"type":"structure","members":{
+			"zeta":{"target":"smithy.api#Long","traits":{"smithy.api#required":{}}},
+			"alpha":{"target":"smithy.api#PrimitiveLong","traits":{"smithy.api#box":{}}},
+			"mid":{"target":"foo#Set"}
+		}},
+		"foo#Set":{"type":"set","member":{"target":"smithy.api#String"}}
+	}}`)
+
+	src, err := Generate(m, "foo#S", Options{Package: "api"})
+
+	require.NoError(t, err)
+	assert.Equal(t, `// Code generated by smithy-ast codegen from foo#S. DO NOT EDIT.
+
+package api
+
+type In struct {
+	Alpha *int64
+	Mid   Set
+	Zeta  int64
+}
+
+type Set []string
+`, string(src))
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		model   string
+		service ast.AbsShapeID
+		opts    Options
+		err     string
+	}{
+		{
+			name:    "service not found",
+			model:   `{"version":"1.0"}`,
+			service: "foo#S",
+			err:     "codegen: service foo#S not found",
+		},
+		{
+			name:    "not a service",
+			model:   `{"version":"1.0","shapes":{"foo#S":{"type":"string"}}}`,
+			service: "foo#S",
+			err:     "codegen: shape foo#S is a string, not a service",
+		},
+		{
+			name:    "invalid package",
+			model:   `{"version":"1.0","shapes":{"foo#S":{"type":"service","version":"1"}}}`,
+			service: "foo#S",
+			opts:    Options{Package: "not-valid"},
+			err:     `codegen: invalid package name "not-valid"`,
+		},
+		{
+			name: "type conflict",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","errors":["foo#E","bar#E"]},
+				"foo#E":{"type":"structure","traits":{"smithy.api#error":"client"}},
+				"bar#E":{"type":"structure","traits":{"smithy.api#error":"client"}}
+			}}`,
+			service: "foo#S",
+			err:     "codegen: bar#E and foo#E both generate identifier E",
+		},
+		{
+			name: "enum constant conflict",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","errors":["foo#E"]},
+				"foo#E":{"type":"structure","members":{"c":{"target":"foo#Color"},"r":{"target":"foo#ColorRed"}}},
+				"foo#Color":{"type":"string","traits":{"smithy.api#enum":[{"value":"red","name":"RED"}]}},
+				"foo#ColorRed":{"type":"structure"}
+			}}`,
+			service: "foo#S",
+			err:     "codegen: foo#Color$RED and foo#ColorRed both generate identifier ColorRed",
+		},
+		{
+			name: "field conflict",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","errors":["foo#E"]},
+				"foo#E":{"type":"structure","members":{"a":{"target":"smithy.api#String"},"A":{"target":"smithy.api#String"}}}
+			}}`,
+			service: "foo#S",
+			err:     "codegen: members A and a of foo#E both generate field A",
+		},
+		{
+			name: "missing target",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","errors":["foo#E"]},
+				"foo#E":{"type":"structure","members":{"a":{"target":"foo#Missing"}}}
+			}}`,
+			service: "foo#S",
+			err:     "codegen: shape foo#Missing not found",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := testmodel.Read(t, testCase.model)
+
+			_, err := Generate(m, testCase.service, testCase.opts)
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
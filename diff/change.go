@@ -0,0 +1,216 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// A Change is one difference between two models. The concrete type of
+// a Change says what kind of difference it is.
+type Change interface {
+	// ShapeID returns the ID of the shape or member which changed, or
+	// the empty string for a change to the model's metadata.
+	ShapeID() ast.AbsShapeID
+
+	// String returns a one-line description of the change.
+	String() string
+}
+
+// ShapeAdded records a shape present in the new model but not the old.
+type ShapeAdded struct {
+	ID    ast.AbsShapeID
+	Shape ast.Shape
+}
+
+func (c ShapeAdded) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ShapeAdded) String() string {
+	return fmt.Sprintf("added %s %s", c.Shape.Type, c.ID)
+}
+
+// ShapeRemoved records a shape present in the old model but not the new.
+type ShapeRemoved struct {
+	ID    ast.AbsShapeID
+	Shape ast.Shape
+}
+
+func (c ShapeRemoved) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ShapeRemoved) String() string {
+	return fmt.Sprintf("removed %s %s", c.Shape.Type, c.ID)
+}
+
+// ShapeTypeChanged records a shape whose type changed.
+type ShapeTypeChanged struct {
+	ID       ast.AbsShapeID
+	Old, New ast.ShapeType
+}
+
+func (c ShapeTypeChanged) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ShapeTypeChanged) String() string {
+	return fmt.Sprintf("changed type of %s from %s to %s", c.ID, c.Old, c.New)
+}
+
+// MemberAdded records a member present in a shape in the new model but
+// not in the old. The ID is the member's ID, for example "foo#A$b".
+type MemberAdded struct {
+	ID     ast.AbsShapeID
+	Member ast.Member
+}
+
+func (c MemberAdded) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c MemberAdded) String() string {
+	return fmt.Sprintf("added member %s targeting %s", c.ID, c.Member.Target.Value)
+}
+
+// MemberRemoved records a member present in a shape in the old model but
+// not in the new. The ID is the member's ID, for example "foo#A$b".
+type MemberRemoved struct {
+	ID     ast.AbsShapeID
+	Member ast.Member
+}
+
+func (c MemberRemoved) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c MemberRemoved) String() string {
+	return fmt.Sprintf("removed member %s targeting %s", c.ID, c.Member.Target.Value)
+}
+
+// MemberTargetChanged records a member whose target changed.
+type MemberTargetChanged struct {
+	ID       ast.AbsShapeID
+	Old, New ast.AbsShapeID
+}
+
+func (c MemberTargetChanged) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c MemberTargetChanged) String() string {
+	return fmt.Sprintf("changed target of member %s from %s to %s", c.ID, c.Old, c.New)
+}
+
+// TraitAdded records a trait applied to a shape or member in the new
+// model but not in the old.
+type TraitAdded struct {
+	ID    ast.AbsShapeID
+	Trait ast.AbsShapeID
+	Value ast.Node
+}
+
+func (c TraitAdded) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c TraitAdded) String() string {
+	return fmt.Sprintf("added trait %s to %s", c.Trait, c.ID)
+}
+
+// TraitRemoved records a trait applied to a shape or member in the old
+// model but not in the new.
+type TraitRemoved struct {
+	ID    ast.AbsShapeID
+	Trait ast.AbsShapeID
+	Value ast.Node
+}
+
+func (c TraitRemoved) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c TraitRemoved) String() string {
+	return fmt.Sprintf("removed trait %s from %s", c.Trait, c.ID)
+}
+
+// TraitChanged records a trait applied to a shape or member in both
+// models whose value changed.
+type TraitChanged struct {
+	ID       ast.AbsShapeID
+	Trait    ast.AbsShapeID
+	Old, New ast.Node
+}
+
+func (c TraitChanged) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c TraitChanged) String() string {
+	return fmt.Sprintf("changed trait %s on %s", c.Trait, c.ID)
+}
+
+// ReferenceAdded records a shape ID held by a service, resource or
+// operation in the new model but not the old, such as an operation
+// bound to a service. Property is the JSON AST property holding the
+// reference, for example "operations", "input" or "identifiers/id".
+type ReferenceAdded struct {
+	ID       ast.AbsShapeID
+	Property string
+	Target   ast.AbsShapeID
+}
+
+func (c ReferenceAdded) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ReferenceAdded) String() string {
+	return fmt.Sprintf("added %s to %s of %s", c.Target, c.Property, c.ID)
+}
+
+// ReferenceRemoved records a shape ID held by a service, resource or
+// operation in the old model but not the new. Property is as described
+// for ReferenceAdded.
+type ReferenceRemoved struct {
+	ID       ast.AbsShapeID
+	Property string
+	Target   ast.AbsShapeID
+}
+
+func (c ReferenceRemoved) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ReferenceRemoved) String() string {
+	return fmt.Sprintf("removed %s from %s of %s", c.Target, c.Property, c.ID)
+}
+
+// ServiceVersionChanged records a service whose version changed.
+type ServiceVersionChanged struct {
+	ID       ast.AbsShapeID
+	Old, New string
+}
+
+func (c ServiceVersionChanged) ShapeID() ast.AbsShapeID { return c.ID }
+
+func (c ServiceVersionChanged) String() string {
+	return fmt.Sprintf("changed version of %s from %q to %q", c.ID, c.Old, c.New)
+}
+
+// MetadataAdded records a metadata key present in the new model but not
+// the old.
+type MetadataAdded struct {
+	Key   string
+	Value ast.InterfaceNode
+}
+
+func (c MetadataAdded) ShapeID() ast.AbsShapeID { return "" }
+
+func (c MetadataAdded) String() string {
+	return fmt.Sprintf("added metadata %q", c.Key)
+}
+
+// MetadataRemoved records a metadata key present in the old model but
+// not the new.
+type MetadataRemoved struct {
+	Key   string
+	Value ast.InterfaceNode
+}
+
+func (c MetadataRemoved) ShapeID() ast.AbsShapeID { return "" }
+
+func (c MetadataRemoved) String() string {
+	return fmt.Sprintf("removed metadata %q", c.Key)
+}
+
+// MetadataChanged records a metadata key present in both models whose
+// value changed.
+type MetadataChanged struct {
+	Key      string
+	Old, New ast.InterfaceNode
+}
+
+func (c MetadataChanged) ShapeID() ast.AbsShapeID { return "" }
+
+func (c MetadataChanged) String() string {
+	return fmt.Sprintf("changed metadata %q", c.Key)
+}
@@ -0,0 +1,333 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	// Note is the severity of a change which is backward compatible
+	// but worth knowing about, such as an added shape.
+	Note Severity = iota
+	// Warning is the severity of a change which may break some
+	// clients, such as a new operation error.
+	Warning
+	// Error is the severity of a change which breaks backward
+	// compatibility.
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Note:
+		return "NOTE"
+	case Warning:
+		return "WARNING"
+	case Error:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Names of the rules evaluated by a Checker.
+const (
+	RuleAddedShape              = "AddedShape"
+	RuleRemovedShape            = "RemovedShape"
+	RuleChangedShapeType        = "ChangedShapeType"
+	RuleAddedMember             = "AddedMember"
+	RuleRemovedMember           = "RemovedMember"
+	RuleChangedMemberTarget     = "ChangedMemberTarget"
+	RuleAddedOperationBinding   = "AddedOperationBinding"
+	RuleRemovedOperationBinding = "RemovedOperationBinding"
+	RuleRemovedResourceBinding  = "RemovedResourceBinding"
+	RuleChangedOperationInput   = "ChangedOperationInput"
+	RuleChangedOperationOutput  = "ChangedOperationOutput"
+	RuleAddedOperationError     = "AddedOperationError"
+	RuleRemovedOperationError   = "RemovedOperationError"
+	RuleChangedResourceBinding  = "ChangedResourceBinding"
+	RuleAddedRequiredTrait      = "AddedRequiredTrait"
+	RuleChangedLengthTrait      = "ChangedLengthTrait"
+	RuleChangedRangeTrait       = "ChangedRangeTrait"
+	RuleChangedPatternTrait     = "ChangedPatternTrait"
+	RuleChangedEnumTrait        = "ChangedEnumTrait"
+	RuleChangedHTTPTrait        = "ChangedHttpTrait"
+	RuleChangedJSONName         = "ChangedJsonName"
+	RuleChangedXMLName          = "ChangedXmlName"
+	RuleChangedTimestampFormat  = "ChangedTimestampFormat"
+)
+
+// A Finding is the result of a breaking-change rule matching a change
+// between two models.
+type Finding struct {
+	Rule     string         // name of the rule, for example RuleRemovedShape
+	Severity Severity       // severity of the finding, after overrides
+	ShapeID  ast.AbsShapeID // shape or member which changed
+	Message  string         // description of the finding
+	Change   Change         // change which the rule matched
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s)", f.Severity, f.ShapeID, f.Message, f.Rule)
+}
+
+// An Override changes the severity of, or suppresses, the findings of
+// a rule.
+type Override struct {
+	// Rule is the name of the rule whose findings are overridden. If
+	// empty, the findings of every rule are overridden.
+	Rule string
+
+	// ShapeID restricts the override to findings about one shape and
+	// its members. If it ends with '#', as in "example.weather#", the
+	// override applies to findings about any shape in that namespace.
+	// If empty, the override applies to findings about any shape.
+	ShapeID ast.AbsShapeID
+
+	// Severity, if not nil, is the severity given to matching findings.
+	// Otherwise their severity is left as it is.
+	Severity *Severity
+
+	// Suppress removes matching findings altogether.
+	Suppress bool
+}
+
+func (o *Override) matches(f *Finding) bool {
+	if o.Rule != "" && o.Rule != f.Rule {
+		return false
+	}
+	switch id := string(o.ShapeID); {
+	case id == "":
+		return true
+	case strings.HasSuffix(id, "#"):
+		return strings.HasPrefix(string(f.ShapeID), id)
+	default:
+		return f.ShapeID == o.ShapeID || strings.HasPrefix(string(f.ShapeID), id+"$")
+	}
+}
+
+// A Checker evaluates breaking-change rules against the differences
+// between two models. The zero value is a usable Checker with no
+// overrides.
+type Checker struct {
+	// Overrides change the severity of findings. When more than one
+	// override matches a finding, the last one wins.
+	Overrides []Override
+}
+
+// Check compares two models using a zero-value Checker.
+func Check(from, to ast.Model) []Finding {
+	var c Checker
+	return c.Check(from, to)
+}
+
+// Check compares an old version of a model, from, with a new version,
+// to, and returns the findings of the breaking-change rules for the
+// changes between them, in the order of the changes returned by Diff.
+//
+// The rules, and their default severities, are: removing a shape or a
+// member, or changing a shape's type or a member's target (Error);
+// removing an operation or resource from a service or resource, or
+// changing a resource's lifecycle operations or identifiers (Error);
+// changing an operation's input or output (Error); adding an error to
+// an operation (Warning) or removing one (Note); adding the required
+// trait, or a member with the required trait (Error); narrowing the
+// length or range trait (Error); adding or changing the pattern trait
+// (Error); adding the enum trait, or removing or renaming enum values
+// (Error); adding, removing or changing the http, jsonName, xmlName and
+// timestampFormat traits (Error); and adding a shape, a member or an
+// operation (Note).
+func (c *Checker) Check(from, to ast.Model) []Finding {
+	var findings []Finding
+	for _, change := range Diff(from, to) {
+		for _, f := range evaluate(change) {
+			f.Change = change
+			if c.override(&f) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// override applies the overrides to f and reports whether f should be
+// kept.
+func (c *Checker) override(f *Finding) bool {
+	keep := true
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		if o.matches(f) {
+			if o.Severity != nil {
+				f.Severity = *o.Severity
+			}
+			keep = !o.Suppress
+		}
+	}
+	return keep
+}
+
+func finding(rule string, severity Severity, id ast.AbsShapeID, format string, a ...interface{}) Finding {
+	return Finding{Rule: rule, Severity: severity, ShapeID: id, Message: fmt.Sprintf(format, a...)}
+}
+
+// evaluate returns the findings of each rule which matches change.
+func evaluate(change Change) []Finding {
+	switch c := change.(type) {
+	case ShapeAdded:
+		return []Finding{finding(RuleAddedShape, Note, c.ID, "added %s shape", c.Shape.Type)}
+	case ShapeRemoved:
+		return []Finding{finding(RuleRemovedShape, Error, c.ID, "removed %s shape", c.Shape.Type)}
+	case ShapeTypeChanged:
+		return []Finding{finding(RuleChangedShapeType, Error, c.ID, "changed shape type from %s to %s", c.Old, c.New)}
+	case MemberAdded:
+		if _, ok := c.Member.Traits[ast.RequiredTraitID]; ok {
+			return []Finding{finding(RuleAddedRequiredTrait, Error, c.ID, "added required member")}
+		}
+		return []Finding{finding(RuleAddedMember, Note, c.ID, "added member")}
+	case MemberRemoved:
+		return []Finding{finding(RuleRemovedMember, Error, c.ID, "removed member")}
+	case MemberTargetChanged:
+		return []Finding{finding(RuleChangedMemberTarget, Error, c.ID, "changed member target from %s to %s", c.Old, c.New)}
+	case ReferenceAdded:
+		return evaluateReference(c.ID, c.Property, c.Target, true)
+	case ReferenceRemoved:
+		return evaluateReference(c.ID, c.Property, c.Target, false)
+	case TraitAdded:
+		return evaluateTrait(c.ID, c.Trait, nil, c.Value)
+	case TraitRemoved:
+		return evaluateTrait(c.ID, c.Trait, c.Value, nil)
+	case TraitChanged:
+		return evaluateTrait(c.ID, c.Trait, c.Old, c.New)
+	}
+	return nil
+}
+
+func evaluateReference(id ast.AbsShapeID, prop string, target ast.AbsShapeID, added bool) []Finding {
+	switch {
+	case prop == "operations" && added:
+		return []Finding{finding(RuleAddedOperationBinding, Note, id, "added operation %s", target)}
+	case prop == "operations" || prop == "collectionOperations":
+		return []Finding{finding(RuleRemovedOperationBinding, Error, id, "removed operation %s from %s", target, prop)}
+	case prop == "resources" && !added:
+		return []Finding{finding(RuleRemovedResourceBinding, Error, id, "removed resource %s", target)}
+	case prop == "input" || prop == "output":
+		rule := RuleChangedOperationInput
+		if prop == "output" {
+			rule = RuleChangedOperationOutput
+		}
+		if added {
+			return []Finding{finding(rule, Error, id, "%s is now %s", prop, target)}
+		}
+		return []Finding{finding(rule, Error, id, "%s is no longer %s", prop, target)}
+	case prop == "errors" && added:
+		return []Finding{finding(RuleAddedOperationError, Warning, id, "added error %s", target)}
+	case prop == "errors":
+		return []Finding{finding(RuleRemovedOperationError, Note, id, "removed error %s", target)}
+	case prop == "create", prop == "put", prop == "read", prop == "update", prop == "delete", prop == "list",
+		strings.HasPrefix(prop, "identifiers/"):
+		if added {
+			return []Finding{finding(RuleChangedResourceBinding, Error, id, "%s is now %s", prop, target)}
+		}
+		return []Finding{finding(RuleChangedResourceBinding, Error, id, "%s is no longer %s", prop, target)}
+	}
+	return nil
+}
+
+// evaluateTrait evaluates the trait rules for a trait which was added
+// (from is nil), removed (to is nil) or changed.
+func evaluateTrait(id, trait ast.AbsShapeID, from, to ast.Node) []Finding {
+	switch trait {
+	case ast.RequiredTraitID:
+		if from == nil {
+			return []Finding{finding(RuleAddedRequiredTrait, Error, id, "added required trait")}
+		}
+	case ast.LengthTraitID:
+		o, _ := from.(*ast.LengthTrait)
+		n, _ := to.(*ast.LengthTrait)
+		if n != nil && narrowedLength(o, n) {
+			return []Finding{finding(RuleChangedLengthTrait, Error, id, "narrowed length from %s to %s", jsonString(from), jsonString(to))}
+		}
+	case ast.RangeTraitID:
+		o, _ := from.(*ast.RangeTrait)
+		n, _ := to.(*ast.RangeTrait)
+		if n != nil && narrowedRange(o, n) {
+			return []Finding{finding(RuleChangedRangeTrait, Error, id, "narrowed range from %s to %s", jsonString(from), jsonString(to))}
+		}
+	case ast.PatternTraitID:
+		if to != nil {
+			return []Finding{finding(RuleChangedPatternTrait, Error, id, "changed pattern from %s to %s", jsonString(from), jsonString(to))}
+		}
+	case ast.EnumTraitID:
+		o, _ := from.(*ast.EnumTrait)
+		n, _ := to.(*ast.EnumTrait)
+		switch {
+		case o == nil && n != nil:
+			return []Finding{finding(RuleChangedEnumTrait, Error, id, "added enum trait")}
+		case o != nil && n != nil:
+			return evaluateEnum(id, o, n)
+		}
+	case ast.HTTPTraitID:
+		return []Finding{finding(RuleChangedHTTPTrait, Error, id, "changed http trait from %s to %s", jsonString(from), jsonString(to))}
+	case ast.JSONNameTraitID:
+		return []Finding{finding(RuleChangedJSONName, Error, id, "changed jsonName from %s to %s", jsonString(from), jsonString(to))}
+	case ast.XMLNameTraitID:
+		return []Finding{finding(RuleChangedXMLName, Error, id, "changed xmlName from %s to %s", jsonString(from), jsonString(to))}
+	case ast.TimestampFormatTraitID:
+		return []Finding{finding(RuleChangedTimestampFormat, Error, id, "changed timestampFormat from %s to %s", jsonString(from), jsonString(to))}
+	}
+	return nil
+}
+
+func narrowedLength(from, to *ast.LengthTrait) bool {
+	if from == nil {
+		from = &ast.LengthTrait{}
+	}
+	return to.Min != nil && (from.Min == nil || to.Min.Value > from.Min.Value) ||
+		to.Max != nil && (from.Max == nil || to.Max.Value < from.Max.Value)
+}
+
+func narrowedRange(from, to *ast.RangeTrait) bool {
+	if from == nil {
+		from = &ast.RangeTrait{}
+	}
+	return to.Min != nil && (from.Min == nil || to.Min.Value.Cmp(&from.Min.Value) > 0) ||
+		to.Max != nil && (from.Max == nil || to.Max.Value.Cmp(&from.Max.Value) < 0)
+}
+
+func evaluateEnum(id ast.AbsShapeID, from, to *ast.EnumTrait) []Finding {
+	var findings []Finding
+	names := make(map[string]*ast.StringNode, len(to.Items))
+	for i := range to.Items {
+		names[to.Items[i].Value.Value] = to.Items[i].Name
+	}
+	for i := range from.Items {
+		value := from.Items[i].Value.Value
+		name, ok := names[value]
+		switch {
+		case !ok:
+			findings = append(findings, finding(RuleChangedEnumTrait, Error, id, "removed enum value %q", value))
+		case from.Items[i].Name != nil && (name == nil || name.Value != from.Items[i].Name.Value):
+			findings = append(findings, finding(RuleChangedEnumTrait, Error, id, "changed name of enum value %q", value))
+		}
+	}
+	return findings
+}
+
+// jsonString returns the JSON AST encoding of a trait value, or "none"
+// if there is no value.
+func jsonString(n ast.Node) string {
+	if n == nil {
+		return "none"
+	}
+	p, err := json.Marshal(n)
+	if err != nil {
+		return "?"
+	}
+	return string(p)
+}
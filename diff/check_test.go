@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from, to string
+		findings []string
+	}{
+		{
+			name: "shapes",
+			from: `"foo#A": {"type": "string"}, "foo#B": {"type": "string"}`,
+			to:   `"foo#A": {"type": "blob"}, "foo#C": {"type": "string"}`,
+			findings: []string{
+				"[ERROR] foo#A: changed shape type from string to blob (ChangedShapeType)",
+				"[ERROR] foo#B: removed string shape (RemovedShape)",
+				"[NOTE] foo#C: added string shape (AddedShape)",
+			},
+		},
+		{
+			name: "members",
+			from: `"foo#S": {"type": "structure", "members": {"a": {"target": "foo#X"}, "b": {"target": "foo#X"}}}`,
+			to: `"foo#S": {"type": "structure", "members": {"a": {"target": "foo#Y", "traits": {"smithy.api#required": {}}},
+				"c": {"target": "foo#X"}, "d": {"target": "foo#X", "traits": {"smithy.api#required": {}}}}}`,
+			findings: []string{
+				"[ERROR] foo#S$a: changed member target from foo#X to foo#Y (ChangedMemberTarget)",
+				"[ERROR] foo#S$a: added required trait (AddedRequiredTrait)",
+				"[ERROR] foo#S$b: removed member (RemovedMember)",
+				"[NOTE] foo#S$c: added member (AddedMember)",
+				"[ERROR] foo#S$d: added required member (AddedRequiredTrait)",
+			},
+		},
+		{
+			name: "operations",
+			from: `"foo#Svc": {"type": "service", "version": "1", "operations": ["foo#Op1", "foo#Op2"]},
+				"foo#Op1": {"type": "operation", "input": "foo#In", "errors": ["foo#E1"]}`,
+			to: `"foo#Svc": {"type": "service", "version": "1", "operations": ["foo#Op1", "foo#Op3"]},
+				"foo#Op1": {"type": "operation", "output": "foo#Out", "errors": ["foo#E2"]}`,
+			findings: []string{
+				"[NOTE] foo#Op1: removed error foo#E1 (RemovedOperationError)",
+				"[WARNING] foo#Op1: added error foo#E2 (AddedOperationError)",
+				"[ERROR] foo#Op1: input is no longer foo#In (ChangedOperationInput)",
+				"[ERROR] foo#Op1: output is now foo#Out (ChangedOperationOutput)",
+				"[ERROR] foo#Svc: removed operation foo#Op2 from operations (RemovedOperationBinding)",
+				"[NOTE] foo#Svc: added operation foo#Op3 (AddedOperationBinding)",
+			},
+		},
+		{
+			name: "constraints",
+			from: `"foo#L": {"type": "string", "traits": {"smithy.api#length": {"min": 1, "max": 10}}},
+				"foo#M": {"type": "string", "traits": {"smithy.api#length": {"min": 1, "max": 10}}},
+				"foo#R": {"type": "integer", "traits": {"smithy.api#range": {"max": 10.5}}},
+				"foo#P": {"type": "string"}`,
+			to: `"foo#L": {"type": "string", "traits": {"smithy.api#length": {"min": 2, "max": 10}}},
+				"foo#M": {"type": "string", "traits": {"smithy.api#length": {"max": 20}}},
+				"foo#R": {"type": "integer", "traits": {"smithy.api#range": {"min": 0, "max": 10.5}}},
+				"foo#P": {"type": "string", "traits": {"smithy.api#pattern": "^a$"}}`,
+			findings: []string{
+				`[ERROR] foo#L: narrowed length from {"min":1,"max":10} to {"min":2,"max":10} (ChangedLengthTrait)`,
+				`[ERROR] foo#P: changed pattern from none to "^a$" (ChangedPatternTrait)`,
+				`[ERROR] foo#R: narrowed range from {"max":10.5} to {"min":0,"max":10.5} (ChangedRangeTrait)`,
+			},
+		},
+		{
+			name: "enum",
+			from: `"foo#E": {"type": "string", "traits": {"smithy.api#enum": [
+				{"value": "a", "name": "A"}, {"value": "b", "name": "B"}, {"value": "c"}]}},
+				"foo#F": {"type": "string"}`,
+			to: `"foo#E": {"type": "string", "traits": {"smithy.api#enum": [
+				{"value": "a", "name": "AA"}, {"value": "c"}, {"value": "d"}]}},
+				"foo#F": {"type": "string", "traits": {"smithy.api#enum": [{"value": "a"}]}}`,
+			findings: []string{
+				`[ERROR] foo#E: changed name of enum value "a" (ChangedEnumTrait)`,
+				`[ERROR] foo#E: removed enum value "b" (ChangedEnumTrait)`,
+				`[ERROR] foo#F: added enum trait (ChangedEnumTrait)`,
+			},
+		},
+		{
+			name: "protocol traits",
+			from: `"foo#Op": {"type": "operation", "traits": {"smithy.api#http": {"method": "GET", "uri": "/a"}}},
+				"foo#S": {"type": "structure", "members": {
+					"a": {"target": "foo#X", "traits": {"smithy.api#jsonName": "A"}},
+					"b": {"target": "foo#X", "traits": {"smithy.api#timestampFormat": "epoch-seconds"}}}}`,
+			to: `"foo#Op": {"type": "operation", "traits": {"smithy.api#http": {"method": "GET", "uri": "/b"}}},
+				"foo#S": {"type": "structure", "members": {
+					"a": {"target": "foo#X", "traits": {"smithy.api#xmlName": "A"}},
+					"b": {"target": "foo#X", "traits": {"smithy.api#timestampFormat": "date-time"}}}}`,
+			findings: []string{
+				`[ERROR] foo#Op: changed http trait from {"method":"GET","uri":"/a"} to {"method":"GET","uri":"/b"} (ChangedHttpTrait)`,
+				`[ERROR] foo#S$a: changed jsonName from "A" to none (ChangedJsonName)`,
+				`[ERROR] foo#S$a: changed xmlName from none to "A" (ChangedXmlName)`,
+				`[ERROR] foo#S$b: changed timestampFormat from "epoch-seconds" to "date-time" (ChangedTimestampFormat)`,
+			},
+		},
+		{
+			name: "compatible",
+			from: `"foo#L": {"type": "string", "traits": {"smithy.api#length": {"min": 1}, "smithy.api#documentation": "a"}}`,
+			to:   `"foo#L": {"type": "string", "traits": {"smithy.api#length": {"min": 0}}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			from := testmodel.Read(t, `{"version": "1.0", "shapes": {`+testCase.from+`}}`)
+			to := testmodel.Read(t, `{"version": "1.0", "shapes": {`+testCase.to+`}}`)
+
+			findings := Check(from, to)
+
+			var actual []string
+			for _, f := range findings {
+				actual = append(actual, f.String())
+				assert.NotNil(t, f.Change)
+			}
+			assert.Equal(t, testCase.findings, actual)
+		})
+	}
+}
+
+func TestChecker_Overrides(t *testing.T) {
+	from := testmodel.Read(t, `{"version": "1.0", "shapes": {
+		"foo#A": {"type": "string"}, "foo#B": {"type": "string"}, "bar#C": {"type": "string"},
+		"foo#S": {"type": "structure", "members": {"a": {"target": "foo#A"}}}
+	}}`)
+	to := testmodel.Read(t, `{"version": "1.0", "shapes": {
+		"foo#S": {"type": "structure", "members": {}}
+	}}`)
+	note, warning := Note, Warning
+	c := Checker{
+		Overrides: []Override{
+			{Rule: RuleRemovedShape, Severity: &warning},
+			{ShapeID: "bar#", Severity: &note},
+			{Rule: RuleRemovedShape, ShapeID: "foo#B", Suppress: true},
+			{ShapeID: "foo#S", Severity: &warning},
+			{Rule: RuleRemovedMember},
+		},
+	}
+
+	findings := c.Check(from, to)
+
+	var actual []string
+	for _, f := range findings {
+		actual = append(actual, f.String())
+	}
+	assert.Equal(t, []string{
+		"[NOTE] bar#C: removed string shape (RemovedShape)",
+		"[WARNING] foo#A: removed string shape (RemovedShape)",
+		"[WARNING] foo#S$a: removed member (RemovedMember)",
+	}, actual)
+	assert.Equal(t, "Severity(7)", Severity(7).String())
+	assert.Equal(t, ast.AbsShapeID("foo#S$a"), findings[2].Change.ShapeID())
+}
@@ -0,0 +1,251 @@
+// Package diff compares two Smithy models and reports the differences
+// between them as typed change records.
+package diff
+
+import (
+	"sort"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/jsonequal"
+)
+
+// Diff compares an old version of a model, from, with a new version,
+// to, and returns the changes which turn from into to.
+//
+// Metadata changes come first, ordered by key, followed by shape changes
+// ordered by shape ID. Changes to one shape are ordered as follows: the
+// shape being added, removed or changing type; its traits; its members
+// and their traits; and finally the shape IDs held by services,
+// resources and operations. Added and removed shapes are reported as a
+// single change, without separate changes for their members and traits.
+//
+// Trait and metadata values are compared by their JSON AST encoding, so
+// differences in node locations are not reported.
+func Diff(from, to ast.Model) []Change {
+	var changes []Change
+
+	for _, key := range sortedKeys(from.Metadata, to.Metadata) {
+		o, inOld := from.Metadata[key]
+		n, inNew := to.Metadata[key]
+		switch {
+		case !inNew:
+			changes = append(changes, MetadataRemoved{Key: key, Value: o})
+		case !inOld:
+			changes = append(changes, MetadataAdded{Key: key, Value: n})
+		case !jsonequal.Equal(&o, &n):
+			changes = append(changes, MetadataChanged{Key: key, Old: o, New: n})
+		}
+	}
+
+	ids := make(map[ast.AbsShapeID]bool, len(from.Shapes)+len(to.Shapes))
+	for id := range from.Shapes {
+		ids[id] = true
+	}
+	for id := range to.Shapes {
+		ids[id] = true
+	}
+	sorted := make([]ast.AbsShapeID, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, id := range sorted {
+		o, inOld := from.Shapes[id]
+		n, inNew := to.Shapes[id]
+		switch {
+		case !inNew:
+			changes = append(changes, ShapeRemoved{ID: id, Shape: o})
+		case !inOld:
+			changes = append(changes, ShapeAdded{ID: id, Shape: n})
+		default:
+			changes = diffShape(changes, id, o, n)
+		}
+	}
+
+	return changes
+}
+
+func diffShape(changes []Change, id ast.AbsShapeID, o, n ast.Shape) []Change {
+	if o.Type != n.Type {
+		changes = append(changes, ShapeTypeChanged{ID: id, Old: o.Type, New: n.Type})
+	}
+	changes = diffTraits(changes, id, o.Traits, n.Traits)
+
+	oldMembers, newMembers := members(o), members(n)
+	for _, name := range sortedKeys(oldMembers, newMembers) {
+		mo, inOld := oldMembers[name]
+		mn, inNew := newMembers[name]
+		memberID := id + "$" + ast.AbsShapeID(name)
+		switch {
+		case !inNew:
+			changes = append(changes, MemberRemoved{ID: memberID, Member: mo})
+		case !inOld:
+			changes = append(changes, MemberAdded{ID: memberID, Member: mn})
+		default:
+			if mo.Target.Value != mn.Target.Value {
+				changes = append(changes, MemberTargetChanged{ID: memberID, Old: mo.Target.Value, New: mn.Target.Value})
+			}
+			changes = diffTraits(changes, memberID, mo.Traits, mn.Traits)
+		}
+	}
+
+	if o.Service != nil || n.Service != nil {
+		var so, sn ast.Service
+		if o.Service != nil {
+			so = *o.Service
+		}
+		if n.Service != nil {
+			sn = *n.Service
+		}
+		if so.Version.Value != sn.Version.Value {
+			changes = append(changes, ServiceVersionChanged{ID: id, Old: so.Version.Value, New: sn.Version.Value})
+		}
+	}
+
+	oldRefs, newRefs := references(o), references(n)
+	for _, prop := range sortedKeys(oldRefs, newRefs) {
+		ro, rn := oldRefs[prop], newRefs[prop]
+		for _, target := range ro {
+			if !contains(rn, target) {
+				changes = append(changes, ReferenceRemoved{ID: id, Property: prop, Target: target})
+			}
+		}
+		for _, target := range rn {
+			if !contains(ro, target) {
+				changes = append(changes, ReferenceAdded{ID: id, Property: prop, Target: target})
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffTraits(changes []Change, id ast.AbsShapeID, o, n ast.Traits) []Change {
+	for _, key := range sortedKeys(o, n) {
+		trait := ast.AbsShapeID(key)
+		vo, inOld := o[trait]
+		vn, inNew := n[trait]
+		switch {
+		case !inNew:
+			changes = append(changes, TraitRemoved{ID: id, Trait: trait, Value: vo})
+		case !inOld:
+			changes = append(changes, TraitAdded{ID: id, Trait: trait, Value: vn})
+		case !jsonequal.Equal(vo, vn):
+			changes = append(changes, TraitChanged{ID: id, Trait: trait, Old: vo, New: vn})
+		}
+	}
+	return changes
+}
+
+// members returns the members of s by name, including the member of a
+// list or set and the key and value of a map.
+func members(s ast.Shape) map[string]ast.Member {
+	if s.Key == nil && s.Value == nil {
+		return s.Members
+	}
+	m := make(map[string]ast.Member, len(s.Members)+2)
+	for name, member := range s.Members {
+		m[name] = member
+	}
+	if s.Key != nil {
+		m["key"] = *s.Key
+	}
+	if s.Value != nil && s.Type == ast.MapType {
+		m["value"] = *s.Value
+	} else if s.Value != nil {
+		m["member"] = *s.Value
+	}
+	return m
+}
+
+// references returns the shape IDs held by a service, resource or
+// operation, keyed by JSON AST property.
+func references(s ast.Shape) map[string][]ast.AbsShapeID {
+	refs := make(map[string][]ast.AbsShapeID)
+	add := func(prop string, ids ...ast.AbsShapeIDNode) {
+		for _, id := range ids {
+			refs[prop] = append(refs[prop], id.Value)
+		}
+	}
+	addPtr := func(prop string, id *ast.AbsShapeIDNode) {
+		if id != nil {
+			add(prop, *id)
+		}
+	}
+
+	if svc := s.Service; svc != nil {
+		add("operations", svc.Operations...)
+		add("resources", svc.Resources...)
+		add("errors", svc.Errors...)
+	}
+	if res := s.Resource; res != nil {
+		for name, id := range res.Identifiers {
+			add("identifiers/"+name, id)
+		}
+		addPtr("create", res.Create)
+		addPtr("put", res.Put)
+		addPtr("read", res.Read)
+		addPtr("update", res.Update)
+		addPtr("delete", res.Delete)
+		addPtr("list", res.List)
+		add("operations", res.Operations...)
+		add("collectionOperations", res.CollectionOperations...)
+		add("resources", res.Resources...)
+	}
+	if op := s.Operation; op != nil {
+		addPtr("input", op.Input)
+		addPtr("output", op.Output)
+		add("errors", op.Errors...)
+	}
+	return refs
+}
+
+func contains(ids []ast.AbsShapeID, id ast.AbsShapeID) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys present in either of two maps with the
+// same string-based key type, in sorted order.
+func sortedKeys(a, b interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []interface{}{a, b} {
+		for _, key := range mapKeys(m) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mapKeys(m interface{}) []string {
+	var keys []string
+	switch x := m.(type) {
+	case map[string]ast.InterfaceNode:
+		for key := range x {
+			keys = append(keys, key)
+		}
+	case map[string]ast.Member:
+		for key := range x {
+			keys = append(keys, key)
+		}
+	case map[string][]ast.AbsShapeID:
+		for key := range x {
+			keys = append(keys, key)
+		}
+	case ast.Traits:
+		for key := range x {
+			keys = append(keys, string(key))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testFrom = `{
+	"version": "1.0",
+	"metadata": {"a": 1, "b": "x", "c": true},
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"operations": ["foo#Op1", "foo#Op2"]
+		},
+		"foo#Op1": {"type": "operation", "input": "foo#In"},
+		"foo#Op2": {"type": "operation"},
+		"foo#In": {
+			"type": "structure",
+			"members": {
+				"a": {"target": "smithy.api#String", "traits": {"smithy.api#required": {}}},
+				"b": {"target": "smithy.api#String"},
+				"c": {"target": "smithy.api#Integer", "traits": {"smithy.api#documentation": "C"}}
+			},
+			"traits": {"smithy.api#documentation": "In", "smithy.api#sensitive": {}}
+		},
+		"foo#List": {"type": "list", "member": {"target": "smithy.api#String"}},
+		"foo#Gone": {"type": "string"},
+		"foo#Num": {"type": "integer", "traits": {"smithy.api#range": {"min": 1}}}
+	}
+}`
+
+const testTo = `{
+	"version": "1.0",
+	"metadata": {"a": 2, "b": "x", "d": null},
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "2",
+			"operations": ["foo#Op2", "foo#Op3"]
+		},
+		"foo#Op1": {"type": "operation", "input": "foo#In2"},
+		"foo#Op2": {"type": "operation"},
+		"foo#Op3": {"type": "operation"},
+		"foo#In": {
+			"type": "structure",
+			"members": {
+				"a": {"target": "smithy.api#String"},
+				"c": {"target": "smithy.api#Long", "traits": {"smithy.api#documentation": "C!"}},
+				"d": {"target": "smithy.api#String"}
+			},
+			"traits": {"smithy.api#documentation": "In", "smithy.api#tags": ["x"]}
+		},
+		"foo#In2": {"type": "structure", "members": {}},
+		"foo#List": {"type": "list", "member": {"target": "smithy.api#Integer"}},
+		"foo#Num": {"type": "long", "traits": {"smithy.api#range": {"min": 1}}}
+	}
+}`
+
+func TestDiff(t *testing.T) {
+	from, to := testmodel.Read(t, testFrom), testmodel.Read(t, testTo)
+
+	changes := Diff(from, to)
+
+	var descriptions []string
+	for _, c := range changes {
+		descriptions = append(descriptions, c.String())
+	}
+	assert.Equal(t, []string{
+		`changed metadata "a"`,
+		`removed metadata "c"`,
+		`added metadata "d"`,
+		`removed string foo#Gone`,
+		`removed trait smithy.api#sensitive from foo#In`,
+		`added trait smithy.api#tags to foo#In`,
+		`removed trait smithy.api#required from foo#In$a`,
+		`removed member foo#In$b targeting smithy.api#String`,
+		`changed target of member foo#In$c from smithy.api#Integer to smithy.api#Long`,
+		`changed trait smithy.api#documentation on foo#In$c`,
+		`added member foo#In$d targeting smithy.api#String`,
+		`added structure foo#In2`,
+		`changed target of member foo#List$member from smithy.api#String to smithy.api#Integer`,
+		`changed type of foo#Num from integer to long`,
+		`removed foo#In from input of foo#Op1`,
+		`added foo#In2 to input of foo#Op1`,
+		`added operation foo#Op3`,
+		`changed version of foo#Service from "1" to "2"`,
+		`removed foo#Op1 from operations of foo#Service`,
+		`added foo#Op3 to operations of foo#Service`,
+	}, descriptions)
+
+	assert.Equal(t, MetadataChanged{Key: "a", Old: from.Metadata["a"], New: to.Metadata["a"]}, changes[0])
+	assert.Equal(t, TraitChanged{
+		ID:    "foo#In$c",
+		Trait: ast.DocumentationTraitID,
+		Old:   from.Shapes["foo#In"].Members["c"].Traits[ast.DocumentationTraitID],
+		New:   to.Shapes["foo#In"].Members["c"].Traits[ast.DocumentationTraitID],
+	}, changes[9])
+	assert.Equal(t, ReferenceAdded{ID: "foo#Service", Property: "operations", Target: "foo#Op3"}, changes[19])
+	assert.Equal(t, ast.AbsShapeID("foo#Service"), changes[19].ShapeID())
+	assert.Equal(t, ast.AbsShapeID(""), changes[0].ShapeID())
+}
+
+func TestDiff_Same(t *testing.T) {
+	from, to := testmodel.Read(t, testFrom), testmodel.Read(t, strings.Replace(testFrom, "\t", "  ", -1))
+
+	assert.Empty(t, Diff(from, to))
+}
@@ -0,0 +1,614 @@
+// Package generate generates random values of the shapes of a Smithy
+// model, for property-based tests and realistic fixtures.
+//
+// Value generates a value of a shape from a *rand.Rand, so a seeded
+// source yields the same value every time. The value satisfies the
+// length, range, pattern, enum and uniqueItems traits of its shapes and
+// members, has every required member, and only has nulls in sparse
+// lists and maps, so that validate.Value reports no violations for it.
+// Patterns are satisfied by generating strings from the regular
+// expression itself.
+//
+// FromBytes derives a value from a byte slice instead, which lets Go's
+// native fuzzing explore the values of a shape:
+//
+//	func FuzzPutThing(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			input, err := generate.FromBytes(m, "foo#PutThingInput", data, generate.Options{})
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			// Exercise the code under test with input.
+//		})
+//	}
+package generate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// Options controls the size of the values Value generates.
+type Options struct {
+	// MaxDepth is the nesting depth of structures, unions, lists and
+	// maps beyond which only required members and the minimum number
+	// of items are generated, so that values of recursive shapes stay
+	// finite. The default is 5.
+	MaxDepth int
+
+	// MaxItems is the most items generated for a list or map beyond the
+	// minimum of its length trait. The default is 4.
+	MaxItems int
+
+	// MaxLength is the most characters or bytes generated for a string
+	// or blob beyond the minimum of its length trait. The default is
+	// 16.
+	MaxLength int
+}
+
+const (
+	defaultMaxDepth  = 5
+	defaultMaxItems  = 4
+	defaultMaxLength = 16
+)
+
+// depthLimit is how many levels beyond MaxDepth values may nest before
+// Value gives up on a shape, such as a structure with a required member
+// targeting itself, whose values cannot be finite.
+const depthLimit = 32
+
+// attempts is how many times a value is generated again when it fails
+// a constraint, such as a pattern or uniqueness, before giving up.
+const attempts = 100
+
+// Value generates a random value of the shape with the given ID in m,
+// drawing every random choice from r. Shapes not in m are looked up in
+// the prelude.
+//
+// Values have the types of the value tree documented by jsoncodec.Codec:
+// structures and unions are map[string]interface{} keyed by member
+// name, lists and sets are []interface{}, blobs are []byte, integers are
+// int64, floating point numbers are float64, big numbers are *big.Int
+// and *big.Float, and timestamps are time.Time values in UTC. Documents
+// are built from booleans, strings, json.Number integers, slices and
+// maps. Optional members are set about half the time.
+//
+// Value returns an error if the shape, or a shape it refers to, cannot
+// be found, if a pattern trait is not a valid regular expression, or if
+// no value satisfies the constraints, or none is found after repeated
+// attempts.
+func Value(m ast.Model, id ast.AbsShapeID, r *rand.Rand, opts Options) (interface{}, error) {
+	g := newGenerator(m, r, opts)
+	return g.value(id, id, nil, 0)
+}
+
+// FromBytes generates a value as Value does, drawing the random choices
+// from data by way of NewSource. Equal data yields equal values, and
+// short data yields small values.
+func FromBytes(m ast.Model, id ast.AbsShapeID, data []byte, opts Options) (interface{}, error) {
+	return Value(m, id, rand.New(NewSource(data)), opts)
+}
+
+// NewSource returns a rand.Source which reads its values from data,
+// eight bytes at a time, so that mutating data, as a fuzzer does,
+// changes the values generated from it. Once data is exhausted, the
+// source returns zero, which makes Value choose the smallest values it
+// can. Seeding the source has no effect.
+func NewSource(data []byte) rand.Source {
+	return &byteSource{data: data}
+}
+
+type byteSource struct {
+	data []byte
+}
+
+func (s *byteSource) Int63() int64 {
+	var p [8]byte
+	n := copy(p[:], s.data)
+	s.data = s.data[n:]
+	return int64(binary.BigEndian.Uint64(p[:]) >> 1)
+}
+
+func (s *byteSource) Seed(int64) {}
+
+type generator struct {
+	r        *rand.Rand
+	opts     Options
+	shapes   map[ast.AbsShapeID]ast.Shape
+	patterns map[string]*pattern
+}
+
+func newGenerator(m ast.Model, r *rand.Rand, opts Options) *generator {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultMaxDepth
+	}
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = defaultMaxItems
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = defaultMaxLength
+	}
+	shapes := prelude.Shapes(m)
+	return &generator{
+		r:        r,
+		opts:     opts,
+		shapes:   shapes,
+		patterns: make(map[string]*pattern),
+	}
+}
+
+// value generates a value of the shape with ID target at the given
+// nesting depth. If the value is a member value, id is the member ID and
+// memberTraits are the member's traits; otherwise id is the target.
+func (g *generator) value(id, target ast.AbsShapeID, memberTraits ast.Traits, depth int) (interface{}, error) {
+	s, ok := g.shapes[target]
+	if !ok {
+		if id != target {
+			return nil, newErrorf("target %s of member %s not found", target, id)
+		}
+		return nil, newErrorf("shape %s not found", target)
+	}
+	traits := s.Traits.Merge(memberTraits)
+	if (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value == nil ||
+		s.Type == ast.MapType && (s.Key == nil || s.Value == nil) {
+		return nil, newErrorf("%s %s has no members", s.Type, target)
+	}
+	if !ast.SimpleShapeTypes[s.Type] && depth > g.opts.MaxDepth+depthLimit {
+		return nil, newErrorf("values of %s nest more than %d levels deep", target, depth)
+	}
+
+	switch s.Type {
+	case ast.StructureType:
+		obj := make(map[string]interface{})
+		for _, name := range s.MemberNames() {
+			member := s.Members[name]
+			if !member.Traits.HasTrait(ast.RequiredTraitID) && (depth >= g.opts.MaxDepth || g.r.Intn(2) == 0) {
+				continue
+			}
+			v, err := g.value(target+"$"+ast.AbsShapeID(name), member.Target.Value, member.Traits, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = v
+		}
+		return obj, nil
+
+	case ast.UnionType:
+		names := s.MemberNames()
+		if depth >= g.opts.MaxDepth {
+			// Prefer members whose values cannot nest any deeper.
+			var simple []string
+			for _, name := range names {
+				if ast.SimpleShapeTypes[g.shapes[s.Members[name].Target.Value].Type] {
+					simple = append(simple, name)
+				}
+			}
+			if len(simple) > 0 {
+				names = simple
+			}
+		}
+		if len(names) == 0 {
+			return nil, newErrorf("union %s has no members", target)
+		}
+		name := names[g.r.Intn(len(names))]
+		member := s.Members[name]
+		v, err := g.value(target+"$"+ast.AbsShapeID(name), member.Target.Value, member.Traits, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{name: v}, nil
+
+	case ast.ListType, ast.SetType:
+		min, n, err := g.length(target, traits, g.opts.MaxItems, depth >= g.opts.MaxDepth)
+		if err != nil {
+			return nil, err
+		}
+		unique := s.Type == ast.SetType || traits.HasTrait(ast.UniqueItemsTraitID)
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		items := make([]interface{}, 0, n)
+		seen := make(map[string]bool)
+		for len(items) < n {
+			item, ok, err := g.unique(seen, unique, func() (interface{}, string, error) {
+				if sparse && g.r.Intn(8) == 7 {
+					return nil, "null", nil
+				}
+				v, err := g.value(target+"$member", s.Value.Target.Value, s.Value.Traits, depth+1)
+				return v, canonical(v), err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if len(items) < min {
+					return nil, newErrorf("cannot generate %d unique items of %s", min, target)
+				}
+				break
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case ast.MapType:
+		min, n, err := g.length(target, traits, g.opts.MaxItems, depth >= g.opts.MaxDepth)
+		if err != nil {
+			return nil, err
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		obj := make(map[string]interface{}, n)
+		seen := make(map[string]bool)
+		for len(obj) < n {
+			key, ok, err := g.unique(seen, true, func() (interface{}, string, error) {
+				k, err := g.value(target+"$key", s.Key.Target.Value, s.Key.Traits, depth+1)
+				if err != nil {
+					return nil, "", err
+				}
+				str, ok := k.(string)
+				if !ok {
+					return nil, "", newErrorf("key of map %s is not a string", target)
+				}
+				return str, str, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if len(obj) < min {
+					return nil, newErrorf("cannot generate %d unique keys of %s", min, target)
+				}
+				break
+			}
+			var value interface{}
+			if !sparse || g.r.Intn(8) != 7 {
+				value, err = g.value(target+"$value", s.Value.Target.Value, s.Value.Traits, depth+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			obj[key.(string)] = value
+		}
+		return obj, nil
+
+	case ast.StringType:
+		return g.string(id, traits)
+
+	case ast.BlobType:
+		_, n, err := g.length(id, traits, g.opts.MaxLength, false)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(g.r.Intn(256))
+		}
+		return b, nil
+
+	case ast.BooleanType:
+		return g.r.Intn(2) == 1, nil
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		i, err := g.integer(id, s.Type, traits)
+		if err != nil {
+			return nil, err
+		}
+		if s.Type == ast.BigIntegerType {
+			return i, nil
+		}
+		return i.Int64(), nil
+
+	case ast.FloatType, ast.DoubleType, ast.BigDecimalType:
+		f, err := g.float(id, s.Type, traits)
+		if err != nil {
+			return nil, err
+		}
+		if s.Type == ast.BigDecimalType {
+			return big.NewFloat(f), nil
+		}
+		return f, nil
+
+	case ast.TimestampType:
+		// Timestamps fall between 1970 and 2100, in whole seconds, so
+		// that every timestamp format represents them exactly.
+		return time.Unix(g.r.Int63n(4102444800), 0).UTC(), nil
+
+	case ast.DocumentType:
+		return g.document(depth), nil
+
+	default:
+		return nil, newErrorf("shape %s is a %s, which has no values", target, s.Type)
+	}
+}
+
+// unique generates a value with gen, whose canonical key must not be in
+// seen if unique is true, and records its key. It reports false if no
+// such value is found after repeated attempts.
+//
+// A random source can repeat itself, as NewSource's does once its data
+// is exhausted, so after the first attempts gen draws from sources
+// seeded with a draw from the generator's source mixed with the attempt
+// number instead. These still yield equal values for equal sources, and
+// depend on the source as well as the attempt.
+func (g *generator) unique(seen map[string]bool, unique bool, gen func() (interface{}, string, error)) (interface{}, bool, error) {
+	r := g.r
+	defer func() { g.r = r }()
+	for i := 0; i < 2*attempts; i++ {
+		if i >= attempts {
+			g.r = rand.New(rand.NewSource(r.Int63() ^ int64(i)))
+		}
+		v, key, err := gen()
+		if err != nil {
+			return nil, false, err
+		}
+		if !unique || !seen[key] {
+			seen[key] = true
+			return v, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// length chooses a length satisfying the length trait in traits, at most
+// limit beyond its minimum, and returns the minimum and the length. If
+// minimal is true, the length is the minimum.
+func (g *generator) length(id ast.AbsShapeID, traits ast.Traits, limit int, minimal bool) (int, int, error) {
+	min, max := int64(0), int64(limit)
+	if length, ok := traits[ast.LengthTraitID].(*ast.LengthTrait); ok {
+		if length.Min != nil && length.Min.Value > 0 {
+			min = length.Min.Value
+		}
+		max = min + int64(limit)
+		if length.Max != nil && length.Max.Value < max {
+			max = length.Max.Value
+		}
+	}
+	if max < min {
+		return 0, 0, newErrorf("length trait of %s admits no values", id)
+	}
+	if minimal {
+		max = min
+	}
+	return int(min), int(min + g.r.Int63n(max-min+1)), nil
+}
+
+// string generates a string value, which is one of the values of the
+// enum trait if there is one.
+func (g *generator) string(id ast.AbsShapeID, traits ast.Traits) (string, error) {
+	var p *pattern
+	if str, ok := traits[ast.PatternTraitID].(*ast.StringNode); ok {
+		var err error
+		if p, err = g.pattern(str.Value); err != nil {
+			return "", err
+		}
+	}
+	if enum, ok := traits[ast.EnumTraitID].(*ast.EnumTrait); ok {
+		var values []string
+		for _, item := range enum.Items {
+			if satisfiesLength(item.Value.Value, traits) && (p == nil || p.re.MatchString(item.Value.Value)) {
+				values = append(values, item.Value.Value)
+			}
+		}
+		if len(values) == 0 {
+			return "", newErrorf("no enum value of %s satisfies its constraints", id)
+		}
+		return values[g.r.Intn(len(values))], nil
+	}
+
+	min, n, err := g.length(id, traits, g.opts.MaxLength, false)
+	if err != nil {
+		return "", err
+	}
+	if p != nil {
+		max := math.MaxInt32
+		if length, ok := traits[ast.LengthTraitID].(*ast.LengthTrait); ok && length.Max != nil && length.Max.Value < int64(max) {
+			max = int(length.Max.Value)
+		}
+		return g.matching(id, p, min, max)
+	}
+	return g.chars(n), nil
+}
+
+// chars generates a string of n characters, mostly printable ASCII.
+func (g *generator) chars(n int) string {
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = g.char()
+	}
+	return string(runes)
+}
+
+// nonASCII are the characters chars occasionally chooses to exercise
+// multi-byte encodings.
+var nonASCII = []rune("éßΩж中文😀")
+
+func (g *generator) char() rune {
+	if g.r.Intn(16) == 15 {
+		return nonASCII[g.r.Intn(len(nonASCII))]
+	}
+	return rune(' ' + g.r.Intn('~'-' '+1))
+}
+
+// integer generates an integer of the given type satisfying the range
+// trait in traits. It chooses one of the bounds a quarter of the time.
+func (g *generator) integer(id ast.AbsShapeID, t ast.ShapeType, traits ast.Traits) (*big.Int, error) {
+	var lo, hi *big.Int
+	if bits := valuetree.IntegerBits[t]; bits > 0 {
+		lo = new(big.Int).Lsh(big.NewInt(-1), bits-1)
+		hi = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits-1), big.NewInt(1))
+	} else {
+		hi = new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+		lo = new(big.Int).Neg(hi)
+	}
+	if r, ok := traits[ast.RangeTraitID].(*ast.RangeTrait); ok {
+		if r.Min != nil {
+			min, acc := r.Min.Value.Int(nil)
+			if acc == big.Below {
+				min.Add(min, big.NewInt(1))
+			}
+			if min.Cmp(lo) > 0 {
+				lo = min
+			}
+		}
+		if r.Max != nil {
+			max, acc := r.Max.Value.Int(nil)
+			if acc == big.Above {
+				max.Sub(max, big.NewInt(1))
+			}
+			if max.Cmp(hi) < 0 {
+				hi = max
+			}
+		}
+	}
+	if lo.Cmp(hi) > 0 {
+		return nil, newErrorf("range trait of %s admits no %s values", id, t)
+	}
+	if g.r.Intn(4) == 0 {
+		if g.r.Intn(2) == 0 {
+			return lo, nil
+		}
+		return hi, nil
+	}
+	span := new(big.Int).Add(new(big.Int).Sub(hi, lo), big.NewInt(1))
+	return new(big.Int).Add(lo, new(big.Int).Rand(g.r, span)), nil
+}
+
+// float generates a floating point number of the given type satisfying
+// the range trait in traits. It chooses one of the bounds a quarter of
+// the time.
+func (g *generator) float(id ast.AbsShapeID, t ast.ShapeType, traits ast.Traits) (float64, error) {
+	const span = 1e6
+	lo, hi := -span, span
+	if r, ok := traits[ast.RangeTraitID].(*ast.RangeTrait); ok {
+		if r.Min != nil {
+			lo = bound(&r.Min.Value, t, 1)
+			if r.Max == nil {
+				hi = lo + 2*span
+			}
+		}
+		if r.Max != nil {
+			hi = bound(&r.Max.Value, t, -1)
+			if r.Min == nil {
+				lo = hi - 2*span
+			}
+		}
+	}
+	if lo > hi || math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return 0, newErrorf("range trait of %s admits no %s values", id, t)
+	}
+	var f float64
+	switch {
+	case g.r.Intn(4) == 0:
+		f = lo
+		if g.r.Intn(2) == 1 {
+			f = hi
+		}
+	default:
+		f = lo + g.r.Float64()*(hi-lo)
+	}
+	if t == ast.FloatType {
+		// Rounding to the nearest float32 keeps f within the bounds,
+		// which are themselves float32 values.
+		f = float64(float32(f))
+	}
+	return f, nil
+}
+
+// bound returns the float64, or float32 for float shapes, nearest to b
+// which is on the inside of the bound: not below b if dir is 1, and not
+// above b if dir is -1.
+func bound(b *big.Float, t ast.ShapeType, dir float64) float64 {
+	if t == ast.FloatType {
+		f, _ := b.Float32()
+		if new(big.Float).SetFloat64(float64(f)).Cmp(b)*int(dir) < 0 {
+			f = math.Nextafter32(f, float32(dir)*math.MaxFloat32)
+		}
+		return float64(f)
+	}
+	f, _ := b.Float64()
+	if new(big.Float).SetFloat64(f).Cmp(b)*int(dir) < 0 {
+		f = math.Nextafter(f, dir*math.MaxFloat64)
+	}
+	return f
+}
+
+// document generates a document value. Lists and maps are only
+// generated below the maximum depth.
+func (g *generator) document(depth int) interface{} {
+	kinds := 3
+	if depth < g.opts.MaxDepth {
+		kinds = 5
+	}
+	switch g.r.Intn(kinds) {
+	case 0:
+		return g.r.Intn(2) == 1
+	case 1:
+		return json.Number(strconv.Itoa(g.r.Intn(2001) - 1000))
+	case 2:
+		return g.chars(g.r.Intn(g.opts.MaxLength + 1))
+	case 3:
+		items := make([]interface{}, g.r.Intn(g.opts.MaxItems+1))
+		for i := range items {
+			items[i] = g.document(depth + 1)
+		}
+		return items
+	default:
+		obj := make(map[string]interface{})
+		for n := g.r.Intn(g.opts.MaxItems + 1); n > 0; n-- {
+			obj[g.chars(1+g.r.Intn(g.opts.MaxLength))] = g.document(depth + 1)
+		}
+		return obj
+	}
+}
+
+func (g *generator) pattern(str string) (*pattern, error) {
+	if p, ok := g.patterns[str]; ok {
+		return p, nil
+	}
+	re, err := regexp.Compile(str)
+	if err != nil {
+		return nil, newErrorf("invalid pattern %q: %w", str, err)
+	}
+	tree, err := syntax.Parse(str, syntax.Perl)
+	if err != nil {
+		return nil, newErrorf("invalid pattern %q: %w", str, err)
+	}
+	p := &pattern{str: str, re: re, tree: tree}
+	g.patterns[str] = p
+	return p, nil
+}
+
+func satisfiesLength(str string, traits ast.Traits) bool {
+	length, ok := traits[ast.LengthTraitID].(*ast.LengthTrait)
+	if !ok {
+		return true
+	}
+	n := int64(utf8.RuneCountInString(str))
+	return (length.Min == nil || n >= length.Min.Value) && (length.Max == nil || n <= length.Max.Value)
+}
+
+// canonical returns a string which is equal for equal values, for
+// generating unique items.
+func canonical(v interface{}) string {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(p)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "generate: "
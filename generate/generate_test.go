@@ -0,0 +1,252 @@
+package generate
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+	"github.com/gogama/smithy-ast/validate"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#User": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "foo#Name", "traits": {"smithy.api#required": {}}},
+				"age": {"target": "smithy.api#Byte", "traits": {"smithy.api#range": {"min": 0, "max": 120}}},
+				"code": {"target": "foo#Code", "traits": {"smithy.api#required": {}}},
+				"email": {"target": "foo#Email"},
+				"role": {"target": "foo#Role", "traits": {"smithy.api#required": {}}},
+				"tags": {"target": "foo#Tags"},
+				"ids": {"target": "foo#Ids"},
+				"attrs": {"target": "foo#Attrs"},
+				"notes": {"target": "foo#Notes"},
+				"contact": {"target": "foo#Contact"},
+				"avatar": {"target": "foo#Avatar"},
+				"score": {"target": "smithy.api#Double", "traits": {"smithy.api#range": {"min": -0.5, "max": 0.1}}},
+				"ratio": {"target": "smithy.api#Float", "traits": {"smithy.api#range": {"min": 0.1}}},
+				"count": {"target": "smithy.api#Long", "traits": {"smithy.api#range": {"max": -1}}},
+				"big": {"target": "smithy.api#BigInteger", "traits": {"smithy.api#range": {"min": 1.5, "max": 3.5}}},
+				"price": {"target": "smithy.api#BigDecimal"},
+				"created": {"target": "smithy.api#Timestamp"},
+				"extra": {"target": "smithy.api#Document"},
+				"manager": {"target": "foo#User"},
+				"reports": {"target": "foo#Users"}
+			}
+		},
+		"foo#Name": {
+			"type": "string",
+			"traits": {"smithy.api#length": {"min": 2, "max": 8}, "smithy.api#pattern": "^[a-z]+$"}
+		},
+		"foo#Code": {
+			"type": "string",
+			"traits": {"smithy.api#pattern": "^[A-Z]{3}-\\d{2,4}(x|yz)?$"}
+		},
+		"foo#Email": {
+			"type": "string",
+			"traits": {"smithy.api#length": {"min": 20}, "smithy.api#pattern": "[a-z]+@example\\.(com|org)"}
+		},
+		"foo#Role": {
+			"type": "string",
+			"traits": {
+				"smithy.api#enum": [{"value": "admin"}, {"value": "user"}, {"value": "x"}],
+				"smithy.api#length": {"min": 2}
+			}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "foo#Role"},
+			"traits": {"smithy.api#length": {"min": 1, "max": 2}, "smithy.api#uniqueItems": {}}
+		},
+		"foo#Ids": {
+			"type": "set",
+			"member": {"target": "smithy.api#Integer", "traits": {"smithy.api#range": {"min": 1, "max": 3}}}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "foo#Name"},
+			"value": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#sparse": {}, "smithy.api#length": {"min": 1}}
+		},
+		"foo#Notes": {
+			"type": "list",
+			"member": {"target": "smithy.api#String", "traits": {"smithy.api#length": {"max": 3}}},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Contact": {
+			"type": "union",
+			"members": {
+				"email": {"target": "foo#Email"},
+				"phone": {"target": "smithy.api#String", "traits": {"smithy.api#pattern": "^\\+?[0-9 ]{7,}$"}},
+				"user": {"target": "foo#User"}
+			}
+		},
+		"foo#Avatar": {
+			"type": "blob",
+			"traits": {"smithy.api#length": {"min": 1, "max": 3}}
+		},
+		"foo#Users": {
+			"type": "list",
+			"member": {"target": "foo#User"}
+		}
+	}
+}`
+
+func TestValue(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	for seed := int64(0); seed < 200; seed++ {
+		v, err := Value(m, "foo#User", rand.New(rand.NewSource(seed)), Options{})
+		require.NoError(t, err, "seed %d", seed)
+
+		violations, err := validate.Value(m, "foo#User", v, validate.Options{})
+		require.NoError(t, err)
+		assert.Empty(t, violations, "seed %d", seed)
+
+		again, err := Value(m, "foo#User", rand.New(rand.NewSource(seed)), Options{})
+		require.NoError(t, err)
+		assert.Equal(t, v, again, "seed %d", seed)
+	}
+}
+
+func TestValue_MaxDepth(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	for _, maxDepth := range []int{1, 2, 3} {
+		for seed := int64(0); seed < 50; seed++ {
+			v, err := Value(m, "foo#User", rand.New(rand.NewSource(seed)), Options{MaxDepth: maxDepth})
+			require.NoError(t, err)
+
+			// Below the maximum depth, structures only have their
+			// required members, which are strings.
+			assert.LessOrEqual(t, depth(v), maxDepth+1, "max depth %d, seed %d", maxDepth, seed)
+		}
+	}
+}
+
+func TestValue_Errors(t *testing.T) {
+	m := testmodel.Read(t, `{"version":"1.0","shapes":{
+		"foo#S":{"type":"structure","members":{"a":{"target":"foo#Missing"}}},
+		"foo#P":{"type":"string","traits":{"smithy.api#pattern":"("}},
+		"foo#L":{"type":"string","traits":{"smithy.api#length":{"min":3,"max":2}}},
+		"foo#R":{"type":"byte","traits":{"smithy.api#range":{"min":200}}},
+		"foo#F":{"type":"float","traits":{"smithy.api#range":{"min":2,"max":1}}},
+		"foo#E":{"type":"string","traits":{"smithy.api#enum":[{"value":"a"}],"smithy.api#length":{"min":2}}},
+		"foo#M":{"type":"string","traits":{"smithy.api#pattern":"^ab$","smithy.api#length":{"min":3}}},
+		"foo#U":{"type":"set","member":{"target":"smithy.api#Boolean"},"traits":{"smithy.api#length":{"min":3}}},
+		"foo#Loop":{"type":"structure","members":{"next":{"target":"foo#Loop","traits":{"smithy.api#required":{}}}}},
+		"foo#Op":{"type":"operation"}
+	}}`)
+
+	testCases := []struct {
+		name string
+		id   ast.AbsShapeID
+		err  string
+	}{
+		{name: "shape not found", id: "foo#Missing", err: "generate: shape foo#Missing not found"},
+		{name: "target not found", id: "foo#S", err: "generate: target foo#Missing of member foo#S$a not found"},
+		{name: "invalid pattern", id: "foo#P", err: "generate: invalid pattern \"(\": error parsing regexp: missing closing ): `(`"},
+		{name: "empty length", id: "foo#L", err: "generate: length trait of foo#L admits no values"},
+		{name: "empty integer range", id: "foo#R", err: "generate: range trait of foo#R admits no byte values"},
+		{name: "empty float range", id: "foo#F", err: "generate: range trait of foo#F admits no float values"},
+		{name: "no enum value", id: "foo#E", err: "generate: no enum value of foo#E satisfies its constraints"},
+		{name: "unsatisfiable pattern", id: "foo#M", err: "generate: cannot generate a value of foo#M matching pattern \"^ab$\" with length at least 3"},
+		{name: "not enough unique items", id: "foo#U", err: "generate: cannot generate 3 unique items of foo#U"},
+		{name: "infinite recursion", id: "foo#Loop", err: "generate: values of foo#Loop nest more than 38 levels deep"},
+		{name: "no values", id: "foo#Op", err: "generate: shape foo#Op is a operation, which has no values"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Value(m, testCase.id, rand.New(rand.NewSource(1)), Options{})
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	r := rand.New(rand.NewSource(1))
+	inputs := [][]byte{nil, {0xff}, make([]byte, 64)}
+	for i := 0; i < 100; i++ {
+		data := make([]byte, r.Intn(512))
+		r.Read(data)
+		inputs = append(inputs, data)
+	}
+	for i, data := range inputs {
+		v, err := FromBytes(m, "foo#User", data, Options{})
+		require.NoError(t, err, "input %d", i)
+
+		violations, err := validate.Value(m, "foo#User", v, validate.Options{})
+		require.NoError(t, err)
+		assert.Empty(t, violations, "input %d", i)
+
+		again, err := FromBytes(m, "foo#User", data, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, v, again, "input %d", i)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		v, err := FromBytes(m, "foo#User", nil, Options{})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"name": "aa", "code": "AAA-00", "role": "admin"}, v)
+	})
+
+	t.Run("empty unique", func(t *testing.T) {
+		m := testmodel.Read(t, `{"version":"1.0","shapes":{
+			"foo#S":{"type":"structure","members":{
+				"names":{"target":"foo#Names","traits":{"smithy.api#required":{}}},
+				"ids":{"target":"foo#Ids","traits":{"smithy.api#required":{}}},
+				"attrs":{"target":"foo#Attrs","traits":{"smithy.api#required":{}}}
+			}},
+			"foo#Names":{"type":"set","member":{"target":"smithy.api#String"},"traits":{"smithy.api#length":{"min":2}}},
+			"foo#Ids":{"type":"list","member":{"target":"smithy.api#Integer"},"traits":{"smithy.api#length":{"min":3},"smithy.api#uniqueItems":{}}},
+			"foo#Attrs":{"type":"map","key":{"target":"smithy.api#String"},"value":{"target":"smithy.api#Integer"},"traits":{"smithy.api#length":{"min":2}}}
+		}}`)
+
+		for i, data := range [][]byte{nil, {0}, {0xff, 0x01}} {
+			v, err := FromBytes(m, "foo#S", data, Options{})
+
+			require.NoError(t, err, "input %d", i)
+			violations, err := validate.Value(m, "foo#S", v, validate.Options{})
+			require.NoError(t, err)
+			assert.Empty(t, violations, "input %d", i)
+			again, err := FromBytes(m, "foo#S", data, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, v, again, "input %d", i)
+		}
+	})
+}
+
+// depth returns the nesting depth of the maps in a value.
+func depth(v interface{}) int {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		d := 0
+		for _, item := range v {
+			if n := depth(item); n > d {
+				d = n
+			}
+		}
+		return d + 1
+	case []interface{}:
+		d := 0
+		for _, item := range v {
+			if n := depth(item); n > d {
+				d = n
+			}
+		}
+		return d
+	default:
+		return 0
+	}
+}
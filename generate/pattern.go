@@ -0,0 +1,163 @@
+package generate
+
+import (
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// A pattern is the regular expression of a pattern trait, both compiled
+// for matching and parsed for generating strings.
+type pattern struct {
+	str  string
+	re   *regexp.Regexp
+	tree *syntax.Regexp
+}
+
+// repeatLimit is the most repetitions generated for a repetition
+// operator, such as * or {2,}, beyond its minimum.
+const repeatLimit = 4
+
+// matching generates a string of min to max characters which matches p.
+// The pattern alone limits the length if max is math.MaxInt32.
+//
+// The string is generated from the parsed regular expression, choosing
+// among alternatives, characters of classes and numbers of repetitions.
+// Since patterns are not anchored, a string too short is padded with
+// random characters, which keeps it matching unless p is anchored at
+// the end. Each attempt which fails for being too short adds one to the
+// repetitions of every operator, and each which is too long removes
+// one, so that patterns with long matches are satisfied too.
+func (g *generator) matching(id ast.AbsShapeID, p *pattern, min, max int) (string, error) {
+	stretch := 0
+	for i := 0; i < attempts; i++ {
+		var b strings.Builder
+		g.expand(&b, p.tree, stretch)
+		str := b.String()
+		n := utf8.RuneCountInString(str)
+		if n > max {
+			if stretch > 0 {
+				stretch--
+			}
+			continue
+		}
+		if n < min {
+			pad := min + g.r.Intn(g.opts.MaxLength+1)
+			if pad > max {
+				pad = max
+			}
+			str += g.chars(pad - n)
+		}
+		if p.re.MatchString(str) {
+			return str, nil
+		}
+		if n < min {
+			stretch++
+		}
+	}
+	if max == math.MaxInt32 {
+		return "", newErrorf("cannot generate a value of %s matching pattern %q with length at least %d", id, p.str, min)
+	}
+	return "", newErrorf("cannot generate a value of %s matching pattern %q with length %d to %d", id, p.str, min, max)
+}
+
+// expand writes a string matching re to b, repeating operators stretch
+// more times than their minimum.
+func (g *generator) expand(b *strings.Builder, re *syntax.Regexp, stretch int) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.r.Intn(2) == 1 {
+				r = unicode.SimpleFold(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(g.class(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteRune(g.char())
+	case syntax.OpCapture:
+		g.expand(b, re.Sub[0], stretch)
+	case syntax.OpStar:
+		g.repeat(b, re.Sub[0], 0, -1, stretch)
+	case syntax.OpPlus:
+		g.repeat(b, re.Sub[0], 1, -1, stretch)
+	case syntax.OpQuest:
+		g.repeat(b, re.Sub[0], 0, 1, stretch)
+	case syntax.OpRepeat:
+		g.repeat(b, re.Sub[0], re.Min, re.Max, stretch)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.expand(b, sub, stretch)
+		}
+	case syntax.OpAlternate:
+		g.expand(b, re.Sub[g.r.Intn(len(re.Sub))], stretch)
+	default:
+		// Empty matches, anchors and word boundaries match no
+		// characters. The string is checked against the pattern
+		// afterwards, so assertions which fail only cost an attempt.
+	}
+}
+
+// repeat writes min to max repetitions of re to b, or at most
+// repeatLimit beyond the minimum if max is -1.
+func (g *generator) repeat(b *strings.Builder, re *syntax.Regexp, min, max, stretch int) {
+	n := min + stretch
+	if max < 0 {
+		n += g.r.Intn(repeatLimit + 1)
+	} else {
+		if n > max {
+			n = max
+		}
+		n += g.r.Intn(max - n + 1)
+	}
+	for i := 0; i < n; i++ {
+		g.expand(b, re, stretch)
+	}
+}
+
+// class chooses a character from a class given as pairs of inclusive
+// bounds. It mostly chooses printable ASCII characters, if the class
+// has any.
+func (g *generator) class(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 && g.r.Intn(8) != 7 {
+		ranges = ascii
+	}
+	if len(ranges) == 0 {
+		return utf8.RuneError
+	}
+	total := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	k := rune(g.r.Intn(total))
+	for i := 0; i+1 < len(ranges); i += 2 {
+		size := ranges[i+1] - ranges[i] + 1
+		if k < size {
+			if r := ranges[i] + k; utf8.ValidRune(r) {
+				return r
+			}
+			return ranges[i]
+		}
+		k -= size
+	}
+	return ranges[0]
+}
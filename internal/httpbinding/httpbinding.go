@@ -0,0 +1,18 @@
+// Package httpbinding holds the rules of Smithy's HTTP binding traits
+// which the openapi and restbind packages have in common.
+package httpbinding
+
+import "github.com/gogama/smithy-ast/ast"
+
+// StatusCode returns the HTTP status code of an error shape with the
+// given traits: the value of its httpError trait, or else 500 for
+// server errors and 400 for client errors.
+func StatusCode(traits ast.Traits) int {
+	if code, ok := traits[ast.HTTPErrorTraitID].(*ast.Int32Node); ok {
+		return int(code.Value)
+	}
+	if traits.StringTrait(ast.ErrorTraitID) == "server" {
+		return 500
+	}
+	return 400
+}
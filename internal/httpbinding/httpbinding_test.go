@@ -0,0 +1,33 @@
+package httpbinding
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+func TestStatusCode(t *testing.T) {
+	testCases := []struct {
+		name   string
+		traits string
+		code   int
+	}{
+		{name: "client", traits: `{"smithy.api#error":"client"}`, code: 400},
+		{name: "server", traits: `{"smithy.api#error":"server"}`, code: 500},
+		{name: "httpError", traits: `{"smithy.api#error":"client","smithy.api#httpError":404}`, code: 404},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var traits ast.Traits
+			require.NoError(t, json.NewDecoder(strings.NewReader(testCase.traits)).Decode(&traits))
+
+			assert.Equal(t, testCase.code, StatusCode(traits))
+		})
+	}
+}
@@ -0,0 +1,17 @@
+// Package jsonequal compares values by their JSON encoding, which is how
+// the ast and diff packages decide whether two nodes are the same.
+package jsonequal
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Equal reports whether a and b have the same JSON encoding. Values which
+// cannot be encoded are not equal to anything. Since nodes encode to their
+// JSON AST representation, node locations are not compared.
+func Equal(a, b interface{}) bool {
+	p, err1 := json.Marshal(a)
+	q, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(p, q)
+}
@@ -0,0 +1,27 @@
+package jsonequal
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		name  string
+		a, b  interface{}
+		equal bool
+	}{
+		{name: "maps", a: map[string]int{"a": 1, "b": 2}, b: map[string]int{"b": 2, "a": 1}, equal: true},
+		{name: "different", a: []string{"a"}, b: []string{"b"}},
+		{name: "types", a: 1, b: "1"},
+		{name: "not encodable", a: math.NaN(), b: math.NaN()},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.equal, Equal(testCase.a, testCase.b))
+		})
+	}
+}
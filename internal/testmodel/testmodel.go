@@ -0,0 +1,21 @@
+// Package testmodel reads the Smithy models which the tests of other
+// packages are written against.
+package testmodel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Read decodes a model from its JSON AST, failing the test if it is not
+// valid.
+func Read(t testing.TB, src string) ast.Model {
+	t.Helper()
+	m, err := ast.ReadModel(strings.NewReader(src))
+	require.NoError(t, err)
+	return m
+}
@@ -0,0 +1,87 @@
+// Package traitschema maps Smithy shapes and traits to the JSON Schema
+// keywords which the jsonschema and openapi packages have in common.
+package traitschema
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Apply adds to a schema the keywords for the documentation, deprecated
+// and constraint traits of a shape or member, where t is the type of the
+// shape or of the member's target.
+func Apply(schema map[string]interface{}, t ast.ShapeType, traits ast.Traits) {
+	if doc := traits.StringTrait(ast.DocumentationTraitID); doc != "" {
+		schema["description"] = doc
+	}
+	if traits.HasTrait(ast.DeprecatedTraitID) {
+		schema["deprecated"] = true
+	}
+	// The length of a blob constrains its decoded bytes, which JSON
+	// Schema cannot express.
+	if length, ok := traits[ast.LengthTraitID].(*ast.LengthTrait); ok && t != ast.BlobType {
+		min, max := "minLength", "maxLength"
+		switch t {
+		case ast.ListType, ast.SetType:
+			min, max = "minItems", "maxItems"
+		case ast.MapType:
+			min, max = "minProperties", "maxProperties"
+		}
+		if length.Min != nil {
+			schema[min] = length.Min.Value
+		}
+		if length.Max != nil {
+			schema[max] = length.Max.Value
+		}
+	}
+	if r, ok := traits[ast.RangeTraitID].(*ast.RangeTrait); ok {
+		if r.Min != nil {
+			schema["minimum"] = number(&r.Min.Value)
+		}
+		if r.Max != nil {
+			schema["maximum"] = number(&r.Max.Value)
+		}
+	}
+	if pattern := traits.StringTrait(ast.PatternTraitID); pattern != "" {
+		schema["pattern"] = pattern
+	}
+	if enum, ok := traits[ast.EnumTraitID].(*ast.EnumTrait); ok {
+		values := make([]string, len(enum.Items))
+		for i := range enum.Items {
+			values[i] = enum.Items[i].Value.Value
+		}
+		schema["enum"] = values
+	}
+	if traits.HasTrait(ast.UniqueItemsTraitID) {
+		schema["uniqueItems"] = true
+	}
+}
+
+// Nullable returns a schema which also allows null values. It modifies
+// schema if it has a single type, and otherwise wraps it in anyOf.
+func Nullable(schema map[string]interface{}) map[string]interface{} {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	if len(schema) == 0 {
+		return schema // Already allows anything.
+	}
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
+
+// PropertyName returns the name of the property holding a member in the
+// JSON representation of its structure or union: its jsonName trait, or
+// else its member name.
+func PropertyName(name string, member ast.Member) string {
+	if n := member.Traits.StringTrait(ast.JSONNameTraitID); n != "" {
+		return n
+	}
+	return name
+}
+
+func number(f *big.Float) json.Number {
+	return json.Number(f.Text('g', -1))
+}
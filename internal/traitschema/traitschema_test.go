@@ -0,0 +1,87 @@
+package traitschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name   string
+		t      ast.ShapeType
+		traits string
+		want   string
+	}{
+		{
+			name:   "string",
+			t:      ast.StringType,
+			traits: `{"smithy.api#documentation":"d","smithy.api#length":{"min":1},"smithy.api#pattern":"^a","smithy.api#enum":[{"value":"a"}]}`,
+			want:   `{"description":"d","minLength":1,"pattern":"^a","enum":["a"]}`,
+		},
+		{
+			name:   "list",
+			t:      ast.ListType,
+			traits: `{"smithy.api#deprecated":{},"smithy.api#length":{"max":2},"smithy.api#uniqueItems":{}}`,
+			want:   `{"deprecated":true,"maxItems":2,"uniqueItems":true}`,
+		},
+		{
+			name:   "map",
+			t:      ast.MapType,
+			traits: `{"smithy.api#length":{"min":1,"max":2}}`,
+			want:   `{"minProperties":1,"maxProperties":2}`,
+		},
+		{
+			name:   "blob length",
+			t:      ast.BlobType,
+			traits: `{"smithy.api#length":{"max":2}}`,
+			want:   `{}`,
+		},
+		{
+			name:   "range",
+			t:      ast.IntegerType,
+			traits: `{"smithy.api#range":{"min":-1.5,"max":10}}`,
+			want:   `{"minimum":-1.5,"maximum":10}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var traits ast.Traits
+			require.NoError(t, json.NewDecoder(strings.NewReader(testCase.traits)).Decode(&traits))
+			schema := map[string]interface{}{}
+
+			Apply(schema, testCase.t, traits)
+
+			b, err := json.Marshal(schema)
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.want, string(b))
+		})
+	}
+}
+
+func TestNullable(t *testing.T) {
+	testCases := []struct {
+		name   string
+		schema map[string]interface{}
+		want   string
+	}{
+		{name: "type", schema: map[string]interface{}{"type": "string"}, want: `{"type":["string","null"]}`},
+		{name: "ref", schema: map[string]interface{}{"$ref": "#/a"}, want: `{"anyOf":[{"$ref":"#/a"},{"type":"null"}]}`},
+		{name: "empty", schema: map[string]interface{}{}, want: `{}`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			b, err := json.Marshal(Nullable(testCase.schema))
+
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.want, string(b))
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package valuetree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// FromNode converts a Smithy node value, as found in traits, to a value
+// tree for the shape with ID target in shapes. Node values are decoded
+// JSON values in which blobs are strings and timestamps are epoch
+// seconds or date-time strings. The JSON Pointer path of v begins the
+// messages of the errors FromNode returns.
+func FromNode(shapes map[ast.AbsShapeID]ast.Shape, path string, target ast.AbsShapeID, v interface{}) (interface{}, error) {
+	s, ok := shapes[target]
+	if !ok {
+		return nil, ErrorAt(path, "shape %s not found", target)
+	}
+	v = Unwrap(v)
+	if v == nil {
+		return nil, nil
+	}
+	mismatch := func() error {
+		return ErrorAt(path, "expected %s, got %s", s.Type, nodeType(v))
+	}
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := Object(v)
+		if !ok {
+			return nil, mismatch()
+		}
+		values := make(map[string]interface{}, len(obj))
+		for name, value := range obj {
+			member, ok := s.Members[name]
+			if !ok {
+				return nil, ErrorAt(path, "unknown member %q of %s", name, target)
+			}
+			var err error
+			if values[name], err = FromNode(shapes, path+"/"+Escape(name), member.Target.Value, value); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.ListType, ast.SetType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice || s.Value == nil {
+			return nil, mismatch()
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			var err error
+			if values[i], err = FromNode(shapes, fmt.Sprintf("%s/%d", path, i), s.Value.Target.Value, rv.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.MapType:
+		obj, ok := Object(v)
+		if !ok || s.Value == nil {
+			return nil, mismatch()
+		}
+		values := make(map[string]interface{}, len(obj))
+		for key, value := range obj {
+			var err error
+			if values[key], err = FromNode(shapes, path+"/"+Escape(key), s.Value.Target.Value, value); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.StringType:
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+		return nil, mismatch()
+
+	case ast.BlobType:
+		if str, ok := v.(string); ok {
+			return []byte(str), nil
+		}
+		return nil, mismatch()
+
+	case ast.BooleanType:
+		if x, ok := v.(bool); ok {
+			return x, nil
+		}
+		return nil, mismatch()
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, mismatch()
+		}
+		// The shape's range is [-2^(bits-1), 2^(bits-1)), whose bounds
+		// are exact as float64.
+		limit := math.Ldexp(1, int(IntegerBits[s.Type])-1)
+		if f != math.Trunc(f) || f < -limit || f >= limit {
+			return nil, ErrorAt(path, "expected %s, got %v", s.Type, f)
+		}
+		return int64(f), nil
+
+	case ast.FloatType, ast.DoubleType:
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case string:
+			switch x {
+			case "NaN":
+				return math.NaN(), nil
+			case "Infinity":
+				return math.Inf(1), nil
+			case "-Infinity":
+				return math.Inf(-1), nil
+			}
+		}
+		return nil, mismatch()
+
+	case ast.BigIntegerType:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, mismatch()
+		}
+		i, _ := big.NewFloat(f).Int(nil)
+		return i, nil
+
+	case ast.BigDecimalType:
+		f, ok := v.(float64)
+		if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, mismatch()
+		}
+		return big.NewFloat(f), nil
+
+	case ast.TimestampType:
+		switch x := v.(type) {
+		case float64:
+			sec, frac := math.Modf(x)
+			return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, x); err == nil {
+				return t.UTC(), nil
+			}
+			if t, err := http.ParseTime(x); err == nil {
+				return t.UTC(), nil
+			}
+			return nil, ErrorAt(path, "invalid timestamp %q", x)
+		}
+		return nil, mismatch()
+
+	case ast.DocumentType:
+		return document(v), nil
+	}
+	return nil, ErrorAt(path, "shape %s is a %s, which has no values", target, s.Type)
+}
+
+// document converts the numbers of a node value to json.Number, as
+// documents parsed from JSON have them.
+func document(v interface{}) interface{} {
+	switch x := Unwrap(v).(type) {
+	case float64:
+		return json.Number(strconv.FormatFloat(x, 'g', -1, 64))
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(x))
+		for key, value := range x {
+			obj[key] = document(value)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(x))
+		for i, value := range x {
+			arr[i] = document(value)
+		}
+		return arr
+	default:
+		return x
+	}
+}
+
+// nodeType describes the type of a node value in an error message.
+func nodeType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Nodes unwraps a map of node values.
+func Nodes(m map[string]ast.InterfaceNode) map[string]interface{} {
+	obj := make(map[string]interface{}, len(m))
+	for key, node := range m {
+		obj[key] = node.Value
+	}
+	return obj
+}
+
+// ErrorAt returns an error about the value at the JSON Pointer path.
+func ErrorAt(path, format string, a ...interface{}) error {
+	if path == "" {
+		return fmt.Errorf(format, a...)
+	}
+	return fmt.Errorf(path+": "+format, a...)
+}
+
+// Equal reports whether two value trees are equal.
+func Equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			if other, ok := y[key]; !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case float64:
+		y, ok := b.(float64)
+		return ok && (x == y || math.IsNaN(x) && math.IsNaN(y))
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	case *big.Int:
+		y, ok := b.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case *big.Float:
+		y, ok := b.(*big.Float)
+		if !ok {
+			return false
+		}
+		f, _ := x.Float64()
+		g, _ := y.Float64()
+		return f == g
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := strconv.ParseFloat(string(x), 64)
+		g, err2 := strconv.ParseFloat(string(y), 64)
+		return x == y || err == nil && err2 == nil && f == g
+	}
+	return reflect.DeepEqual(a, b)
+}
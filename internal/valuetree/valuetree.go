@@ -0,0 +1,144 @@
+// Package valuetree holds helpers for the generic value trees which hold
+// the values of shapes: maps for structures, unions and maps, slices for
+// lists and sets, and Go strings, booleans, numbers, []byte blobs and
+// time.Time timestamps for simple shapes. The packages which work with
+// the values of shapes share them.
+package valuetree
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// IntegerBits gives the size of the integer shape types, with zero for
+// bigInteger.
+var IntegerBits = map[ast.ShapeType]uint{
+	ast.ByteType:       8,
+	ast.ShortType:      16,
+	ast.IntegerType:    32,
+	ast.LongType:       64,
+	ast.BigIntegerType: 0,
+}
+
+// IsMinimum reports whether i is the smallest integer of the given size,
+// the only one whose bit length equals the size.
+func IsMinimum(i *big.Int, bits uint) bool {
+	return i.Sign() < 0 && i.BitLen() == int(bits) && i.TrailingZeroBits() == bits-1
+}
+
+// Unwrap returns the value held by an ast.InterfaceNode, or v itself.
+func Unwrap(v interface{}) interface{} {
+	switch n := v.(type) {
+	case ast.InterfaceNode:
+		return Unwrap(n.Value)
+	case *ast.InterfaceNode:
+		if n == nil {
+			return nil
+		}
+		return Unwrap(n.Value)
+	}
+	return v
+}
+
+// Object returns a structure, union or map value as a map.
+func Object(v interface{}) (map[string]interface{}, bool) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return obj, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	obj := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		obj[iter.Key().String()] = iter.Value().Interface()
+	}
+	return obj, true
+}
+
+// Number returns a numeric value as a big.Float. NaN and infinities are
+// not numbers.
+func Number(v interface{}) (*big.Float, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := ParseNumber(n)
+		return f, err == nil
+	case *big.Int:
+		return new(big.Float).SetInt(n), n != nil
+	case *big.Float:
+		return n, n != nil
+	case float32:
+		return floatNumber(float64(n))
+	case float64:
+		return floatNumber(n)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetUint64(rv.Uint()), true
+	}
+	return nil, false
+}
+
+// ParseNumber parses a JSON number with enough precision to hold it
+// exactly.
+func ParseNumber(n json.Number) (*big.Float, error) {
+	prec := uint(4*len(n) + 64)
+	f, _, err := big.ParseFloat(string(n), 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", n)
+	}
+	return f, nil
+}
+
+func floatNumber(f float64) (*big.Float, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return big.NewFloat(f), true
+}
+
+// IsBytes reports whether v is a blob value.
+func IsBytes(v interface{}) bool {
+	_, ok := v.([]byte)
+	return ok
+}
+
+// TypeName describes the type of a value in an error message.
+func TypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []byte:
+		return "blob"
+	case time.Time:
+		return "timestamp"
+	}
+	if _, ok := Number(v); ok {
+		return "number"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Escape escapes a key for use in a JSON Pointer.
+func Escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
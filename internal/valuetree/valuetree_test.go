@@ -0,0 +1,124 @@
+package valuetree
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/internal/testmodel"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+func TestNumber(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value interface{}
+		want  string
+		ok    bool
+	}{
+		{name: "int8", value: int8(-3), want: "-3", ok: true},
+		{name: "uint64", value: uint64(math.MaxUint64), want: "18446744073709551615", ok: true},
+		{name: "float64", value: 1.5, want: "1.5", ok: true},
+		{name: "json.Number", value: json.Number("12345678901234567890.5"), want: "12345678901234567890.5", ok: true},
+		{name: "big.Int", value: big.NewInt(7), want: "7", ok: true},
+		{name: "NaN", value: math.NaN()},
+		{name: "invalid json.Number", value: json.Number("x")},
+		{name: "string", value: "1"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			f, ok := Number(testCase.value)
+
+			require.Equal(t, testCase.ok, ok)
+			if ok {
+				assert.Equal(t, testCase.want, f.Text('f', -1))
+			}
+		})
+	}
+}
+
+func TestFromNode(t *testing.T) {
+	m := testmodel.Read(t, `{"version":"1.0","shapes":{
+		"foo#S":{"type":"structure","members":{
+			"name":{"target":"smithy.api#String"},
+			"data":{"target":"smithy.api#Blob"},
+			"count":{"target":"smithy.api#Integer"},
+			"small":{"target":"smithy.api#Byte"},
+			"big":{"target":"smithy.api#Long"},
+			"when":{"target":"smithy.api#Timestamp"},
+			"tags":{"target":"foo#Tags"},
+			"doc":{"target":"smithy.api#Document"}
+		}},
+		"foo#Tags":{"type":"list","member":{"target":"smithy.api#String"}}
+	}}`)
+	shapes := prelude.Shapes(m)
+
+	t.Run("valid", func(t *testing.T) {
+		v, err := FromNode(shapes, "", "foo#S", map[string]interface{}{
+			"name":  "a",
+			"data":  "xy",
+			"count": 3.0,
+			"when":  "2020-01-02T03:04:05Z",
+			"tags":  []interface{}{"b"},
+			"doc":   map[string]interface{}{"n": 1.5},
+		})
+
+		require.NoError(t, err)
+		assert.True(t, Equal(map[string]interface{}{
+			"name":  "a",
+			"data":  []byte("xy"),
+			"count": int64(3),
+			"when":  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			"tags":  []interface{}{"b"},
+			"doc":   map[string]interface{}{"n": json.Number("1.5")},
+		}, v))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := FromNode(shapes, "/input", "foo#S", map[string]interface{}{"tags": []interface{}{1.0}})
+		assert.EqualError(t, err, "/input/tags/0: expected string, got number")
+
+		_, err = FromNode(shapes, "", "foo#S", map[string]interface{}{"count": float64(1 << 31)})
+		assert.EqualError(t, err, "/count: expected integer, got 2.147483648e+09")
+
+		_, err = FromNode(shapes, "", "foo#S", map[string]interface{}{"small": -129.0})
+		assert.EqualError(t, err, "/small: expected byte, got -129")
+
+		_, err = FromNode(shapes, "", "foo#S", map[string]interface{}{"big": float64(1 << 63)})
+		assert.EqualError(t, err, "/big: expected long, got 9.223372036854776e+18")
+
+		_, err = FromNode(shapes, "", "foo#S", map[string]interface{}{"nope": 1.0})
+		assert.EqualError(t, err, `unknown member "nope" of foo#S`)
+
+		_, err = FromNode(shapes, "", "foo#Missing", 1.0)
+		assert.EqualError(t, err, "shape foo#Missing not found")
+	})
+}
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		name  string
+		a, b  interface{}
+		equal bool
+	}{
+		{name: "maps", a: map[string]interface{}{"a": []interface{}{1.0}}, b: map[string]interface{}{"a": []interface{}{1.0}}, equal: true},
+		{name: "missing key", a: map[string]interface{}{"a": nil}, b: map[string]interface{}{"b": nil}},
+		{name: "NaN", a: math.NaN(), b: math.NaN(), equal: true},
+		{name: "times", a: time.Unix(1, 0), b: time.Unix(1, 0).UTC(), equal: true},
+		{name: "big.Int", a: big.NewInt(2), b: big.NewInt(3)},
+		{name: "json.Number", a: json.Number("1.0"), b: json.Number("1"), equal: true},
+		{name: "bytes", a: []byte("a"), b: "a"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.equal, Equal(testCase.a, testCase.b))
+		})
+	}
+}
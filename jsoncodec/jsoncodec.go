@@ -0,0 +1,631 @@
+// Package jsoncodec serializes values to and from JSON according to the
+// shapes of a Smithy model, following the JSON protocols restJson1,
+// awsJson1_0 and awsJson1_1.
+//
+// A Codec converts between JSON bytes and a generic value tree, so
+// payloads of any shape can be handled without generating code first.
+// Structure and union members are named by their jsonName trait,
+// blobs are base64 strings, timestamps follow their timestampFormat
+// trait, documents pass through unchanged, and only sparse lists and
+// maps may hold nulls.
+package jsoncodec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// Timestamp formats named by the timestampFormat trait.
+const (
+	DateTime     = "date-time"
+	EpochSeconds = "epoch-seconds"
+	HTTPDate     = "http-date"
+)
+
+// Options controls how a Codec serializes values.
+type Options struct {
+	// IgnoreJSONName makes the Codec name members by their member names
+	// even if they have a jsonName trait, as the awsJson1_0 and
+	// awsJson1_1 protocols do. By default, the jsonName trait is
+	// respected, as the restJson1 protocol does.
+	IgnoreJSONName bool
+
+	// TimestampFormat is the format of timestamps which have no
+	// timestampFormat trait: DateTime, EpochSeconds or HTTPDate. The
+	// default is EpochSeconds.
+	TimestampFormat string
+}
+
+// An UnknownMember is the value of a union whose member is not in the
+// model, as when a newer version of a service adds a member to the
+// union. Unmarshal returns it so that clients, and intermediaries such
+// as gateways, accept such unions, and Marshal encodes it unchanged.
+type UnknownMember struct {
+	// Name is the JSON key of the member.
+	Name string
+
+	// Value is the member's value, as produced by encoding/json with
+	// json.Decoder.UseNumber.
+	Value interface{}
+}
+
+// A Codec serializes values of the shapes of a model to and from JSON.
+//
+// Values in the value tree have the following types:
+//
+//	structure, union  map[string]interface{} keyed by member name
+//	list, set         []interface{}
+//	map               map[string]interface{}
+//	string            string
+//	blob              []byte
+//	boolean           bool
+//	byte, short,
+//	integer, long     int64
+//	float, double     float64
+//	bigInteger        *big.Int
+//	bigDecimal        *big.Float
+//	timestamp         time.Time
+//	document          the value produced by encoding/json with
+//	                  json.Decoder.UseNumber, which Marshal accepts
+//	                  as well as any other value encoding/json can
+//	                  marshal
+//
+// Absent structure members are missing from the map, and union values
+// have exactly one key, or are an UnknownMember. Null items of sparse lists and null values of
+// sparse maps are nil.
+//
+// Marshal is lenient in the types it accepts: maps with string keys
+// and slices of any element type, any Go integer or floating point
+// type, json.Number, *big.Int, *big.Float and ast.InterfaceNode values
+// wrapping any of these are accepted too, and nil structure members
+// are treated as absent.
+type Codec struct {
+	opts   Options
+	shapes map[ast.AbsShapeID]ast.Shape
+}
+
+// New returns a Codec for the shapes of m. Shapes not in m are looked up
+// in the prelude.
+func New(m ast.Model, opts Options) *Codec {
+	if opts.TimestampFormat == "" {
+		opts.TimestampFormat = EpochSeconds
+	}
+	shapes := prelude.Shapes(m)
+	return &Codec{opts: opts, shapes: shapes}
+}
+
+// Marshal returns the JSON encoding of the value v of the shape with the
+// given ID. It returns an error, naming the JSON Pointer path of the
+// offending value, if v does not fit the shape.
+func (c *Codec) Marshal(id ast.AbsShapeID, v interface{}) ([]byte, error) {
+	tree, err := c.encode("", id, nil, v)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(tree)
+}
+
+// Unmarshal decodes the JSON data into a value of the shape with the
+// given ID. It returns nil if data is the JSON null. It returns an
+// error, naming the JSON Pointer path of the offending value, if the
+// data does not fit the shape.
+//
+// Unknown structure members are ignored, as is the "__type" member
+// which the awsJson protocols add to errors. A union whose only member
+// is unknown is returned as an UnknownMember.
+func (c *Codec) Unmarshal(id ast.AbsShapeID, data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, newErrorf("%w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, newErrorf("invalid data after top-level value")
+	}
+	if tree == nil {
+		return nil, nil
+	}
+	return c.decode("", id, nil, tree)
+}
+
+// shape returns the shape with ID target, and the traits of the shape
+// merged with memberTraits, which are the traits of the member
+// targeting it, if any.
+func (c *Codec) shape(path string, target ast.AbsShapeID, memberTraits ast.Traits) (ast.Shape, ast.Traits, error) {
+	s, ok := c.shapes[target]
+	if !ok {
+		return s, nil, errorAt(path, "shape %s not found", target)
+	}
+	if (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value == nil ||
+		s.Type == ast.MapType && (s.Key == nil || s.Value == nil) {
+		return s, nil, errorAt(path, "%s %s has no members", s.Type, target)
+	}
+	return s, s.Traits.Merge(memberTraits), nil
+}
+
+// encode converts the value v at path, of the shape with ID target, to
+// a tree which encoding/json marshals to its JSON encoding.
+func (c *Codec) encode(path string, target ast.AbsShapeID, memberTraits ast.Traits, v interface{}) (interface{}, error) {
+	s, traits, err := c.shape(path, target, memberTraits)
+	if err != nil {
+		return nil, err
+	}
+	v = valuetree.Unwrap(v)
+	if v == nil {
+		return nil, errorAt(path, "expected %s, got null", s.Type)
+	}
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", s.Type, valuetree.TypeName(v))
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		if u, ok := v.(UnknownMember); ok && s.Type == ast.UnionType {
+			return map[string]interface{}{u.Name: u.Value}, nil
+		}
+		obj, ok := valuetree.Object(v)
+		if !ok {
+			return nil, mismatch()
+		}
+		return c.encodeMembers(path, target, s, obj)
+
+	case ast.ListType, ast.SetType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || valuetree.IsBytes(v) {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item := valuetree.Unwrap(rv.Index(i).Interface())
+			itemPath := path + "/" + strconv.Itoa(i)
+			if item == nil && sparse {
+				continue
+			} else if item == nil {
+				return nil, errorAt(itemPath, "null item in list which is not sparse")
+			}
+			if items[i], err = c.encode(itemPath, s.Value.Target.Value, s.Value.Traits, item); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+
+	case ast.MapType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		entries := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			value := valuetree.Unwrap(iter.Value().Interface())
+			valuePath := path + "/" + valuetree.Escape(key)
+			if value == nil && sparse {
+				entries[key] = nil
+				continue
+			} else if value == nil {
+				return nil, errorAt(valuePath, "null value in map which is not sparse")
+			}
+			if entries[key], err = c.encode(valuePath, s.Value.Target.Value, s.Value.Traits, value); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+
+	case ast.StringType:
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+		return nil, mismatch()
+
+	case ast.BlobType:
+		if b, ok := v.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+		return nil, mismatch()
+
+	case ast.BooleanType:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, mismatch()
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return nil, mismatch()
+		}
+		if !f.IsInt() {
+			return nil, errorAt(path, "expected %s, got non-integer %s", s.Type, f.Text('g', -1))
+		}
+		i, _ := f.Int(nil)
+		if bits := valuetree.IntegerBits[s.Type]; bits > 0 && (i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits)) {
+			return nil, errorAt(path, "value %s is out of range for %s", i, s.Type)
+		}
+		return json.Number(i.String()), nil
+
+	case ast.FloatType, ast.DoubleType:
+		var f float64
+		switch n := v.(type) {
+		case float32:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			g, ok := valuetree.Number(v)
+			if !ok {
+				return nil, mismatch()
+			}
+			f, _ = g.Float64()
+		}
+		bitSize := 64
+		if s.Type == ast.FloatType {
+			bitSize = 32
+		}
+		switch {
+		case math.IsNaN(f):
+			return "NaN", nil
+		case math.IsInf(f, 1):
+			return "Infinity", nil
+		case math.IsInf(f, -1):
+			return "-Infinity", nil
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize)), nil
+
+	case ast.BigDecimalType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return nil, mismatch()
+		}
+		return json.Number(f.Text('g', -1)), nil
+
+	case ast.TimestampType:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, mismatch()
+		}
+		return c.encodeTimestamp(t, traits), nil
+
+	case ast.DocumentType:
+		p, err := marshal(v)
+		if err != nil {
+			return nil, errorAt(path, "%w", err)
+		}
+		return json.RawMessage(p), nil
+	}
+	return nil, errorAt(path, "shape %s is a %s, which has no values", target, s.Type)
+}
+
+// encodeMembers converts a structure or union value to a JSON object.
+func (c *Codec) encodeMembers(path string, id ast.AbsShapeID, s ast.Shape, obj map[string]interface{}) (interface{}, error) {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	members := make(map[string]interface{}, len(obj))
+	for _, name := range names {
+		member, ok := s.Members[name]
+		if !ok {
+			return nil, errorAt(path, "unknown member %q of %s", name, id)
+		}
+		value := valuetree.Unwrap(obj[name])
+		if value == nil {
+			continue
+		}
+		key := c.key(name, member)
+		var err error
+		if members[key], err = c.encode(path+"/"+valuetree.Escape(key), member.Target.Value, member.Traits, value); err != nil {
+			return nil, err
+		}
+	}
+	if s.Type == ast.UnionType && len(members) != 1 {
+		return nil, errorAt(path, "union must have exactly one member set, got %d", len(members))
+	}
+	return members, nil
+}
+
+func (c *Codec) encodeTimestamp(t time.Time, traits ast.Traits) interface{} {
+	t = t.UTC()
+	switch c.timestampFormat(traits) {
+	case DateTime:
+		return t.Format(time.RFC3339Nano)
+	case HTTPDate:
+		return t.Format(http.TimeFormat)
+	}
+	ms := t.Unix()*1000 + int64(t.Nanosecond()/int(time.Millisecond))
+	sign := ""
+	if ms < 0 {
+		sign, ms = "-", -ms
+	}
+	if ms%1000 == 0 {
+		return json.Number(fmt.Sprintf("%s%d", sign, ms/1000))
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%03d", ms%1000), "0")
+	return json.Number(fmt.Sprintf("%s%d.%s", sign, ms/1000, frac))
+}
+
+// decode converts the tree v at path, decoded by encoding/json, to a
+// value of the shape with ID target.
+func (c *Codec) decode(path string, target ast.AbsShapeID, memberTraits ast.Traits, v interface{}) (interface{}, error) {
+	s, traits, err := c.shape(path, target, memberTraits)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errorAt(path, "expected %s, got null", s.Type)
+	}
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", s.Type, valuetree.TypeName(v))
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		return c.decodeMembers(path, s, obj)
+
+	case ast.ListType, ast.SetType:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			itemPath := path + "/" + strconv.Itoa(i)
+			if item == nil && sparse {
+				continue
+			} else if item == nil {
+				return nil, errorAt(itemPath, "null item in list which is not sparse")
+			}
+			if values[i], err = c.decode(itemPath, s.Value.Target.Value, s.Value.Traits, item); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.MapType:
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		values := make(map[string]interface{}, len(entries))
+		for key, value := range entries {
+			valuePath := path + "/" + valuetree.Escape(key)
+			if value == nil && sparse {
+				values[key] = nil
+				continue
+			} else if value == nil {
+				return nil, errorAt(valuePath, "null value in map which is not sparse")
+			}
+			if values[key], err = c.decode(valuePath, s.Value.Target.Value, s.Value.Traits, value); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case ast.StringType:
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+		return nil, mismatch()
+
+	case ast.BlobType:
+		str, ok := v.(string)
+		if !ok {
+			return nil, mismatch()
+		}
+		b, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, errorAt(path, "invalid base64 blob: %w", err)
+		}
+		return b, nil
+
+	case ast.BooleanType:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, mismatch()
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, mismatch()
+		}
+		f, err := valuetree.ParseNumber(n)
+		if err != nil {
+			return nil, errorAt(path, "%w", err)
+		}
+		if !f.IsInt() {
+			return nil, errorAt(path, "expected %s, got non-integer %s", s.Type, n)
+		}
+		i, _ := f.Int(nil)
+		if s.Type == ast.BigIntegerType {
+			return i, nil
+		}
+		if bits := valuetree.IntegerBits[s.Type]; i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits) {
+			return nil, errorAt(path, "value %s is out of range for %s", n, s.Type)
+		}
+		return i.Int64(), nil
+
+	case ast.FloatType, ast.DoubleType:
+		switch n := v.(type) {
+		case json.Number:
+			bitSize := 64
+			if s.Type == ast.FloatType {
+				bitSize = 32
+			}
+			f, err := strconv.ParseFloat(string(n), bitSize)
+			if err != nil {
+				return nil, errorAt(path, "value %s is out of range for %s", n, s.Type)
+			}
+			return f, nil
+		case string:
+			switch n {
+			case "NaN":
+				return math.NaN(), nil
+			case "Infinity":
+				return math.Inf(1), nil
+			case "-Infinity":
+				return math.Inf(-1), nil
+			}
+		}
+		return nil, mismatch()
+
+	case ast.BigDecimalType:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, mismatch()
+		}
+		f, err := valuetree.ParseNumber(n)
+		if err != nil {
+			return nil, errorAt(path, "%w", err)
+		}
+		return f, nil
+
+	case ast.TimestampType:
+		return c.decodeTimestamp(path, v, traits)
+
+	case ast.DocumentType:
+		return v, nil
+	}
+	return nil, errorAt(path, "shape %s is a %s, which has no values", target, s.Type)
+}
+
+// decodeMembers converts a JSON object to a structure or union value.
+func (c *Codec) decodeMembers(path string, s ast.Shape, obj map[string]interface{}) (interface{}, error) {
+	values := make(map[string]interface{}, len(obj))
+	for name, member := range s.Members {
+		key := c.key(name, member)
+		value := obj[key]
+		if value == nil {
+			continue
+		}
+		var err error
+		if values[name], err = c.decode(path+"/"+valuetree.Escape(key), member.Target.Value, member.Traits, value); err != nil {
+			return nil, err
+		}
+	}
+	if s.Type == ast.UnionType && len(values) == 0 {
+		if name, ok := c.unknownMember(s, obj); ok {
+			return UnknownMember{Name: name, Value: obj[name]}, nil
+		}
+	}
+	if s.Type == ast.UnionType && len(values) != 1 {
+		return nil, errorAt(path, "union must have exactly one member set, got %d", len(values))
+	}
+	return values, nil
+}
+
+// unknownMember returns the key of the only member set in the JSON
+// object of a union, if it is not a member of the union's shape.
+func (c *Codec) unknownMember(s ast.Shape, obj map[string]interface{}) (string, bool) {
+	known := make(map[string]bool, len(s.Members))
+	for name, member := range s.Members {
+		known[c.key(name, member)] = true
+	}
+	var unknown []string
+	for key, value := range obj {
+		if !known[key] && key != "__type" && value != nil {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) != 1 {
+		return "", false
+	}
+	return unknown[0], true
+}
+
+func (c *Codec) decodeTimestamp(path string, v interface{}, traits ast.Traits) (interface{}, error) {
+	format := c.timestampFormat(traits)
+	if format == EpochSeconds {
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, errorAt(path, "expected %s timestamp, got %s", format, valuetree.TypeName(v))
+		}
+		f, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return nil, errorAt(path, "invalid %s timestamp %s", format, n)
+		}
+		sec := math.Floor(f)
+		ms := math.Round((f - sec) * 1000)
+		return time.Unix(int64(sec), int64(ms)*int64(time.Millisecond)).UTC(), nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return nil, errorAt(path, "expected %s timestamp, got %s", format, valuetree.TypeName(v))
+	}
+	layout := time.RFC3339Nano
+	if format == HTTPDate {
+		layout = http.TimeFormat
+	}
+	t, err := time.Parse(layout, str)
+	if err != nil {
+		return nil, errorAt(path, "invalid %s timestamp %q", format, str)
+	}
+	return t.UTC(), nil
+}
+
+// key returns the JSON object key of a structure or union member.
+func (c *Codec) key(name string, member ast.Member) string {
+	if !c.opts.IgnoreJSONName {
+		if jsonName, ok := member.Traits[ast.JSONNameTraitID].(*ast.StringNode); ok {
+			return jsonName.Value
+		}
+	}
+	return name
+}
+
+func (c *Codec) timestampFormat(traits ast.Traits) string {
+	if format, ok := traits[ast.TimestampFormatTraitID].(*ast.StringNode); ok {
+		return format.Value
+	}
+	return c.opts.TimestampFormat
+}
+
+// marshal is json.Marshal without HTML escaping.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// errorAt returns an error about the value at the JSON Pointer path.
+func errorAt(path, format string, a ...interface{}) error {
+	if path == "" {
+		return newErrorf(format, a...)
+	}
+	return newErrorf("%s: "+format, append([]interface{}{path}, a...)...)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "jsoncodec: "
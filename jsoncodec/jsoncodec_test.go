@@ -0,0 +1,267 @@
+package jsoncodec
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Thing": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "smithy.api#String", "traits": {"smithy.api#jsonName": "Name"}},
+				"count": {"target": "smithy.api#Integer"},
+				"tiny": {"target": "smithy.api#Byte"},
+				"ratio": {"target": "smithy.api#Float"},
+				"big": {"target": "smithy.api#BigInteger"},
+				"exact": {"target": "smithy.api#BigDecimal"},
+				"ok": {"target": "smithy.api#Boolean"},
+				"data": {"target": "smithy.api#Blob"},
+				"created": {"target": "smithy.api#Timestamp"},
+				"updated": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "date-time"}},
+				"expires": {"target": "foo#HTTPDate"},
+				"extra": {"target": "smithy.api#Document"},
+				"tags": {"target": "foo#Tags"},
+				"notes": {"target": "foo#Notes"},
+				"attrs": {"target": "foo#Attrs"},
+				"shape": {"target": "foo#Shape"}
+			}
+		},
+		"foo#HTTPDate": {
+			"type": "timestamp",
+			"traits": {"smithy.api#timestampFormat": "http-date"}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#Notes": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "smithy.api#Double"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Shape": {
+			"type": "union",
+			"members": {
+				"circle": {"target": "smithy.api#Double", "traits": {"smithy.api#jsonName": "Circle"}},
+				"none": {"target": "smithy.api#Unit"}
+			}
+		}
+	}
+}`
+
+func testCodec(t *testing.T, opts Options) *Codec {
+	m := testmodel.Read(t, testModel)
+	return New(m, opts)
+}
+
+func TestCodec(t *testing.T) {
+	testCases := []struct {
+		name  string
+		opts  Options
+		id    ast.AbsShapeID
+		value interface{}
+		json  string
+	}{
+		{
+			name: "structure",
+			id:   "foo#Thing",
+			value: map[string]interface{}{
+				"name":    "Bob",
+				"count":   int64(-2147483648),
+				"tiny":    int64(127),
+				"ratio":   float64(0.5),
+				"big":     new(big.Int).Lsh(big.NewInt(1), 70),
+				"exact":   big.NewFloat(1.25),
+				"ok":      true,
+				"data":    []byte("hi"),
+				"created": time.Unix(1515531081, 123000000).UTC(),
+				"updated": time.Date(1985, 4, 12, 23, 20, 50, 520000000, time.UTC),
+				"expires": time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+				"extra":   map[string]interface{}{"a": []interface{}{json.Number("1"), "<b>", nil}},
+				"tags":    []interface{}{"x", "y"},
+				"notes":   []interface{}{"x", nil},
+				"attrs":   map[string]interface{}{"nan": math.NaN(), "inf": math.Inf(-1), "gone": nil},
+				"shape":   map[string]interface{}{"circle": float64(2)},
+			},
+			json: `{
+				"Name": "Bob",
+				"count": -2147483648,
+				"tiny": 127,
+				"ratio": 0.5,
+				"big": 1180591620717411303424,
+				"exact": 1.25,
+				"ok": true,
+				"data": "aGk=",
+				"created": 1515531081.123,
+				"updated": "1985-04-12T23:20:50.52Z",
+				"expires": "Sun, 06 Nov 1994 08:49:37 GMT",
+				"extra": {"a": [1, "<b>", null]},
+				"tags": ["x", "y"],
+				"notes": ["x", null],
+				"attrs": {"nan": "NaN", "inf": "-Infinity", "gone": null},
+				"shape": {"Circle": 2}
+			}`,
+		},
+		{
+			name:  "empty structure",
+			id:    "foo#Thing",
+			value: map[string]interface{}{},
+			json:  `{}`,
+		},
+		{
+			name:  "unit union member",
+			id:    "foo#Shape",
+			value: map[string]interface{}{"none": map[string]interface{}{}},
+			json:  `{"none": {}}`,
+		},
+		{
+			name:  "ignore jsonName",
+			opts:  Options{IgnoreJSONName: true},
+			id:    "foo#Shape",
+			value: map[string]interface{}{"circle": float64(1.5)},
+			json:  `{"circle": 1.5}`,
+		},
+		{
+			name:  "default timestamp format",
+			opts:  Options{TimestampFormat: DateTime},
+			id:    "smithy.api#Timestamp",
+			value: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			json:  `"2020-01-02T03:04:05Z"`,
+		},
+		{
+			name:  "negative epoch seconds",
+			id:    "smithy.api#Timestamp",
+			value: time.Unix(-2, 500000000).UTC(),
+			json:  `-1.5`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := testCodec(t, testCase.opts)
+
+			p, err := c.Marshal(testCase.id, testCase.value)
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.json, string(p))
+
+			v, err := c.Unmarshal(testCase.id, p)
+			require.NoError(t, err)
+			q, err := c.Marshal(testCase.id, v)
+			require.NoError(t, err)
+			assert.Equal(t, string(p), string(q))
+		})
+	}
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	c := testCodec(t, Options{})
+
+	v, err := c.Unmarshal("foo#Thing", []byte(`{
+		"Name": "Bob",
+		"name": "ignored",
+		"__type": "foo#Thing",
+		"count": 3,
+		"ratio": "Infinity",
+		"big": 1e3,
+		"exact": 0.1,
+		"created": 1.5,
+		"updated": "2020-01-02T03:04:05+01:00",
+		"extra": {"n": 12345678901234567890},
+		"notes": [null],
+		"attrs": {"a": null},
+		"shape": {"Circle": null, "none": {}},
+		"tiny": null
+	}`))
+
+	require.NoError(t, err)
+	require.IsType(t, map[string]interface{}{}, v)
+	exact := v.(map[string]interface{})["exact"]
+	require.IsType(t, &big.Float{}, exact)
+	assert.Equal(t, "0.1", exact.(*big.Float).Text('g', 10))
+	assert.Equal(t, map[string]interface{}{
+		"name":    "Bob",
+		"count":   int64(3),
+		"ratio":   math.Inf(1),
+		"big":     big.NewInt(1000),
+		"exact":   exact,
+		"created": time.Unix(1, 500000000).UTC(),
+		"updated": time.Date(2020, 1, 2, 2, 4, 5, 0, time.UTC),
+		"extra":   map[string]interface{}{"n": json.Number("12345678901234567890")},
+		"notes":   []interface{}{nil},
+		"attrs":   map[string]interface{}{"a": nil},
+		"shape":   map[string]interface{}{"none": map[string]interface{}{}},
+	}, v)
+
+	v, err = c.Unmarshal("foo#Shape", []byte(`{"Square": {"side": 2}, "__type": "foo#Shape"}`))
+	require.NoError(t, err)
+	unknown := UnknownMember{Name: "Square", Value: map[string]interface{}{"side": json.Number("2")}}
+	assert.Equal(t, unknown, v)
+	b, err := c.Marshal("foo#Shape", unknown)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Square": {"side": 2}}`, string(b))
+
+	v, err = c.Unmarshal("foo#Thing", []byte(" null "))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestCodec_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		value interface{}
+		json  string
+		err   string
+	}{
+		{name: "marshal/not found", id: "foo#Missing", value: 1, err: "jsoncodec: shape foo#Missing not found"},
+		{name: "marshal/type", id: "foo#Thing", value: map[string]interface{}{"name": 1}, err: "jsoncodec: /Name: expected string, got number"},
+		{name: "marshal/unknown member", id: "foo#Thing", value: map[string]string{"nope": ""}, err: `jsoncodec: unknown member "nope" of foo#Thing`},
+		{name: "marshal/range", id: "foo#Thing", value: map[string]interface{}{"tiny": 128}, err: "jsoncodec: /tiny: value 128 is out of range for byte"},
+		{name: "marshal/non-integer", id: "smithy.api#Long", value: 1.5, err: "jsoncodec: expected long, got non-integer 1.5"},
+		{name: "marshal/null item", id: "foo#Tags", value: []interface{}{"a", nil}, err: "jsoncodec: /1: null item in list which is not sparse"},
+		{name: "marshal/union", id: "foo#Shape", value: map[string]interface{}{}, err: "jsoncodec: union must have exactly one member set, got 0"},
+		{name: "marshal/service", id: "smithy.api#Unit", value: 1, err: "jsoncodec: expected structure, got number"},
+		{name: "unmarshal/syntax", id: "foo#Thing", json: `{`, err: "jsoncodec: unexpected EOF"},
+		{name: "unmarshal/trailing", id: "foo#Thing", json: `{} {}`, err: "jsoncodec: invalid data after top-level value"},
+		{name: "unmarshal/type", id: "foo#Thing", json: `{"tags": [1]}`, err: "jsoncodec: /tags/0: expected string, got number"},
+		{name: "unmarshal/range", id: "foo#Thing", json: `{"tiny": -129}`, err: "jsoncodec: /tiny: value -129 is out of range for byte"},
+		{name: "unmarshal/base64", id: "smithy.api#Blob", json: `"!"`, err: "jsoncodec: invalid base64 blob: illegal base64 data at input byte 0"},
+		{name: "unmarshal/timestamp", id: "foo#HTTPDate", json: `"yesterday"`, err: `jsoncodec: invalid http-date timestamp "yesterday"`},
+		{name: "unmarshal/timestamp type", id: "smithy.api#Timestamp", json: `"2020-01-01T00:00:00Z"`, err: "jsoncodec: expected epoch-seconds timestamp, got string"},
+		{name: "unmarshal/null value", id: "foo#Tags", json: `[null]`, err: "jsoncodec: /0: null item in list which is not sparse"},
+		{name: "unmarshal/union", id: "foo#Shape", json: `{"Circle": 1, "none": {}}`, err: "jsoncodec: union must have exactly one member set, got 2"},
+		{name: "unmarshal/unknown union members", id: "foo#Shape", json: `{"a": 1, "b": 2}`, err: "jsoncodec: union must have exactly one member set, got 0"},
+	}
+
+	c := testCodec(t, Options{})
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var err error
+			if testCase.json != "" {
+				_, err = c.Unmarshal(testCase.id, []byte(testCase.json))
+			} else {
+				_, err = c.Marshal(testCase.id, testCase.value)
+			}
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
@@ -0,0 +1,449 @@
+// Package jsonschema converts Smithy shapes to JSON Schema documents
+// following draft 2020-12.
+//
+// Convert produces a document for the closure of a shape: the shape
+// itself and every shape it refers to through its members. Structures,
+// unions, lists, sets and maps are placed in the document's $defs,
+// named after their shapes, and referred to with $ref; simple shapes
+// are inlined. The schemas describe the JSON representation of values
+// used by Smithy's JSON protocols: members are named by their jsonName
+// trait, blobs are base64 strings, timestamps follow their
+// timestampFormat trait and unions are objects with exactly one member.
+//
+// The constraint traits length, range, pattern, enum and uniqueItems
+// become the corresponding keywords, required members are listed in
+// the required keyword, and sparse lists and maps allow null values.
+package jsonschema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/traitschema"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// Dialect is the value of the $schema keyword of the documents produced
+// by Convert.
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// A Schema is a JSON Schema object. A Schema marshals to JSON with
+// encoding/json.
+type Schema map[string]interface{}
+
+// Recursion says how Convert handles recursive shapes, which are
+// shapes that contain themselves through their members, directly or
+// indirectly.
+type Recursion int
+
+const (
+	// RecursionRef places recursive shapes in $defs, like other
+	// aggregate shapes, so they refer to themselves with $ref.
+	RecursionRef Recursion = iota
+
+	// RecursionError makes Convert return an error if the closure
+	// contains a recursive shape. It suits consumers of the schema
+	// which cannot handle recursive references.
+	RecursionError
+
+	// RecursionTruncate inlines recursive shapes instead of placing
+	// them in $defs, expanding a recursive shape within itself at most
+	// Options.MaxDepth times. Beyond that depth, any value is allowed.
+	RecursionTruncate
+)
+
+// Options controls the conversion of shapes to JSON Schema.
+type Options struct {
+	// Recursion says how recursive shapes are converted. The default is
+	// RecursionRef.
+	Recursion Recursion
+
+	// MaxDepth is the number of times RecursionTruncate expands a
+	// recursive shape within itself. Zero means 1.
+	MaxDepth int
+
+	// TimestampFormat is the format of timestamps which have no
+	// timestampFormat trait: "date-time", "epoch-seconds" or
+	// "http-date". The default is "date-time".
+	TimestampFormat string
+}
+
+// Convert converts the closure of the shape with the given ID in m to a
+// JSON Schema document. Shapes not in m are looked up in the prelude.
+//
+// If root is a structure, union, list, set or map, the document refers
+// to its schema in $defs with $ref; if it is a simple shape, the
+// document contains its schema directly. If root is a service, resource
+// or operation, the document has no schema of its own, and its $defs
+// contain the input, output and error structures of the operations in
+// the closure, and the shapes they refer to. The names of shapes in a
+// service's closure follow the service's rename property.
+//
+// Convert returns an error if root or a shape in its closure cannot be
+// found, or if two shapes would have the same name in $defs.
+func Convert(m ast.Model, root ast.AbsShapeID, opts Options) (Schema, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 1
+	}
+	if opts.TimestampFormat == "" {
+		opts.TimestampFormat = "date-time"
+	}
+
+	shapes := prelude.Shapes(m)
+	c := &converter{
+		opts:      opts,
+		shapes:    shapes,
+		defs:      Schema{},
+		owners:    make(map[string]ast.AbsShapeID),
+		recursive: make(map[ast.AbsShapeID]bool),
+		depth:     make(map[ast.AbsShapeID]int),
+	}
+
+	s, ok := shapes[root]
+	if !ok {
+		return nil, newErrorf("shape %s not found", root)
+	}
+	doc := Schema{}
+	switch s.Type {
+	case ast.ServiceType, ast.ResourceType, ast.OperationType:
+		if s.Service != nil {
+			c.rename = s.Service.Rename
+		}
+		if err := c.closure(root); err != nil {
+			return nil, err
+		}
+	default:
+		schema, err := c.schema(root, "")
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range schema {
+			doc[k] = v
+		}
+	}
+
+	doc["$schema"] = Dialect
+	if len(c.defs) > 0 {
+		doc["$defs"] = c.defs
+	}
+	return doc, nil
+}
+
+type converter struct {
+	opts      Options
+	shapes    map[ast.AbsShapeID]ast.Shape
+	rename    map[ast.AbsShapeID]ast.StringNode
+	defs      Schema                    // Schemas in $defs by name.
+	owners    map[string]ast.AbsShapeID // Shapes of schemas in $defs by name.
+	recursive map[ast.AbsShapeID]bool   // Memo of isRecursive.
+	depth     map[ast.AbsShapeID]int    // Expansions in progress, for RecursionTruncate.
+}
+
+// closure adds to $defs the input, output and error structures of the
+// operations bound, directly or indirectly, to a service, resource or
+// operation.
+func (c *converter) closure(id ast.AbsShapeID) error {
+	seen := make(map[ast.AbsShapeID]bool)
+	var visit func(id ast.AbsShapeID) error
+	visit = func(id ast.AbsShapeID) error {
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		s, ok := c.shapes[id]
+		if !ok {
+			return newErrorf("shape %s not found", id)
+		}
+
+		var refs []ast.AbsShapeIDNode
+		add := func(ref *ast.AbsShapeIDNode) {
+			if ref != nil {
+				refs = append(refs, *ref)
+			}
+		}
+		switch s.Type {
+		case ast.ServiceType:
+			if svc := s.Service; svc != nil {
+				refs = append(append(append(refs, svc.Operations...), svc.Resources...), svc.Errors...)
+			}
+		case ast.ResourceType:
+			if r := s.Resource; r != nil {
+				for _, ref := range []*ast.AbsShapeIDNode{r.Create, r.Put, r.Read, r.Update, r.Delete, r.List} {
+					add(ref)
+				}
+				refs = append(append(append(refs, r.Operations...), r.CollectionOperations...), r.Resources...)
+			}
+		case ast.OperationType:
+			if op := s.Operation; op != nil {
+				add(op.Input)
+				add(op.Output)
+				refs = append(refs, op.Errors...)
+			}
+		default:
+			_, err := c.schema(id, "")
+			return err
+		}
+
+		sort.Slice(refs, func(i, j int) bool { return refs[i].Value < refs[j].Value })
+		for _, ref := range refs {
+			if err := visit(ref.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(id)
+}
+
+// schema returns the schema of the shape with the given ID. The
+// timestampFormat is the format of a timestamp shape, or the empty
+// string to use the shape's timestampFormat trait or else the default
+// format.
+func (c *converter) schema(id ast.AbsShapeID, timestampFormat string) (Schema, error) {
+	s, ok := c.shapes[id]
+	if !ok {
+		return nil, newErrorf("shape %s not found", id)
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType, ast.ListType, ast.SetType, ast.MapType:
+		return c.aggregate(id, s)
+	}
+
+	var schema Schema
+	switch s.Type {
+	case ast.BlobType:
+		schema = Schema{"type": "string", "contentEncoding": "base64"}
+		if mediaType := s.Traits.StringTrait(ast.MediaTypeTraitID); mediaType != "" {
+			schema["contentMediaType"] = mediaType
+		}
+	case ast.BooleanType:
+		schema = Schema{"type": "boolean"}
+	case ast.StringType:
+		schema = Schema{"type": "string"}
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		schema = Schema{"type": "integer"}
+	case ast.FloatType, ast.DoubleType, ast.BigDecimalType:
+		schema = Schema{"type": "number"}
+	case ast.TimestampType:
+		if timestampFormat == "" {
+			timestampFormat = s.Traits.StringTrait(ast.TimestampFormatTraitID)
+		}
+		if timestampFormat == "" {
+			timestampFormat = c.opts.TimestampFormat
+		}
+		switch timestampFormat {
+		case "epoch-seconds":
+			schema = Schema{"type": "number"}
+		case "http-date":
+			schema = Schema{"type": "string", "pattern": httpDatePattern}
+		default:
+			schema = Schema{"type": "string", "format": "date-time"}
+		}
+	case ast.DocumentType:
+		schema = Schema{}
+	default:
+		return nil, newErrorf("shape %s is a %s, which has no schema", id, s.Type)
+	}
+	traitschema.Apply(schema, s.Type, s.Traits)
+	return schema, nil
+}
+
+// httpDatePattern matches the IMF-fixdate format of HTTP dates.
+const httpDatePattern = `^(Mon|Tue|Wed|Thu|Fri|Sat|Sun), \d{2} (Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{4} \d{2}:\d{2}:\d{2} GMT$`
+
+// aggregate returns the schema of a structure, union, list, set or
+// map: usually a reference to its schema in $defs, but an inline schema
+// for recursive shapes with RecursionTruncate.
+func (c *converter) aggregate(id ast.AbsShapeID, s ast.Shape) (Schema, error) {
+	if c.isRecursive(id) {
+		switch c.opts.Recursion {
+		case RecursionError:
+			return nil, newErrorf("shape %s is recursive", id)
+		case RecursionTruncate:
+			if c.depth[id] > c.opts.MaxDepth {
+				return Schema{}, nil
+			}
+			c.depth[id]++
+			defer func() { c.depth[id]-- }()
+			schema := Schema{}
+			err := c.define(schema, id, s)
+			return schema, err
+		}
+	}
+
+	name := c.name(id)
+	if prev, ok := c.owners[name]; ok && prev != id {
+		return nil, newErrorf("%s and %s both convert to schema %s", prev, id, name)
+	}
+	c.owners[name] = id
+	if _, ok := c.defs[name]; !ok {
+		// Add the schema before filling it in, so recursive references
+		// to the shape find it. Recursive shapes expanded by
+		// RecursionTruncate do not apply to shapes in $defs.
+		def := Schema{}
+		c.defs[name] = def
+		depth := c.depth
+		c.depth = make(map[ast.AbsShapeID]int)
+		err := c.define(def, id, s)
+		c.depth = depth
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Schema{"$ref": "#/$defs/" + name}, nil
+}
+
+// define fills in the schema of a structure, union, list, set or map.
+func (c *converter) define(schema Schema, id ast.AbsShapeID, s ast.Shape) error {
+	switch s.Type {
+	case ast.StructureType:
+		props := Schema{}
+		var required []string
+		for _, name := range s.MemberNames() {
+			member := s.Members[name]
+			ms, err := c.memberSchema(id+"$"+ast.AbsShapeID(name), member)
+			if err != nil {
+				return err
+			}
+			props[traitschema.PropertyName(name, member)] = ms
+			if member.Traits.HasTrait(ast.RequiredTraitID) {
+				required = append(required, traitschema.PropertyName(name, member))
+			}
+		}
+		schema["type"] = "object"
+		if len(props) > 0 {
+			schema["properties"] = props
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	case ast.UnionType:
+		var oneOf []interface{}
+		for _, name := range s.MemberNames() {
+			member := s.Members[name]
+			ms, err := c.memberSchema(id+"$"+ast.AbsShapeID(name), member)
+			if err != nil {
+				return err
+			}
+			n := traitschema.PropertyName(name, member)
+			oneOf = append(oneOf, Schema{
+				"type":                 "object",
+				"properties":           Schema{n: ms},
+				"required":             []string{n},
+				"additionalProperties": false,
+			})
+		}
+		schema["oneOf"] = oneOf
+	case ast.ListType, ast.SetType:
+		if s.Value == nil {
+			return newErrorf("%s %s has no member", s.Type, id)
+		}
+		items, err := c.memberSchema(id+"$member", *s.Value)
+		if err != nil {
+			return err
+		}
+		if s.Traits.HasTrait(ast.SparseTraitID) {
+			items = traitschema.Nullable(items)
+		}
+		schema["type"] = "array"
+		schema["items"] = items
+		if s.Type == ast.SetType {
+			schema["uniqueItems"] = true
+		}
+	case ast.MapType:
+		if s.Key == nil || s.Value == nil {
+			return newErrorf("map %s has no key or value", id)
+		}
+		keys, err := c.memberSchema(id+"$key", *s.Key)
+		if err != nil {
+			return err
+		}
+		values, err := c.memberSchema(id+"$value", *s.Value)
+		if err != nil {
+			return err
+		}
+		if s.Traits.HasTrait(ast.SparseTraitID) {
+			values = traitschema.Nullable(values)
+		}
+		schema["type"] = "object"
+		if len(keys) > 1 || keys["type"] != "string" {
+			schema["propertyNames"] = keys
+		}
+		schema["additionalProperties"] = values
+	}
+	traitschema.Apply(schema, s.Type, s.Traits)
+	return nil
+}
+
+// memberSchema returns the schema of a member: the schema of its target
+// with the member's traits applied.
+func (c *converter) memberSchema(id ast.AbsShapeID, member ast.Member) (Schema, error) {
+	target, ok := c.shapes[member.Target.Value]
+	if !ok {
+		return nil, newErrorf("target %s of member %s not found", member.Target.Value, id)
+	}
+	schema, err := c.schema(member.Target.Value, member.Traits.StringTrait(ast.TimestampFormatTraitID))
+	if err != nil {
+		return nil, err
+	}
+	traitschema.Apply(schema, target.Type, member.Traits)
+	return schema, nil
+}
+
+// isRecursive reports whether the shape with the given ID contains
+// itself through the targets of its members.
+func (c *converter) isRecursive(id ast.AbsShapeID) bool {
+	if r, ok := c.recursive[id]; ok {
+		return r
+	}
+	seen := make(map[ast.AbsShapeID]bool)
+	var reaches func(from ast.AbsShapeID) bool
+	reaches = func(from ast.AbsShapeID) bool {
+		for _, target := range targets(c.shapes[from]) {
+			if target == id {
+				return true
+			}
+			if !seen[target] {
+				seen[target] = true
+				if reaches(target) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	r := reaches(id)
+	c.recursive[id] = r
+	return r
+}
+
+// targets returns the targets of the members of s.
+func targets(s ast.Shape) []ast.AbsShapeID {
+	var ids []ast.AbsShapeID
+	for _, member := range s.Members {
+		ids = append(ids, member.Target.Value)
+	}
+	if s.Key != nil {
+		ids = append(ids, s.Key.Target.Value)
+	}
+	if s.Value != nil {
+		ids = append(ids, s.Value.Target.Value)
+	}
+	return ids
+}
+
+// name returns the name in $defs of a shape.
+func (c *converter) name(id ast.AbsShapeID) string {
+	if name, ok := c.rename[id]; ok {
+		return name.Value
+	}
+	return id.Name()
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "jsonschema: "
@@ -0,0 +1,300 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"operations": ["foo#GetThing"],
+			"errors": ["foo#Error"],
+			"rename": {"foo#Thing": "Item"}
+		},
+		"foo#GetThing": {
+			"type": "operation",
+			"input": "foo#GetThingInput",
+			"output": "foo#Thing"
+		},
+		"foo#GetThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "foo#Id", "traits": {"smithy.api#required": {}}}
+			}
+		},
+		"foo#Error": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "client"}
+		},
+		"foo#Id": {
+			"type": "string",
+			"traits": {"smithy.api#length": {"min": 1, "max": 8}, "smithy.api#pattern": "^[a-z]+$"}
+		},
+		"foo#Thing": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "smithy.api#String", "traits": {"smithy.api#jsonName": "Name", "smithy.api#required": {}}},
+				"size": {"target": "smithy.api#Integer", "traits": {"smithy.api#range": {"min": 0, "max": 1.5}}},
+				"color": {"target": "foo#Color", "traits": {"smithy.api#documentation": "The color."}},
+				"created": {"target": "smithy.api#Timestamp"},
+				"updated": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "epoch-seconds"}},
+				"expires": {"target": "foo#HTTPDate"},
+				"data": {"target": "smithy.api#Blob"},
+				"labels": {"target": "foo#Labels"},
+				"attrs": {"target": "foo#Attrs"},
+				"shape": {"target": "foo#Shape"},
+				"extra": {"target": "smithy.api#Document"}
+			}
+		},
+		"foo#Color": {
+			"type": "string",
+			"traits": {"smithy.api#enum": [{"value": "RED"}, {"value": "BLUE"}]}
+		},
+		"foo#HTTPDate": {
+			"type": "timestamp",
+			"traits": {"smithy.api#timestampFormat": "http-date"}
+		},
+		"foo#Labels": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#sparse": {}, "smithy.api#uniqueItems": {}, "smithy.api#length": {"max": 3}}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "foo#Id"},
+			"value": {"target": "foo#Thing"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Shape": {
+			"type": "union",
+			"members": {
+				"circle": {"target": "smithy.api#Double"},
+				"square": {"target": "smithy.api#Double", "traits": {"smithy.api#jsonName": "Square"}}
+			}
+		},
+		"foo#Node": {
+			"type": "structure",
+			"members": {
+				"value": {"target": "smithy.api#String"},
+				"next": {"target": "foo#Node"}
+			}
+		},
+		"foo#Holder": {
+			"type": "structure",
+			"members": {
+				"node": {"target": "foo#Node"}
+			}
+		},
+		"bar#Labels": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#Conflict": {
+			"type": "structure",
+			"members": {
+				"a": {"target": "foo#Labels"},
+				"b": {"target": "bar#Labels"}
+			}
+		}
+	}
+}`
+
+const thingSchema = `{
+	"type": "object",
+	"properties": {
+		"Name": {"type": "string"},
+		"size": {"type": "integer", "minimum": 0, "maximum": 1.5},
+		"color": {"type": "string", "enum": ["RED", "BLUE"], "description": "The color."},
+		"created": {"type": "string", "format": "date-time"},
+		"updated": {"type": "number"},
+		"expires": {"type": "string", "pattern": "^(Mon|Tue|Wed|Thu|Fri|Sat|Sun), \\d{2} (Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \\d{4} \\d{2}:\\d{2}:\\d{2} GMT$"},
+		"data": {"type": "string", "contentEncoding": "base64"},
+		"labels": {"$ref": "#/$defs/Labels"},
+		"attrs": {"$ref": "#/$defs/Attrs"},
+		"shape": {"$ref": "#/$defs/Shape"},
+		"extra": {}
+	},
+	"required": ["Name"]
+}`
+
+const closureDefs = `
+	"Attrs": {
+		"type": "object",
+		"propertyNames": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+		"additionalProperties": {"anyOf": [{"$ref": "#/$defs/Thing"}, {"type": "null"}]}
+	},
+	"Labels": {
+		"type": "array",
+		"items": {"type": ["string", "null"]},
+		"uniqueItems": true,
+		"maxItems": 3
+	},
+	"Shape": {
+		"oneOf": [
+			{"type": "object", "properties": {"circle": {"type": "number"}}, "required": ["circle"], "additionalProperties": false},
+			{"type": "object", "properties": {"Square": {"type": "number"}}, "required": ["Square"], "additionalProperties": false}
+		]
+	}`
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name string
+		root ast.AbsShapeID
+		opts Options
+		doc  string
+		err  string
+	}{
+		{
+			name: "simple shape",
+			root: "foo#Id",
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "string",
+				"minLength": 1,
+				"maxLength": 8,
+				"pattern": "^[a-z]+$"
+			}`,
+		},
+		{
+			name: "structure",
+			root: "foo#Thing",
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$ref": "#/$defs/Thing",
+				"$defs": {
+					"Thing": ` + thingSchema + `,` + closureDefs + `
+				}
+			}`,
+		},
+		{
+			name: "service",
+			root: "foo#Service",
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$defs": {
+					"Error": {"type": "object", "properties": {"message": {"type": "string"}}},
+					"GetThingInput": {
+						"type": "object",
+						"properties": {"id": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"}},
+						"required": ["id"]
+					},
+					"Item": ` + thingSchema + `,` +
+				strings.Replace(closureDefs, `#/$defs/Thing`, `#/$defs/Item`, -1) + `
+				}
+			}`,
+		},
+		{
+			name: "default timestamp format",
+			root: "smithy.api#Timestamp",
+			opts: Options{TimestampFormat: "epoch-seconds"},
+			doc:  `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "number"}`,
+		},
+		{
+			name: "recursion/ref",
+			root: "foo#Node",
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$ref": "#/$defs/Node",
+				"$defs": {
+					"Node": {
+						"type": "object",
+						"properties": {"value": {"type": "string"}, "next": {"$ref": "#/$defs/Node"}}
+					}
+				}
+			}`,
+		},
+		{
+			name: "recursion/error",
+			root: "foo#Holder",
+			opts: Options{Recursion: RecursionError},
+			err:  "jsonschema: shape foo#Node is recursive",
+		},
+		{
+			name: "recursion/truncate",
+			root: "foo#Holder",
+			opts: Options{Recursion: RecursionTruncate},
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$ref": "#/$defs/Holder",
+				"$defs": {
+					"Holder": {
+						"type": "object",
+						"properties": {
+							"node": {
+								"type": "object",
+								"properties": {
+									"value": {"type": "string"},
+									"next": {
+										"type": "object",
+										"properties": {"value": {"type": "string"}, "next": {}}
+									}
+								}
+							}
+						}
+					}
+				}
+			}`,
+		},
+		{
+			name: "recursion/truncate deeper",
+			root: "foo#Node",
+			opts: Options{Recursion: RecursionTruncate, MaxDepth: 2},
+			doc: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"properties": {
+					"value": {"type": "string"},
+					"next": {
+						"type": "object",
+						"properties": {
+							"value": {"type": "string"},
+							"next": {
+								"type": "object",
+								"properties": {"value": {"type": "string"}, "next": {}}
+							}
+						}
+					}
+				}
+			}`,
+		},
+		{
+			name: "error/not found",
+			root: "foo#Missing",
+			err:  "jsonschema: shape foo#Missing not found",
+		},
+		{
+			name: "error/name conflict",
+			root: "foo#Conflict",
+			err:  "jsonschema: foo#Labels and bar#Labels both convert to schema Labels",
+		},
+	}
+
+	m := testmodel.Read(t, testModel)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			doc, err := Convert(m, testCase.root, testCase.opts)
+
+			if testCase.err != "" {
+				assert.EqualError(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			p, err := json.Marshal(doc)
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.doc, string(p))
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package loader loads Smithy models from sets of model files, such as
+// the files in a directory tree, an embedded file system, or a Smithy
+// JAR, decoding the files concurrently and merging them into a single
+// ast.Model.
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// A DecodeFunc decodes a model file. The decoding options passed in
+// have Path set to the file's Location path.
+type DecodeFunc func(r io.Reader, opts ast.DecodeOptions) (ast.Model, error)
+
+// A Loader loads models from one or more Sources. The zero value is a
+// usable Loader which decodes JSON AST files with default options.
+type Loader struct {
+	// Options are the decoding options used for each file. The Path
+	// option is set by the Loader to each file's Location path.
+	//
+	// If Options.Recover is set, a file which decodes with errors
+	// still contributes the shapes that decoded successfully to the
+	// loaded model.
+	Options ast.DecodeOptions
+
+	// Concurrency is the maximum number of files decoded at once. If
+	// zero or negative, runtime.GOMAXPROCS(0) is used.
+	Concurrency int
+
+	// Decoders maps a file name extension, such as ".smithy", to the
+	// function which decodes files with that extension. Files with
+	// the extension ".json" are decoded as JSON AST using
+	// ast.ReadModelWithOptions unless Decoders says otherwise. Loading
+	// a file with any other extension not present in Decoders fails.
+	Decoders map[string]DecodeFunc
+}
+
+// Load loads all model files from the given sources using a zero-value
+// Loader.
+func Load(ctx context.Context, sources ...Source) (ast.Model, error) {
+	var l Loader
+	return l.Load(ctx, sources...)
+}
+
+// Load decodes the model files from the given sources concurrently and
+// merges them, in source order and then file order, using
+// ast.MergeModels.
+//
+// If any file cannot be loaded, the returned error has type FileErrors
+// and contains an error for each such file. Otherwise, if the merge
+// produces conflicts, the returned error has type
+// ast.MergeConflictsError, and the returned model is the best-effort
+// merge. If ctx is cancelled, Load stops and returns ctx.Err().
+func (l *Loader) Load(ctx context.Context, sources ...Source) (ast.Model, error) {
+	var files []File
+	for _, src := range sources {
+		f, err := src.Files(ctx)
+		if err != nil {
+			return ast.Model{}, err
+		}
+		files = append(files, f...)
+	}
+
+	if len(files) == 0 {
+		return ast.Model{}, newError("no model files to load")
+	}
+
+	models, errs := l.decodeAll(ctx, files)
+	if err := ctx.Err(); err != nil {
+		return ast.Model{}, err
+	}
+
+	var decoded []ast.Model
+	for i := range models {
+		if models[i] != nil {
+			decoded = append(decoded, *models[i])
+		}
+	}
+
+	var m ast.Model
+	var err error
+	if len(decoded) > 0 {
+		m, err = ast.MergeModels(decoded...)
+	}
+	if len(errs) > 0 {
+		return m, errs
+	}
+	return m, err
+}
+
+// decodeAll decodes the given files concurrently. It returns the
+// decoded model for each file, or nil if the file could not be
+// decoded, and the errors encountered in file order.
+func (l *Loader) decodeAll(ctx context.Context, files []File) ([]*ast.Model, FileErrors) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := l.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	models := make([]*ast.Model, len(files))
+	errs := make([]error, len(files))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				models[i], errs[i] = l.decode(ctx, files[i])
+				if errs[i] != nil && !l.Options.Recover {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	var fileErrs FileErrors
+	for i := range errs {
+		if errs[i] != nil && !errors.Is(errs[i], context.Canceled) {
+			fileErrs = append(fileErrs, FileError{Path: files[i].Path, Err: errs[i]})
+		}
+	}
+	return models, fileErrs
+}
+
+// decode decodes one file. It returns a nil model if the file could not
+// be decoded at all.
+func (l *Loader) decode(ctx context.Context, file File) (*ast.Model, error) {
+	ext := path.Ext(file.Name)
+	decodeFunc, ok := l.Decoders[ext]
+	switch {
+	case ok:
+	case ext == ".json":
+		decodeFunc = ast.ReadModelWithOptions
+	case ext == ".smithy":
+		return nil, newErrorf("no decoder for %q files: the Smithy IDL is not supported, supply a decoder in Loader.Decoders", ext)
+	default:
+		return nil, newErrorf("no decoder for %q files", ext)
+	}
+
+	f, err := file.FS.Open(file.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	opts := l.Options
+	opts.Path = file.Path
+	m, err := decodeFunc(&ctxReader{ctx: ctx, r: f}, opts)
+	if err != nil {
+		var jsonErrs ast.JSONErrors
+		if !opts.Recover || !errors.As(err, &jsonErrs) {
+			return nil, err
+		}
+	}
+	return &m, err
+}
+
+// ctxReader reads from an underlying reader until its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// FileError records an error loading a model file.
+type FileError struct {
+	Path string // Location path of the file
+	Err  error
+}
+
+func (err *FileError) Error() string {
+	return prefix + err.Path + ": " + err.Err.Error()
+}
+
+func (err *FileError) Unwrap() error {
+	return err.Err
+}
+
+// FileErrors is returned when one or more model files cannot be loaded.
+// It contains one error per file, in the order the files were to be
+// merged.
+type FileErrors []FileError
+
+func (err FileErrors) Error() string {
+	if len(err) == 1 {
+		return err[0].Error()
+	}
+
+	return prefix + strconv.Itoa(len(err)) + " files with errors"
+}
+
+// Unwrap returns each error contained in err, so that errors.Is and
+// errors.As can match individual errors.
+func (err FileErrors) Unwrap() []error {
+	errs := make([]error, len(err))
+	for i := range err {
+		errs[i] = &err[i]
+	}
+	return errs
+}
+
+func newError(text string) error {
+	return errors.New(prefix + text)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "loader: "
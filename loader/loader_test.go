@@ -0,0 +1,160 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+var testFS = fstest.MapFS{
+	"a.json":            {Data: []byte(`{"version":"1.0","shapes":{"foo#A":{"type":"string"}}}`)},
+	"models/b.json":     {Data: []byte(`{"version":"1.0","metadata":{"m":["b"]},"shapes":{"foo#B":{"type":"blob"}}}`)},
+	"models/x/c.json":   {Data: []byte(`{"version":"1.0","metadata":{"m":["c"]},"shapes":{"foo#C":{"type":"structure","members":{"b":{"target":"foo#B"}}}}}`)},
+	"models/x/d.smithy": {Data: []byte(`namespace foo`)},
+	"models/readme.txt": {Data: []byte(`not a model`)},
+	"bad/e.json":        {Data: []byte(`{"version":"1.0","shapes":{"foo#E":{"type":"string"},"foo#F":{"type":"nope"}}}`)},
+	"bad/f.json":        {Data: []byte(`{"version":"1.0","shapes":{"foo#A":{"type":"blob"}}}`)},
+}
+
+func TestFS(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		names    []string
+	}{
+		{
+			name:  "default",
+			names: []string{"a.json", "bad/e.json", "bad/f.json", "models/b.json", "models/x/c.json"},
+		},
+		{
+			name:     "star",
+			patterns: []string{"models/*"},
+			names:    []string{"models/b.json", "models/readme.txt"},
+		},
+		{
+			name:     "double star",
+			patterns: []string{"models/**/*.json", "**/*.smithy"},
+			names:    []string{"models/b.json", "models/x/c.json", "models/x/d.smithy"},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"*.xml"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			files, err := FS(testFS, testCase.patterns...).Files(context.Background())
+
+			require.NoError(t, err)
+			var names []string
+			for _, f := range files {
+				assert.Equal(t, f.Name, f.Path)
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, testCase.names, names)
+		})
+	}
+
+	t.Run("bad pattern", func(t *testing.T) {
+		_, err := FS(testFS, "[").Files(context.Background())
+
+		assert.ErrorIs(t, err, path.ErrBadPattern)
+		assert.EqualError(t, err, `loader: bad pattern "[": syntax error in pattern`)
+	})
+}
+
+func TestLoader_Load(t *testing.T) {
+	t.Run("merges", func(t *testing.T) {
+		l := Loader{Concurrency: 2}
+
+		m, err := l.Load(context.Background(), FS(testFS, "a.json"), FS(testFS, "models/**/*.json"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "1.0", m.Version.Value)
+		assert.Equal(t, []interface{}{"b", "c"}, m.Metadata["m"].Value)
+		require.Len(t, m.Shapes, 3)
+		a := m.Shapes["foo#A"]
+		assert.Equal(t, ast.Location{Path: "a.json", Offset: 35, Row: 1, Col: 36}, a.Location())
+		b := m.Shapes["foo#C"].Members["b"]
+		assert.Equal(t, ast.Location{Path: "models/x/c.json", Offset: 93, Row: 1, Col: 94}, b.Location())
+		assert.Equal(t, ast.Location{Path: "models/x/c.json", Offset: 103, Row: 1, Col: 104}, b.Target.Location())
+	})
+
+	t.Run("decoders", func(t *testing.T) {
+		var decodedPath string
+		l := Loader{
+			Decoders: map[string]DecodeFunc{
+				".smithy": func(r io.Reader, opts ast.DecodeOptions) (ast.Model, error) {
+					decodedPath = opts.Path
+					return ast.Model{Version: ast.StringNode{Value: "1.0"}}, nil
+				},
+			},
+		}
+
+		_, err := l.Load(context.Background(), FS(testFS, "**/*.smithy"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "models/x/d.smithy", decodedPath)
+	})
+
+	t.Run("no decoder", func(t *testing.T) {
+		_, err := Load(context.Background(), FS(testFS, "models/*.txt"))
+
+		assert.EqualError(t, err, `loader: models/readme.txt: loader: no decoder for ".txt" files`)
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		_, err := Load(context.Background(), FS(testFS, "*.xml"))
+
+		assert.EqualError(t, err, "loader: no model files to load")
+	})
+
+	t.Run("file error", func(t *testing.T) {
+		_, err := Load(context.Background(), FS(testFS, "bad/e.json"))
+
+		var fileErrs FileErrors
+		require.ErrorAs(t, err, &fileErrs)
+		require.Len(t, fileErrs, 1)
+		assert.Equal(t, "bad/e.json", fileErrs[0].Path)
+		var jsonErr *ast.JSONError
+		require.ErrorAs(t, err, &jsonErr)
+		assert.Equal(t, "/shapes/foo#F/type", jsonErr.Path)
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		l := Loader{Options: ast.DecodeOptions{Recover: true}}
+
+		m, err := l.Load(context.Background(), FS(testFS, "bad/e.json", "models/b.json"))
+
+		var jsonErrs ast.JSONErrors
+		require.ErrorAs(t, err, &jsonErrs)
+		assert.Len(t, jsonErrs, 1)
+		assert.Contains(t, m.Shapes, ast.AbsShapeID("foo#B"))
+		assert.Contains(t, m.Shapes, ast.AbsShapeID("foo#E"))
+		assert.NotContains(t, m.Shapes, ast.AbsShapeID("foo#F"))
+	})
+
+	t.Run("merge conflict", func(t *testing.T) {
+		m, err := Load(context.Background(), FS(testFS, "a.json", "bad/f.json"))
+
+		assert.IsType(t, ast.MergeConflictsError{}, err)
+		assert.Equal(t, ast.StringType, m.Shapes["foo#A"].Type)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := Load(ctx, FS(testFS))
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
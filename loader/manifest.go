@@ -0,0 +1,111 @@
+package loader
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestName is the name of the Smithy manifest file within a JAR
+// or other file system containing a Smithy package.
+const ManifestName = "META-INF/smithy/manifest"
+
+// Manifest returns a Source of the model files listed in the Smithy
+// manifest, ManifestName, within fsys.
+//
+// Each non-blank line of the manifest is the name of a model file
+// relative to the directory containing the manifest. The files are
+// ordered as they appear in the manifest, and their Location path is
+// their name within fsys.
+//
+// Manifests often list Smithy IDL files, with the extension ".smithy".
+// Loading them fails, naming the file, unless the Loader's Decoders
+// has a decoder for them.
+func Manifest(fsys fs.FS) Source {
+	return &manifestSource{fsys: fsys}
+}
+
+// JAR returns a Source of the model files listed in the Smithy
+// manifest within the JAR or zip archive at the operating system path
+// name. The Location path of each file is a "jar:file:" URL naming the
+// archive and the file within it, for example
+// "jar:file:/cache/traits.jar!/META-INF/smithy/traits.json". As with
+// Manifest, loading Smithy IDL files listed in the manifest requires a
+// decoder for them in the Loader's Decoders.
+//
+// The archive is read into memory when the Source's Files method is
+// called, so the returned Files remain usable after the archive is
+// modified or removed.
+func JAR(name string) Source {
+	return &jarSource{name: name}
+}
+
+type manifestSource struct {
+	fsys   fs.FS
+	prefix string
+}
+
+func (src *manifestSource) Files(ctx context.Context) ([]File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b, err := fs.ReadFile(src.fsys, ManifestName)
+	if err != nil {
+		return nil, newErrorf("failed to read manifest: %w", err)
+	}
+
+	var files []File
+	dir := path.Dir(ManifestName)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		name := path.Join(dir, line)
+		if !fs.ValidPath(name) || !strings.HasPrefix(name, dir+"/") {
+			return nil, newErrorf("invalid model file %q on manifest line %d", line, n)
+		}
+		files = append(files, File{FS: src.fsys, Name: name, Path: src.prefix + name})
+	}
+	if err = s.Err(); err != nil {
+		return nil, newErrorf("failed to read manifest: %w", err)
+	}
+	return files, nil
+}
+
+type jarSource struct {
+	name string
+}
+
+func (src *jarSource) Files(ctx context.Context) ([]File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(src.name)
+	if err != nil {
+		return nil, newErrorf("failed to read JAR: %w", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, newErrorf("failed to read JAR %s: %w", src.name, err)
+	}
+	abs, err := filepath.Abs(src.name)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := (&manifestSource{fsys: r, prefix: "jar:file:" + filepath.ToSlash(abs) + "!/"}).Files(ctx)
+	if err != nil {
+		return nil, newErrorf("JAR %s: %w", src.name, err)
+	}
+	return files, nil
+}
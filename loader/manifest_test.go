@@ -0,0 +1,157 @@
+package loader
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+func TestManifest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		names    []string
+		err      string
+	}{
+		{
+			name:     "files",
+			manifest: "b.json\n\n  a/c.json  \r\n",
+			names:    []string{"META-INF/smithy/b.json", "META-INF/smithy/a/c.json"},
+		},
+		{
+			name:     "empty",
+			manifest: "",
+		},
+		{
+			name:     "escapes",
+			manifest: "b.json\n../x.json\n",
+			err:      `loader: invalid model file "../x.json" on manifest line 2`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fsys := fstest.MapFS{ManifestName: {Data: []byte(testCase.manifest)}}
+
+			files, err := Manifest(fsys).Files(context.Background())
+
+			if testCase.err != "" {
+				assert.EqualError(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, f := range files {
+				assert.Equal(t, f.Name, f.Path)
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, testCase.names, names)
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := Manifest(fstest.MapFS{}).Files(context.Background())
+
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestJAR(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.jar")
+	writeJAR(t, name, map[string]string{
+		ManifestName:                "a.json\nb/b.json\n",
+		"META-INF/smithy/a.json":    `{"version":"1.0","shapes":{"foo#A":{"type":"string"}}}`,
+		"META-INF/smithy/b/b.json":  `{"version":"1.0","shapes":{"foo#B":{"type":"blob"}}}`,
+		"META-INF/smithy/c.json":    `{"version":"1.0","shapes":{"foo#C":{"type":"blob"}}}`,
+		"com/example/Unrelated.txt": "x",
+	})
+	abs, err := filepath.Abs(name)
+	require.NoError(t, err)
+
+	t.Run("load", func(t *testing.T) {
+		m, err := Load(context.Background(), JAR(name))
+
+		require.NoError(t, err)
+		require.Len(t, m.Shapes, 2)
+		a := m.Shapes["foo#A"]
+		assert.Equal(t, "jar:file:"+filepath.ToSlash(abs)+"!/META-INF/smithy/a.json", a.Location().Path)
+		assert.Equal(t, ast.BlobType, m.Shapes["foo#B"].Type)
+	})
+
+	t.Run("mixed manifest", func(t *testing.T) {
+		mixed := filepath.Join(t.TempDir(), "mixed.jar")
+		writeJAR(t, mixed, map[string]string{
+			ManifestName:               "a.json\nb.smithy\n",
+			"META-INF/smithy/a.json":   `{"version":"1.0","shapes":{"foo#A":{"type":"string"}}}`,
+			"META-INF/smithy/b.smithy": "namespace foo\n\nblob B\n",
+		})
+		mixedAbs, err := filepath.Abs(mixed)
+		require.NoError(t, err)
+
+		_, err = Load(context.Background(), JAR(mixed))
+
+		assert.EqualError(t, err, "loader: jar:file:"+filepath.ToSlash(mixedAbs)+"!/META-INF/smithy/b.smithy: "+
+			`loader: no decoder for ".smithy" files: the Smithy IDL is not supported, supply a decoder in Loader.Decoders`)
+
+		l := Loader{
+			Decoders: map[string]DecodeFunc{
+				".smithy": func(r io.Reader, opts ast.DecodeOptions) (ast.Model, error) {
+					return ast.ReadModel(strings.NewReader(`{"version":"1.0","shapes":{"foo#B":{"type":"blob"}}}`))
+				},
+			},
+		}
+		m, err := l.Load(context.Background(), JAR(mixed))
+
+		require.NoError(t, err)
+		assert.Len(t, m.Shapes, 2)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load(context.Background(), JAR(filepath.Join(t.TempDir(), "nope.jar")))
+
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "bad.jar")
+		require.NoError(t, os.WriteFile(bad, []byte("not a zip"), 0o600))
+
+		_, err := Load(context.Background(), JAR(bad))
+
+		assert.ErrorIs(t, err, zip.ErrFormat)
+	})
+
+	t.Run("no manifest", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.jar")
+		writeJAR(t, empty, map[string]string{"a.json": "{}"})
+
+		_, err := Load(context.Background(), JAR(empty))
+
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func writeJAR(t *testing.T, name string, files map[string]string) {
+	f, err := os.Create(name)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for n, content := range files {
+		fw, err := w.Create(n)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A File is a model file to be loaded.
+type File struct {
+	FS   fs.FS  // file system containing the file
+	Name string // name of the file within FS
+	Path string // path recorded in the Location of the file's nodes
+}
+
+// A Source provides the model files to be loaded by a Loader.
+type Source interface {
+	// Files returns the model files in the source, in the order in
+	// which they should be merged.
+	Files(ctx context.Context) ([]File, error)
+}
+
+// FS returns a Source of the files in fsys whose names match at least
+// one of the given patterns. If no patterns are given, the Source
+// contains every file in fsys with the extension ".json".
+//
+// Patterns have the syntax of path.Match, extended so that a "**"
+// path element matches zero or more directories. For example,
+// "models/**/*.json" matches "models/a.json" and "models/b/c.json".
+//
+// The files are ordered by name, and their Location path is their name
+// within fsys.
+func FS(fsys fs.FS, patterns ...string) Source {
+	return &fsSource{fsys: fsys, patterns: patterns}
+}
+
+// Dir returns a Source of the files in the directory dir whose names
+// match at least one of the given patterns. It is equivalent to FS
+// applied to os.DirFS(dir), except that the Location path of each file
+// is its operating system path, such as "dir/models/a.json".
+func Dir(dir string, patterns ...string) Source {
+	return &fsSource{fsys: os.DirFS(dir), patterns: patterns, dir: dir}
+}
+
+type fsSource struct {
+	fsys     fs.FS
+	patterns []string
+	dir      string
+}
+
+func (src *fsSource) Files(ctx context.Context) ([]File, error) {
+	patterns := src.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.json"}
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, newErrorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+
+	var files []File
+	err := fs.WalkDir(src.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !matchAny(patterns, name) {
+			return nil
+		}
+		p := name
+		if src.dir != "" {
+			p = filepath.Join(src.dir, filepath.FromSlash(name))
+		}
+		files = append(files, File{FS: src.fsys, Name: name, Path: p})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the path elements of name match the path
+// elements of pattern, where a "**" pattern element matches zero or
+// more name elements.
+func match(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if match(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
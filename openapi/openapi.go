@@ -0,0 +1,656 @@
+// Package openapi converts Smithy services to OpenAPI 3 documents.
+//
+// Convert walks the operations bound to a service, directly or through
+// its resources, and uses their HTTP binding traits to produce the
+// paths, parameters, request bodies and responses of the document.
+// Structures, unions, lists, sets and maps become component schemas
+// named after their shapes, while simple shapes are inlined. The
+// documentation, externalDocumentation, examples, deprecated and tags
+// traits become descriptions, external documentation, examples,
+// deprecation flags and tags; the length, range, pattern, enum and
+// uniqueItems constraint traits become schema keywords; the built-in
+// authentication traits become security schemes; and the cors trait
+// becomes preflight operations and CORS response headers.
+//
+// Traits Convert does not know about can be mapped to OpenAPI by
+// custom TraitMapper functions.
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/httpbinding"
+	"github.com/gogama/smithy-ast/internal/traitschema"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// OpenAPI versions supported by Convert.
+const (
+	Version30 = "3.0.3"
+	Version31 = "3.1.0"
+)
+
+// An Object is a JSON object in an OpenAPI document. An Object
+// marshals to JSON with encoding/json.
+type Object map[string]interface{}
+
+// A TraitMapper maps a trait to OpenAPI by modifying the OpenAPI object
+// produced for a shape or member the trait is applied to. Depending on
+// the shape, obj is the schema of a simple or aggregate shape or of a
+// member, the operation object of an operation, or the whole document
+// for the service. The shapeID is the ID of the shape or member, traitID
+// the ID of the trait and value the trait value.
+//
+// Trait mappers run after Convert has mapped the traits it knows about,
+// so they may also replace or remove what Convert produced.
+type TraitMapper func(obj Object, shapeID, traitID ast.AbsShapeID, value ast.Node) error
+
+// Options controls the conversion of a service to OpenAPI.
+type Options struct {
+	// Version is the OpenAPI version of the document, such as
+	// Version30 or Version31. Any 3.0.x or 3.1.x version is allowed.
+	// The default is Version30.
+	Version string
+
+	// TraitMappers maps trait IDs to functions mapping the traits to
+	// OpenAPI. See TraitMapper.
+	TraitMappers map[ast.AbsShapeID]TraitMapper
+}
+
+// Convert converts the service with the given ID in m to an OpenAPI
+// document. Shapes not in m are looked up in the prelude.
+//
+// Every operation of the service must have the http trait. Convert
+// returns an error if the service or a shape it refers to cannot be
+// found, if two operations are bound to the same method and path, if
+// two shapes would produce component schemas with the same name, or if
+// a trait mapper returns an error.
+func Convert(m ast.Model, service ast.AbsShapeID, opts Options) (Object, error) {
+	switch {
+	case opts.Version == "":
+		opts.Version = Version30
+	case strings.HasPrefix(opts.Version, "3.0."), strings.HasPrefix(opts.Version, "3.1."):
+	default:
+		return nil, newErrorf("unsupported OpenAPI version %q", opts.Version)
+	}
+
+	shapes := prelude.Shapes(m)
+	c := &converter{
+		opts:    opts,
+		v31:     strings.HasPrefix(opts.Version, "3.1."),
+		shapes:  shapes,
+		schemas: Object{},
+		owners:  make(map[string]string),
+	}
+	return c.convert(service)
+}
+
+type converter struct {
+	opts    Options
+	v31     bool
+	shapes  map[ast.AbsShapeID]ast.Shape
+	rename  map[ast.AbsShapeID]ast.StringNode
+	schemas Object            // Component schemas by name.
+	owners  map[string]string // Sources of component schemas by name.
+}
+
+func (c *converter) convert(id ast.AbsShapeID) (Object, error) {
+	s, ok := c.shapes[id]
+	if !ok {
+		return nil, newErrorf("service %s not found", id)
+	}
+	if s.Type != ast.ServiceType || s.Service == nil {
+		return nil, newErrorf("shape %s is a %s, not a service", id, s.Type)
+	}
+	c.rename = s.Service.Rename
+
+	info := Object{"title": id.Name(), "version": s.Service.Version.Value}
+	if title := s.Traits.StringTrait(ast.TitleTraitID); title != "" {
+		info["title"] = title
+	}
+	if doc := s.Traits.StringTrait(ast.DocumentationTraitID); doc != "" {
+		info["description"] = doc
+	}
+	doc := Object{"openapi": c.opts.Version, "info": info}
+	if ext := externalDocs(s.Traits); ext != nil {
+		doc["externalDocs"] = ext
+	}
+
+	schemes, auth := securitySchemes(s.Traits)
+	if len(auth) > 0 {
+		doc["security"] = securityRequirements(auth, false)
+	}
+
+	ops, err := c.operations(s)
+	if err != nil {
+		return nil, err
+	}
+	paths := Object{}
+	bound := make(map[string]ast.AbsShapeID)
+	for _, opID := range ops {
+		op := c.shapes[opID]
+		http, _ := op.Traits[ast.HTTPTraitID].(*ast.HTTPTrait)
+		if http == nil {
+			return nil, newErrorf("operation %s has no http trait", opID)
+		}
+		method := strings.ToLower(http.Method.Value)
+		path := uriPath(http.URI.Value)
+		key := strings.ToUpper(method) + " " + path
+		if prev, ok := bound[key]; ok {
+			return nil, newErrorf("operations %s and %s are both bound to %s", prev, opID, key)
+		}
+		bound[key] = opID
+
+		obj, err := c.operation(opID, op, http, s, auth)
+		if err != nil {
+			return nil, err
+		}
+		item, _ := paths[path].(Object)
+		if item == nil {
+			item = Object{}
+			paths[path] = item
+		}
+		item[method] = obj
+	}
+	if cors, ok := s.Traits[ast.CORSTraitID].(*ast.CORSTrait); ok {
+		addCORS(paths, cors)
+	}
+	doc["paths"] = paths
+
+	components := Object{}
+	if len(c.schemas) > 0 {
+		components["schemas"] = c.schemas
+	}
+	if len(schemes) > 0 {
+		components["securitySchemes"] = schemes
+	}
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+
+	if err = c.mapTraits(doc, id, s.Traits); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// operations returns the IDs of the operations bound to the service s
+// and to its resources, in sorted order.
+func (c *converter) operations(s ast.Shape) ([]ast.AbsShapeID, error) {
+	seen := make(map[ast.AbsShapeID]bool)
+	var ops []ast.AbsShapeID
+	var visit func(refs []ast.AbsShapeIDNode, resources bool) error
+	visit = func(refs []ast.AbsShapeIDNode, resources bool) error {
+		for _, ref := range refs {
+			id := ref.Value
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			s, ok := c.shapes[id]
+			switch {
+			case !ok:
+				return newErrorf("shape %s not found", id)
+			case !resources && s.Type != ast.OperationType:
+				return newErrorf("shape %s is a %s, not an operation", id, s.Type)
+			case resources && s.Type != ast.ResourceType:
+				return newErrorf("shape %s is a %s, not a resource", id, s.Type)
+			case !resources:
+				ops = append(ops, id)
+				continue
+			}
+			if r := s.Resource; r != nil {
+				var lifecycle []ast.AbsShapeIDNode
+				for _, ref := range []*ast.AbsShapeIDNode{r.Create, r.Put, r.Read, r.Update, r.Delete, r.List} {
+					if ref != nil {
+						lifecycle = append(lifecycle, *ref)
+					}
+				}
+				for _, refs := range [][]ast.AbsShapeIDNode{lifecycle, r.Operations, r.CollectionOperations} {
+					if err := visit(refs, false); err != nil {
+						return err
+					}
+				}
+				if err := visit(r.Resources, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := visit(s.Service.Operations, false); err != nil {
+		return nil, err
+	}
+	if err := visit(s.Service.Resources, true); err != nil {
+		return nil, err
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops, nil
+}
+
+func (c *converter) operation(id ast.AbsShapeID, s ast.Shape, http *ast.HTTPTrait, svc ast.Shape, auth []ast.AbsShapeID) (Object, error) {
+	var op ast.Operation
+	if s.Operation != nil {
+		op = *s.Operation
+	}
+	name := c.name(id)
+	obj := Object{"operationId": name}
+	if doc := s.Traits.StringTrait(ast.DocumentationTraitID); doc != "" {
+		obj["description"] = doc
+	}
+	if ext := externalDocs(s.Traits); ext != nil {
+		obj["externalDocs"] = ext
+	}
+	if tags, ok := s.Traits[ast.TagsTraitID].(*ast.TagsTrait); ok && len(tags.Items) > 0 {
+		names := make([]string, len(tags.Items))
+		for i := range tags.Items {
+			names[i] = tags.Items[i].Value
+		}
+		obj["tags"] = names
+	}
+	if _, ok := s.Traits[ast.DeprecatedTraitID]; ok {
+		obj["deprecated"] = true
+	}
+	if a, ok := s.Traits[ast.AuthTraitID].(*ast.AuthTrait); ok {
+		auth = make([]ast.AbsShapeID, len(a.Items))
+		for i := range a.Items {
+			auth[i] = a.Items[i].Value
+		}
+		obj["security"] = securityRequirements(auth, s.Traits.HasTrait(ast.OptionalAuthTraitID))
+	} else if s.Traits.HasTrait(ast.OptionalAuthTraitID) {
+		obj["security"] = securityRequirements(auth, true)
+	}
+
+	// Request.
+	in, err := c.bind(op.Input, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.params) > 0 {
+		obj["parameters"] = in.params
+	}
+	content, err := c.content([]*binding{in}, name+"RequestContent")
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		body := Object{"content": content}
+		if in.required {
+			body["required"] = true
+		}
+		obj["requestBody"] = body
+	}
+
+	// Successful response.
+	responses := Object{}
+	code := 200
+	if http.Code != nil {
+		code = int(http.Code.Value)
+	}
+	out, err := c.bind(op.Output, true)
+	if err != nil {
+		return nil, err
+	}
+	if responses[strconv.Itoa(code)], err = c.response(fmt.Sprintf("%s %d response", name, code), name+"ResponseContent", out); err != nil {
+		return nil, err
+	}
+
+	// Error responses, grouping errors with the same status code.
+	errs := make(map[int][]*binding)
+	seen := make(map[ast.AbsShapeID]bool)
+	for _, ref := range append(append([]ast.AbsShapeIDNode(nil), op.Errors...), svc.Service.Errors...) {
+		if seen[ref.Value] {
+			continue
+		}
+		seen[ref.Value] = true
+		b, err := c.bind(&ref, true)
+		if err != nil {
+			return nil, err
+		}
+		code := httpbinding.StatusCode(c.shapes[ref.Value].Traits)
+		errs[code] = append(errs[code], b)
+	}
+	codes := make([]int, 0, len(errs))
+	for code := range errs {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		bs := errs[code]
+		sort.Slice(bs, func(i, j int) bool { return bs[i].id < bs[j].id })
+		names := make([]string, len(bs))
+		for i, b := range bs {
+			names[i] = c.name(b.id)
+		}
+		description := fmt.Sprintf("%s %d response", strings.Join(names, ", "), code)
+		if responses[strconv.Itoa(code)], err = c.response(description, "", bs...); err != nil {
+			return nil, err
+		}
+	}
+	obj["responses"] = responses
+
+	if ex, ok := s.Traits[ast.ExamplesTraitID].(*ast.ExamplesTrait); ok {
+		addExamples(ex, in, out, errs)
+	}
+
+	if err = c.mapTraits(obj, id, s.Traits); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// A binding is an operation input, output or error structure whose
+// members are bound to parts of an HTTP request or response.
+type binding struct {
+	id       ast.AbsShapeID
+	params   []interface{}         // Request parameter objects.
+	headers  Object                // Response header objects by name.
+	payload  string                // Name of the httpPayload member.
+	body     map[string]ast.Member // Members bound to the JSON body.
+	media    map[string]Object     // Media type objects by content type.
+	required bool                  // Whether the body is required.
+}
+
+// bind classifies the members of the structure ref refers to by their
+// HTTP binding traits. A nil ref, which is an operation without input
+// or output, has no members.
+func (c *converter) bind(ref *ast.AbsShapeIDNode, response bool) (*binding, error) {
+	b := &binding{body: make(map[string]ast.Member), media: make(map[string]Object)}
+	if ref == nil {
+		return b, nil
+	}
+	b.id = ref.Value
+	s, ok := c.shapes[b.id]
+	if !ok {
+		return nil, newErrorf("shape %s not found", b.id)
+	}
+	if s.Type != ast.StructureType {
+		return nil, newErrorf("shape %s is a %s, not a structure", b.id, s.Type)
+	}
+
+	var path, query, header []interface{}
+	for _, name := range s.MemberNames() {
+		member := s.Members[name]
+		memberID := b.id + "$" + ast.AbsShapeID(name)
+		required := member.Traits.HasTrait(ast.RequiredTraitID)
+		var err error
+		switch {
+		case member.Traits.HasTrait(ast.HTTPLabelTraitID) && !response:
+			path, err = c.param(path, name, "path", memberID, member, true, "date-time")
+		case member.Traits.HasTrait(ast.HTTPQueryTraitID) && !response:
+			param := member.Traits.StringTrait(ast.HTTPQueryTraitID)
+			query, err = c.param(query, param, "query", memberID, member, required, "date-time")
+		case member.Traits.HasTrait(ast.HTTPQueryParamsTraitID) && !response:
+			query, err = c.param(query, name, "query", memberID, member, false, "date-time")
+			if err == nil {
+				p := query[len(query)-1].(Object)
+				p["style"] = "form"
+				p["explode"] = true
+			}
+		case member.Traits.HasTrait(ast.HTTPHeaderTraitID):
+			header, err = c.param(header, member.Traits.StringTrait(ast.HTTPHeaderTraitID), "header", memberID, member, required, "http-date")
+		case member.Traits.HasTrait(ast.HTTPPrefixedHeadersTraitID), member.Traits.HasTrait(ast.HTTPResponseCodeTraitID):
+			// OpenAPI cannot describe prefixed headers, and the response
+			// code is the response's key.
+		case member.Traits.HasTrait(ast.HTTPPayloadTraitID):
+			b.payload = name
+			b.body[name] = member
+			b.required = required
+		default:
+			b.body[name] = member
+			b.required = b.required || required
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response {
+		b.headers = Object{}
+		for _, p := range header {
+			p := p.(Object)
+			name := p["name"].(string)
+			delete(p, "name")
+			delete(p, "in")
+			b.headers[name] = p
+		}
+	} else {
+		b.params = append(append(path, query...), header...)
+	}
+	return b, nil
+}
+
+// param appends to params a parameter object for a member bound to the
+// HTTP request or response.
+func (c *converter) param(params []interface{}, name, in string, memberID ast.AbsShapeID, member ast.Member, required bool, timestampFormat string) ([]interface{}, error) {
+	schema, err := c.memberSchema(memberID, member, timestampFormat)
+	if err != nil {
+		return nil, err
+	}
+	p := Object{"name": name, "in": in, "schema": schema}
+	if required {
+		p["required"] = true
+	}
+	if doc := member.Traits.StringTrait(ast.DocumentationTraitID); doc != "" {
+		p["description"] = doc
+	}
+	return append(params, p), nil
+}
+
+// content returns the content object for the bodies of the bindings, or
+// nil if none of them has a body. A binding whose body is not an
+// httpPayload member gets a component schema for its body members,
+// named schemaName if set and otherwise after its structure. The
+// schemas of bindings with the same media type are combined with oneOf.
+func (c *converter) content(bs []*binding, schemaName string) (Object, error) {
+	schemas := make(map[string][]interface{})
+	types := make(map[*binding]string)
+	var order []string
+	for _, b := range bs {
+		var mediaType string
+		var schema Object
+		var err error
+		if b.payload != "" {
+			member := b.body[b.payload]
+			mediaType = c.mediaType(member)
+			schema, err = c.memberSchema(b.id+"$"+ast.AbsShapeID(b.payload), member, "")
+		} else if len(b.body) > 0 {
+			mediaType = "application/json"
+			name := schemaName
+			if name == "" {
+				name = c.name(b.id) + "ResponseContent"
+			}
+			schema, err = c.bodySchema(name, b)
+		} else {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := schemas[mediaType]; !ok {
+			order = append(order, mediaType)
+		}
+		schemas[mediaType] = append(schemas[mediaType], schema)
+		types[b] = mediaType
+	}
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	content := Object{}
+	for _, mediaType := range order {
+		var schema interface{} = schemas[mediaType][0]
+		if len(schemas[mediaType]) > 1 {
+			schema = Object{"oneOf": schemas[mediaType]}
+		}
+		content[mediaType] = Object{"schema": schema}
+	}
+	for b, mediaType := range types {
+		b.media[mediaType] = content[mediaType].(Object)
+	}
+	return content, nil
+}
+
+// response returns a response object for the bindings.
+func (c *converter) response(description, schemaName string, bs ...*binding) (Object, error) {
+	resp := Object{"description": description}
+	headers := Object{}
+	for _, b := range bs {
+		for name, h := range b.headers {
+			headers[name] = h
+		}
+	}
+	if len(headers) > 0 {
+		resp["headers"] = headers
+	}
+	content, err := c.content(bs, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		resp["content"] = content
+	}
+	return resp, nil
+}
+
+func (c *converter) mediaType(member ast.Member) string {
+	if mediaType := member.Traits.StringTrait(ast.MediaTypeTraitID); mediaType != "" {
+		return mediaType
+	}
+	target := c.shapes[member.Target.Value]
+	if mediaType := target.Traits.StringTrait(ast.MediaTypeTraitID); mediaType != "" {
+		return mediaType
+	}
+	switch target.Type {
+	case ast.BlobType:
+		return "application/octet-stream"
+	case ast.StringType:
+		return "text/plain"
+	default:
+		return "application/json"
+	}
+}
+
+// addExamples adds the examples of an operation to the media type
+// objects of its request, response and error bodies.
+func addExamples(ex *ast.ExamplesTrait, in, out *binding, errs map[int][]*binding) {
+	for i := range ex.Items {
+		item := &ex.Items[i]
+		example := Object{"summary": item.Title.Value}
+		if item.Documentation != nil {
+			example["description"] = item.Documentation.Value
+		}
+		add := func(b *binding, values map[string]ast.InterfaceNode) {
+			value, ok := b.example(values)
+			if !ok {
+				return
+			}
+			for _, media := range b.media {
+				examples, _ := media["examples"].(Object)
+				if examples == nil {
+					examples = Object{}
+					media["examples"] = examples
+				}
+				e := Object{"value": value}
+				for k, v := range example {
+					e[k] = v
+				}
+				examples[item.Title.Value] = e
+			}
+		}
+		add(in, item.Input)
+		if item.Error == nil {
+			add(out, item.Output)
+			continue
+		}
+		for _, bs := range errs {
+			for _, b := range bs {
+				if b.id == item.Error.ShapeID.Value {
+					add(b, item.Error.Content)
+				}
+			}
+		}
+	}
+}
+
+// example returns the body of a binding given the values of the members
+// of its structure.
+func (b *binding) example(values map[string]ast.InterfaceNode) (interface{}, bool) {
+	if len(b.media) == 0 || values == nil {
+		return nil, false
+	}
+	if b.payload != "" {
+		v, ok := values[b.payload]
+		return v.Value, ok
+	}
+	body := make(map[string]interface{})
+	for name, member := range b.body {
+		if v, ok := values[name]; ok {
+			body[traitschema.PropertyName(name, member)] = v.Value
+		}
+	}
+	return body, true
+}
+
+// uriPath returns the path of an http trait URI pattern as an OpenAPI
+// path template. Literal query strings are dropped, and greedy labels
+// become ordinary path parameters.
+func uriPath(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+	return strings.ReplaceAll(uri, "+}", "}")
+}
+
+// name returns the name of a shape in the service being converted.
+func (c *converter) name(id ast.AbsShapeID) string {
+	if name, ok := c.rename[id]; ok {
+		return name.Value
+	}
+	return id.Name()
+}
+
+// mapTraits runs the trait mappers for the traits applied to a shape or
+// member, in trait ID order.
+func (c *converter) mapTraits(obj Object, id ast.AbsShapeID, traits ast.Traits) error {
+	if len(c.opts.TraitMappers) == 0 {
+		return nil
+	}
+	ids := make([]ast.AbsShapeID, 0, len(traits))
+	for traitID := range traits {
+		ids = append(ids, traitID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, traitID := range ids {
+		if mapper := c.opts.TraitMappers[traitID]; mapper != nil {
+			if err := mapper(obj, id, traitID, traits[traitID]); err != nil {
+				return newErrorf("trait %s on %s: %w", traitID, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func externalDocs(traits ast.Traits) Object {
+	ext, ok := traits[ast.ExternalDocumentationTraitID].(*ast.ExternalDocumentationTrait)
+	if !ok || len(ext.Items) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(ext.Items))
+	for name := range ext.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return Object{"description": names[0], "url": ext.Items[names[0]].Value}
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "openapi: "
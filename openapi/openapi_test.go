@@ -0,0 +1,436 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "2020-01-01",
+			"operations": ["foo#GetThing"],
+			"resources": ["foo#Things"],
+			"errors": ["foo#ServerError"],
+			"rename": {"foo#Thing": "Item"},
+			"traits": {
+				"smithy.api#title": "Foo Service",
+				"smithy.api#documentation": "Manages things.",
+				"smithy.api#externalDocumentation": {"Guide": "https://example.com/guide"},
+				"smithy.api#httpBasicAuth": {},
+				"smithy.api#httpApiKeyAuth": {"name": "X-Api-Key", "in": "header"},
+				"smithy.api#cors": {"additionalAllowedHeaders": ["Authorization"]}
+			}
+		},
+		"foo#Things": {
+			"type": "resource",
+			"identifiers": {"id": "foo#Id"},
+			"create": "foo#PutThing"
+		},
+		"foo#GetThing": {
+			"type": "operation",
+			"input": "foo#GetThingInput",
+			"output": "foo#GetThingOutput",
+			"errors": ["foo#NotFound"],
+			"traits": {
+				"smithy.api#http": {"method": "GET", "uri": "/things/{id}?mode=full"},
+				"smithy.api#readonly": {},
+				"smithy.api#documentation": "Gets a thing.",
+				"smithy.api#tags": ["read"],
+				"smithy.api#optionalAuth": {},
+				"smithy.api#examples": [
+					{
+						"title": "Found",
+						"input": {"id": "abc"},
+						"output": {"thing": {"name": "A"}, "etag": "x"}
+					},
+					{
+						"title": "Missing",
+						"input": {"id": "zzz"},
+						"error": {"shapeId": "foo#NotFound", "content": {"message": "no"}}
+					}
+				]
+			}
+		},
+		"foo#GetThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "foo#Id", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"limit": {"target": "smithy.api#Integer", "traits": {"smithy.api#httpQuery": "limit", "smithy.api#range": {"min": 1, "max": 100}}},
+				"since": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#httpHeader": "X-Since"}},
+				"filters": {"target": "foo#Filters", "traits": {"smithy.api#httpQueryParams": {}}}
+			}
+		},
+		"foo#GetThingOutput": {
+			"type": "structure",
+			"members": {
+				"thing": {"target": "foo#Thing", "traits": {"smithy.api#documentation": "The thing."}},
+				"etag": {"target": "smithy.api#String", "traits": {"smithy.api#httpHeader": "ETag"}}
+			}
+		},
+		"foo#PutThing": {
+			"type": "operation",
+			"input": "foo#PutThingInput",
+			"traits": {
+				"smithy.api#http": {"method": "PUT", "uri": "/things/{id}", "code": 201},
+				"smithy.api#idempotent": {},
+				"smithy.api#auth": ["smithy.api#httpApiKeyAuth"],
+				"smithy.api#deprecated": {}
+			}
+		},
+		"foo#PutThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "foo#Id", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"data": {"target": "foo#Data", "traits": {"smithy.api#httpPayload": {}}}
+			}
+		},
+		"foo#Id": {
+			"type": "string",
+			"traits": {"smithy.api#length": {"min": 3, "max": 64}, "smithy.api#pattern": "^[a-z]+$"}
+		},
+		"foo#Data": {
+			"type": "blob",
+			"traits": {"smithy.api#mediaType": "image/png"}
+		},
+		"foo#Filters": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "smithy.api#String"}
+		},
+		"foo#Thing": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "smithy.api#String", "traits": {"smithy.api#required": {}, "smithy.api#jsonName": "Name"}},
+				"color": {"target": "foo#Color"},
+				"tags": {"target": "foo#Tags"},
+				"parts": {"target": "foo#Parts"},
+				"created": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "epoch-seconds"}}
+			}
+		},
+		"foo#Color": {
+			"type": "string",
+			"traits": {"smithy.api#enum": [{"value": "RED"}, {"value": "BLUE"}]}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#length": {"max": 10}, "smithy.api#uniqueItems": {}}
+		},
+		"foo#Parts": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "foo#Thing"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#NotFound": {
+			"type": "structure",
+			"members": {
+				"message": {"target": "smithy.api#String"}
+			},
+			"traits": {"smithy.api#error": "client", "smithy.api#httpError": 404}
+		},
+		"foo#ServerError": {
+			"type": "structure",
+			"members": {
+				"message": {"target": "smithy.api#String"}
+			},
+			"traits": {"smithy.api#error": "server"}
+		}
+	}
+}`
+
+// jsonOf returns the JSON encoding of the value at a path of keys and
+// array indexes in an OpenAPI document.
+func jsonOf(t *testing.T, doc Object, path ...string) string {
+	var v interface{} = doc
+	for _, key := range path {
+		if a, ok := v.([]interface{}); ok {
+			i, err := strconv.Atoi(key)
+			require.NoError(t, err)
+			require.Less(t, i, len(a), "no %q in %q", key, path)
+			v = a[i]
+			continue
+		}
+		obj, ok := v.(Object)
+		require.True(t, ok, "no object at %q in %q", key, path)
+		v, ok = obj[key]
+		require.True(t, ok, "no %q in %q", key, path)
+	}
+	p, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(p)
+}
+
+func TestConvert(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	doc, err := Convert(m, "foo#Service", Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, Version30, doc["openapi"])
+	assert.JSONEq(t, `{"title":"Foo Service","version":"2020-01-01","description":"Manages things."}`, jsonOf(t, doc, "info"))
+	assert.JSONEq(t, `{"description":"Guide","url":"https://example.com/guide"}`, jsonOf(t, doc, "externalDocs"))
+	assert.JSONEq(t, `[{"smithy.api.httpApiKeyAuth":[]},{"smithy.api.httpBasicAuth":[]}]`, jsonOf(t, doc, "security"))
+	assert.JSONEq(t, `{
+		"smithy.api.httpApiKeyAuth": {"type":"apiKey","name":"X-Api-Key","in":"header"},
+		"smithy.api.httpBasicAuth": {"type":"http","scheme":"basic"}
+	}`, jsonOf(t, doc, "components", "securitySchemes"))
+
+	t.Run("GetThing", func(t *testing.T) {
+		assert.JSONEq(t, `{
+			"operationId": "GetThing",
+			"description": "Gets a thing.",
+			"tags": ["read"],
+			"security": [{"smithy.api.httpApiKeyAuth":[]},{"smithy.api.httpBasicAuth":[]},{}],
+			"parameters": [
+				{"name":"id","in":"path","required":true,"schema":{"type":"string","minLength":3,"maxLength":64,"pattern":"^[a-z]+$"}},
+				{"name":"filters","in":"query","style":"form","explode":true,"schema":{"$ref":"#/components/schemas/Filters"}},
+				{"name":"limit","in":"query","schema":{"type":"integer","format":"int32","minimum":1,"maximum":100}},
+				{"name":"X-Since","in":"header","schema":{"type":"string","format":"http-date"}}
+			],
+			"responses": {
+				"200": {
+					"description": "GetThing 200 response",
+					"headers": {
+						"ETag": {"schema":{"type":"string"}},
+						"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}},
+						"Access-Control-Expose-Headers": {"schema":{"type":"string","enum":["ETag"]}}
+					},
+					"content": {
+						"application/json": {
+							"schema": {"$ref":"#/components/schemas/GetThingResponseContent"},
+							"examples": {"Found":{"summary":"Found","value":{"thing":{"name":"A"}}}}
+						}
+					}
+				},
+				"404": {
+					"description": "NotFound 404 response",
+					"headers": {"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}}},
+					"content": {
+						"application/json": {
+							"schema": {"$ref":"#/components/schemas/NotFoundResponseContent"},
+							"examples": {"Missing":{"summary":"Missing","value":{"message":"no"}}}
+						}
+					}
+				},
+				"500": {
+					"description": "ServerError 500 response",
+					"headers": {"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}}},
+					"content": {"application/json": {"schema": {"$ref":"#/components/schemas/ServerErrorResponseContent"}}}
+				}
+			}
+		}`, jsonOf(t, doc, "paths", "/things/{id}", "get"))
+	})
+
+	t.Run("PutThing", func(t *testing.T) {
+		assert.JSONEq(t, `{
+			"operationId": "PutThing",
+			"deprecated": true,
+			"security": [{"smithy.api.httpApiKeyAuth":[]}],
+			"parameters": [
+				{"name":"id","in":"path","required":true,"schema":{"type":"string","minLength":3,"maxLength":64,"pattern":"^[a-z]+$"}}
+			],
+			"requestBody": {
+				"content": {"image/png": {"schema": {"type":"string","format":"byte"}}}
+			},
+			"responses": {
+				"201": {
+					"description": "PutThing 201 response",
+					"headers": {"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}}}
+				},
+				"500": {
+					"description": "ServerError 500 response",
+					"headers": {"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}}},
+					"content": {"application/json": {"schema": {"$ref":"#/components/schemas/ServerErrorResponseContent"}}}
+				}
+			}
+		}`, jsonOf(t, doc, "paths", "/things/{id}", "put"))
+	})
+
+	t.Run("CORS preflight", func(t *testing.T) {
+		assert.JSONEq(t, `{
+			"description": "Handles CORS preflight requests.",
+			"security": [],
+			"responses": {
+				"200": {
+					"description": "CORS preflight response",
+					"headers": {
+						"Access-Control-Allow-Headers": {"schema":{"type":"string","enum":["Authorization,Content-Type,X-Since"]}},
+						"Access-Control-Allow-Methods": {"schema":{"type":"string","enum":["GET,PUT"]}},
+						"Access-Control-Allow-Origin": {"schema":{"type":"string","enum":["*"]}},
+						"Access-Control-Max-Age": {"schema":{"type":"string","enum":["600"]}}
+					}
+				}
+			}
+		}`, jsonOf(t, doc, "paths", "/things/{id}", "options"))
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		assert.JSONEq(t, `{
+			"Filters": {"type":"object","additionalProperties":{"type":"string"}},
+			"GetThingResponseContent": {
+				"type": "object",
+				"properties": {"thing": {"allOf":[{"$ref":"#/components/schemas/Item"}],"description":"The thing."}}
+			},
+			"Item": {
+				"type": "object",
+				"properties": {
+					"Name": {"type":"string"},
+					"color": {"type":"string","enum":["RED","BLUE"]},
+					"created": {"type":"number"},
+					"parts": {"$ref":"#/components/schemas/Parts"},
+					"tags": {"$ref":"#/components/schemas/Tags"}
+				},
+				"required": ["Name"]
+			},
+			"NotFoundResponseContent": {"type":"object","properties":{"message":{"type":"string"}}},
+			"Parts": {"type":"object","additionalProperties":{"allOf":[{"$ref":"#/components/schemas/Item"}],"nullable":true}},
+			"ServerErrorResponseContent": {"type":"object","properties":{"message":{"type":"string"}}},
+			"Tags": {"type":"array","items":{"type":"string"},"maxItems":10,"uniqueItems":true}
+		}`, jsonOf(t, doc, "components", "schemas"))
+	})
+}
+
+func TestConvert_Version31(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	doc, err := Convert(m, "foo#Service", Options{Version: Version31})
+
+	require.NoError(t, err)
+	assert.Equal(t, Version31, doc["openapi"])
+	assert.JSONEq(t, `{"$ref":"#/components/schemas/Item","description":"The thing."}`,
+		jsonOf(t, doc, "components", "schemas", "GetThingResponseContent", "properties", "thing"))
+	assert.JSONEq(t, `{"anyOf":[{"$ref":"#/components/schemas/Item"},{"type":"null"}]}`,
+		jsonOf(t, doc, "components", "schemas", "Parts", "additionalProperties"))
+}
+
+func TestConvert_TraitMappers(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+	var calls []string
+	opts := Options{
+		TraitMappers: map[ast.AbsShapeID]TraitMapper{
+			ast.ReadOnlyTraitID: func(obj Object, shapeID, traitID ast.AbsShapeID, _ ast.Node) error {
+				calls = append(calls, string(shapeID))
+				obj["x-readonly"] = true
+				return nil
+			},
+			ast.PatternTraitID: func(obj Object, shapeID, traitID ast.AbsShapeID, value ast.Node) error {
+				calls = append(calls, string(shapeID))
+				delete(obj, "pattern")
+				return nil
+			},
+		},
+	}
+
+	doc, err := Convert(m, "foo#Service", opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "true", jsonOf(t, doc, "paths", "/things/{id}", "get", "x-readonly"))
+	assert.JSONEq(t, `{"type":"string","minLength":3,"maxLength":64}`,
+		jsonOf(t, doc, "paths", "/things/{id}", "put", "parameters", "0", "schema"))
+	assert.Equal(t, []string{"foo#Id", "foo#GetThing", "foo#Id"}, calls)
+
+	t.Run("error", func(t *testing.T) {
+		errMapper := errors.New("mapper failed")
+		opts.TraitMappers[ast.ReadOnlyTraitID] = func(Object, ast.AbsShapeID, ast.AbsShapeID, ast.Node) error {
+			return errMapper
+		}
+
+		_, err := Convert(m, "foo#Service", opts)
+
+		assert.EqualError(t, err, "openapi: trait smithy.api#readonly on foo#GetThing: mapper failed")
+		assert.ErrorIs(t, err, errMapper)
+	})
+}
+
+func TestConvert_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		model   string
+		service ast.AbsShapeID
+		opts    Options
+		err     string
+	}{
+		{
+			name:    "unsupported version",
+			model:   testModel,
+			service: "foo#Service",
+			opts:    Options{Version: "2.0"},
+			err:     `openapi: unsupported OpenAPI version "2.0"`,
+		},
+		{
+			name:    "service not found",
+			model:   testModel,
+			service: "foo#Missing",
+			err:     "openapi: service foo#Missing not found",
+		},
+		{
+			name:    "not a service",
+			model:   testModel,
+			service: "foo#Thing",
+			err:     "openapi: shape foo#Thing is a structure, not a service",
+		},
+		{
+			name: "no http trait",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","operations":["foo#Op"]},
+				"foo#Op":{"type":"operation"}
+			}}`,
+			service: "foo#S",
+			err:     "openapi: operation foo#Op has no http trait",
+		},
+		{
+			name: "conflicting routes",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","operations":["foo#A","foo#B"]},
+				"foo#A":{"type":"operation","traits":{"smithy.api#http":{"method":"GET","uri":"/x/{a}"}}},
+				"foo#B":{"type":"operation","traits":{"smithy.api#http":{"method":"get","uri":"/x/{a}?b=c"}}}
+			}}`,
+			service: "foo#S",
+			err:     "openapi: operations foo#A and foo#B are both bound to GET /x/{a}",
+		},
+		{
+			name: "schema name conflict",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","operations":["foo#Op"]},
+				"foo#Op":{"type":"operation","input":"foo#In","traits":{"smithy.api#http":{"method":"POST","uri":"/"}}},
+				"foo#In":{"type":"structure","members":{"a":{"target":"foo#L"},"b":{"target":"bar#L"}}},
+				"foo#L":{"type":"list","member":{"target":"smithy.api#String"}},
+				"bar#L":{"type":"list","member":{"target":"smithy.api#Integer"}}
+			}}`,
+			service: "foo#S",
+			err:     "openapi: foo#L and bar#L both convert to schema L",
+		},
+		{
+			name: "missing target",
+			model: `{"version":"1.0","shapes":{
+				"foo#S":{"type":"service","version":"1","operations":["foo#Op"]},
+				"foo#Op":{"type":"operation","input":"foo#In","traits":{"smithy.api#http":{"method":"POST","uri":"/"}}},
+				"foo#In":{"type":"structure","members":{"a":{"target":"foo#Missing"}}}
+			}}`,
+			service: "foo#S",
+			err:     "openapi: target foo#Missing of member foo#In$a not found",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := testmodel.Read(t, testCase.model)
+
+			_, err := Convert(m, testCase.service, testCase.opts)
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
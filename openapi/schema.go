@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/traitschema"
+)
+
+// schema returns the schema of the shape with the given ID. Structures,
+// unions, lists, sets and maps get a component schema, and the returned
+// schema refers to it; simple shapes are inlined. The timestampFormat
+// is the format of a timestamp shape, or the empty string to use the
+// shape's timestampFormat trait or else date-time.
+func (c *converter) schema(id ast.AbsShapeID, timestampFormat string) (Object, error) {
+	s, ok := c.shapes[id]
+	if !ok {
+		return nil, newErrorf("shape %s not found", id)
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType, ast.ListType, ast.SetType, ast.MapType:
+		name := c.name(id)
+		if err := c.claim(name, string(id)); err != nil {
+			return nil, err
+		}
+		if _, ok := c.schemas[name]; !ok {
+			// Add the schema before filling it in, so recursive
+			// references to the shape find it.
+			def := Object{}
+			c.schemas[name] = def
+			if err := c.define(def, id, s); err != nil {
+				return nil, err
+			}
+		}
+		return ref(name), nil
+	}
+
+	var obj Object
+	switch s.Type {
+	case ast.BlobType:
+		obj = Object{"type": "string", "format": "byte"}
+	case ast.BooleanType:
+		obj = Object{"type": "boolean"}
+	case ast.StringType:
+		obj = Object{"type": "string"}
+	case ast.ByteType, ast.ShortType, ast.IntegerType:
+		obj = Object{"type": "integer", "format": "int32"}
+	case ast.LongType:
+		obj = Object{"type": "integer", "format": "int64"}
+	case ast.BigIntegerType:
+		obj = Object{"type": "integer"}
+	case ast.FloatType:
+		obj = Object{"type": "number", "format": "float"}
+	case ast.DoubleType:
+		obj = Object{"type": "number", "format": "double"}
+	case ast.BigDecimalType:
+		obj = Object{"type": "number"}
+	case ast.TimestampType:
+		if timestampFormat == "" {
+			timestampFormat = s.Traits.StringTrait(ast.TimestampFormatTraitID)
+		}
+		switch timestampFormat {
+		case "epoch-seconds":
+			obj = Object{"type": "number"}
+		case "http-date":
+			obj = Object{"type": "string", "format": "http-date"}
+		default:
+			obj = Object{"type": "string", "format": "date-time"}
+		}
+	case ast.DocumentType:
+		obj = Object{}
+	default:
+		return nil, newErrorf("shape %s is a %s, which has no schema", id, s.Type)
+	}
+	traitschema.Apply(obj, s.Type, s.Traits)
+	if err := c.mapTraits(obj, id, s.Traits); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// define fills in the component schema def of an aggregate shape.
+func (c *converter) define(def Object, id ast.AbsShapeID, s ast.Shape) error {
+	switch s.Type {
+	case ast.StructureType:
+		props := Object{}
+		var required []string
+		for _, name := range s.MemberNames() {
+			member := s.Members[name]
+			schema, err := c.memberSchema(id+"$"+ast.AbsShapeID(name), member, "")
+			if err != nil {
+				return err
+			}
+			props[traitschema.PropertyName(name, member)] = schema
+			if member.Traits.HasTrait(ast.RequiredTraitID) {
+				required = append(required, traitschema.PropertyName(name, member))
+			}
+		}
+		def["type"] = "object"
+		if len(props) > 0 {
+			def["properties"] = props
+		}
+		if len(required) > 0 {
+			def["required"] = required
+		}
+	case ast.UnionType:
+		var oneOf []interface{}
+		for _, name := range s.MemberNames() {
+			member := s.Members[name]
+			schema, err := c.memberSchema(id+"$"+ast.AbsShapeID(name), member, "")
+			if err != nil {
+				return err
+			}
+			n := traitschema.PropertyName(name, member)
+			oneOf = append(oneOf, Object{
+				"type":       "object",
+				"title":      n,
+				"properties": Object{n: schema},
+				"required":   []string{n},
+			})
+		}
+		def["oneOf"] = oneOf
+	case ast.ListType, ast.SetType:
+		if s.Value == nil {
+			return newErrorf("%s %s has no member", s.Type, id)
+		}
+		items, err := c.memberSchema(id+"$member", *s.Value, "")
+		if err != nil {
+			return err
+		}
+		if s.Traits.HasTrait(ast.SparseTraitID) {
+			items = c.nullable(items)
+		}
+		def["type"] = "array"
+		def["items"] = items
+		if s.Type == ast.SetType {
+			def["uniqueItems"] = true
+		}
+	case ast.MapType:
+		if s.Value == nil {
+			return newErrorf("map %s has no value", id)
+		}
+		values, err := c.memberSchema(id+"$value", *s.Value, "")
+		if err != nil {
+			return err
+		}
+		if s.Traits.HasTrait(ast.SparseTraitID) {
+			values = c.nullable(values)
+		}
+		def["type"] = "object"
+		def["additionalProperties"] = values
+	}
+	traitschema.Apply(def, s.Type, s.Traits)
+	return c.mapTraits(def, id, s.Traits)
+}
+
+// memberSchema returns the schema of a member: the schema of its target
+// with the member's traits applied. The timestampFormat is the default
+// format of a timestamp member with no timestampFormat trait on either
+// the member or its target, or the empty string for date-time.
+func (c *converter) memberSchema(id ast.AbsShapeID, member ast.Member, timestampFormat string) (Object, error) {
+	target, ok := c.shapes[member.Target.Value]
+	if !ok {
+		return nil, newErrorf("target %s of member %s not found", member.Target.Value, id)
+	}
+	format := member.Traits.StringTrait(ast.TimestampFormatTraitID)
+	if format == "" && !target.Traits.HasTrait(ast.TimestampFormatTraitID) {
+		format = timestampFormat
+	}
+	schema, err := c.schema(member.Target.Value, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := schema["$ref"]; ok {
+		keywords := Object{}
+		traitschema.Apply(keywords, target.Type, member.Traits)
+		if len(keywords) > 0 {
+			if !c.v31 {
+				// OpenAPI 3.0 ignores the siblings of $ref.
+				schema = Object{"allOf": []interface{}{schema}}
+			}
+			for k, v := range keywords {
+				schema[k] = v
+			}
+		}
+	} else {
+		traitschema.Apply(schema, target.Type, member.Traits)
+	}
+	if err = c.mapTraits(schema, id, member.Traits); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// bodySchema returns a reference to a component schema, with the given
+// name, for the members of a binding bound to the JSON body.
+func (c *converter) bodySchema(name string, b *binding) (Object, error) {
+	if err := c.claim(name, "body of "+string(b.id)); err != nil {
+		return nil, err
+	}
+	if _, ok := c.schemas[name]; !ok {
+		def := Object{}
+		c.schemas[name] = def
+		err := c.define(def, b.id, ast.Shape{Type: ast.StructureType, Members: b.body})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ref(name), nil
+}
+
+// claim records that the component schema with the given name is
+// produced from source, and returns an error if another source produces
+// a schema with the same name.
+func (c *converter) claim(name, source string) error {
+	if prev, ok := c.owners[name]; ok && prev != source {
+		return newErrorf("%s and %s both convert to schema %s", prev, source, name)
+	}
+	c.owners[name] = source
+	return nil
+}
+
+// nullable returns a schema which also allows null values, as JSON
+// Schema does in OpenAPI 3.1 and with the nullable keyword in 3.0.
+func (c *converter) nullable(schema Object) Object {
+	if c.v31 {
+		return traitschema.Nullable(schema)
+	}
+	if _, isRef := schema["$ref"]; isRef {
+		schema = Object{"allOf": []interface{}{schema}}
+	}
+	schema["nullable"] = true
+	return schema
+}
+
+func ref(name string) Object {
+	return Object{"$ref": "#/components/schemas/" + name}
+}
@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// securitySchemes returns the security schemes for the authentication
+// traits applied to a service, by scheme name, and the IDs of the
+// schemes the service's operations use by default: those listed by the
+// service's auth trait, or else all of them in trait ID order.
+func securitySchemes(traits ast.Traits) (Object, []ast.AbsShapeID) {
+	schemes := Object{}
+	var ids []ast.AbsShapeID
+	for id, value := range traits {
+		var scheme Object
+		switch id {
+		case ast.HTTPBasicAuthTraitID:
+			scheme = Object{"type": "http", "scheme": "basic"}
+		case ast.HTTPDigestAuthTraitID:
+			scheme = Object{"type": "http", "scheme": "digest"}
+		case ast.HTTPBearerAuthTraitID:
+			scheme = Object{"type": "http", "scheme": "bearer"}
+		case ast.HTTPAPIKeyAuthTraitID:
+			if t, ok := value.(*ast.HTTPAPIKeyAuthTrait); ok {
+				scheme = Object{"type": "apiKey", "name": t.Name.Value, "in": t.In.Value}
+			}
+		}
+		if scheme != nil {
+			schemes[schemeName(id)] = scheme
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if auth, ok := traits[ast.AuthTraitID].(*ast.AuthTrait); ok {
+		ids = ids[:0]
+		for i := range auth.Items {
+			ids = append(ids, auth.Items[i].Value)
+		}
+	}
+	return schemes, ids
+}
+
+// securityRequirements returns a list of alternative security
+// requirements, one per scheme, and an empty requirement if optional is
+// true.
+func securityRequirements(ids []ast.AbsShapeID, optional bool) []interface{} {
+	reqs := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		reqs = append(reqs, Object{schemeName(id): []string{}})
+	}
+	if optional {
+		reqs = append(reqs, Object{})
+	}
+	return reqs
+}
+
+// schemeName returns the name of the security scheme for an
+// authentication trait, such as "smithy.api.httpBasicAuth".
+func schemeName(id ast.AbsShapeID) string {
+	return strings.Replace(string(id), "#", ".", 1)
+}
+
+// addCORS adds to each path an options operation answering CORS
+// preflight requests, and adds CORS headers to the responses of the
+// path's operations.
+func addCORS(paths Object, cors *ast.CORSTrait) {
+	origin, maxAge := "*", 600
+	if cors.Origin != nil {
+		origin = cors.Origin.Value
+	}
+	if cors.MaxAge != nil {
+		maxAge = int(cors.MaxAge.Value)
+	}
+	var exposed []string
+	for i := range cors.AdditionalExposedHeaders {
+		exposed = append(exposed, cors.AdditionalExposedHeaders[i].Value)
+	}
+
+	for _, item := range paths {
+		item := item.(Object)
+		var methods []string
+		allowed := make(map[string]bool)
+		for i := range cors.AdditionalAllowedHeaders {
+			allowed[cors.AdditionalAllowedHeaders[i].Value] = true
+		}
+		for method, op := range item {
+			op := op.(Object)
+			methods = append(methods, strings.ToUpper(method))
+			params, _ := op["parameters"].([]interface{})
+			for _, p := range params {
+				if p := p.(Object); p["in"] == "header" {
+					allowed[p["name"].(string)] = true
+				}
+			}
+			if _, ok := op["requestBody"]; ok {
+				allowed["Content-Type"] = true
+			}
+			for _, resp := range op["responses"].(Object) {
+				resp := resp.(Object)
+				headers, _ := resp["headers"].(Object)
+				expose := append([]string(nil), exposed...)
+				for name := range headers {
+					expose = append(expose, name)
+				}
+				if headers == nil {
+					headers = Object{}
+					resp["headers"] = headers
+				}
+				headers["Access-Control-Allow-Origin"] = header(origin)
+				if len(expose) > 0 {
+					sort.Strings(expose)
+					headers["Access-Control-Expose-Headers"] = header(strings.Join(expose, ","))
+				}
+			}
+		}
+		if _, ok := item["options"]; ok {
+			continue
+		}
+		sort.Strings(methods)
+		headers := Object{
+			"Access-Control-Allow-Origin":  header(origin),
+			"Access-Control-Allow-Methods": header(strings.Join(methods, ",")),
+			"Access-Control-Max-Age":       header(strconv.Itoa(maxAge)),
+		}
+		if len(allowed) > 0 {
+			names := make([]string, 0, len(allowed))
+			for name := range allowed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			headers["Access-Control-Allow-Headers"] = header(strings.Join(names, ","))
+		}
+		item["options"] = Object{
+			"description": "Handles CORS preflight requests.",
+			"security":    []interface{}{},
+			"responses": Object{
+				"200": Object{"description": "CORS preflight response", "headers": headers},
+			},
+		}
+	}
+}
+
+// header returns a response header object for a header with a fixed
+// value.
+func header(value string) Object {
+	return Object{"schema": Object{"type": "string", "enum": []string{value}}}
+}
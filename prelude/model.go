@@ -0,0 +1,26 @@
+package prelude
+
+import (
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Model decodes and returns the prelude model. Each call returns a new
+// model, which the caller is free to modify.
+func Model() ast.Model {
+	m, err := ast.ReadModel(NewReader())
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Shapes returns the shapes of the prelude model overlaid with the
+// shapes of m, which replace any prelude shapes with the same IDs. Each
+// call returns a new map, which the caller is free to modify.
+func Shapes(m ast.Model) map[ast.AbsShapeID]ast.Shape {
+	shapes := Model().Shapes
+	for id, s := range m.Shapes {
+		shapes[id] = s
+	}
+	return shapes
+}
@@ -93,3 +93,27 @@ func TestNewReader(t *testing.T) {
 		assert.Equal(t, "1.0", m.Version.Value)
 	})
 }
+
+func TestModel(t *testing.T) {
+	m1 := Model()
+	m2 := Model()
+
+	assert.Equal(t, "1.0", m1.Version.Value)
+	assert.Contains(t, m1.Shapes, ast.AbsShapeID("smithy.api#String"))
+	delete(m1.Shapes, "smithy.api#String")
+	assert.Contains(t, m2.Shapes, ast.AbsShapeID("smithy.api#String"))
+}
+
+func TestShapes(t *testing.T) {
+	m := ast.Model{Shapes: map[ast.AbsShapeID]ast.Shape{
+		"smithy.api#String": {Type: ast.BlobType},
+		"foo#Bar":           {Type: ast.StringType},
+	}}
+
+	shapes := Shapes(m)
+
+	assert.Equal(t, ast.BlobType, shapes["smithy.api#String"].Type)
+	assert.Equal(t, ast.StringType, shapes["foo#Bar"].Type)
+	assert.Equal(t, ast.IntegerType, shapes["smithy.api#Integer"].Type)
+	assert.Equal(t, ast.StringType, Model().Shapes["smithy.api#String"].Type)
+}
@@ -0,0 +1,379 @@
+package protocoltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+)
+
+// compareQuery compares the raw query string of a request with the
+// query parameters a test case expects, forbids and requires, and
+// returns a description of each difference.
+func compareQuery(rawQuery string, params, forbid, require []ast.StringNode) []string {
+	actual := splitQuery(rawQuery)
+	used := make([]bool, len(actual))
+	var problems []string
+	for _, param := range params {
+		expected := splitQuery(param.Value)
+		if len(expected) != 1 {
+			continue
+		}
+		found := false
+		for i, pair := range actual {
+			if !used[i] && pair == expected[0] {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("query: missing parameter %s", param.Value))
+		}
+	}
+	has := func(key string) bool {
+		for _, pair := range actual {
+			if pair[0] == key {
+				return true
+			}
+		}
+		return false
+	}
+	for _, key := range forbid {
+		if has(key.Value) {
+			problems = append(problems, fmt.Sprintf("query: forbidden parameter %s is present", key.Value))
+		}
+	}
+	for _, key := range require {
+		if !has(key.Value) {
+			problems = append(problems, fmt.Sprintf("query: required parameter %s is missing", key.Value))
+		}
+	}
+	return problems
+}
+
+// splitQuery splits a raw query string into its decoded key and value
+// pairs, in order.
+func splitQuery(rawQuery string) [][2]string {
+	var pairs [][2]string
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		var pair [2]string
+		pair[0] = part
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			pair[0], pair[1] = part[:i], part[i+1:]
+		}
+		for j := range pair {
+			if s, err := url.QueryUnescape(pair[j]); err == nil {
+				pair[j] = s
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// compareHeaders compares the headers of a message with the headers a
+// test case expects, forbids and requires, and returns a description of
+// each difference. Headers with several values are compared as their
+// values joined by commas.
+func compareHeaders(header http.Header, expected map[string]ast.StringNode, forbid, require []ast.StringNode) []string {
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var problems []string
+	for _, key := range keys {
+		values := header.Values(key)
+		if len(values) == 0 {
+			problems = append(problems, fmt.Sprintf("header %s: expected %q, but it is missing", key, expected[key].Value))
+		} else if actual := strings.Join(values, ", "); actual != expected[key].Value {
+			problems = append(problems, fmt.Sprintf("header %s: expected %q, got %q", key, expected[key].Value, actual))
+		}
+	}
+	for _, key := range forbid {
+		if values := header.Values(key.Value); len(values) > 0 {
+			problems = append(problems, fmt.Sprintf("header %s: forbidden, got %q", key.Value, strings.Join(values, ", ")))
+		}
+	}
+	for _, key := range require {
+		if len(header.Values(key.Value)) == 0 {
+			problems = append(problems, fmt.Sprintf("header %s: required, but it is missing", key.Value))
+		}
+	}
+	return problems
+}
+
+// compareBody compares the body of a message with the body a test case
+// expects, if any, and describes the difference. JSON and XML bodies
+// are compared as documents, and CBOR bodies as values of the structure
+// shape refers to. Other bodies are compared byte for byte.
+func (r *runner) compareBody(expected, mediaType *ast.StringNode, actual []byte, shape *ast.AbsShapeIDNode) string {
+	if expected == nil {
+		return ""
+	}
+	p, err := r.body(expected, mediaType)
+	if err != nil {
+		return fmt.Sprintf("body: invalid expected body: %s", err)
+	}
+	if len(p) == 0 && len(actual) == 0 {
+		return ""
+	}
+	mt := ""
+	if mediaType != nil {
+		mt = mediaType.Value
+	}
+	switch {
+	case isJSON(mt):
+		equal, err := jsonEqual(p, actual)
+		if err != nil {
+			return fmt.Sprintf("body: %s", err)
+		} else if !equal {
+			return fmt.Sprintf("body: expected JSON %s, got %s", p, actual)
+		}
+	case isXML(mt):
+		equal, err := xmlEqual(p, actual)
+		if err != nil {
+			return fmt.Sprintf("body: %s", err)
+		} else if !equal {
+			return fmt.Sprintf("body: expected XML %s, got %s", p, actual)
+		}
+	case isCBOR(mt) && shape != nil:
+		v, err := r.cbor.Unmarshal(shape.Value, p)
+		if err != nil {
+			return fmt.Sprintf("body: invalid expected CBOR: %s", err)
+		}
+		w, err := r.cbor.Unmarshal(shape.Value, actual)
+		if err != nil {
+			return fmt.Sprintf("body: invalid CBOR: %s", err)
+		}
+		if problem := compareValues(v, w); problem != "" {
+			return "body: " + problem
+		}
+	default:
+		if !bytes.Equal(p, actual) {
+			return fmt.Sprintf("body: expected %q, got %q", p, actual)
+		}
+	}
+	return ""
+}
+
+func isJSON(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXML(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func isCBOR(mediaType string) bool {
+	return baseMediaType(mediaType) == "application/cbor"
+}
+
+// baseMediaType returns a media type without its parameters.
+func baseMediaType(mediaType string) string {
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// jsonEqual reports whether two JSON documents are equal, ignoring the
+// order of object members and the formatting of numbers.
+func jsonEqual(expected, actual []byte) (bool, error) {
+	v, err := decodeJSON(expected)
+	if err != nil {
+		return false, fmt.Errorf("invalid expected JSON: %w", err)
+	}
+	w, err := decodeJSON(actual)
+	if err != nil {
+		return false, fmt.Errorf("invalid JSON %q: %w", actual, err)
+	}
+	return valuetree.Equal(v, w), nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value")
+	}
+	return v, nil
+}
+
+// An xmlNode is an XML element, normalized for comparison.
+type xmlNode struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Children []*xmlNode
+	// Text is the character data of an element with no child elements.
+	Text string
+}
+
+// xmlEqual reports whether two XML documents are equal, ignoring the
+// order of attributes and the whitespace between elements.
+func xmlEqual(expected, actual []byte) (bool, error) {
+	v, err := parseXML(expected)
+	if err != nil {
+		return false, fmt.Errorf("invalid expected XML: %w", err)
+	}
+	w, err := parseXML(actual)
+	if err != nil {
+		return false, fmt.Errorf("invalid XML %q: %w", actual, err)
+	}
+	return reflect.DeepEqual(v, w), nil
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{Name: t.Name, Attrs: append([]xml.Attr(nil), t.Attr...)}
+			sort.Slice(n.Attrs, func(i, j int) bool {
+				a, b := n.Attrs[i].Name, n.Attrs[j].Name
+				return a.Space < b.Space || a.Space == b.Space && a.Local < b.Local
+			})
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			} else if root == nil {
+				root = n
+			} else {
+				return nil, fmt.Errorf("more than one root element")
+			}
+			stack = append(stack, n)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			if len(n.Children) == 0 {
+				n.Text = text.String()
+			}
+			stack = stack[:len(stack)-1]
+			text.Reset()
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+// compareValues compares two value trees, and describes the first
+// difference, if any, with its JSON Pointer path.
+func compareValues(expected, actual interface{}) string {
+	return difference("", expected, actual)
+}
+
+func difference(path string, a, b interface{}) string {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(x)+len(y))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		for key := range y {
+			if _, ok := x[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v, w := x[key], y[key]
+			switch {
+			case v == nil && w == nil:
+			case v == nil:
+				return valuetree.ErrorAt(path+"/"+valuetree.Escape(key), "unexpected value %s", show(w)).Error()
+			case w == nil:
+				return valuetree.ErrorAt(path+"/"+valuetree.Escape(key), "expected %s, but it is missing", show(v)).Error()
+			default:
+				if problem := difference(path+"/"+valuetree.Escape(key), v, w); problem != "" {
+					return problem
+				}
+			}
+		}
+		return ""
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if len(x) != len(y) {
+			return valuetree.ErrorAt(path, "expected %d items, got %d", len(x), len(y)).Error()
+		}
+		for i := range x {
+			if problem := difference(fmt.Sprintf("%s/%d", path, i), x[i], y[i]); problem != "" {
+				return problem
+			}
+		}
+		return ""
+	}
+	if !valuetree.Equal(a, b) {
+		return valuetree.ErrorAt(path, "expected %s, got %s", show(a), show(b)).Error()
+	}
+	return ""
+}
+
+// show formats a value of a value tree for a message.
+func show(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case []byte:
+		return fmt.Sprintf("blob %q", x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case *big.Int:
+		return x.String()
+	case *big.Float:
+		return x.Text('g', -1)
+	case json.Number:
+		return string(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Sprint(x)
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	p, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(p)
+}
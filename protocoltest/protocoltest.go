@@ -0,0 +1,536 @@
+// Package protocoltest runs the protocol compliance tests a model holds
+// in smithy.test#httpRequestTests, smithy.test#httpResponseTests and
+// smithy.test#smokeTests traits against client and server
+// implementations of a protocol.
+//
+// RunClientTests checks a Client serializes requests and deserializes
+// responses as the test cases expect, RunServerTests does the same for
+// a Server, and RunSmokeTests calls the operations of a live service
+// through a Caller. Each test case runs as a subtest named by its ID,
+// which fails with a description of every way the implementation's
+// messages or values differ from the expected ones.
+//
+// The params of test cases are converted from Smithy node values, in
+// which blobs are strings and timestamps are epoch seconds or date-time
+// strings, to the generic value trees documented by jsoncodec.Codec. The
+// Client, Server and Caller interfaces are those of package restbind, so
+// that a *restbind.Binder is a Client, and error responses are expected
+// to be returned as a *restbind.Error.
+package protocoltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/cborcodec"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+	"github.com/gogama/smithy-ast/restbind"
+)
+
+// A Client is the client side of a protocol implementation.
+type Client interface {
+	// NewRequest serializes the input of an operation into a request
+	// to the endpoint, a base URL such as "https://example.com".
+	NewRequest(ctx context.Context, endpoint string, operation ast.AbsShapeID, input interface{}) (*http.Request, error)
+
+	// ParseResponse deserializes a response to a request for an
+	// operation into its output value, or into a *restbind.Error
+	// holding the value of an error shape.
+	ParseResponse(operation ast.AbsShapeID, resp *http.Response) (interface{}, error)
+}
+
+// A Server is the server side of a protocol implementation.
+type Server interface {
+	// ParseRequest deserializes a request into the ID of the operation
+	// it is for and the operation's input value.
+	ParseRequest(req *http.Request) (ast.AbsShapeID, interface{}, error)
+
+	// WriteResponse serializes the output value of an operation as the
+	// response to a request for it.
+	WriteResponse(w http.ResponseWriter, operation ast.AbsShapeID, output interface{}) error
+
+	// WriteError serializes the value of an error shape as the response
+	// to a request for an operation.
+	WriteError(w http.ResponseWriter, operation, errorID ast.AbsShapeID, value interface{}) error
+}
+
+// A Caller calls the operations of a live service.
+type Caller interface {
+	// Call calls an operation with an input value, and returns its
+	// output value, or an error, which wraps a *restbind.Error if the
+	// service replied with an error response.
+	Call(ctx context.Context, operation ast.AbsShapeID, input interface{}) (interface{}, error)
+}
+
+// The CallerFunc type is an adapter to allow the use of ordinary
+// functions as a Caller.
+type CallerFunc func(ctx context.Context, operation ast.AbsShapeID, input interface{}) (interface{}, error)
+
+// Call calls f(ctx, operation, input).
+func (f CallerFunc) Call(ctx context.Context, operation ast.AbsShapeID, input interface{}) (interface{}, error) {
+	return f(ctx, operation, input)
+}
+
+// Options controls which test cases run.
+type Options struct {
+	// Protocol is the ID of the protocol whose request and response
+	// test cases run, such as "aws.protocols#restJson1". If it is empty,
+	// the test cases of every protocol run.
+	Protocol ast.AbsShapeID
+
+	// Skip lists the IDs of test cases to skip.
+	Skip []string
+}
+
+// DefaultHost is the host requests are sent to when a request test case
+// names none.
+const DefaultHost = "example.com"
+
+// RunClientTests runs the request and response test cases in m which
+// apply to clients against c. For each request test case, c serializes
+// the params as the input of the operation, and the request must match
+// the expected one. For each response test case, c deserializes the
+// response, and the output, or the error for a test case of an error
+// shape, must equal the params. An error shape's test cases are run for
+// the first operation, by ID, which can return it.
+func RunClientTests(t *testing.T, m ast.Model, c Client, opts Options) {
+	t.Helper()
+	r := newRunner(m, opts)
+	for _, id := range r.ids(ast.HTTPRequestTestsTraitID) {
+		trait := r.shapes[id].Traits[ast.HTTPRequestTestsTraitID].(*ast.HTTPRequestTestsTrait)
+		for _, item := range trait.Items {
+			item := item
+			if !r.runs(item.ID.Value, item.Protocol.Value, item.AppliesTo, "client") {
+				continue
+			}
+			t.Run(item.ID.Value, func(t *testing.T) {
+				r.clientRequest(t, id, &item, c)
+			})
+		}
+	}
+	for _, id := range r.ids(ast.HTTPResponseTestsTraitID) {
+		trait := r.shapes[id].Traits[ast.HTTPResponseTestsTraitID].(*ast.HTTPResponseTestsTrait)
+		for _, item := range trait.Items {
+			item := item
+			if !r.runs(item.ID.Value, item.Protocol.Value, item.AppliesTo, "client") {
+				continue
+			}
+			t.Run(item.ID.Value, func(t *testing.T) {
+				r.clientResponse(t, id, &item, c)
+			})
+		}
+	}
+}
+
+// RunServerTests runs the request and response test cases in m which
+// apply to servers against s. For each request test case, s
+// deserializes the request, which must be routed to the operation, and
+// the input must equal the params. For each response test case, s
+// serializes the params as the output of the operation, or as the error
+// for a test case of an error shape, and the response must match the
+// expected one.
+func RunServerTests(t *testing.T, m ast.Model, s Server, opts Options) {
+	t.Helper()
+	r := newRunner(m, opts)
+	for _, id := range r.ids(ast.HTTPRequestTestsTraitID) {
+		trait := r.shapes[id].Traits[ast.HTTPRequestTestsTraitID].(*ast.HTTPRequestTestsTrait)
+		for _, item := range trait.Items {
+			item := item
+			if !r.runs(item.ID.Value, item.Protocol.Value, item.AppliesTo, "server") {
+				continue
+			}
+			t.Run(item.ID.Value, func(t *testing.T) {
+				r.serverRequest(t, id, &item, s)
+			})
+		}
+	}
+	for _, id := range r.ids(ast.HTTPResponseTestsTraitID) {
+		trait := r.shapes[id].Traits[ast.HTTPResponseTestsTraitID].(*ast.HTTPResponseTestsTrait)
+		for _, item := range trait.Items {
+			item := item
+			if !r.runs(item.ID.Value, item.Protocol.Value, item.AppliesTo, "server") {
+				continue
+			}
+			t.Run(item.ID.Value, func(t *testing.T) {
+				r.serverResponse(t, id, &item, s)
+			})
+		}
+	}
+}
+
+// RunSmokeTests runs the smoke test cases in m against c. Each calls an
+// operation with the params as its input, and must succeed, or fail, as
+// the test case expects. A failing call must fail with the error shape
+// the test case names, if any.
+func RunSmokeTests(t *testing.T, m ast.Model, c Caller, opts Options) {
+	t.Helper()
+	r := newRunner(m, opts)
+	for _, id := range r.ids(ast.SmokeTestsTraitID) {
+		trait := r.shapes[id].Traits[ast.SmokeTestsTraitID].(*ast.SmokeTestsTrait)
+		for _, item := range trait.Items {
+			item := item
+			if !r.runs(item.ID.Value, "", nil, "") {
+				continue
+			}
+			t.Run(item.ID.Value, func(t *testing.T) {
+				r.smoke(t, id, &item, c)
+			})
+		}
+	}
+}
+
+type runner struct {
+	opts   Options
+	shapes map[ast.AbsShapeID]ast.Shape
+	cbor   *cborcodec.Codec
+}
+
+func newRunner(m ast.Model, opts Options) *runner {
+	shapes := prelude.Shapes(m)
+	return &runner{opts: opts, shapes: shapes, cbor: cborcodec.New(m)}
+}
+
+// ids returns the IDs of the shapes with a trait, in order.
+func (r *runner) ids(trait ast.AbsShapeID) []ast.AbsShapeID {
+	var ids []ast.AbsShapeID
+	for id, s := range r.shapes {
+		if _, ok := s.Traits[trait]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// runs reports whether a test case runs: it is not skipped and, unless
+// side is empty, it is a test case of the protocol which applies to the
+// side, client or server.
+func (r *runner) runs(id string, protocol ast.AbsShapeID, appliesTo *ast.StringNode, side string) bool {
+	for _, skip := range r.opts.Skip {
+		if skip == id {
+			return false
+		}
+	}
+	if side == "" {
+		return true
+	}
+	if r.opts.Protocol != "" && protocol != r.opts.Protocol {
+		return false
+	}
+	return appliesTo == nil || appliesTo.Value == side
+}
+
+// operation returns the operation a test case of the shape with the
+// given ID runs for, and the error shape, if the shape is one.
+func (r *runner) operation(t *testing.T, id ast.AbsShapeID) (ast.AbsShapeID, ast.Operation, ast.AbsShapeID) {
+	t.Helper()
+	s := r.shapes[id]
+	if s.Type == ast.OperationType {
+		if s.Operation == nil {
+			return id, ast.Operation{}, ""
+		}
+		return id, *s.Operation, ""
+	}
+	if op := r.errorOperation(id); op != "" {
+		return op, *r.shapes[op].Operation, id
+	}
+	t.Fatalf("no operation can return error %s", id)
+	return "", ast.Operation{}, ""
+}
+
+// errorOperation returns the ID of the first operation which can return
+// an error shape, or the empty string if there is none.
+func (r *runner) errorOperation(errorID ast.AbsShapeID) ast.AbsShapeID {
+	var ids []ast.AbsShapeID
+	for id, s := range r.shapes {
+		if s.Type == ast.OperationType && s.Operation != nil {
+			for _, ref := range s.Operation.Errors {
+				if ref.Value == errorID {
+					ids = append(ids, id)
+				}
+			}
+		}
+		if s.Type == ast.ServiceType && s.Service != nil {
+			for _, ref := range s.Service.Errors {
+				if ref.Value == errorID {
+					ids = append(ids, r.serviceOperations(s.Service)...)
+				}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids[0]
+}
+
+// serviceOperations returns the IDs of the operations a service binds,
+// directly or through its resources.
+func (r *runner) serviceOperations(service *ast.Service) []ast.AbsShapeID {
+	var ids []ast.AbsShapeID
+	seen := make(map[ast.AbsShapeID]bool)
+	var visit func(refs []ast.AbsShapeIDNode)
+	visit = func(refs []ast.AbsShapeIDNode) {
+		for _, ref := range refs {
+			if seen[ref.Value] {
+				continue
+			}
+			seen[ref.Value] = true
+			s := r.shapes[ref.Value]
+			switch {
+			case s.Type == ast.OperationType && s.Operation != nil:
+				ids = append(ids, ref.Value)
+			case s.Type == ast.ResourceType && s.Resource != nil:
+				res := s.Resource
+				for _, op := range []*ast.AbsShapeIDNode{res.Create, res.Put, res.Read, res.Update, res.Delete, res.List} {
+					if op != nil {
+						visit([]ast.AbsShapeIDNode{*op})
+					}
+				}
+				visit(res.Operations)
+				visit(res.CollectionOperations)
+				visit(res.Resources)
+			}
+		}
+	}
+	visit(service.Operations)
+	visit(service.Resources)
+	return ids
+}
+
+// params converts the params of a test case to a value of the structure
+// ref refers to.
+func (r *runner) params(t *testing.T, ref *ast.AbsShapeIDNode, params map[string]ast.InterfaceNode) map[string]interface{} {
+	t.Helper()
+	if ref == nil {
+		if len(params) > 0 {
+			t.Fatalf("test case has params, but the operation has no input or output")
+		}
+		return map[string]interface{}{}
+	}
+	return r.structure(t, ref.Value, params)
+}
+
+func (r *runner) structure(t *testing.T, id ast.AbsShapeID, params map[string]ast.InterfaceNode) map[string]interface{} {
+	t.Helper()
+	obj := make(map[string]interface{}, len(params))
+	for key, node := range params {
+		obj[key] = node.Value
+	}
+	v, err := valuetree.FromNode(r.shapes, "", id, obj)
+	if err != nil {
+		t.Fatalf("invalid params: %s", err)
+	}
+	return v.(map[string]interface{})
+}
+
+func (r *runner) clientRequest(t *testing.T, id ast.AbsShapeID, item *ast.HTTPRequestTestsTraitItem, c Client) {
+	op := r.shapes[id].Operation
+	if op == nil {
+		op = &ast.Operation{}
+	}
+	input := r.params(t, op.Input, item.Params)
+	host := DefaultHost
+	if item.Host != nil {
+		host = item.Host.Value
+	}
+	req, err := c.NewRequest(context.Background(), "https://"+host, id, input)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %s", err)
+	}
+	var body []byte
+	if req.Body != nil {
+		if body, err = io.ReadAll(req.Body); err != nil {
+			t.Fatalf("reading request body failed: %s", err)
+		}
+		req.Body.Close()
+	}
+
+	if !strings.EqualFold(req.Method, item.Method.Value) {
+		t.Errorf("method: expected %s, got %s", item.Method.Value, req.Method)
+	}
+	if path := req.URL.EscapedPath(); path != item.URI.Value {
+		t.Errorf("URI: expected %s, got %s", item.URI.Value, path)
+	}
+	if item.ResolvedHost != nil && req.URL.Host != item.ResolvedHost.Value {
+		t.Errorf("host: expected %s, got %s", item.ResolvedHost.Value, req.URL.Host)
+	}
+	for _, problem := range compareQuery(req.URL.RawQuery, item.QueryParams, item.ForbidQueryParams, item.RequireQueryParams) {
+		t.Error(problem)
+	}
+	for _, problem := range compareHeaders(req.Header, item.Headers, item.ForbidHeaders, item.RequireHeaders) {
+		t.Error(problem)
+	}
+	if problem := r.compareBody(item.Body, item.BodyMediaType, body, op.Input); problem != "" {
+		t.Error(problem)
+	}
+}
+
+func (r *runner) serverRequest(t *testing.T, id ast.AbsShapeID, item *ast.HTTPRequestTestsTraitItem, s Server) {
+	op := r.shapes[id].Operation
+	if op == nil {
+		op = &ast.Operation{}
+	}
+	expected := r.params(t, op.Input, item.Params)
+	host := DefaultHost
+	if item.Host != nil {
+		host = item.Host.Value
+	}
+	target := "https://" + host + item.URI.Value
+	if len(item.QueryParams) > 0 {
+		params := make([]string, len(item.QueryParams))
+		for i, param := range item.QueryParams {
+			params[i] = param.Value
+		}
+		target += "?" + strings.Join(params, "&")
+	}
+	body, err := r.body(item.Body, item.BodyMediaType)
+	if err != nil {
+		t.Fatalf("invalid body: %s", err)
+	}
+	req := httptest.NewRequest(item.Method.Value, target, bytes.NewReader(body))
+	for key, value := range item.Headers {
+		req.Header.Set(key, value.Value)
+	}
+
+	operation, input, err := s.ParseRequest(req)
+	if err != nil {
+		t.Fatalf("ParseRequest failed: %s", err)
+	}
+	if operation != id {
+		t.Fatalf("operation: expected %s, got %s", id, operation)
+	}
+	if problem := compareValues(expected, readStreams(t, input)); problem != "" {
+		t.Errorf("input: %s", problem)
+	}
+}
+
+func (r *runner) clientResponse(t *testing.T, id ast.AbsShapeID, item *ast.HTTPResponseTestsTraitItem, c Client) {
+	operation, op, errorID := r.operation(t, id)
+	body, err := r.body(item.Body, item.BodyMediaType)
+	if err != nil {
+		t.Fatalf("invalid body: %s", err)
+	}
+	header := make(http.Header)
+	for key, value := range item.Headers {
+		header.Set(key, value.Value)
+	}
+	resp := &http.Response{
+		StatusCode: int(item.Code.Value),
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+
+	output, err := c.ParseResponse(operation, resp)
+	if errorID == "" {
+		if err != nil {
+			t.Fatalf("ParseResponse failed: %s", err)
+		}
+		if problem := compareValues(r.params(t, op.Output, item.Params), readStreams(t, output)); problem != "" {
+			t.Errorf("output: %s", problem)
+		}
+		return
+	}
+	var e *restbind.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("ParseResponse: expected error %s, got %v", errorID, err)
+	}
+	if e.ShapeID != errorID {
+		t.Fatalf("error: expected %s, got %s", errorID, e.ShapeID)
+	}
+	if problem := compareValues(r.structure(t, errorID, item.Params), e.Value); problem != "" {
+		t.Errorf("error: %s", problem)
+	}
+}
+
+func (r *runner) serverResponse(t *testing.T, id ast.AbsShapeID, item *ast.HTTPResponseTestsTraitItem, s Server) {
+	operation, op, errorID := r.operation(t, id)
+	w := httptest.NewRecorder()
+	var err error
+	var shape *ast.AbsShapeIDNode
+	if errorID == "" {
+		shape = op.Output
+		err = s.WriteResponse(w, operation, r.params(t, op.Output, item.Params))
+	} else {
+		shape = &ast.AbsShapeIDNode{Value: errorID}
+		err = s.WriteError(w, operation, errorID, r.structure(t, errorID, item.Params))
+	}
+	if err != nil {
+		t.Fatalf("writing response failed: %s", err)
+	}
+
+	if w.Code != int(item.Code.Value) {
+		t.Errorf("status code: expected %d, got %d", item.Code.Value, w.Code)
+	}
+	for _, problem := range compareHeaders(w.Header(), item.Headers, item.ForbidHeaders, item.RequireHeaders) {
+		t.Error(problem)
+	}
+	if problem := r.compareBody(item.Body, item.BodyMediaType, w.Body.Bytes(), shape); problem != "" {
+		t.Error(problem)
+	}
+}
+
+func (r *runner) smoke(t *testing.T, id ast.AbsShapeID, item *ast.SmokeTestsTraitItem, c Caller) {
+	op := r.shapes[id].Operation
+	if op == nil {
+		op = &ast.Operation{}
+	}
+	output, err := c.Call(context.Background(), id, r.params(t, op.Input, item.Params))
+	readStreams(t, output)
+	failure := item.Expect.Failure
+	switch {
+	case failure == nil && err != nil:
+		t.Errorf("expected success, got error: %s", err)
+	case failure != nil && err == nil:
+		t.Errorf("expected failure, got success")
+	case failure != nil && failure.ErrorID != nil:
+		var e *restbind.Error
+		if !errors.As(err, &e) || e.ShapeID != failure.ErrorID.Value {
+			t.Errorf("expected error %s, got %s", failure.ErrorID.Value, err)
+		}
+	}
+}
+
+// body returns the bytes of the body of a test case. Binary bodies, in
+// CBOR, are base64 encoded.
+func (r *runner) body(body, mediaType *ast.StringNode) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	if mediaType != nil && isCBOR(mediaType.Value) {
+		return base64.StdEncoding.DecodeString(body.Value)
+	}
+	return []byte(body.Value), nil
+}
+
+// readStreams replaces the streaming blobs of a value, which are
+// io.ReadCloser values, with their contents.
+func readStreams(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for name, value := range obj {
+		if rc, ok := value.(io.ReadCloser); ok {
+			p, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("reading member %s failed: %s", name, err)
+			}
+			obj[name] = p
+		}
+	}
+	return obj
+}
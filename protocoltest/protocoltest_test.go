@@ -0,0 +1,332 @@
+package protocoltest
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+	"github.com/gogama/smithy-ast/restbind"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"operations": ["foo#PutThing", "foo#GetBlob"]
+		},
+		"foo#PutThing": {
+			"type": "operation",
+			"input": "foo#PutThingInput",
+			"output": "foo#PutThingOutput",
+			"errors": ["foo#NotFound"],
+			"traits": {
+				"smithy.api#http": {"method": "PUT", "uri": "/things/{id}?mode=raw", "code": 201},
+				"smithy.api#examples": [
+					{"title": "Found", "output": {"name": "Bob"}},
+					{"title": "Missing", "input": {"id": "missing"}, "error": {"shapeId": "foo#NotFound", "content": {"message": "gone"}}}
+				],
+				"smithy.test#httpRequestTests": [
+					{
+						"id": "PutThing",
+						"protocol": "foo#restJson",
+						"method": "PUT",
+						"uri": "/things/a%20b",
+						"queryParams": ["mode=raw", "tag=x", "tag=y"],
+						"forbidQueryParams": ["name"],
+						"requireQueryParams": ["tag"],
+						"headers": {"X-When": "Sun, 06 Nov 1994 08:49:37 GMT", "Content-Type": "application/json"},
+						"forbidHeaders": ["X-Other"],
+						"body": "{\"count\": 3, \"name\": \"Bob\"}",
+						"bodyMediaType": "application/json",
+						"params": {"id": "a b", "tags": ["x", "y"], "when": 784111777, "name": "Bob", "count": 3}
+					},
+					{
+						"id": "PutThingServerOnly",
+						"protocol": "foo#restJson",
+						"method": "PUT",
+						"uri": "/things/1",
+						"queryParams": ["mode=raw"],
+						"body": "{}",
+						"bodyMediaType": "application/json",
+						"params": {"id": "1"},
+						"appliesTo": "server"
+					},
+					{
+						"id": "PutThingOtherProtocol",
+						"protocol": "foo#other",
+						"method": "POST",
+						"uri": "/",
+						"params": {"id": "1"}
+					},
+					{
+						"id": "PutThingSkipped",
+						"protocol": "foo#restJson",
+						"method": "POST",
+						"uri": "/",
+						"params": {"id": "1"}
+					}
+				],
+				"smithy.test#httpResponseTests": [
+					{
+						"id": "PutThingResponse",
+						"protocol": "foo#restJson",
+						"code": 201,
+						"headers": {"ETag": "abc"},
+						"requireHeaders": ["Content-Type"],
+						"body": "{\"name\": \"Bob\"}",
+						"bodyMediaType": "application/json",
+						"params": {"etag": "abc", "name": "Bob"}
+					}
+				],
+				"smithy.test#smokeTests": [
+					{"id": "PutThingFound", "params": {"id": "1"}, "expect": {"success": {}}},
+					{"id": "PutThingMissing", "params": {"id": "missing"}, "expect": {"failure": {"errorId": "foo#NotFound"}}}
+				]
+			}
+		},
+		"foo#PutThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"tags": {"target": "foo#Strings", "traits": {"smithy.api#httpQuery": "tag"}},
+				"when": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#httpHeader": "X-When"}},
+				"name": {"target": "smithy.api#String"},
+				"count": {"target": "smithy.api#Integer"}
+			}
+		},
+		"foo#PutThingOutput": {
+			"type": "structure",
+			"members": {
+				"etag": {"target": "smithy.api#String", "traits": {"smithy.api#httpHeader": "ETag"}},
+				"name": {"target": "smithy.api#String"}
+			}
+		},
+		"foo#GetBlob": {
+			"type": "operation",
+			"input": "foo#GetBlobInput",
+			"output": "foo#GetBlobOutput",
+			"traits": {
+				"smithy.api#http": {"method": "GET", "uri": "/blobs/{id}"},
+				"smithy.api#readonly": {},
+				"smithy.test#httpRequestTests": [
+					{"id": "GetBlob", "protocol": "foo#restJson", "method": "GET", "uri": "/blobs/x%2Fy", "body": "", "params": {"id": "x/y"}}
+				],
+				"smithy.test#httpResponseTests": [
+					{
+						"id": "GetBlobResponse",
+						"protocol": "foo#restJson",
+						"code": 200,
+						"headers": {"Content-Type": "application/octet-stream"},
+						"body": "hello",
+						"bodyMediaType": "application/octet-stream",
+						"params": {"data": "hello"}
+					}
+				]
+			}
+		},
+		"foo#GetBlobInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}}
+			}
+		},
+		"foo#GetBlobOutput": {
+			"type": "structure",
+			"members": {
+				"data": {"target": "foo#Stream", "traits": {"smithy.api#httpPayload": {}}}
+			}
+		},
+		"foo#Stream": {
+			"type": "blob",
+			"traits": {"smithy.api#streaming": {}}
+		},
+		"foo#Strings": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#NotFound": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {
+				"smithy.api#error": "client",
+				"smithy.api#httpError": 404,
+				"smithy.test#httpResponseTests": [
+					{
+						"id": "NotFound",
+						"protocol": "foo#restJson",
+						"code": 404,
+						"headers": {"X-Amzn-Errortype": "NotFound"},
+						"body": "{\"message\": \"gone\"}",
+						"bodyMediaType": "application/json",
+						"params": {"message": "gone"}
+					}
+				]
+			}
+		}
+	}
+}`
+
+var testOptions = Options{Protocol: "foo#restJson", Skip: []string{"PutThingSkipped"}}
+
+func readTestModel(t *testing.T) ast.Model {
+	m := testmodel.Read(t, testModel)
+	return m
+}
+
+type server struct {
+	router *restbind.Router
+	*restbind.Binder
+}
+
+func (s server) ParseRequest(req *http.Request) (ast.AbsShapeID, interface{}, error) {
+	return s.router.ParseRequest(req)
+}
+
+func TestRunClientTests(t *testing.T) {
+	m := readTestModel(t)
+	b, err := restbind.New(m, "foo#Service")
+	require.NoError(t, err)
+
+	RunClientTests(t, m, b, testOptions)
+}
+
+func TestRunServerTests(t *testing.T) {
+	m := readTestModel(t)
+	b, err := restbind.New(m, "foo#Service")
+	require.NoError(t, err)
+	r, err := restbind.NewRouter(b)
+	require.NoError(t, err)
+
+	RunServerTests(t, m, server{router: r, Binder: b}, testOptions)
+}
+
+func TestRunSmokeTests(t *testing.T) {
+	m := readTestModel(t)
+	h, err := restbind.NewMock(m, "foo#Service")
+	require.NoError(t, err)
+	s := httptest.NewServer(h)
+	defer s.Close()
+	b, err := restbind.New(m, "foo#Service")
+	require.NoError(t, err)
+
+	RunSmokeTests(t, m, CallerFunc(func(ctx context.Context, operation ast.AbsShapeID, input interface{}) (interface{}, error) {
+		return b.Do(ctx, s.Client(), s.URL, operation, input)
+	}), testOptions)
+}
+
+func strs(values ...string) []ast.StringNode {
+	nodes := make([]ast.StringNode, len(values))
+	for i, value := range values {
+		nodes[i] = ast.StringNode{Value: value}
+	}
+	return nodes
+}
+
+func TestCompareQuery(t *testing.T) {
+	problems := compareQuery("a=1&b=x%20y&a=2&c", strs("a=2", "b=x+y", "a=1", "a=1", "d=4"), strs("c", "e"), strs("b", "f"))
+
+	assert.Equal(t, []string{
+		"query: missing parameter a=1",
+		"query: missing parameter d=4",
+		"query: forbidden parameter c is present",
+		"query: required parameter f is missing",
+	}, problems)
+}
+
+func TestCompareHeaders(t *testing.T) {
+	header := http.Header{"X-A": {"1", "2"}, "X-B": {"b"}, "X-C": {"c"}}
+
+	problems := compareHeaders(header, map[string]ast.StringNode{
+		"x-a": {Value: "1, 2"},
+		"X-B": {Value: "B"},
+		"X-D": {Value: "d"},
+	}, strs("X-C"), strs("X-A", "X-E"))
+
+	assert.Equal(t, []string{
+		`header X-B: expected "B", got "b"`,
+		`header X-D: expected "d", but it is missing`,
+		`header X-C: forbidden, got "c"`,
+		`header X-E: required, but it is missing`,
+	}, problems)
+}
+
+func TestCompareBody(t *testing.T) {
+	m := testmodel.Read(t, `{"version": "1.0", "shapes": {
+		"foo#S": {"type": "structure", "members": {"a": {"target": "smithy.api#Integer"}}}
+	}}`)
+	r := newRunner(m, Options{})
+	cbor := func(s string) []byte {
+		p, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return p
+	}
+
+	testCases := []struct {
+		name      string
+		expected  *ast.StringNode
+		mediaType string
+		actual    []byte
+		problem   string
+	}{
+		{name: "no expectation", actual: []byte("x")},
+		{name: "empty", expected: &ast.StringNode{}, mediaType: "application/json"},
+		{name: "bytes", expected: &ast.StringNode{Value: "abc"}, actual: []byte("abc")},
+		{name: "bytes differ", expected: &ast.StringNode{Value: "abc"}, mediaType: "text/plain", actual: []byte("abd"), problem: `body: expected "abc", got "abd"`},
+		{name: "json", expected: &ast.StringNode{Value: `{"a": [1.0, "x"], "b": null}`}, mediaType: "application/json", actual: []byte(`{"b":null,"a":[1,"x"]}`)},
+		{name: "json differs", expected: &ast.StringNode{Value: `{"a": 1}`}, mediaType: "application/json", actual: []byte(`{"a":2}`), problem: `body: expected JSON {"a": 1}, got {"a":2}`},
+		{name: "json invalid", expected: &ast.StringNode{Value: `{}`}, mediaType: "application/vnd.x+json", actual: []byte(`{`), problem: `body: invalid JSON "{": unexpected EOF`},
+		{
+			name:      "xml",
+			expected:  &ast.StringNode{Value: "<A xmlns=\"urn:x\" b=\"1\" a=\"2\">\n  <B> x </B>\n  <C/>\n</A>"},
+			mediaType: "application/xml",
+			actual:    []byte(`<A a="2" b="1" xmlns="urn:x"><B> x </B><C></C></A>`),
+		},
+		{name: "xml differs", expected: &ast.StringNode{Value: "<A><B>x</B></A>"}, mediaType: "text/xml", actual: []byte(`<A><B>y</B></A>`), problem: "body: expected XML <A><B>x</B></A>, got <A><B>y</B></A>"},
+		{name: "cbor", expected: &ast.StringNode{Value: "oWFhAQ=="}, mediaType: "application/cbor", actual: cbor("a161611801")},
+		{name: "cbor differs", expected: &ast.StringNode{Value: "oWFhAQ=="}, mediaType: "application/cbor", actual: cbor("a1616102"), problem: "body: /a: expected 1, got 2"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var mediaType *ast.StringNode
+			if testCase.mediaType != "" {
+				mediaType = &ast.StringNode{Value: testCase.mediaType}
+			}
+
+			problem := r.compareBody(testCase.expected, mediaType, testCase.actual, &ast.AbsShapeIDNode{Value: "foo#S"})
+
+			assert.Equal(t, testCase.problem, problem)
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected interface{}
+		actual   interface{}
+		problem  string
+	}{
+		{name: "equal", expected: map[string]interface{}{"a": []interface{}{int64(1)}, "b": nil}, actual: map[string]interface{}{"a": []interface{}{int64(1)}}},
+		{name: "missing", expected: map[string]interface{}{"a": "x"}, actual: map[string]interface{}{}, problem: `/a: expected "x", but it is missing`},
+		{name: "unexpected", expected: map[string]interface{}{}, actual: map[string]interface{}{"a~b": true}, problem: `/a~0b: unexpected value true`},
+		{name: "items", expected: []interface{}{"x"}, actual: []interface{}{}, problem: "expected 1 items, got 0"},
+		{name: "nested", expected: map[string]interface{}{"a": []interface{}{[]byte("x")}}, actual: map[string]interface{}{"a": []interface{}{[]byte("y")}}, problem: `/a/0: expected blob "x", got blob "y"`},
+		{name: "type", expected: int64(1), actual: "1", problem: `expected 1, got "1"`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.problem, compareValues(testCase.expected, testCase.actual))
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package restbind
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/jsoncodec"
+)
+
+// A location is a part of an HTTP message a simple value is bound to.
+type location string
+
+const (
+	labelLocation  location = "label"
+	queryLocation  location = "query string"
+	headerLocation location = "header"
+)
+
+// timestampFormat is the default timestamp format of a location.
+func (loc location) timestampFormat() string {
+	if loc == headerLocation {
+		return jsoncodec.HTTPDate
+	}
+	return jsoncodec.DateTime
+}
+
+// formatList returns the strings of the value of a member bound to a
+// location. Lists and sets have a string per item, and other values a
+// single string.
+func (b *Binder) formatList(name string, member ast.Member, v interface{}, loc location) ([]string, error) {
+	s := b.shapes[member.Target.Value]
+	if (s.Type != ast.ListType && s.Type != ast.SetType) || s.Value == nil || loc == labelLocation {
+		str, err := b.format(name, member.Target.Value, member.Traits, v, loc)
+		if err != nil {
+			return nil, err
+		}
+		return []string{str}, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, newErrorf("member %s: expected %s, got %T", name, s.Type, v)
+	}
+	strs := make([]string, rv.Len())
+	for i := range strs {
+		var err error
+		if strs[i], err = b.format(name, s.Value.Target.Value, s.Value.Traits, rv.Index(i).Interface(), loc); err != nil {
+			return nil, err
+		}
+	}
+	return strs, nil
+}
+
+// format returns the string of a simple value of the shape with ID
+// target bound to a location. The traits are those of the member bound.
+func (b *Binder) format(name string, target ast.AbsShapeID, traits ast.Traits, v interface{}, loc location) (string, error) {
+	s, ok := b.shapes[target]
+	if !ok {
+		return "", newErrorf("shape %s not found", target)
+	}
+	if !isSimple(s.Type) {
+		return "", newErrorf("member %s targets a %s, which cannot be bound to a %s", name, s.Type, loc)
+	}
+	if str, ok := valuetree.Unwrap(v).(string); ok && s.Type == ast.StringType && loc == headerLocation && hasMediaType(s, traits) {
+		return base64.StdEncoding.EncodeToString([]byte(str)), nil
+	}
+	p, err := b.codec(s, traits, loc).Marshal(target, v)
+	if err != nil {
+		return "", newErrorf("member %s: %w", name, err)
+	}
+	var str string
+	if json.Unmarshal(p, &str) == nil {
+		return str, nil
+	}
+	return string(p), nil
+}
+
+// parse parses the string of a simple value of the shape with ID target
+// bound to a location.
+func (b *Binder) parse(name string, target ast.AbsShapeID, traits ast.Traits, str string, loc location) (interface{}, error) {
+	s, ok := b.shapes[target]
+	if !ok {
+		return nil, newErrorf("shape %s not found", target)
+	}
+	if !isSimple(s.Type) {
+		return nil, newErrorf("member %s targets a %s, which cannot be bound to a %s", name, s.Type, loc)
+	}
+	str = strings.TrimSpace(str)
+	codec := b.codec(s, traits, loc)
+	var p []byte
+	switch s.Type {
+	case ast.StringType:
+		if loc == headerLocation && hasMediaType(s, traits) {
+			decoded, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, newErrorf("member %s: invalid base64 %s value: %w", name, loc, err)
+			}
+			str = string(decoded)
+		}
+		p, _ = json.Marshal(str)
+	case ast.BlobType:
+		p, _ = json.Marshal(str)
+	case ast.TimestampType:
+		p = []byte(str)
+		if codec != b.codecs[jsoncodec.EpochSeconds] {
+			p, _ = json.Marshal(str)
+		}
+	case ast.FloatType, ast.DoubleType:
+		p = []byte(str)
+		if str == "NaN" || str == "Infinity" || str == "-Infinity" {
+			p, _ = json.Marshal(str)
+		}
+	default:
+		p = []byte(str)
+	}
+	v, err := codec.Unmarshal(target, p)
+	if err != nil {
+		return nil, newErrorf("member %s: invalid %s value %q", name, loc, str)
+	}
+	return v, nil
+}
+
+// parseHeader parses the value of a header bound to a member.
+func (b *Binder) parseHeader(name string, member ast.Member, header string) (interface{}, error) {
+	s := b.shapes[member.Target.Value]
+	if (s.Type != ast.ListType && s.Type != ast.SetType) || s.Value == nil {
+		return b.parse(name, member.Target.Value, member.Traits, header, headerLocation)
+	}
+	items := splitHeader(header)
+	item := b.shapes[s.Value.Target.Value]
+	if item.Type == ast.TimestampType && b.codec(item, s.Value.Traits, headerLocation) == b.codecs[jsoncodec.HTTPDate] {
+		// HTTP dates contain a comma, and are not quoted in lists.
+		var dates []string
+		for i := 0; i+1 < len(items); i += 2 {
+			dates = append(dates, items[i]+", "+items[i+1])
+		}
+		items = dates
+	}
+	values := make([]interface{}, len(items))
+	for i, str := range items {
+		var err error
+		if values[i], err = b.parse(name, s.Value.Target.Value, s.Value.Traits, str, headerLocation); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// parseList parses the strings of the value of a member bound to a
+// location. Lists and sets have a string per item, and other values
+// take the first string.
+func (b *Binder) parseList(name string, member ast.Member, strs []string, loc location) (interface{}, error) {
+	s := b.shapes[member.Target.Value]
+	if (s.Type != ast.ListType && s.Type != ast.SetType) || s.Value == nil {
+		return b.parse(name, member.Target.Value, member.Traits, strs[0], loc)
+	}
+	values := make([]interface{}, len(strs))
+	for i, str := range strs {
+		var err error
+		if values[i], err = b.parse(name, s.Value.Target.Value, s.Value.Traits, str, loc); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// parseMap parses the entries of a map value bound to a location.
+func (b *Binder) parseMap(name string, member ast.Member, entries url.Values, loc location) (interface{}, error) {
+	s := b.shapes[member.Target.Value]
+	if s.Type != ast.MapType || s.Value == nil {
+		return nil, newErrorf("member %s targets a %s, not a map", name, s.Type)
+	}
+	values := make(map[string]interface{}, len(entries))
+	for key, strs := range entries {
+		var err error
+		if values[key], err = b.parseList(name, *s.Value, strs, loc); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// stringMap returns the entries of a map value bound to a location as
+// strings, or lists of strings for maps of lists.
+func (b *Binder) stringMap(name string, member ast.Member, v interface{}, loc location) (map[string]interface{}, error) {
+	s := b.shapes[member.Target.Value]
+	if s.Type != ast.MapType || s.Value == nil {
+		return nil, newErrorf("member %s targets a %s, not a map", name, s.Type)
+	}
+	obj, ok := valuetree.Object(v)
+	if !ok {
+		return nil, newErrorf("member %s: expected map, got %T", name, v)
+	}
+	entries := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		strs, err := b.formatList(name, *s.Value, value, loc)
+		if err != nil {
+			return nil, err
+		}
+		if t := b.shapes[s.Value.Target.Value].Type; t == ast.ListType || t == ast.SetType {
+			entries[key] = strs
+		} else {
+			entries[key] = strs[0]
+		}
+	}
+	return entries, nil
+}
+
+// codec returns the codec which formats timestamps bound to a location.
+func (b *Binder) codec(s ast.Shape, traits ast.Traits, loc location) *jsoncodec.Codec {
+	if format := traits.StringTrait(ast.TimestampFormatTraitID); format != "" {
+		if codec, ok := b.codecs[format]; ok {
+			return codec
+		}
+	}
+	if format := s.Traits.StringTrait(ast.TimestampFormatTraitID); format != "" {
+		if codec, ok := b.codecs[format]; ok {
+			return codec
+		}
+	}
+	return b.codecs[loc.timestampFormat()]
+}
+
+// encodePayload returns the body and content type of a payload member.
+func (b *Binder) encodePayload(name string, member ast.Member, v interface{}) (io.Reader, string, error) {
+	s, ok := b.shapes[member.Target.Value]
+	if !ok {
+		return nil, "", newErrorf("shape %s not found", member.Target.Value)
+	}
+	mediaType := member.Traits.StringTrait(ast.MediaTypeTraitID)
+	if mediaType == "" {
+		mediaType = s.Traits.StringTrait(ast.MediaTypeTraitID)
+	}
+	switch s.Type {
+	case ast.BlobType:
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		switch p := v.(type) {
+		case []byte:
+			return bytes.NewReader(p), mediaType, nil
+		case io.Reader:
+			return p, mediaType, nil
+		}
+		return nil, "", newErrorf("member %s: expected blob, got %T", name, v)
+	case ast.StringType:
+		if mediaType == "" {
+			mediaType = "text/plain"
+		}
+		if str, ok := v.(string); ok {
+			return strings.NewReader(str), mediaType, nil
+		}
+		return nil, "", newErrorf("member %s: expected string, got %T", name, v)
+	case ast.StructureType, ast.UnionType, ast.DocumentType:
+		p, err := b.codecs[jsoncodec.EpochSeconds].Marshal(member.Target.Value, v)
+		if err != nil {
+			return nil, "", newErrorf("member %s: %w", name, err)
+		}
+		return bytes.NewReader(p), "application/json", nil
+	}
+	return nil, "", newErrorf("member %s targets a %s, which cannot be a payload", name, s.Type)
+}
+
+// decodePayload reads the value of a payload member from a body. It
+// returns nil if the body is empty.
+func (b *Binder) decodePayload(name string, member ast.Member, body io.Reader) (interface{}, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, newErrorf("%w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	switch t := b.shapes[member.Target.Value].Type; t {
+	case ast.BlobType:
+		return data, nil
+	case ast.StringType:
+		return string(data), nil
+	case ast.StructureType, ast.UnionType, ast.DocumentType:
+		v, err := b.codecs[jsoncodec.EpochSeconds].Unmarshal(member.Target.Value, data)
+		if err != nil {
+			return nil, newErrorf("member %s: %w", name, err)
+		}
+		return v, nil
+	default:
+		return nil, newErrorf("member %s targets a %s, which cannot be a payload", name, t)
+	}
+}
+
+// splitURI splits an http trait URI pattern into its path and the
+// literal parameters of its query string.
+func splitURI(uri string) (string, []string) {
+	i := strings.IndexByte(uri, '?')
+	if i < 0 {
+		return uri, nil
+	}
+	var literals []string
+	for _, literal := range strings.Split(uri[i+1:], "&") {
+		if literal != "" {
+			literals = append(literals, literal)
+		}
+	}
+	return uri[:i], literals
+}
+
+// expand replaces the labels of a URI pattern path with their
+// percent-encoded values. Greedy labels keep the slashes of their values.
+func expand(path string, labels map[string]string) (string, error) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := segment[1 : len(segment)-1]
+		greedy := strings.HasSuffix(name, "+")
+		name = strings.TrimSuffix(name, "+")
+		value := labels[name]
+		if value == "" {
+			return "", newErrorf("label %q has no value", name)
+		}
+		if !greedy {
+			segments[i] = url.PathEscape(value)
+			continue
+		}
+		parts := strings.Split(value, "/")
+		for j := range parts {
+			parts[j] = url.PathEscape(parts[j])
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// joinHeader joins the values of a list bound to a header, quoting
+// values which contain a comma or double quote.
+func joinHeader(values []string, quote bool) string {
+	if quote {
+		for i, v := range values {
+			if strings.ContainsAny(v, `,"`) {
+				values[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+			}
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// splitHeader splits a header into comma-separated values, unquoting
+// quoted values.
+func splitHeader(header string) []string {
+	var values []string
+	var value strings.Builder
+	quoted, escaped := false, false
+	for _, r := range header {
+		switch {
+		case escaped:
+			value.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			values = append(values, strings.TrimSpace(value.String()))
+			value.Reset()
+		default:
+			value.WriteRune(r)
+		}
+	}
+	return append(values, strings.TrimSpace(value.String()))
+}
+
+func isSimple(t ast.ShapeType) bool {
+	switch t {
+	case ast.ListType, ast.SetType, ast.MapType, ast.StructureType, ast.UnionType, ast.DocumentType,
+		ast.ServiceType, ast.ResourceType, ast.OperationType:
+		return false
+	}
+	return true
+}
+
+func hasMediaType(s ast.Shape, traits ast.Traits) bool {
+	return traits.HasTrait(ast.MediaTypeTraitID) || s.Traits.HasTrait(ast.MediaTypeTraitID)
+}
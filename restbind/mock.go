@@ -0,0 +1,181 @@
+package restbind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+)
+
+// NewMock returns an http.Handler which stands in for the service with
+// the given ID in m, replying to requests with the examples in the
+// examples traits of its operations.
+//
+// The handler routes each request to an operation with a Router, parses
+// its input, and replies with the output or error of the operation's
+// example whose input matches the input of the request. An example
+// matches if every member of its input equals the member of the request
+// input; members it leaves unset may have any value. If several examples
+// match, the one setting the most members wins, and then the first.
+//
+// If the request cannot be routed or parsed, or no example matches it,
+// the handler replies with a 4xx status code and a JSON body whose
+// message member describes the problem.
+//
+// NewMock returns an error if the operations of the service cannot be
+// routed, or if an example does not fit the shapes of its operation.
+func NewMock(m ast.Model, service ast.AbsShapeID) (http.Handler, error) {
+	b, err := New(m, service)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRouter(b)
+	if err != nil {
+		return nil, err
+	}
+	h := &mock{binder: b, router: r, examples: make(map[ast.AbsShapeID][]example)}
+	for _, id := range b.Operations() {
+		op, _, err := b.operation(id)
+		if err != nil {
+			return nil, err
+		}
+		trait, ok := op.Traits[ast.ExamplesTraitID].(*ast.ExamplesTrait)
+		if !ok {
+			continue
+		}
+		for _, item := range trait.Items {
+			ex, err := b.example(op, item)
+			if err != nil {
+				return nil, newErrorf("example %q of operation %s: %w", item.Title.Value, id, err)
+			}
+			h.examples[id] = append(h.examples[id], ex)
+		}
+	}
+	return h, nil
+}
+
+type mock struct {
+	binder   *Binder
+	router   *Router
+	examples map[ast.AbsShapeID][]example
+}
+
+// An example is an item of an examples trait with its values converted
+// to value trees.
+type example struct {
+	title   string
+	input   map[string]interface{}
+	output  interface{}
+	errorID ast.AbsShapeID
+	content interface{}
+}
+
+func (h *mock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	operation, input, err := h.router.ParseRequest(req)
+	var routeErr *RouteError
+	if errors.As(err, &routeErr) {
+		if routeErr.StatusCode == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", strings.Join(routeErr.Allow, ", "))
+		}
+		reply(w, routeErr.StatusCode, routeErr.Error())
+		return
+	} else if err != nil {
+		reply(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	values := input.(map[string]interface{})
+	for name, value := range values {
+		if rc, ok := value.(io.ReadCloser); ok {
+			p, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				reply(w, http.StatusBadRequest, fmt.Sprintf("restbind: member %s: %s", name, err))
+				return
+			}
+			values[name] = p
+		}
+	}
+
+	examples := h.examples[operation]
+	if len(examples) == 0 {
+		reply(w, http.StatusNotFound, fmt.Sprintf("restbind: operation %s has no examples", operation))
+		return
+	}
+	var best *example
+	for i := range examples {
+		ex := &examples[i]
+		if matches(ex.input, values) && (best == nil || len(ex.input) > len(best.input)) {
+			best = ex
+		}
+	}
+	if best == nil {
+		reply(w, http.StatusNotFound, fmt.Sprintf("restbind: none of the %d examples of operation %s matches the request", len(examples), operation))
+		return
+	}
+
+	if best.errorID != "" {
+		err = h.binder.WriteError(w, operation, best.errorID, best.content)
+	} else {
+		err = h.binder.WriteResponse(w, operation, best.output)
+	}
+	if err != nil {
+		reply(w, http.StatusInternalServerError, fmt.Sprintf("restbind: example %q of operation %s: %s", best.title, operation, err))
+	}
+}
+
+// reply writes a response with a JSON body holding a message.
+func reply(w http.ResponseWriter, status int, message string) {
+	p, _ := json.Marshal(map[string]string{"message": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(p)
+}
+
+// example converts an item of the examples trait of an operation.
+func (b *Binder) example(op ast.Shape, item ast.ExamplesTraitItem) (example, error) {
+	ex := example{title: item.Title.Value, input: make(map[string]interface{})}
+	if len(item.Input) > 0 {
+		if op.Operation.Input == nil {
+			return ex, errors.New("operation has no input")
+		}
+		v, err := valuetree.FromNode(b.shapes, "", op.Operation.Input.Value, valuetree.Nodes(item.Input))
+		if err != nil {
+			return ex, fmt.Errorf("input: %w", err)
+		}
+		ex.input = v.(map[string]interface{})
+	}
+	if item.Error != nil {
+		ex.errorID = item.Error.ShapeID.Value
+		v, err := valuetree.FromNode(b.shapes, "", ex.errorID, valuetree.Nodes(item.Error.Content))
+		if err != nil {
+			return ex, fmt.Errorf("error: %w", err)
+		}
+		ex.content = v
+	} else if len(item.Output) > 0 {
+		if op.Operation.Output == nil {
+			return ex, errors.New("operation has no output")
+		}
+		v, err := valuetree.FromNode(b.shapes, "", op.Operation.Output.Value, valuetree.Nodes(item.Output))
+		if err != nil {
+			return ex, fmt.Errorf("output: %w", err)
+		}
+		ex.output = v
+	}
+	return ex, nil
+}
+
+// matches reports whether every member of an example input equals the
+// member of a request input.
+func matches(example, input map[string]interface{}) bool {
+	for name, value := range example {
+		if value != nil && !valuetree.Equal(value, input[name]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,833 @@
+// Package restbind binds values of Smithy shapes to HTTP messages
+// following the HTTP binding traits, as the restJson1 protocol does.
+//
+// A Binder turns the input value of an operation into an *http.Request
+// and parses an *http.Response into the operation's output value, or
+// into an *Error holding the value of one of its error shapes. Members
+// are bound to the URI, query string, headers or body by the httpLabel,
+// httpQuery, httpQueryParams, httpHeader, httpPrefixedHeaders,
+// httpPayload and httpResponseCode traits. Members with none of these
+// traits make up a JSON document body, serialized by package jsoncodec.
+//
+// On the server side, a Router routes an *http.Request to one of the
+// operations of the service by the method and URI pattern of its http
+// trait, and parses it into the operation's input value. The Binder
+// writes the operation's output or error value as the response.
+// NewMock builds a handler serving the examples of a model this way.
+//
+// Values are the generic value trees documented by jsoncodec.Codec. In
+// addition, a streaming blob payload may be an io.Reader in an input
+// value, and is an io.ReadCloser, which the caller must close, in an
+// output value.
+package restbind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/httpbinding"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/jsoncodec"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// A Binder binds the inputs, outputs and errors of the operations of a
+// service to HTTP messages.
+type Binder struct {
+	service    ast.AbsShapeID
+	shapes     map[ast.AbsShapeID]ast.Shape
+	operations map[ast.AbsShapeID]bool
+	errors     []ast.AbsShapeID
+	codecs     map[string]*jsoncodec.Codec
+}
+
+// New returns a Binder for the operations of the service with the given
+// ID in m. Shapes not in m are looked up in the prelude.
+func New(m ast.Model, service ast.AbsShapeID) (*Binder, error) {
+	shapes := prelude.Shapes(m)
+	s, ok := shapes[service]
+	if !ok {
+		return nil, newErrorf("service %s not found", service)
+	}
+	if s.Type != ast.ServiceType || s.Service == nil {
+		return nil, newErrorf("shape %s is a %s, not a service", service, s.Type)
+	}
+
+	b := &Binder{
+		service:    service,
+		shapes:     shapes,
+		operations: make(map[ast.AbsShapeID]bool),
+		codecs:     make(map[string]*jsoncodec.Codec),
+	}
+	for _, ref := range s.Service.Errors {
+		b.errors = append(b.errors, ref.Value)
+	}
+	for _, format := range []string{jsoncodec.DateTime, jsoncodec.EpochSeconds, jsoncodec.HTTPDate} {
+		b.codecs[format] = jsoncodec.New(m, jsoncodec.Options{TimestampFormat: format})
+	}
+	if err := b.addOperations(s.Service.Operations, s.Service.Resources); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// addOperations adds the operations bound to a service or resource,
+// directly or through its resources.
+func (b *Binder) addOperations(operations, resources []ast.AbsShapeIDNode) error {
+	for _, ref := range operations {
+		b.operations[ref.Value] = true
+	}
+	for _, ref := range resources {
+		r, ok := b.shapes[ref.Value]
+		if !ok {
+			return newErrorf("shape %s not found", ref.Value)
+		}
+		if r.Type != ast.ResourceType || r.Resource == nil {
+			return newErrorf("shape %s is a %s, not a resource", ref.Value, r.Type)
+		}
+		var ops []ast.AbsShapeIDNode
+		ops = append(ops, r.Resource.Operations...)
+		ops = append(ops, r.Resource.CollectionOperations...)
+		for _, ref := range []*ast.AbsShapeIDNode{r.Resource.Create, r.Resource.Put, r.Resource.Read, r.Resource.Update, r.Resource.Delete, r.Resource.List} {
+			if ref != nil {
+				ops = append(ops, *ref)
+			}
+		}
+		if err := b.addOperations(ops, r.Resource.Resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Operations returns the IDs of the operations of the service, sorted.
+func (b *Binder) Operations() []ast.AbsShapeID {
+	ids := make([]ast.AbsShapeID, 0, len(b.operations))
+	for id := range b.operations {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// An Error is an error response of an operation.
+type Error struct {
+	// ShapeID is the ID of the error shape the response was identified
+	// as, or empty if it could not be identified.
+	ShapeID ast.AbsShapeID
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Code is the error code named by the response, if any, with any
+	// namespace and suffix removed.
+	Code string
+
+	// Value is the value of the error shape, or nil if ShapeID is empty.
+	Value map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	name := string(e.ShapeID)
+	if name == "" {
+		name = "unknown error"
+		if e.Code != "" {
+			name = fmt.Sprintf("unknown error %q", e.Code)
+		}
+	}
+	msg := fmt.Sprintf("restbind: %s (status %d)", name, e.StatusCode)
+	for _, key := range []string{"message", "Message"} {
+		if text, ok := e.Value[key].(string); ok {
+			return msg + ": " + text
+		}
+	}
+	return msg
+}
+
+// Do sends a request for the operation with the given ID and input to
+// the endpoint using client, or http.DefaultClient if client is nil,
+// and returns the output of the operation. If the response is an error
+// response, Do returns an *Error.
+func (b *Binder) Do(ctx context.Context, client *http.Client, endpoint string, operation ast.AbsShapeID, input interface{}) (interface{}, error) {
+	req, err := b.NewRequest(ctx, endpoint, operation, input)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return b.ParseResponse(operation, resp)
+}
+
+// NewRequest returns a request for the operation with the given ID and
+// input, sent to the endpoint, which is a base URL such as
+// "https://example.com" or "https://example.com/prefix". A nil input is
+// an input with no members set.
+//
+// NewRequest returns an error if the input does not fit the input shape
+// of the operation, or if a label has no value.
+func (b *Binder) NewRequest(ctx context.Context, endpoint string, operation ast.AbsShapeID, input interface{}) (*http.Request, error) {
+	op, trait, err := b.operation(operation)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := b.members("input", op.Operation.Input, input)
+	if err != nil {
+		return nil, err
+	}
+
+	path, literals := splitURI(trait.URI.Value)
+	labels := make(map[string]string)
+	query := make(url.Values)
+	header := make(http.Header)
+	var queryParams, body map[string]interface{}
+	var payload interface{}
+	var payloadName string
+	if op.Operation.Input != nil {
+		s := b.shapes[op.Operation.Input.Value]
+		for _, name := range sortedNames(obj) {
+			member := s.Members[name]
+			value := obj[name]
+			switch {
+			case member.Traits.HasTrait(ast.HTTPLabelTraitID):
+				if labels[name], err = b.format(name, member.Target.Value, member.Traits, value, labelLocation); err != nil {
+					return nil, err
+				}
+			case member.Traits.HasTrait(ast.HTTPQueryTraitID):
+				key := member.Traits.StringTrait(ast.HTTPQueryTraitID)
+				values, err := b.formatList(name, member, value, queryLocation)
+				if err != nil {
+					return nil, err
+				}
+				query[key] = append(query[key], values...)
+			case member.Traits.HasTrait(ast.HTTPQueryParamsTraitID):
+				if queryParams, err = b.stringMap(name, member, value, queryLocation); err != nil {
+					return nil, err
+				}
+			case member.Traits.HasTrait(ast.HTTPHeaderTraitID):
+				values, err := b.formatList(name, member, value, headerLocation)
+				if err != nil {
+					return nil, err
+				}
+				header.Set(member.Traits.StringTrait(ast.HTTPHeaderTraitID), joinHeader(values, b.isStringList(member)))
+			case member.Traits.HasTrait(ast.HTTPPrefixedHeadersTraitID):
+				prefixed, err := b.stringMap(name, member, value, headerLocation)
+				if err != nil {
+					return nil, err
+				}
+				for key, value := range prefixed {
+					header.Set(member.Traits.StringTrait(ast.HTTPPrefixedHeadersTraitID)+key, value.(string))
+				}
+			case member.Traits.HasTrait(ast.HTTPPayloadTraitID):
+				payload, payloadName = value, name
+			default:
+				if body == nil {
+					body = make(map[string]interface{})
+				}
+				body[name] = value
+			}
+		}
+	}
+	// Query parameters bound by httpQuery take precedence over those
+	// bound by httpQueryParams.
+	for key, value := range queryParams {
+		if _, ok := query[key]; ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			query[key] = []string{v}
+		case []string:
+			query[key] = v
+		}
+	}
+
+	expanded, err := expand(path, labels)
+	if err != nil {
+		return nil, err
+	}
+	rawQuery := strings.Join(literals, "&")
+	if encoded := query.Encode(); encoded != "" {
+		if rawQuery != "" {
+			rawQuery += "&"
+		}
+		rawQuery += encoded
+	}
+	target := strings.TrimSuffix(endpoint, "/") + expanded
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+
+	var reader io.Reader
+	contentType := ""
+	switch {
+	case payloadName != "":
+		member := b.shapes[op.Operation.Input.Value].Members[payloadName]
+		if reader, contentType, err = b.encodePayload(payloadName, member, payload); err != nil {
+			return nil, err
+		}
+	case body != nil || b.hasBody(op.Operation.Input):
+		if body == nil {
+			body = make(map[string]interface{})
+		}
+		p, err := b.codecs[jsoncodec.EpochSeconds].Marshal(op.Operation.Input.Value, body)
+		if err != nil {
+			return nil, newErrorf("input of %s: %w", operation, err)
+		}
+		reader, contentType = bytes.NewReader(p), "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, trait.Method.Value, target, reader)
+	if err != nil {
+		return nil, newErrorf("%w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// ParseResponse parses a response to a request for the operation with
+// the given ID. If the response has a 2xx status code, ParseResponse
+// returns the operation's output value; otherwise it returns an *Error.
+// The response body is closed, unless the output has a streaming blob
+// payload, which is returned unread.
+//
+// Error responses are identified by the error code in their
+// X-Amzn-Errortype header, or else in the __type or code member of their
+// body, which is matched against the names of the errors of the
+// operation and the service. If there is no error code, or it matches
+// no error, the response is identified by its status code, if exactly
+// one error has that code.
+func (b *Binder) ParseResponse(operation ast.AbsShapeID, resp *http.Response) (interface{}, error) {
+	op, _, err := b.operation(operation)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if op.Operation.Output == nil {
+			resp.Body.Close()
+			return map[string]interface{}{}, nil
+		}
+		return b.parseMessage(op.Operation.Output.Value, responseMessage(resp))
+	}
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newErrorf("%w", err)
+	}
+	e := &Error{StatusCode: resp.StatusCode, Code: errorCode(resp.Header, data)}
+	e.ShapeID = b.identify(op, e.Code, e.StatusCode)
+	if e.ShapeID == "" {
+		return nil, e
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	value, err := b.parseMessage(e.ShapeID, responseMessage(resp))
+	if err != nil {
+		return nil, err
+	}
+	e.Value = value.(map[string]interface{})
+	return nil, e
+}
+
+// ParseRequest parses a request for the operation with the given ID
+// into the operation's input value. The labels are the values of the
+// labels of the operation's URI pattern, as returned by Router.Route.
+// The request body is closed, unless the input has a streaming blob
+// payload, which is returned unread.
+//
+// ParseRequest returns an error if the request does not fit the input
+// shape of the operation.
+func (b *Binder) ParseRequest(operation ast.AbsShapeID, req *http.Request, labels map[string]string) (interface{}, error) {
+	op, trait, err := b.operation(operation)
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if op.Operation.Input == nil {
+		body.Close()
+		return map[string]interface{}{}, nil
+	}
+	// Parameters which are literals of the URI pattern select the
+	// operation, and are not bound to the input.
+	query := req.URL.Query()
+	_, literals := splitURI(trait.URI.Value)
+	for _, literal := range literals {
+		key := literal
+		if i := strings.IndexByte(literal, '='); i >= 0 {
+			key = literal[:i]
+		}
+		if key, err = url.QueryUnescape(key); err == nil {
+			query.Del(key)
+		}
+	}
+	return b.parseMessage(op.Operation.Input.Value, message{
+		header: req.Header,
+		body:   body,
+		labels: labels,
+		query:  query,
+	})
+}
+
+// WriteResponse writes the output of the operation with the given ID as
+// the response to a request for it. A nil output is an output with no
+// members set. The status code is the one the operation's http trait
+// names, 200 by default, unless a member bound by httpResponseCode sets
+// it.
+//
+// WriteResponse returns an error, having written nothing, if the output
+// does not fit the output shape of the operation.
+func (b *Binder) WriteResponse(w http.ResponseWriter, operation ast.AbsShapeID, output interface{}) error {
+	op, trait, err := b.operation(operation)
+	if err != nil {
+		return err
+	}
+	status := http.StatusOK
+	if trait.Code != nil {
+		status = int(trait.Code.Value)
+	}
+	return b.writeMessage(w, "output", op.Operation.Output, output, status, make(http.Header))
+}
+
+// WriteError writes the value of an error shape as the response to a
+// request for the operation with the given ID. The error must be one of
+// the errors of the operation or the service. The status code is the one
+// the error's httpError trait names, or else 400 for client errors and
+// 500 for server errors, and the X-Amzn-Errortype header names the
+// error.
+//
+// WriteError returns an error, having written nothing, if the value does
+// not fit the error shape.
+func (b *Binder) WriteError(w http.ResponseWriter, operation, errorID ast.AbsShapeID, value interface{}) error {
+	op, _, err := b.operation(operation)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, ref := range op.Operation.Errors {
+		found = found || ref.Value == errorID
+	}
+	for _, id := range b.errors {
+		found = found || id == errorID
+	}
+	if !found {
+		return newErrorf("shape %s is not an error of operation %s", errorID, operation)
+	}
+	s, ok := b.shapes[errorID]
+	if !ok {
+		return newErrorf("shape %s not found", errorID)
+	}
+	header := http.Header{"X-Amzn-Errortype": {errorID.Name()}}
+	return b.writeMessage(w, "error", &ast.AbsShapeIDNode{Value: errorID}, value, httpbinding.StatusCode(s.Traits), header)
+}
+
+// writeMessage writes a value of the structure referred to by ref as a
+// response with the given status code, unless a member overrides it.
+// The headers members are bound to are added to header.
+func (b *Binder) writeMessage(w http.ResponseWriter, kind string, ref *ast.AbsShapeIDNode, v interface{}, status int, header http.Header) error {
+	obj, err := b.members(kind, ref, v)
+	if err != nil {
+		return err
+	}
+	var body map[string]interface{}
+	var reader io.Reader
+	contentType := ""
+	if ref != nil {
+		s := b.shapes[ref.Value]
+		for _, name := range sortedNames(obj) {
+			member := s.Members[name]
+			value := obj[name]
+			switch {
+			case member.Traits.HasTrait(ast.HTTPHeaderTraitID):
+				values, err := b.formatList(name, member, value, headerLocation)
+				if err != nil {
+					return err
+				}
+				header.Set(member.Traits.StringTrait(ast.HTTPHeaderTraitID), joinHeader(values, b.isStringList(member)))
+			case member.Traits.HasTrait(ast.HTTPPrefixedHeadersTraitID):
+				prefixed, err := b.stringMap(name, member, value, headerLocation)
+				if err != nil {
+					return err
+				}
+				for key, value := range prefixed {
+					header.Set(member.Traits.StringTrait(ast.HTTPPrefixedHeadersTraitID)+key, value.(string))
+				}
+			case member.Traits.HasTrait(ast.HTTPResponseCodeTraitID):
+				if status, err = statusValue(name, value); err != nil {
+					return err
+				}
+			case member.Traits.HasTrait(ast.HTTPPayloadTraitID):
+				if reader, contentType, err = b.encodePayload(name, member, value); err != nil {
+					return err
+				}
+			case isBound(member):
+				return newErrorf("member %s is bound to the request, not the response", name)
+			default:
+				if body == nil {
+					body = make(map[string]interface{})
+				}
+				body[name] = value
+			}
+		}
+	}
+	if reader == nil && (body != nil || b.hasBody(ref)) {
+		if body == nil {
+			body = make(map[string]interface{})
+		}
+		p, err := b.codecs[jsoncodec.EpochSeconds].Marshal(ref.Value, body)
+		if err != nil {
+			return newErrorf("body of %s: %w", ref.Value, err)
+		}
+		reader, contentType = bytes.NewReader(p), "application/json"
+	}
+
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+	if contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	if reader != nil {
+		_, err = io.Copy(w, reader)
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return err
+}
+
+// A message holds the parts of an HTTP request or response which
+// members are bound to.
+type message struct {
+	header http.Header
+	body   io.ReadCloser
+	// status is the status code of a response, or zero for a request.
+	status int
+	// labels and query are the label values and query string of a
+	// request.
+	labels map[string]string
+	query  url.Values
+}
+
+func responseMessage(resp *http.Response) message {
+	return message{header: resp.Header, body: resp.Body, status: resp.StatusCode}
+}
+
+// parseMessage parses a message into a value of the structure with the
+// given ID, closing the body unless it is a streaming payload.
+func (b *Binder) parseMessage(id ast.AbsShapeID, msg message) (interface{}, error) {
+	s, ok := b.shapes[id]
+	if !ok {
+		msg.body.Close()
+		return nil, newErrorf("shape %s not found", id)
+	}
+	closeBody := true
+	defer func() {
+		if closeBody {
+			msg.body.Close()
+		}
+	}()
+	boundQuery := make(map[string]bool)
+	for _, member := range s.Members {
+		if key := member.Traits.StringTrait(ast.HTTPQueryTraitID); key != "" {
+			boundQuery[key] = true
+		}
+	}
+
+	values := make(map[string]interface{})
+	hasBody := false
+	for _, name := range s.MemberNames() {
+		member := s.Members[name]
+		var value interface{}
+		var err error
+		switch {
+		case member.Traits.HasTrait(ast.HTTPLabelTraitID) && msg.status == 0:
+			str, ok := msg.labels[name]
+			if !ok {
+				continue
+			}
+			value, err = b.parse(name, member.Target.Value, member.Traits, str, labelLocation)
+		case member.Traits.HasTrait(ast.HTTPQueryTraitID) && msg.status == 0:
+			strs, ok := msg.query[member.Traits.StringTrait(ast.HTTPQueryTraitID)]
+			if !ok {
+				continue
+			}
+			value, err = b.parseList(name, member, strs, queryLocation)
+		case member.Traits.HasTrait(ast.HTTPQueryParamsTraitID) && msg.status == 0:
+			query := make(url.Values)
+			for key, strs := range msg.query {
+				if !boundQuery[key] {
+					query[key] = strs
+				}
+			}
+			if len(query) == 0 {
+				continue
+			}
+			value, err = b.parseMap(name, member, query, queryLocation)
+		case member.Traits.HasTrait(ast.HTTPHeaderTraitID):
+			key := member.Traits.StringTrait(ast.HTTPHeaderTraitID)
+			if _, ok := msg.header[http.CanonicalHeaderKey(key)]; !ok {
+				continue
+			}
+			value, err = b.parseHeader(name, member, strings.Join(msg.header.Values(key), ", "))
+		case member.Traits.HasTrait(ast.HTTPPrefixedHeadersTraitID):
+			prefix := http.CanonicalHeaderKey(member.Traits.StringTrait(ast.HTTPPrefixedHeadersTraitID))
+			prefixed := make(url.Values)
+			for key, strs := range msg.header {
+				if len(key) > len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+					prefixed[key[len(prefix):]] = []string{strings.Join(strs, ", ")}
+				}
+			}
+			if len(prefixed) == 0 {
+				continue
+			}
+			value, err = b.parseMap(name, member, prefixed, headerLocation)
+		case member.Traits.HasTrait(ast.HTTPResponseCodeTraitID):
+			if msg.status == 0 {
+				continue
+			}
+			value = int64(msg.status)
+		case member.Traits.HasTrait(ast.HTTPPayloadTraitID):
+			if b.isStreaming(member) {
+				closeBody = false
+				values[name] = msg.body
+				continue
+			}
+			value, err = b.decodePayload(name, member, msg.body)
+			if value == nil && err == nil {
+				continue
+			}
+		default:
+			hasBody = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+
+	if hasBody {
+		data, err := io.ReadAll(msg.body)
+		if err != nil {
+			return nil, newErrorf("%w", err)
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			v, err := b.codecs[jsoncodec.EpochSeconds].Unmarshal(id, data)
+			if err != nil {
+				return nil, newErrorf("body of %s: %w", id, err)
+			}
+			if obj, ok := v.(map[string]interface{}); ok {
+				for name, value := range obj {
+					member := s.Members[name]
+					if !isBound(member) {
+						values[name] = value
+					}
+				}
+			}
+		}
+	}
+	return values, nil
+}
+
+// identify returns the ID of the error shape of an error response, or
+// the empty string if it cannot be identified.
+func (b *Binder) identify(op ast.Shape, code string, status int) ast.AbsShapeID {
+	var candidates []ast.AbsShapeID
+	for _, ref := range op.Operation.Errors {
+		candidates = append(candidates, ref.Value)
+	}
+	candidates = append(candidates, b.errors...)
+	if code != "" {
+		for _, id := range candidates {
+			if id.Name() == code {
+				return id
+			}
+		}
+	}
+	var match ast.AbsShapeID
+	for _, id := range candidates {
+		s, ok := b.shapes[id]
+		if !ok || httpbinding.StatusCode(s.Traits) != status {
+			continue
+		}
+		if match != "" && match != id {
+			return ""
+		}
+		match = id
+	}
+	return match
+}
+
+// operation returns the operation with the given ID and its http trait.
+func (b *Binder) operation(id ast.AbsShapeID) (ast.Shape, *ast.HTTPTrait, error) {
+	if !b.operations[id] {
+		return ast.Shape{}, nil, newErrorf("operation %s is not bound to service %s", id, b.service)
+	}
+	s, ok := b.shapes[id]
+	if !ok {
+		return s, nil, newErrorf("shape %s not found", id)
+	}
+	if s.Type != ast.OperationType {
+		return s, nil, newErrorf("shape %s is a %s, not an operation", id, s.Type)
+	}
+	if s.Operation == nil {
+		// An operation with no input, output or errors.
+		s.Operation = &ast.Operation{}
+	}
+	trait, ok := s.Traits[ast.HTTPTraitID].(*ast.HTTPTrait)
+	if !ok {
+		return s, nil, newErrorf("operation %s has no http trait", id)
+	}
+	return s, trait, nil
+}
+
+// members returns the members of an input, output or error value, as
+// the kind says, checking they are members of its structure.
+func (b *Binder) members(kind string, ref *ast.AbsShapeIDNode, v interface{}) (map[string]interface{}, error) {
+	v = valuetree.Unwrap(v)
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	obj, ok := valuetree.Object(v)
+	if !ok {
+		return nil, newErrorf("expected %s structure, got %T", kind, v)
+	}
+	var s ast.Shape
+	if ref != nil {
+		if s, ok = b.shapes[ref.Value]; !ok {
+			return nil, newErrorf("shape %s not found", ref.Value)
+		}
+	}
+	members := make(map[string]interface{}, len(obj))
+	for name, value := range obj {
+		if _, ok := s.Members[name]; !ok {
+			return nil, newErrorf("unknown %s member %q", kind, name)
+		}
+		if value = valuetree.Unwrap(value); value != nil {
+			members[name] = value
+		}
+	}
+	return members, nil
+}
+
+// hasBody reports whether the structure referred to by ref has members
+// bound to the document body.
+func (b *Binder) hasBody(ref *ast.AbsShapeIDNode) bool {
+	if ref == nil {
+		return false
+	}
+	for _, member := range b.shapes[ref.Value].Members {
+		if !isBound(member) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStreaming reports whether a member targets a streaming blob.
+func (b *Binder) isStreaming(member ast.Member) bool {
+	s := b.shapes[member.Target.Value]
+	return s.Type == ast.BlobType && s.Traits.HasTrait(ast.StreamingTraitID)
+}
+
+// isStringList reports whether a member targets a list or set of
+// strings.
+func (b *Binder) isStringList(member ast.Member) bool {
+	s := b.shapes[member.Target.Value]
+	return (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value != nil &&
+		b.shapes[s.Value.Target.Value].Type == ast.StringType
+}
+
+// isBound reports whether a member is bound to a part of an HTTP
+// message other than the document body.
+func isBound(member ast.Member) bool {
+	for _, id := range []ast.AbsShapeID{
+		ast.HTTPLabelTraitID, ast.HTTPQueryTraitID, ast.HTTPQueryParamsTraitID,
+		ast.HTTPHeaderTraitID, ast.HTTPPrefixedHeadersTraitID, ast.HTTPPayloadTraitID,
+		ast.HTTPResponseCodeTraitID,
+	} {
+		if member.Traits.HasTrait(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCode returns the error code of an error response.
+func errorCode(header http.Header, body []byte) string {
+	code := header.Get("X-Amzn-Errortype")
+	if code == "" {
+		var obj map[string]interface{}
+		if json.Unmarshal(body, &obj) == nil {
+			for _, key := range []string{"__type", "code"} {
+				if s, ok := obj[key].(string); ok {
+					code = s
+					break
+				}
+			}
+		}
+	}
+	if i := strings.IndexByte(code, ':'); i >= 0 {
+		code = code[:i]
+	}
+	if i := strings.LastIndexByte(code, '#'); i >= 0 {
+		code = code[i+1:]
+	}
+	return strings.TrimSpace(code)
+}
+
+// statusValue returns the status code a member bound by
+// httpResponseCode sets.
+func statusValue(name string, v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == float64(int(f)) {
+			return int(f), nil
+		}
+	}
+	return 0, newErrorf("member %s: expected integer status code, got %v", name, v)
+}
+
+func sortedNames(obj map[string]interface{}) []string {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "restbind: "
@@ -0,0 +1,701 @@
+package restbind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"operations": ["foo#PutThing"],
+			"resources": ["foo#Blob"],
+			"errors": ["foo#ServiceError"]
+		},
+		"foo#Blob": {
+			"type": "resource",
+			"identifiers": {"id": "smithy.api#String"},
+			"read": "foo#GetBlob"
+		},
+		"foo#PutThing": {
+			"type": "operation",
+			"input": "foo#PutThingInput",
+			"output": "foo#PutThingOutput",
+			"errors": ["foo#NotFound", "foo#Conflict"],
+			"traits": {"smithy.api#http": {"method": "PUT", "uri": "/things/{id}/files/{path+}?mode=raw", "code": 201}}
+		},
+		"foo#PutThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"path": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"tags": {"target": "foo#Strings", "traits": {"smithy.api#httpQuery": "tag"}},
+				"since": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#httpQuery": "since"}},
+				"params": {"target": "foo#StringMap", "traits": {"smithy.api#httpQueryParams": {}}},
+				"ids": {"target": "foo#Strings", "traits": {"smithy.api#httpHeader": "X-Ids"}},
+				"when": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#httpHeader": "X-When"}},
+				"meta": {"target": "foo#StringMap", "traits": {"smithy.api#httpPrefixedHeaders": "X-Meta-"}},
+				"name": {"target": "smithy.api#String"},
+				"count": {"target": "smithy.api#Integer"}
+			}
+		},
+		"foo#PutThingOutput": {
+			"type": "structure",
+			"members": {
+				"etag": {"target": "smithy.api#String", "traits": {"smithy.api#httpHeader": "ETag"}},
+				"dates": {"target": "foo#Timestamps", "traits": {"smithy.api#httpHeader": "X-Dates"}},
+				"status": {"target": "smithy.api#Integer", "traits": {"smithy.api#httpResponseCode": {}}},
+				"meta": {"target": "foo#StringMap", "traits": {"smithy.api#httpPrefixedHeaders": "X-Meta-"}},
+				"name": {"target": "smithy.api#String"}
+			}
+		},
+		"foo#GetBlob": {
+			"type": "operation",
+			"input": "foo#GetBlobInput",
+			"output": "foo#GetBlobOutput",
+			"traits": {"smithy.api#http": {"method": "GET", "uri": "/blobs/{id}"}, "smithy.api#readonly": {}}
+		},
+		"foo#GetBlobInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}}
+			}
+		},
+		"foo#GetBlobOutput": {
+			"type": "structure",
+			"members": {
+				"data": {"target": "foo#Stream", "traits": {"smithy.api#httpPayload": {}}},
+				"size": {"target": "smithy.api#Long", "traits": {"smithy.api#httpHeader": "Content-Length"}}
+			}
+		},
+		"foo#Stream": {
+			"type": "blob",
+			"traits": {"smithy.api#streaming": {}}
+		},
+		"foo#Strings": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#Timestamps": {
+			"type": "list",
+			"member": {"target": "smithy.api#Timestamp"}
+		},
+		"foo#StringMap": {
+			"type": "map",
+			"key": {"target": "smithy.api#String"},
+			"value": {"target": "smithy.api#String"}
+		},
+		"foo#NotFound": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "client", "smithy.api#httpError": 404}
+		},
+		"foo#Conflict": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "client", "smithy.api#httpError": 409}
+		},
+		"foo#ServiceError": {
+			"type": "structure",
+			"members": {
+				"message": {"target": "smithy.api#String"},
+				"retryAfter": {"target": "smithy.api#Integer", "traits": {"smithy.api#httpHeader": "Retry-After"}}
+			},
+			"traits": {"smithy.api#error": "server"}
+		}
+	}
+}`
+
+func testBinder(t *testing.T) *Binder {
+	m := testmodel.Read(t, testModel)
+	b, err := New(m, "foo#Service")
+	require.NoError(t, err)
+	return b
+}
+
+func TestBinder_NewRequest(t *testing.T) {
+	b := testBinder(t)
+
+	req, err := b.NewRequest(context.Background(), "https://example.com/v1/", "foo#PutThing", map[string]interface{}{
+		"id":     "a b",
+		"path":   "dir/file?.txt",
+		"tags":   []string{"x", "y&z"},
+		"since":  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"params": map[string]interface{}{"tag": "ignored", "extra": "1"},
+		"ids":    []interface{}{"p", "q,r"},
+		"when":   time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+		"meta":   map[string]string{"Color": "red"},
+		"name":   "Bob",
+		"count":  nil,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", req.Method)
+	assert.Equal(t, "https://example.com/v1/things/a%20b/files/dir/file%3F.txt?mode=raw&extra=1&since=2020-01-02T03%3A04%3A05Z&tag=x&tag=y%26z", req.URL.String())
+	assert.Equal(t, `p, "q,r"`, req.Header.Get("X-Ids"))
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", req.Header.Get("X-When"))
+	assert.Equal(t, "red", req.Header.Get("X-Meta-Color"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Bob"}`, string(body))
+
+	req, err = b.NewRequest(context.Background(), "http://localhost", "foo#GetBlob", map[string]interface{}{"id": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/blobs/1", req.URL.String())
+	assert.Nil(t, req.Body)
+	assert.Empty(t, req.Header)
+}
+
+func TestBinder_NewRequest_Errors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation ast.AbsShapeID
+		input     interface{}
+		err       string
+	}{
+		{name: "not bound", operation: "foo#GetBlobInput", err: "restbind: operation foo#GetBlobInput is not bound to service foo#Service"},
+		{name: "input type", operation: "foo#GetBlob", input: "x", err: "restbind: expected input structure, got string"},
+		{name: "unknown member", operation: "foo#GetBlob", input: map[string]interface{}{"x": 1}, err: `restbind: unknown input member "x"`},
+		{name: "missing label", operation: "foo#GetBlob", input: map[string]interface{}{}, err: `restbind: label "id" has no value`},
+		{name: "value type", operation: "foo#GetBlob", input: map[string]interface{}{"id": 1}, err: "restbind: member id: jsoncodec: expected string, got number"},
+		{name: "body type", operation: "foo#PutThing", input: map[string]interface{}{"id": "a", "path": "b", "count": "1"}, err: "restbind: input of foo#PutThing: jsoncodec: /count: expected integer, got string"},
+	}
+
+	b := testBinder(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := b.NewRequest(context.Background(), "http://localhost", testCase.operation, testCase.input)
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
+
+func response(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestBinder_ParseResponse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation ast.AbsShapeID
+		resp      *http.Response
+		output    interface{}
+		err       string
+	}{
+		{
+			name:      "output",
+			operation: "foo#PutThing",
+			resp: response(201, http.Header{
+				"Etag":         {`"abc"`},
+				"X-Dates":      {"Mon, 16 Dec 2019 23:48:18 GMT, Tue, 17 Dec 2019 23:48:18 GMT"},
+				"X-Meta-Color": {"red"},
+			}, `{"name": "Bob", "status": 1}`),
+			output: map[string]interface{}{
+				"etag":   `"abc"`,
+				"dates":  []interface{}{time.Date(2019, 12, 16, 23, 48, 18, 0, time.UTC), time.Date(2019, 12, 17, 23, 48, 18, 0, time.UTC)},
+				"status": int64(201),
+				"meta":   map[string]interface{}{"Color": "red"},
+				"name":   "Bob",
+			},
+		},
+		{
+			name:      "empty body",
+			operation: "foo#PutThing",
+			resp:      response(200, nil, ""),
+			output:    map[string]interface{}{"status": int64(200)},
+		},
+		{
+			name:      "error code header",
+			operation: "foo#PutThing",
+			resp:      response(400, http.Header{"X-Amzn-Errortype": {"NotFound:http://internal.amazon.com/"}}, `{"message": "gone"}`),
+			err:       "restbind: foo#NotFound (status 400): gone",
+		},
+		{
+			name:      "error code body",
+			operation: "foo#PutThing",
+			resp:      response(400, nil, `{"__type": "foo#Conflict", "message": "busy"}`),
+			err:       "restbind: foo#Conflict (status 400): busy",
+		},
+		{
+			name:      "error status",
+			operation: "foo#PutThing",
+			resp:      response(500, http.Header{"Retry-After": {"5"}}, `{"message": "oops"}`),
+			err:       "restbind: foo#ServiceError (status 500): oops",
+		},
+		{
+			name:      "unknown error",
+			operation: "foo#PutThing",
+			resp:      response(400, nil, `{"code": "Throttled"}`),
+			err:       `restbind: unknown error "Throttled" (status 400)`,
+		},
+		{
+			name:      "invalid header",
+			operation: "foo#GetBlob",
+			resp:      response(200, http.Header{"Content-Length": {"many"}}, ""),
+			err:       `restbind: member size: invalid header value "many"`,
+		},
+		{
+			name:      "invalid body",
+			operation: "foo#PutThing",
+			resp:      response(200, nil, `{"name": 1}`),
+			err:       "restbind: body of foo#PutThingOutput: jsoncodec: /name: expected string, got number",
+		},
+	}
+
+	b := testBinder(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			output, err := b.ParseResponse(testCase.operation, testCase.resp)
+
+			if testCase.err != "" {
+				assert.EqualError(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.output, output)
+		})
+	}
+}
+
+func TestBinder_ParseResponse_Error(t *testing.T) {
+	b := testBinder(t)
+
+	_, err := b.ParseResponse("foo#PutThing", response(500, http.Header{"Retry-After": {"5"}}, `{"message": "oops"}`))
+
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, &Error{
+		ShapeID:    "foo#ServiceError",
+		StatusCode: 500,
+		Value:      map[string]interface{}{"message": "oops", "retryAfter": int64(5)},
+	}, e)
+
+	_, err = b.ParseResponse("foo#PutThing", response(503, nil, ``))
+
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, &Error{StatusCode: 503}, e)
+}
+
+func TestBinder_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/blobs/x%2Fy", r.URL.RawPath)
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+	b := testBinder(t)
+
+	output, err := b.Do(context.Background(), server.Client(), server.URL, "foo#GetBlob", map[string]interface{}{"id": "x/y"})
+
+	require.NoError(t, err)
+	require.IsType(t, map[string]interface{}{}, output)
+	values := output.(map[string]interface{})
+	assert.Equal(t, int64(5), values["size"])
+	require.Implements(t, (*io.ReadCloser)(nil), values["data"])
+	data := values["data"].(io.ReadCloser)
+	p, err := io.ReadAll(data)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(p))
+	assert.NoError(t, data.Close())
+}
+
+func TestNew_Errors(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	_, err := New(m, "foo#Missing")
+	assert.EqualError(t, err, "restbind: service foo#Missing not found")
+	_, err = New(m, "foo#Blob")
+	assert.EqualError(t, err, "restbind: shape foo#Blob is a resource, not a service")
+}
+
+func routerBinder(t *testing.T, routes ...string) *Binder {
+	shapes := map[string]interface{}{}
+	var operations []string
+	for i, r := range routes {
+		parts := strings.SplitN(r, " ", 2)
+		members := map[string]interface{}{}
+		path, _ := splitURI(parts[1])
+		for _, seg := range strings.Split(path, "/") {
+			if strings.HasPrefix(seg, "{") {
+				members[strings.Trim(seg, "{}+")] = map[string]interface{}{
+					"target": "smithy.api#String",
+					"traits": map[string]interface{}{"smithy.api#httpLabel": map[string]interface{}{}, "smithy.api#required": map[string]interface{}{}},
+				}
+			}
+		}
+		id := fmt.Sprintf("foo#Op%d", i)
+		operations = append(operations, id)
+		shapes[id] = map[string]interface{}{
+			"type":   "operation",
+			"input":  id + "Input",
+			"traits": map[string]interface{}{"smithy.api#http": map[string]interface{}{"method": parts[0], "uri": parts[1]}},
+		}
+		shapes[id+"Input"] = map[string]interface{}{"type": "structure", "members": members}
+	}
+	shapes["foo#Service"] = map[string]interface{}{"type": "service", "version": "1", "operations": operations}
+	p, err := json.Marshal(map[string]interface{}{"version": "1.0", "shapes": shapes})
+	require.NoError(t, err)
+	m, err := ast.ReadModel(bytes.NewReader(p))
+	require.NoError(t, err)
+	b, err := New(m, "foo#Service")
+	require.NoError(t, err)
+	return b
+}
+
+func TestRouter_Route(t *testing.T) {
+	testCases := []struct {
+		name      string
+		method    string
+		target    string
+		operation ast.AbsShapeID
+		labels    map[string]string
+		err       string
+	}{
+		{name: "root", method: "GET", target: "/", operation: "foo#Op0", labels: map[string]string{}},
+		{name: "literal", method: "GET", target: "/things/new", operation: "foo#Op2", labels: map[string]string{}},
+		{name: "label", method: "GET", target: "/things/a%2Fb", operation: "foo#Op1", labels: map[string]string{"id": "a/b"}},
+		{name: "lower case method", method: "get", target: "/things/1", operation: "foo#Op1", labels: map[string]string{"id": "1"}},
+		{name: "greedy", method: "GET", target: "/things/1/files/a/b%20c.txt", operation: "foo#Op3", labels: map[string]string{"id": "1", "path": "a/b c.txt"}},
+		{name: "greedy suffix", method: "GET", target: "/things/1/files/a/b/meta", operation: "foo#Op4", labels: map[string]string{"id": "1", "path": "a/b"}},
+		{name: "query literal", method: "POST", target: "/things/1?x=1&mode=raw", operation: "foo#Op6", labels: map[string]string{"id": "1"}},
+		{name: "most query literals", method: "POST", target: "/things/1?flag&mode=raw", operation: "foo#Op7", labels: map[string]string{"id": "1"}},
+		{name: "query presence", method: "POST", target: "/things/1?flag=&mode=raw", operation: "foo#Op7", labels: map[string]string{"id": "1"}},
+		{name: "query fallback", method: "POST", target: "/things/1?mode=cooked", operation: "foo#Op5", labels: map[string]string{"id": "1"}},
+		{name: "empty label", method: "GET", target: "/things/", err: "restbind: no operation matches GET /things/"},
+		{name: "empty greedy", method: "GET", target: "/things/1/files/", err: "restbind: no operation matches GET /things/1/files/"},
+		{name: "not found", method: "GET", target: "/other", err: "restbind: no operation matches GET /other"},
+		{name: "not allowed", method: "DELETE", target: "/things/1", err: "restbind: method DELETE is not allowed for /things/1, only GET, POST"},
+	}
+
+	b := routerBinder(t,
+		"GET /",
+		"GET /things/{id}",
+		"GET /things/new",
+		"GET /things/{id}/files/{path+}",
+		"GET /things/{id}/files/{path+}/meta",
+		"POST /things/{id}",
+		"POST /things/{id}?mode=raw",
+		"POST /things/{id}?flag&mode=raw",
+	)
+	r, err := NewRouter(b)
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, testCase.target, nil)
+
+			operation, labels, err := r.Route(req)
+
+			if testCase.err != "" {
+				assert.EqualError(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.operation, operation)
+			assert.Equal(t, testCase.labels, labels)
+		})
+	}
+}
+
+func TestRouter_Route_StatusCode(t *testing.T) {
+	r, err := NewRouter(testBinder(t))
+	require.NoError(t, err)
+
+	_, _, err = r.Route(httptest.NewRequest("GET", "/things/1/files/x", nil))
+	var routeErr *RouteError
+	require.True(t, errors.As(err, &routeErr))
+	assert.Equal(t, http.StatusNotFound, routeErr.StatusCode)
+
+	_, _, err = r.Route(httptest.NewRequest("DELETE", "/blobs/1", nil))
+	require.True(t, errors.As(err, &routeErr))
+	assert.Equal(t, http.StatusMethodNotAllowed, routeErr.StatusCode)
+	assert.Equal(t, []string{"GET"}, routeErr.Allow)
+}
+
+func TestNewRouter_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		routes []string
+		err    string
+	}{
+		{name: "conflict", routes: []string{"GET /a/{x}", "GET /a/{y}"}, err: "restbind: operations foo#Op0 and foo#Op1 have conflicting routes GET /a/{x} and GET /a/{y}"},
+		{name: "conflict query", routes: []string{"GET /a?p=1&q", "GET /a?q&p=1"}, err: "restbind: operations foo#Op0 and foo#Op1 have conflicting routes GET /a?p=1&q and GET /a?q&p=1"},
+		{name: "ambiguous labels", routes: []string{"GET /a/{x}/b", "GET /a/c/{y}"}, err: "restbind: operations foo#Op0 and foo#Op1 have ambiguous routes GET /a/{x}/b and GET /a/c/{y}"},
+		{name: "ambiguous greedy", routes: []string{"GET /{x+}/b", "GET /a/{y+}"}, err: "restbind: operations foo#Op0 and foo#Op1 have ambiguous routes GET /{x+}/b and GET /a/{y+}"},
+		{name: "ambiguous query", routes: []string{"GET /a?p", "GET /a?q"}, err: "restbind: operations foo#Op0 and foo#Op1 have ambiguous routes GET /a?p and GET /a?q"},
+		{name: "two greedy labels", routes: []string{"GET /{x+}/{y+}"}, err: `restbind: operation foo#Op0 has invalid URI pattern "/{x+}/{y+}": it has more than one greedy label`},
+		{name: "bad segment", routes: []string{"GET /a{x}"}, err: `restbind: operation foo#Op0 has invalid URI pattern "/a{x}": segment "a{x}" is not a literal or label`},
+		{name: "relative", routes: []string{"GET a"}, err: `restbind: operation foo#Op0 has invalid URI pattern "a": it does not start with /`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewRouter(routerBinder(t, testCase.routes...))
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+
+	t.Run("disjoint", func(t *testing.T) {
+		_, err := NewRouter(routerBinder(t, "GET /a/{x}", "PUT /a/{x}", "GET /a/{x}/b", "GET /b?p=1", "GET /b?p=2", "GET /c/{x+}/c", "GET /c/{x+}/d"))
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestRouter_ParseRequest(t *testing.T) {
+	b := testBinder(t)
+	r, err := NewRouter(b)
+	require.NoError(t, err)
+	input := map[string]interface{}{
+		"id":     "a b",
+		"path":   "dir/file?.txt",
+		"tags":   []interface{}{"x", "y&z"},
+		"since":  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"params": map[string]interface{}{"extra": "1"},
+		"ids":    []interface{}{"p", "q,r"},
+		"when":   time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+		"meta":   map[string]interface{}{"Color": "red"},
+		"name":   "Bob",
+		"count":  int64(3),
+	}
+	req, err := b.NewRequest(context.Background(), "http://localhost", "foo#PutThing", input)
+	require.NoError(t, err)
+
+	operation, actual, err := r.ParseRequest(req)
+
+	require.NoError(t, err)
+	assert.Equal(t, ast.AbsShapeID("foo#PutThing"), operation)
+	assert.Equal(t, input, actual)
+
+	req = httptest.NewRequest("GET", "/blobs/x%2Fy", nil)
+	operation, actual, err = r.ParseRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, ast.AbsShapeID("foo#GetBlob"), operation)
+	assert.Equal(t, map[string]interface{}{"id": "x/y"}, actual)
+
+	req = httptest.NewRequest("PUT", "/things/1/files/x?mode=raw", strings.NewReader(`{"count": "3"}`))
+	_, _, err = r.ParseRequest(req)
+	assert.EqualError(t, err, "restbind: body of foo#PutThingInput: jsoncodec: /count: expected integer, got string")
+}
+
+func TestBinder_WriteResponse(t *testing.T) {
+	b := testBinder(t)
+	w := httptest.NewRecorder()
+
+	err := b.WriteResponse(w, "foo#PutThing", map[string]interface{}{
+		"etag":  `"abc"`,
+		"dates": []interface{}{time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)},
+		"meta":  map[string]interface{}{"Color": "red"},
+		"name":  "Bob",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `"abc"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", w.Header().Get("X-Dates"))
+	assert.Equal(t, "red", w.Header().Get("X-Meta-Color"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"name": "Bob"}`, w.Body.String())
+
+	output, err := b.ParseResponse("foo#PutThing", w.Result())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"etag":   `"abc"`,
+		"dates":  []interface{}{time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)},
+		"meta":   map[string]interface{}{"Color": "red"},
+		"name":   "Bob",
+		"status": int64(201),
+	}, output)
+
+	w = httptest.NewRecorder()
+	err = b.WriteResponse(w, "foo#PutThing", map[string]interface{}{"status": int64(202)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = b.WriteResponse(w, "foo#GetBlob", map[string]interface{}{"data": strings.NewReader("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "hello", w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = b.WriteResponse(w, "foo#PutThing", map[string]interface{}{"name": 1})
+	assert.EqualError(t, err, "restbind: body of foo#PutThingOutput: jsoncodec: /name: expected string, got number")
+	err = b.WriteResponse(w, "foo#PutThing", map[string]interface{}{"id": "1"})
+	assert.EqualError(t, err, `restbind: unknown output member "id"`)
+	assert.Empty(t, w.Header())
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestBinder_WriteError(t *testing.T) {
+	b := testBinder(t)
+	w := httptest.NewRecorder()
+
+	err := b.WriteError(w, "foo#PutThing", "foo#ServiceError", map[string]interface{}{"message": "try later", "retryAfter": int64(5)})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "ServiceError", w.Header().Get("X-Amzn-Errortype"))
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	_, err = b.ParseResponse("foo#PutThing", w.Result())
+	assert.EqualError(t, err, "restbind: foo#ServiceError (status 500): try later")
+
+	w = httptest.NewRecorder()
+	require.NoError(t, b.WriteError(w, "foo#PutThing", "foo#Conflict", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	err = b.WriteError(w, "foo#GetBlob", "foo#NotFound", nil)
+	assert.EqualError(t, err, "restbind: shape foo#NotFound is not an error of operation foo#GetBlob")
+}
+
+const mockModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"operations": ["foo#GetThing", "foo#Ping"]
+		},
+		"foo#GetThing": {
+			"type": "operation",
+			"input": "foo#GetThingInput",
+			"output": "foo#GetThingOutput",
+			"errors": ["foo#NotFound"],
+			"traits": {
+				"smithy.api#http": {"method": "GET", "uri": "/things/{id}"},
+				"smithy.api#examples": [
+					{"title": "Any", "output": {"name": "Anything", "created": 1.5}},
+					{"title": "Blue", "input": {"id": "1", "color": "blue"}, "output": {"name": "Blue thing", "tags": ["b"], "version": "v1"}},
+					{"title": "Missing", "input": {"id": "404"}, "error": {"shapeId": "foo#NotFound", "content": {"message": "no thing 404"}}}
+				]
+			}
+		},
+		"foo#GetThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#httpLabel": {}, "smithy.api#required": {}}},
+				"color": {"target": "smithy.api#String", "traits": {"smithy.api#httpQuery": "color"}}
+			}
+		},
+		"foo#GetThingOutput": {
+			"type": "structure",
+			"members": {
+				"version": {"target": "smithy.api#String", "traits": {"smithy.api#httpHeader": "X-Version"}},
+				"name": {"target": "smithy.api#String"},
+				"created": {"target": "smithy.api#Timestamp"},
+				"tags": {"target": "foo#Strings"}
+			}
+		},
+		"foo#Strings": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#NotFound": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "client", "smithy.api#httpError": 404}
+		},
+		"foo#Ping": {
+			"type": "operation",
+			"traits": {"smithy.api#http": {"method": "POST", "uri": "/ping"}}
+		}
+	}
+}`
+
+func TestNewMock(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		status int
+		header http.Header
+		body   string
+	}{
+		{name: "any", method: "GET", target: "/things/2", status: 200, body: `{"name": "Anything", "created": 1.5}`},
+		{name: "most members", method: "GET", target: "/things/1?color=blue", status: 200, header: http.Header{"X-Version": {"v1"}}, body: `{"name": "Blue thing", "tags": ["b"]}`},
+		{name: "error", method: "GET", target: "/things/404", status: 404, header: http.Header{"X-Amzn-Errortype": {"NotFound"}}, body: `{"message": "no thing 404"}`},
+		{name: "not found", method: "GET", target: "/other", status: 404, body: `{"message": "restbind: no operation matches GET /other"}`},
+		{name: "not allowed", method: "PUT", target: "/things/1", status: 405, header: http.Header{"Allow": {"GET"}}, body: `{"message": "restbind: method PUT is not allowed for /things/1, only GET"}`},
+		{name: "no examples", method: "POST", target: "/ping", status: 404, body: `{"message": "restbind: operation foo#Ping has no examples"}`},
+	}
+
+	m := testmodel.Read(t, mockModel)
+	h, err := NewMock(m, "foo#Service")
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, httptest.NewRequest(testCase.method, testCase.target, nil))
+
+			assert.Equal(t, testCase.status, w.Code)
+			for key := range testCase.header {
+				assert.Equal(t, testCase.header.Get(key), w.Header().Get(key), key)
+			}
+			assert.JSONEq(t, testCase.body, w.Body.String())
+		})
+	}
+}
+
+func TestNewMock_Unmatched(t *testing.T) {
+	m := testmodel.Read(t, mockModel)
+	op := m.Shapes["foo#GetThing"]
+	examples := op.Traits[ast.ExamplesTraitID].(*ast.ExamplesTrait)
+	examples.Items = examples.Items[1:]
+	h, err := NewMock(m, "foo#Service")
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/things/1?color=red", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"message": "restbind: none of the 2 examples of operation foo#GetThing matches the request"}`, w.Body.String())
+}
+
+func TestNewMock_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		example string
+		err     string
+	}{
+		{name: "unknown member", example: `{"title": "X", "input": {"size": 1}}`, err: `restbind: example "X" of operation foo#GetThing: input: unknown member "size" of foo#GetThingInput`},
+		{name: "wrong type", example: `{"title": "X", "output": {"tags": [1]}}`, err: `restbind: example "X" of operation foo#GetThing: output: /tags/0: expected string, got number`},
+		{name: "bad timestamp", example: `{"title": "X", "output": {"created": "yesterday"}}`, err: `restbind: example "X" of operation foo#GetThing: output: /created: invalid timestamp "yesterday"`},
+		{name: "missing error", example: `{"title": "X", "error": {"shapeId": "foo#Gone"}}`, err: `restbind: example "X" of operation foo#GetThing: error: shape foo#Gone not found`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			model := strings.Replace(mockModel, `{"title": "Any", "output": {"name": "Anything", "created": 1.5}}`, testCase.example, 1)
+			m := testmodel.Read(t, model)
+
+			_, err := NewMock(m, "foo#Service")
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
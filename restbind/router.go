@@ -0,0 +1,434 @@
+package restbind
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// A Router routes HTTP requests to the operations of a service by the
+// methods and URI patterns of their http traits.
+//
+// When more than one route matches a request, the most specific one is
+// chosen: literal segments are more specific than labels, which are
+// more specific than greedy labels, and routes with more literal query
+// parameters are more specific than those with fewer. NewRouter rejects
+// services with routes which could match the same request but of which
+// neither is more specific than the other.
+type Router struct {
+	binder *Binder
+	routes []route
+}
+
+// A route is the method and parsed URI pattern of an operation.
+type route struct {
+	operation ast.AbsShapeID
+	method    string
+	uri       string
+	segments  []segment
+	query     []queryLiteral
+}
+
+// A segment is a path segment of a URI pattern: a literal, a label or a
+// greedy label.
+type segment struct {
+	literal string
+	label   string
+	greedy  bool
+}
+
+func (s segment) rank() int {
+	switch {
+	case s.label == "":
+		return 2
+	case !s.greedy:
+		return 1
+	}
+	return 0
+}
+
+// A queryLiteral is a literal query parameter of a URI pattern, such as
+// "mode=raw" or "flag". A literal without a value only requires the
+// parameter to be present.
+type queryLiteral struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// NewRouter returns a router for the operations of the service of b. It
+// returns an error if an operation has no http trait or an invalid URI
+// pattern, or if two operations have routes which conflict or are
+// ambiguous.
+func NewRouter(b *Binder) (*Router, error) {
+	r := &Router{binder: b}
+	for _, id := range b.Operations() {
+		op, trait, err := b.operation(id)
+		if err != nil {
+			return nil, err
+		}
+		rt, err := parseRoute(id, trait)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.checkLabels(rt, op); err != nil {
+			return nil, err
+		}
+		r.routes = append(r.routes, rt)
+	}
+
+	for i := range r.routes {
+		for j := i + 1; j < len(r.routes); j++ {
+			a, c := &r.routes[i], &r.routes[j]
+			if !strings.EqualFold(a.method, c.method) || !a.overlaps(c) {
+				continue
+			}
+			ac, ca := a.covers(c), c.covers(a)
+			if ac && ca {
+				return nil, newErrorf("operations %s and %s have conflicting routes %s %s and %s %s",
+					a.operation, c.operation, a.method, a.uri, c.method, c.uri)
+			} else if !ac && !ca {
+				return nil, newErrorf("operations %s and %s have ambiguous routes %s %s and %s %s",
+					a.operation, c.operation, a.method, a.uri, c.method, c.uri)
+			}
+		}
+	}
+	return r, nil
+}
+
+// A RouteError is returned by Router.Route when no operation matches a
+// request.
+type RouteError struct {
+	// StatusCode is http.StatusNotFound if no route matches the path and
+	// query string of the request, or http.StatusMethodNotAllowed if
+	// some do, but not with the method of the request.
+	StatusCode int
+
+	// Method and Path are the method and path of the request.
+	Method, Path string
+
+	// Allow lists the methods of the routes which match the path and
+	// query string, if StatusCode is http.StatusMethodNotAllowed.
+	Allow []string
+}
+
+func (e *RouteError) Error() string {
+	if e.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Sprintf("restbind: method %s is not allowed for %s, only %s", e.Method, e.Path, strings.Join(e.Allow, ", "))
+	}
+	return fmt.Sprintf("restbind: no operation matches %s %s", e.Method, e.Path)
+}
+
+// Route returns the ID of the operation a request is routed to, and the
+// values of the labels of its URI pattern. If no operation matches the
+// request, Route returns a *RouteError.
+func (r *Router) Route(req *http.Request) (ast.AbsShapeID, map[string]string, error) {
+	parts, ok := splitPath(req.URL.EscapedPath())
+	if !ok {
+		return "", nil, &RouteError{StatusCode: http.StatusNotFound, Method: req.Method, Path: req.URL.Path}
+	}
+	query := req.URL.Query()
+	var best *route
+	var bestLabels map[string]string
+	allow := make(map[string]bool)
+	for i := range r.routes {
+		rt := &r.routes[i]
+		labels, ok := rt.match(parts, query)
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(rt.method, req.Method) {
+			allow[strings.ToUpper(rt.method)] = true
+			continue
+		}
+		if best == nil || rt.covers(best) {
+			best, bestLabels = rt, labels
+		}
+	}
+	if best != nil {
+		return best.operation, bestLabels, nil
+	}
+	if len(allow) == 0 {
+		return "", nil, &RouteError{StatusCode: http.StatusNotFound, Method: req.Method, Path: req.URL.Path}
+	}
+	methods := make([]string, 0, len(allow))
+	for method := range allow {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return "", nil, &RouteError{StatusCode: http.StatusMethodNotAllowed, Method: req.Method, Path: req.URL.Path, Allow: methods}
+}
+
+// ParseRequest routes a request to an operation, and parses it into the
+// operation's input value. It returns the ID of the operation and the
+// input. If no operation matches the request, ParseRequest returns a
+// *RouteError.
+func (r *Router) ParseRequest(req *http.Request) (ast.AbsShapeID, interface{}, error) {
+	operation, labels, err := r.Route(req)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return "", nil, err
+	}
+	input, err := r.binder.ParseRequest(operation, req, labels)
+	if err != nil {
+		return operation, nil, err
+	}
+	return operation, input, nil
+}
+
+// parseRoute parses the URI pattern of an operation's http trait.
+func parseRoute(operation ast.AbsShapeID, trait *ast.HTTPTrait) (route, error) {
+	rt := route{operation: operation, method: trait.Method.Value, uri: trait.URI.Value}
+	invalid := func(reason string) (route, error) {
+		return route{}, newErrorf("operation %s has invalid URI pattern %q: %s", operation, rt.uri, reason)
+	}
+	path, literals := splitURI(rt.uri)
+	if !strings.HasPrefix(path, "/") {
+		return invalid("it does not start with /")
+	}
+	greedy := false
+	seen := make(map[string]bool)
+	if path != "/" {
+		for _, part := range strings.Split(path[1:], "/") {
+			if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+				if strings.ContainsAny(part, "{}") {
+					return invalid(fmt.Sprintf("segment %q is not a literal or label", part))
+				}
+				rt.segments = append(rt.segments, segment{literal: part})
+				continue
+			}
+			seg := segment{label: part[1 : len(part)-1]}
+			if strings.HasSuffix(seg.label, "+") {
+				seg.label, seg.greedy = seg.label[:len(seg.label)-1], true
+				if greedy {
+					return invalid("it has more than one greedy label")
+				}
+				greedy = true
+			}
+			if seg.label == "" || strings.ContainsAny(seg.label, "{}+") {
+				return invalid(fmt.Sprintf("segment %q is not a literal or label", part))
+			}
+			if seen[seg.label] {
+				return invalid(fmt.Sprintf("label %q appears more than once", seg.label))
+			}
+			seen[seg.label] = true
+			rt.segments = append(rt.segments, seg)
+		}
+	}
+	for _, literal := range literals {
+		q := queryLiteral{key: literal}
+		if i := strings.IndexByte(literal, '='); i >= 0 {
+			q = queryLiteral{key: literal[:i], value: literal[i+1:], hasValue: true}
+		}
+		var err error
+		if q.key, err = url.QueryUnescape(q.key); err != nil {
+			return invalid(err.Error())
+		}
+		if q.value, err = url.QueryUnescape(q.value); err != nil {
+			return invalid(err.Error())
+		}
+		rt.query = append(rt.query, q)
+	}
+	return rt, nil
+}
+
+// checkLabels checks the labels of a route are bound to members of its
+// operation's input, and that every member bound to a label has one.
+func (b *Binder) checkLabels(rt route, op ast.Shape) error {
+	var input ast.Shape
+	if op.Operation.Input != nil {
+		input = b.shapes[op.Operation.Input.Value]
+	}
+	members := input.Members
+	labels := make(map[string]bool)
+	for _, seg := range rt.segments {
+		if seg.label == "" {
+			continue
+		}
+		labels[seg.label] = true
+		if !members[seg.label].Traits.HasTrait(ast.HTTPLabelTraitID) {
+			return newErrorf("label %q of operation %s is not bound to an input member", seg.label, rt.operation)
+		}
+	}
+	for _, name := range input.MemberNames() {
+		if members[name].Traits.HasTrait(ast.HTTPLabelTraitID) && !labels[name] {
+			return newErrorf("input member %s of operation %s has no label in URI pattern %q", name, rt.operation, rt.uri)
+		}
+	}
+	return nil
+}
+
+// match matches a route to the percent-decoded segments of a request
+// path and its query string, and returns the values of its labels.
+func (rt *route) match(parts []string, query url.Values) (map[string]string, bool) {
+	for _, q := range rt.query {
+		values, ok := query[q.key]
+		if !ok {
+			return nil, false
+		}
+		if q.hasValue && !contains(values, q.value) {
+			return nil, false
+		}
+	}
+
+	g := len(rt.segments)
+	for i, seg := range rt.segments {
+		if seg.greedy {
+			g = i
+		}
+	}
+	suffix := 0
+	if g < len(rt.segments) {
+		suffix = len(rt.segments) - g - 1
+		if len(parts) < len(rt.segments) {
+			return nil, false
+		}
+	} else if len(parts) != len(rt.segments) {
+		return nil, false
+	}
+
+	labels := make(map[string]string)
+	matchSegment := func(seg segment, part string) bool {
+		if seg.label == "" {
+			return seg.literal == part
+		}
+		labels[seg.label] = part
+		return part != ""
+	}
+	for i := 0; i < g; i++ {
+		if !matchSegment(rt.segments[i], parts[i]) {
+			return nil, false
+		}
+	}
+	for i := 0; i < suffix; i++ {
+		if !matchSegment(rt.segments[len(rt.segments)-1-i], parts[len(parts)-1-i]) {
+			return nil, false
+		}
+	}
+	if g < len(rt.segments) {
+		value := strings.Join(parts[g:len(parts)-suffix], "/")
+		if value == "" {
+			return nil, false
+		}
+		labels[rt.segments[g].label] = value
+	}
+	return labels, true
+}
+
+// overlaps reports whether some request could match both routes,
+// ignoring their methods.
+func (rt *route) overlaps(other *route) bool {
+	for _, q := range rt.query {
+		for _, p := range other.query {
+			if q.key == p.key && q.hasValue && p.hasValue && q.value != p.value {
+				return false
+			}
+		}
+	}
+
+	a, b := rt.segments, other.segments
+	type state struct {
+		i, j         int
+		usedA, usedB bool
+	}
+	seen := make(map[state]bool)
+	var visit func(s state) bool
+	visit = func(s state) bool {
+		if s.i == len(a) && s.j == len(b) {
+			return true
+		}
+		if s.i == len(a) || s.j == len(b) || seen[s] {
+			return false
+		}
+		seen[s] = true
+		x, y := a[s.i], b[s.j]
+		if x.label == "" && y.label == "" && x.literal != y.literal ||
+			x.label == "" && x.literal == "" || y.label == "" && y.literal == "" {
+			return false
+		}
+		// Both consume the same segment, after which a greedy label may
+		// consume more segments or end.
+		nextA := []state{{i: s.i + 1}}
+		if x.greedy {
+			nextA = append(nextA, state{i: s.i, usedA: true})
+		}
+		for _, na := range nextA {
+			if visit(state{i: na.i, j: s.j + 1, usedA: na.usedA}) {
+				return true
+			}
+			if y.greedy && visit(state{i: na.i, j: s.j, usedA: na.usedA, usedB: true}) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(state{})
+}
+
+// covers reports whether a route is at least as specific as another
+// which overlaps it. Path segments are compared from the start, and then
+// from the end, until a greedy label is reached in either route.
+func (rt *route) covers(other *route) bool {
+	a, b := rt.segments, other.segments
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].rank() < b[i].rank() {
+			return false
+		}
+		if a[i].greedy || b[i].greedy {
+			break
+		}
+	}
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if a[i].rank() < b[j].rank() {
+			return false
+		}
+		if a[i].greedy || b[j].greedy {
+			break
+		}
+	}
+	for _, q := range other.query {
+		found := false
+		for _, p := range rt.query {
+			if p.key == q.key && (!q.hasValue || p.hasValue && p.value == q.value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPath splits an escaped request path into percent-decoded
+// segments.
+func splitPath(path string) ([]string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil, true
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		var err error
+		if parts[i], err = url.PathUnescape(part); err != nil {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
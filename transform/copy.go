@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// copyModel returns a copy of m whose shapes map and metadata map can be
+// modified without affecting m. The shapes themselves are not copied.
+func copyModel(m ast.Model) ast.Model {
+	m2 := m
+	if m.Metadata != nil {
+		m2.Metadata = make(map[string]ast.InterfaceNode, len(m.Metadata))
+		for k, v := range m.Metadata {
+			m2.Metadata[k] = v
+		}
+	}
+	if m.Shapes != nil {
+		m2.Shapes = make(map[ast.AbsShapeID]ast.Shape, len(m.Shapes))
+		for id, s := range m.Shapes {
+			m2.Shapes[id] = s
+		}
+	}
+	return m2
+}
+
+// copyShape returns a copy of s whose members, traits maps, and
+// service, resource and operation bindings can be modified without
+// affecting s. Trait values are shared with s, since they are replaced
+// rather than modified.
+func copyShape(s ast.Shape) ast.Shape {
+	s2 := s
+	s2.Traits = copyTraits(s.Traits)
+	s2.Key = copyMember(s.Key)
+	s2.Value = copyMember(s.Value)
+	if s.Members != nil {
+		s2.Members = make(map[string]ast.Member, len(s.Members))
+		for name, member := range s.Members {
+			member.Traits = copyTraits(member.Traits)
+			s2.Members[name] = member
+		}
+	}
+
+	if s.Service != nil {
+		svc := *s.Service
+		svc.Operations = copyRefs(svc.Operations)
+		svc.Resources = copyRefs(svc.Resources)
+		svc.Errors = copyRefs(svc.Errors)
+		if svc.Rename != nil {
+			svc.Rename = make(map[ast.AbsShapeID]ast.StringNode, len(s.Service.Rename))
+			for id, name := range s.Service.Rename {
+				svc.Rename[id] = name
+			}
+		}
+		s2.Service = &svc
+	}
+
+	if s.Resource != nil {
+		res := *s.Resource
+		if res.Identifiers != nil {
+			res.Identifiers = make(map[string]ast.AbsShapeIDNode, len(s.Resource.Identifiers))
+			for name, id := range s.Resource.Identifiers {
+				res.Identifiers[name] = id
+			}
+		}
+		res.Create = copyRef(res.Create)
+		res.Put = copyRef(res.Put)
+		res.Read = copyRef(res.Read)
+		res.Update = copyRef(res.Update)
+		res.Delete = copyRef(res.Delete)
+		res.List = copyRef(res.List)
+		res.Operations = copyRefs(res.Operations)
+		res.CollectionOperations = copyRefs(res.CollectionOperations)
+		res.Resources = copyRefs(res.Resources)
+		s2.Resource = &res
+	}
+
+	if s.Operation != nil {
+		op := *s.Operation
+		op.Input = copyRef(op.Input)
+		op.Output = copyRef(op.Output)
+		op.Errors = copyRefs(op.Errors)
+		s2.Operation = &op
+	}
+
+	return s2
+}
+
+func copyMember(m *ast.Member) *ast.Member {
+	if m == nil {
+		return nil
+	}
+	m2 := *m
+	m2.Traits = copyTraits(m.Traits)
+	return &m2
+}
+
+func copyTraits(t ast.Traits) ast.Traits {
+	if t == nil {
+		return nil
+	}
+	t2 := make(ast.Traits, len(t))
+	for id, v := range t {
+		t2[id] = v
+	}
+	return t2
+}
+
+func copyRef(ref *ast.AbsShapeIDNode) *ast.AbsShapeIDNode {
+	if ref == nil {
+		return nil
+	}
+	ref2 := *ref
+	return &ref2
+}
+
+func copyRefs(refs []ast.AbsShapeIDNode) []ast.AbsShapeIDNode {
+	if refs == nil {
+		return nil
+	}
+	refs2 := make([]ast.AbsShapeIDNode, len(refs))
+	copy(refs2, refs)
+	return refs2
+}
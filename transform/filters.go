@@ -0,0 +1,170 @@
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// IncludeTags returns a copy of m containing only the shapes tagged,
+// using the tags trait, with at least one of the given tags, and the
+// shapes they refer to, as if the tagged shapes were the roots given to
+// Prune. Members which have their own tags trait are kept only if they
+// are tagged with at least one of the given tags, while untagged
+// members are kept.
+func IncludeTags(m ast.Model, tags ...string) ast.Model {
+	m = removeWhere(m, func(t ast.Traits, member bool) bool {
+		_, ok := t[ast.TagsTraitID]
+		return ok && member && !hasTag(t, tags)
+	})
+	var roots []ast.AbsShapeID
+	for id, s := range m.Shapes {
+		if hasTag(s.Traits, tags) {
+			roots = append(roots, id)
+		}
+	}
+	m, _ = Prune(m, roots...)
+	return m
+}
+
+// ExcludeTags returns a copy of m without the shapes and members tagged,
+// using the tags trait, with any of the given tags. Shapes and members
+// are removed as if by Remove.
+func ExcludeTags(m ast.Model, tags ...string) ast.Model {
+	return removeWhere(m, func(t ast.Traits, _ bool) bool {
+		return hasTag(t, tags)
+	})
+}
+
+// ExcludeTraits returns a copy of m without the shapes and members to
+// which any of the given traits is applied. Shapes and members are
+// removed as if by Remove.
+//
+// For example, to remove the internal-only and unstable parts of a
+// model before publishing it, exclude ast.InternalTraitID and
+// ast.UnstableTraitID, and to remove the shapes which are not visible
+// outside their namespace, exclude ast.PrivateTraitID.
+func ExcludeTraits(m ast.Model, ids ...ast.AbsShapeID) ast.Model {
+	return removeWhere(m, func(t ast.Traits, _ bool) bool {
+		for _, id := range ids {
+			if _, ok := t[id]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExcludeDeprecated returns a copy of m without the shapes and members
+// whose deprecated trait has a since value earlier than version. If
+// version is empty, all deprecated shapes and members are removed.
+// Otherwise, shapes and members deprecated without a since value are
+// kept. Versions are compared as described for CompareVersions. Shapes
+// and members are removed as if by Remove.
+func ExcludeDeprecated(m ast.Model, version string) ast.Model {
+	return removeWhere(m, func(t ast.Traits, _ bool) bool {
+		v, ok := t[ast.DeprecatedTraitID]
+		if !ok {
+			return false
+		}
+		if version == "" {
+			return true
+		}
+		d, ok := v.(*ast.DeprecatedTrait)
+		return ok && d.Since != nil && CompareVersions(d.Since.Value, version) < 0
+	})
+}
+
+// ExcludeSince returns a copy of m without the shapes and members whose
+// since trait has a value later than version, that is, those which were
+// added to the model after version. Versions are compared as described
+// for CompareVersions. Shapes and members are removed as if by Remove.
+func ExcludeSince(m ast.Model, version string) ast.Model {
+	return removeWhere(m, func(t ast.Traits, _ bool) bool {
+		v, ok := t[ast.SinceTraitID].(*ast.StringNode)
+		return ok && CompareVersions(v.Value, version) > 0
+	})
+}
+
+// CompareVersions compares two version strings, such as "1.10.2" or
+// "2020-01-31", returning -1 if a is earlier than b, 0 if they are
+// equal, and +1 if a is later than b.
+//
+// The versions are split into segments at each '.' or '-'. Segments
+// are compared in order, numerically if both are decimal numbers and
+// lexically otherwise. A version which is a prefix of another, such as
+// "1.2" of "1.2.1", is the earlier version.
+func CompareVersions(a, b string) int {
+	split := func(r rune) bool { return r == '.' || r == '-' }
+	as, bs := strings.FieldsFunc(a, split), strings.FieldsFunc(b, split)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil && an < bn:
+			return -1
+		case aErr == nil && bErr == nil && an > bn:
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// removeWhere removes from m the shapes whose traits satisfy remove,
+// and the members, including list, set and map members, whose own
+// traits satisfy it.
+func removeWhere(m ast.Model, remove func(t ast.Traits, member bool) bool) ast.Model {
+	var ids []ast.AbsShapeID
+	for id, s := range m.Shapes {
+		if remove(s.Traits, false) {
+			ids = append(ids, id)
+			continue
+		}
+		if s.Key != nil && remove(s.Key.Traits, true) {
+			ids = append(ids, id+"$key")
+		}
+		if s.Value != nil && remove(s.Value.Traits, true) {
+			ids = append(ids, memberID(id, s))
+		}
+		for name, member := range s.Members {
+			if remove(member.Traits, true) {
+				ids = append(ids, id+"$"+ast.AbsShapeID(name))
+			}
+		}
+	}
+	return Remove(m, ids...)
+}
+
+// memberID returns the ID of the Value member of the list, set or map
+// shape s having ID id.
+func memberID(id ast.AbsShapeID, s ast.Shape) ast.AbsShapeID {
+	if s.Type == ast.MapType {
+		return id + "$value"
+	}
+	return id + "$member"
+}
+
+func hasTag(t ast.Traits, tags []string) bool {
+	v, ok := t[ast.TagsTraitID].(*ast.TagsTrait)
+	if !ok {
+		return false
+	}
+	for _, item := range v.Items {
+		for _, tag := range tags {
+			if item.Value == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testFilterModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Public": {
+			"type": "structure",
+			"members": {
+				"a": {"target": "foo#String"},
+				"b": {"target": "foo#String", "traits": {"smithy.api#tags": ["internal"]}},
+				"c": {"target": "foo#String", "traits": {"smithy.api#tags": ["public"]}},
+				"d": {"target": "foo#String", "traits": {"smithy.api#internal": {}}},
+				"e": {"target": "foo#String", "traits": {"smithy.api#deprecated": {"since": "1.2"}}},
+				"f": {"target": "foo#String", "traits": {"smithy.api#since": "1.10"}},
+				"g": {"target": "foo#Unstable"}
+			},
+			"traits": {"smithy.api#tags": ["public"]}
+		},
+		"foo#Internal": {
+			"type": "structure",
+			"members": {},
+			"traits": {"smithy.api#tags": ["internal", "other"], "smithy.api#internal": {}}
+		},
+		"foo#Unstable": {
+			"type": "list",
+			"member": {"target": "foo#String", "traits": {"smithy.api#unstable": {}}}
+		},
+		"foo#Old": {
+			"type": "string",
+			"traits": {"smithy.api#deprecated": {"since": "2019-12-31"}}
+		},
+		"foo#Deprecated": {
+			"type": "string",
+			"traits": {"smithy.api#deprecated": {}}
+		},
+		"foo#Private": {
+			"type": "string",
+			"traits": {"smithy.api#private": {}, "smithy.api#since": "2.0"}
+		},
+		"foo#String": {
+			"type": "string"
+		}
+	}
+}`
+
+func TestFilters(t *testing.T) {
+	testCases := []struct {
+		name      string
+		transform func(m ast.Model) ast.Model
+		ids       []ast.AbsShapeID
+		members   []string
+	}{
+		{
+			name:      "include tags",
+			transform: func(m ast.Model) ast.Model { return IncludeTags(m, "public", "other") },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Internal", "foo#Unstable", "foo#String"},
+			members:   []string{"a", "c", "d", "e", "f", "g"},
+		},
+		{
+			name:      "exclude tags",
+			transform: func(m ast.Model) ast.Model { return ExcludeTags(m, "internal") },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Unstable", "foo#Old", "foo#Deprecated", "foo#Private", "foo#String"},
+			members:   []string{"a", "c", "d", "e", "f", "g"},
+		},
+		{
+			name: "exclude internal and unstable",
+			transform: func(m ast.Model) ast.Model {
+				return ExcludeTraits(m, ast.InternalTraitID, ast.UnstableTraitID)
+			},
+			ids:     []ast.AbsShapeID{"foo#Public", "foo#Old", "foo#Deprecated", "foo#Private", "foo#String"},
+			members: []string{"a", "b", "c", "e", "f"},
+		},
+		{
+			name:      "exclude private",
+			transform: func(m ast.Model) ast.Model { return ExcludeTraits(m, ast.PrivateTraitID) },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Internal", "foo#Unstable", "foo#Old", "foo#Deprecated", "foo#String"},
+			members:   []string{"a", "b", "c", "d", "e", "f", "g"},
+		},
+		{
+			name:      "exclude deprecated before version",
+			transform: func(m ast.Model) ast.Model { return ExcludeDeprecated(m, "2020-01-01") },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Internal", "foo#Unstable", "foo#Deprecated", "foo#Private", "foo#String"},
+			members:   []string{"a", "b", "c", "d", "f", "g"},
+		},
+		{
+			name:      "exclude all deprecated",
+			transform: func(m ast.Model) ast.Model { return ExcludeDeprecated(m, "") },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Internal", "foo#Unstable", "foo#Private", "foo#String"},
+			members:   []string{"a", "b", "c", "d", "f", "g"},
+		},
+		{
+			name:      "exclude since",
+			transform: func(m ast.Model) ast.Model { return ExcludeSince(m, "1.9") },
+			ids:       []ast.AbsShapeID{"foo#Public", "foo#Internal", "foo#Unstable", "foo#Old", "foo#Deprecated", "foo#String"},
+			members:   []string{"a", "b", "c", "d", "e", "g"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := testmodel.Read(t, testFilterModel)
+			before := snapshot(t, m)
+
+			m2 := testCase.transform(m)
+
+			assert.Equal(t, before, snapshot(t, m))
+			assert.ElementsMatch(t, testCase.ids, shapeIDs(m2))
+			var members []string
+			for name := range m2.Shapes["foo#Public"].Members {
+				members = append(members, name)
+			}
+			assert.ElementsMatch(t, testCase.members, members)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		cmp  int
+	}{
+		{"1.2", "1.2", 0},
+		{"1.2", "1.10", -1},
+		{"1.10", "1.2", 1},
+		{"1.2", "1.2.1", -1},
+		{"2020-01-31", "2020-02-01", -1},
+		{"1.0-beta", "1.0-alpha", 1},
+		{"v2", "v10", 1},
+		{"", "1", -1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.a+" "+testCase.b, func(t *testing.T) {
+			assert.Equal(t, testCase.cmp, CompareVersions(testCase.a, testCase.b))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Prune returns a copy of m containing only the shapes reachable from
+// the given root shapes, such as services or operations. Root shapes
+// are always kept.
+//
+// A shape is reachable if it is a root, or if a reachable shape refers
+// to it through a member target; an operation, resource or error bound
+// to a service or resource; a resource identifier or lifecycle
+// operation; or an operation input, output or error. The definition
+// shape of a trait applied to a reachable shape or member is reachable
+// too, so trait definitions still in use are kept and the rest are
+// removed. References held in trait values, such as idRef strings, and
+// the keys of service rename maps do not make a shape reachable.
+//
+// Prune returns an error if a root shape is not in m.
+func Prune(m ast.Model, roots ...ast.AbsShapeID) (ast.Model, error) {
+	reachable := make(map[ast.AbsShapeID]bool, len(roots))
+	queue := make([]ast.AbsShapeID, 0, len(roots))
+	for _, id := range roots {
+		if _, ok := m.Shapes[id]; !ok {
+			return ast.Model{}, newErrorf("root shape %s not found", id)
+		}
+		if !reachable[id] {
+			reachable[id] = true
+			queue = append(queue, id)
+		}
+	}
+
+	reach := func(id ast.AbsShapeID) {
+		if _, ok := m.Shapes[id]; ok && !reachable[id] {
+			reachable[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		s := copyShape(m.Shapes[queue[0]])
+		queue = queue[1:]
+		if s.Service != nil {
+			s.Service.Rename = nil
+		}
+		visitRefs(&s, func(ref *ast.AbsShapeIDNode) bool {
+			reach(ref.Value)
+			return true
+		})
+		for _, t := range shapeTraits(&s) {
+			for id := range t {
+				reach(id)
+			}
+		}
+	}
+
+	return Filter(m, func(id ast.AbsShapeID, _ ast.Shape) bool {
+		return reachable[id]
+	}), nil
+}
+
+// shapeTraits returns the traits applied to s and to each of its
+// members.
+func shapeTraits(s *ast.Shape) []ast.Traits {
+	traits := []ast.Traits{s.Traits}
+	if s.Key != nil {
+		traits = append(traits, s.Key.Traits)
+	}
+	if s.Value != nil {
+		traits = append(traits, s.Value.Traits)
+	}
+	for _, member := range s.Members {
+		traits = append(traits, member.Traits)
+	}
+	return traits
+}
@@ -0,0 +1,88 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+func TestPrune(t *testing.T) {
+	testCases := []struct {
+		name  string
+		roots []ast.AbsShapeID
+		ids   []ast.AbsShapeID
+	}{
+		{
+			name:  "service",
+			roots: []ast.AbsShapeID{"foo#Service"},
+			ids: []ast.AbsShapeID{
+				"foo#Service", "foo#Res", "foo#GetA", "foo#PutA", "foo#GetAInput", "foo#A",
+				"foo#List", "foo#Map", "foo#Err", "foo#Id", "foo#ref",
+			},
+		},
+		{
+			name:  "operation",
+			roots: []ast.AbsShapeID{"foo#PutA"},
+			ids:   []ast.AbsShapeID{"foo#PutA", "foo#A", "foo#List", "foo#Map", "foo#Err", "foo#Id", "foo#ref"},
+		},
+		{
+			name:  "shapes",
+			roots: []ast.AbsShapeID{"foo#List", "foo#GetAInput", "foo#List"},
+			ids:   []ast.AbsShapeID{"foo#List", "foo#GetAInput", "foo#Err", "foo#Id"},
+		},
+		{
+			name: "none",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := testmodel.Read(t, testModel)
+			before := snapshot(t, m)
+
+			m2, err := Prune(m, testCase.roots...)
+
+			require.NoError(t, err)
+			assert.Equal(t, before, snapshot(t, m))
+			assert.ElementsMatch(t, testCase.ids, shapeIDs(m2))
+		})
+	}
+
+	t.Run("unused trait definition", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+		m = Replace(m, map[ast.AbsShapeID]ast.Shape{
+			"foo#A": {Type: ast.StructureType, Members: map[string]ast.Member{}},
+		})
+
+		m2, err := Prune(m, "foo#Service")
+
+		require.NoError(t, err)
+		assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#ref"))
+		assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#List"))
+		assert.Contains(t, m2.Shapes, ast.AbsShapeID("foo#Err"))
+	})
+
+	t.Run("rename entries", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2, err := Prune(m, "foo#Service", "foo#Err")
+
+		require.NoError(t, err)
+		m3, err := Prune(Remove(m2, "foo#Res", "foo#GetA", "foo#PutA"), "foo#Service")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []ast.AbsShapeID{"foo#Service", "foo#Err"}, shapeIDs(m3))
+		assert.Equal(t, map[ast.AbsShapeID]ast.StringNode{"foo#Err": {Value: "Error"}}, m3.Shapes["foo#Service"].Service.Rename)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		_, err := Prune(m, "foo#Nope")
+
+		assert.EqualError(t, err, "transform: root shape foo#Nope not found")
+	})
+}
@@ -0,0 +1,97 @@
+package transform
+
+import (
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// A refFunc is called for each shape ID a shape refers to. It may
+// change the referring node's value in place, and returns false if the
+// reference should be removed.
+type refFunc func(ref *ast.AbsShapeIDNode) bool
+
+// visitRefs calls f for each shape ID referred to by s: member targets
+// and the shape IDs held by services, resources and operations. It does
+// not visit trait keys or trait values.
+//
+// References for which f returns false are removed from s. Removing a
+// structure or union member, a service, resource or operation binding,
+// a resource identifier or a service rename entry leaves s valid.
+// Removing the member of a list, set or map leaves s without a target,
+// and in that case visitRefs returns false to indicate that s itself
+// should be removed.
+//
+// Since visitRefs modifies s, s must be a copy made with copyShape.
+func visitRefs(s *ast.Shape, f refFunc) bool {
+	valid := true
+
+	if s.Key != nil && !f(&s.Key.Target) {
+		valid = false
+	}
+	if s.Value != nil && !f(&s.Value.Target) {
+		valid = false
+	}
+	for name, member := range s.Members {
+		if !f(&member.Target) {
+			delete(s.Members, name)
+		} else {
+			s.Members[name] = member
+		}
+	}
+
+	if svc := s.Service; svc != nil {
+		svc.Operations = visitRefSlice(svc.Operations, f)
+		svc.Resources = visitRefSlice(svc.Resources, f)
+		svc.Errors = visitRefSlice(svc.Errors, f)
+		if len(svc.Rename) > 0 {
+			rename := make(map[ast.AbsShapeID]ast.StringNode, len(svc.Rename))
+			for id, name := range svc.Rename {
+				ref := ast.AbsShapeIDNode{Value: id}
+				if f(&ref) {
+					rename[ref.Value] = name
+				}
+			}
+			svc.Rename = rename
+		}
+	}
+
+	if res := s.Resource; res != nil {
+		for name, id := range res.Identifiers {
+			if !f(&id) {
+				delete(res.Identifiers, name)
+			} else {
+				res.Identifiers[name] = id
+			}
+		}
+		for _, p := range []**ast.AbsShapeIDNode{&res.Create, &res.Put, &res.Read, &res.Update, &res.Delete, &res.List} {
+			*p = visitRefPtr(*p, f)
+		}
+		res.Operations = visitRefSlice(res.Operations, f)
+		res.CollectionOperations = visitRefSlice(res.CollectionOperations, f)
+		res.Resources = visitRefSlice(res.Resources, f)
+	}
+
+	if op := s.Operation; op != nil {
+		op.Input = visitRefPtr(op.Input, f)
+		op.Output = visitRefPtr(op.Output, f)
+		op.Errors = visitRefSlice(op.Errors, f)
+	}
+
+	return valid
+}
+
+func visitRefSlice(refs []ast.AbsShapeIDNode, f refFunc) []ast.AbsShapeIDNode {
+	kept := refs[:0]
+	for i := range refs {
+		if f(&refs[i]) {
+			kept = append(kept, refs[i])
+		}
+	}
+	return kept
+}
+
+func visitRefPtr(ref *ast.AbsShapeIDNode, f refFunc) *ast.AbsShapeIDNode {
+	if ref != nil && !f(ref) {
+		return nil
+	}
+	return ref
+}
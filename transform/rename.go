@@ -0,0 +1,406 @@
+package transform
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Rename returns a copy of m in which each shape whose ID is a key in
+// renames has been given the corresponding new ID, and every reference
+// to a renamed shape refers to the new ID.
+//
+// The references updated are member targets; the shape IDs held by
+// services, resources and operations, including the keys of service
+// rename maps; trait keys, when a trait definition shape is renamed;
+// the values of traits whose definition, or the definition of any
+// nested member, has the idRef trait; and the resource and service
+// values of the references trait. References to members of a renamed
+// shape, such as "foo#A$b", are updated too.
+//
+// Rename returns an error if a shape to be renamed does not exist, if
+// an old or new ID is not a valid shape ID without a member, or if a
+// new ID collides with a shape which is not itself being renamed or
+// with the new ID of another renamed shape.
+func Rename(m ast.Model, renames map[ast.AbsShapeID]ast.AbsShapeID) (ast.Model, error) {
+	olds := make([]ast.AbsShapeID, 0, len(renames))
+	for old := range renames {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool { return olds[i] < olds[j] })
+
+	r := renamer{m: m, renames: make(map[ast.AbsShapeID]ast.AbsShapeID, len(renames))}
+	news := make(map[ast.AbsShapeID]ast.AbsShapeID, len(renames))
+	for _, old := range olds {
+		to := renames[old]
+		if old == to {
+			continue
+		}
+		if !validID(old) {
+			return ast.Model{}, newErrorf("cannot rename %s: invalid shape ID", old)
+		}
+		if !validID(to) {
+			return ast.Model{}, newErrorf("cannot rename %s to %s: invalid shape ID", old, to)
+		}
+		if _, ok := m.Shapes[old]; !ok {
+			return ast.Model{}, newErrorf("cannot rename %s: shape not found", old)
+		}
+		if other, ok := news[to]; ok {
+			return ast.Model{}, newErrorf("cannot rename %s to %s: %s is also renamed to %s", old, to, other, to)
+		}
+		if _, ok := m.Shapes[to]; ok && (renames[to] == "" || renames[to] == to) {
+			return ast.Model{}, newErrorf("cannot rename %s to %s: shape already exists", old, to)
+		}
+		news[to] = old
+		r.renames[old] = to
+	}
+
+	if len(r.renames) == 0 {
+		return copyModel(m), nil
+	}
+
+	m2 := copyModel(m)
+	m2.Shapes = make(map[ast.AbsShapeID]ast.Shape, len(m.Shapes))
+	for id, s := range m.Shapes {
+		s2 := copyShape(s)
+		changed := false
+		visitRefs(&s2, func(ref *ast.AbsShapeIDNode) bool {
+			if to, ok := r.rename(ref.Value); ok {
+				ref.Value = to
+				changed = true
+			}
+			return true
+		})
+		changed = r.renameTraits(&s2.Traits) || changed
+		if s2.Key != nil {
+			changed = r.renameTraits(&s2.Key.Traits) || changed
+		}
+		if s2.Value != nil {
+			changed = r.renameTraits(&s2.Value.Traits) || changed
+		}
+		for name, member := range s2.Members {
+			if r.renameTraits(&member.Traits) {
+				s2.Members[name] = member
+				changed = true
+			}
+		}
+		if !changed {
+			s2 = s
+		}
+		if to, ok := r.renames[id]; ok {
+			id = to
+		}
+		m2.Shapes[id] = s2
+	}
+
+	return m2, nil
+}
+
+// validID reports whether id is an absolute shape ID without a member.
+func validID(id ast.AbsShapeID) bool {
+	s := string(id)
+	i := strings.IndexByte(s, '#')
+	return i > 0 && i < len(s)-1 && strings.IndexByte(s, '$') < 0 && strings.IndexByte(s[i+1:], '#') < 0
+}
+
+type renamer struct {
+	m       ast.Model
+	renames map[ast.AbsShapeID]ast.AbsShapeID
+}
+
+// rename returns the new ID for id, which may be the ID of a shape or
+// of a member, and reports whether id is renamed.
+func (r *renamer) rename(id ast.AbsShapeID) (ast.AbsShapeID, bool) {
+	if to, ok := r.renames[id]; ok {
+		return to, true
+	}
+	if member := id.Member(); member != "" {
+		if to, ok := r.renames[id[:len(id)-len(member)-1]]; ok {
+			return to + "$" + ast.AbsShapeID(member), true
+		}
+	}
+	return id, false
+}
+
+// renameTraits renames the keys and idRef values of the traits in t,
+// replacing t with a new map if anything changed, and reports whether
+// anything changed.
+func (r *renamer) renameTraits(t *ast.Traits) bool {
+	var t2 ast.Traits
+	for id, v := range *t {
+		to, renamed := r.rename(id)
+		v2, changed := r.traitValue(id, v)
+		if !renamed && !changed {
+			continue
+		}
+		if t2 == nil {
+			t2 = copyTraits(*t)
+		}
+		delete(t2, id)
+		t2[to] = v2
+	}
+	if t2 == nil {
+		return false
+	}
+	*t = t2
+	return true
+}
+
+// traitValue returns a copy of the value v of the trait with ID id in
+// which shape IDs held in idRef values are renamed, and reports whether
+// anything changed. If nothing changed, v is returned.
+func (r *renamer) traitValue(id ast.AbsShapeID, v ast.Node) (ast.Node, bool) {
+	switch x := v.(type) {
+	case *ast.InterfaceNode:
+		if value, changed := r.value(id, nil, x.Value); changed {
+			x2 := *x
+			x2.Value = value
+			return &x2, true
+		}
+	case *ast.StringNode:
+		if value, changed := r.value(id, nil, x.Value); changed {
+			x2 := *x
+			x2.Value = value.(string)
+			return &x2, true
+		}
+	case *ast.ReferencesTrait:
+		var x2 *ast.ReferencesTrait
+		for i, item := range x.Items {
+			changed := false
+			if item.Service != nil {
+				if to, ok := r.rename(item.Service.Value); ok {
+					item.Service = copyRef(item.Service)
+					item.Service.Value = to
+					changed = true
+				}
+			}
+			if to, ok := r.rename(item.Resource.Value); ok {
+				item.Resource.Value = to
+				changed = true
+			}
+			if changed {
+				if x2 == nil {
+					x2 = &ast.ReferencesTrait{Items: make([]ast.ReferencesTraitItem, len(x.Items))}
+					x2.SetLocation(x.Location())
+					copy(x2.Items, x.Items)
+				}
+				x2.Items[i] = item
+			}
+		}
+		if x2 != nil {
+			return x2, true
+		}
+	case *ast.HTTPRequestTestsTrait:
+		var x2 *ast.HTTPRequestTestsTrait
+		for i, item := range x.Items {
+			changed := r.renameRef(&item.Protocol)
+			changed = r.renameOptionalRef(&item.AuthScheme) || changed
+			changed = r.renameOptionalRef(&item.VendorParamsShape) || changed
+			if changed {
+				if x2 == nil {
+					x2 = &ast.HTTPRequestTestsTrait{Items: make([]ast.HTTPRequestTestsTraitItem, len(x.Items))}
+					x2.SetLocation(x.Location())
+					copy(x2.Items, x.Items)
+				}
+				x2.Items[i] = item
+			}
+		}
+		if x2 != nil {
+			return x2, true
+		}
+	case *ast.HTTPResponseTestsTrait:
+		var x2 *ast.HTTPResponseTestsTrait
+		for i, item := range x.Items {
+			changed := r.renameRef(&item.Protocol)
+			changed = r.renameOptionalRef(&item.AuthScheme) || changed
+			changed = r.renameOptionalRef(&item.VendorParamsShape) || changed
+			if changed {
+				if x2 == nil {
+					x2 = &ast.HTTPResponseTestsTrait{Items: make([]ast.HTTPResponseTestsTraitItem, len(x.Items))}
+					x2.SetLocation(x.Location())
+					copy(x2.Items, x.Items)
+				}
+				x2.Items[i] = item
+			}
+		}
+		if x2 != nil {
+			return x2, true
+		}
+	case *ast.SmokeTestsTrait:
+		var x2 *ast.SmokeTestsTrait
+		for i, item := range x.Items {
+			changed := r.renameOptionalRef(&item.VendorParamsShape)
+			if failure := item.Expect.Failure; failure != nil && failure.ErrorID != nil {
+				if to, ok := r.rename(failure.ErrorID.Value); ok {
+					failure2 := *failure
+					failure2.ErrorID = copyRef(failure.ErrorID)
+					failure2.ErrorID.Value = to
+					item.Expect.Failure = &failure2
+					changed = true
+				}
+			}
+			if changed {
+				if x2 == nil {
+					x2 = &ast.SmokeTestsTrait{Items: make([]ast.SmokeTestsTraitItem, len(x.Items))}
+					x2.SetLocation(x.Location())
+					copy(x2.Items, x.Items)
+				}
+				x2.Items[i] = item
+			}
+		}
+		if x2 != nil {
+			return x2, true
+		}
+	}
+	return v, false
+}
+
+// renameRef renames the shape ID ref holds, and reports whether it
+// changed.
+func (r *renamer) renameRef(ref *ast.AbsShapeIDNode) bool {
+	to, ok := r.rename(ref.Value)
+	if ok {
+		ref.Value = to
+	}
+	return ok
+}
+
+// renameOptionalRef renames the shape ID *ref holds, if *ref is not nil,
+// replacing *ref with a copy if it changed, and reports whether it
+// changed.
+func (r *renamer) renameOptionalRef(ref **ast.AbsShapeIDNode) bool {
+	if *ref == nil {
+		return false
+	}
+	to, ok := r.rename((*ref).Value)
+	if ok {
+		*ref = copyRef(*ref)
+		(*ref).Value = to
+	}
+	return ok
+}
+
+// value returns a copy of the node value v, whose shape is the shape
+// with ID id, in which idRef strings are renamed. The traits of the
+// member whose value v is, if any, are given by memberTraits. If
+// nothing changed, value returns v.
+func (r *renamer) value(id ast.AbsShapeID, memberTraits ast.Traits, v interface{}) (interface{}, bool) {
+	s, ok := r.m.Shapes[id]
+	_, idRef := memberTraits[ast.IDRefTraitID]
+	if _, ok2 := s.Traits[ast.IDRefTraitID]; ok2 {
+		idRef = true
+	}
+	if idRef {
+		if str, ok2 := v.(string); ok2 {
+			if to, renamed := r.rename(ast.AbsShapeID(str)); renamed {
+				return string(to), true
+			}
+		}
+		return v, false
+	}
+	if !ok {
+		return v, false
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok2 := v.(map[string]interface{})
+		if !ok2 {
+			break
+		}
+		var obj2 map[string]interface{}
+		for name, value := range obj {
+			member, ok3 := s.Members[name]
+			if !ok3 {
+				continue
+			}
+			if value2, changed := r.value(member.Target.Value, member.Traits, value); changed {
+				if obj2 == nil {
+					obj2 = copyObject(obj)
+				}
+				obj2[name] = value2
+			}
+		}
+		if obj2 != nil {
+			return obj2, true
+		}
+	case ast.ListType, ast.SetType:
+		arr, ok2 := v.([]interface{})
+		if !ok2 || s.Value == nil {
+			break
+		}
+		var arr2 []interface{}
+		for i, value := range arr {
+			if value2, changed := r.value(s.Value.Target.Value, s.Value.Traits, value); changed {
+				if arr2 == nil {
+					arr2 = make([]interface{}, len(arr))
+					copy(arr2, arr)
+				}
+				arr2[i] = value2
+			}
+		}
+		if arr2 != nil {
+			return arr2, true
+		}
+	case ast.MapType:
+		obj, ok2 := v.(map[string]interface{})
+		if !ok2 || s.Key == nil || s.Value == nil {
+			break
+		}
+		var obj2 map[string]interface{}
+		for key, value := range obj {
+			key2, keyChanged := r.value(s.Key.Target.Value, s.Key.Traits, key)
+			value2, valueChanged := r.value(s.Value.Target.Value, s.Value.Traits, value)
+			if keyChanged || valueChanged {
+				if obj2 == nil {
+					obj2 = copyObject(obj)
+				}
+				delete(obj2, key)
+				obj2[key2.(string)] = value2
+			}
+		}
+		if obj2 != nil {
+			return obj2, true
+		}
+	}
+	return v, false
+}
+
+func copyObject(obj map[string]interface{}) map[string]interface{} {
+	obj2 := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		obj2[k] = v
+	}
+	return obj2
+}
+
+// RenameNamespaces returns a copy of m in which every shape in a
+// namespace which is a key in renames is moved to the corresponding new
+// namespace, as if by Rename. For example, renaming "example.weather"
+// to "com.acme.vendored.weather" renames "example.weather#City" to
+// "com.acme.vendored.weather#City". Namespaces must match exactly, so
+// the shapes in "example.weather.v2" are not moved.
+//
+// RenameNamespaces returns an error if a namespace is empty or contains
+// '#' or '$', or if a renamed shape would collide with a shape which is
+// not itself being moved.
+func RenameNamespaces(m ast.Model, renames map[string]string) (ast.Model, error) {
+	for from, to := range renames {
+		if !validNamespace(from) || !validNamespace(to) {
+			return ast.Model{}, newErrorf("cannot rename namespace %q to %q: invalid namespace", from, to)
+		}
+	}
+
+	ids := make(map[ast.AbsShapeID]ast.AbsShapeID)
+	for id := range m.Shapes {
+		if to, ok := renames[id.Namespace()]; ok {
+			ids[id] = ast.AbsShapeID(to + "#" + id.Name())
+		}
+	}
+	return Rename(m, ids)
+}
+
+func validNamespace(ns string) bool {
+	return ns != "" && !strings.ContainsAny(ns, "#$")
+}
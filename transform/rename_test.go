@@ -0,0 +1,294 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+func TestRename(t *testing.T) {
+	t.Run("references", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+		before := snapshot(t, m)
+
+		m2, err := Rename(m, map[ast.AbsShapeID]ast.AbsShapeID{
+			"foo#A":    "bar#B",
+			"foo#Err":  "foo#Error",
+			"foo#GetA": "foo#GetB",
+			"foo#Id":   "foo#Id",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, before, snapshot(t, m))
+		assert.Len(t, m2.Shapes, len(m.Shapes))
+		assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#A"))
+		svc := m2.Shapes["foo#Service"].Service
+		assert.Equal(t, []ast.AbsShapeID{"foo#GetB", "foo#PutA"}, refValues(svc.Operations))
+		assert.Equal(t, []ast.AbsShapeID{"foo#Error"}, refValues(svc.Errors))
+		assert.Equal(t, map[ast.AbsShapeID]ast.StringNode{"bar#B": {Value: "AA"}, "foo#Error": {Value: "Error"}}, svc.Rename)
+		assert.Equal(t, ast.AbsShapeID("foo#GetB"), m2.Shapes["foo#Res"].Resource.Read.Value)
+		op := m2.Shapes["foo#GetB"].Operation
+		assert.Equal(t, ast.AbsShapeID("bar#B"), op.Output.Value)
+		assert.Equal(t, []ast.AbsShapeID{"foo#Error"}, refValues(op.Errors))
+		assert.Equal(t, ast.AbsShapeID("bar#B"), m2.Shapes["foo#PutA"].Operation.Input.Value)
+		assert.Equal(t, ast.AbsShapeID("foo#Error"), m2.Shapes["foo#List"].Value.Target.Value)
+		b := m2.Shapes["bar#B"]
+		assert.Equal(t, "bar#B$id", b.Traits["foo#ref"].(*ast.InterfaceNode).Value)
+		assert.Equal(t, "foo#Error", b.Members["id"].Traits["foo#ref"].(*ast.InterfaceNode).Value)
+		assert.Equal(t, "foo#A$id", m.Shapes["foo#A"].Traits["foo#ref"].(*ast.InterfaceNode).Value)
+	})
+
+	t.Run("trait definition", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2, err := Rename(m, map[ast.AbsShapeID]ast.AbsShapeID{"foo#ref": "foo#reference"})
+
+		require.NoError(t, err)
+		a := m2.Shapes["foo#A"]
+		assert.Contains(t, a.Traits, ast.AbsShapeID("foo#reference"))
+		assert.NotContains(t, a.Traits, ast.AbsShapeID("foo#ref"))
+		assert.Contains(t, a.Members["id"].Traits, ast.AbsShapeID("foo#reference"))
+		assert.Contains(t, m.Shapes["foo#A"].Traits, ast.AbsShapeID("foo#ref"))
+	})
+
+	t.Run("nested idRef", func(t *testing.T) {
+		m := testmodel.Read(t, `{
+			"version": "1.0",
+			"shapes": {
+				"foo#links": {
+					"type": "structure",
+					"members": {
+						"targets": {"target": "foo#Targets"},
+						"byName": {"target": "foo#ByName"},
+						"other": {"target": "smithy.api#String"}
+					},
+					"traits": {"smithy.api#trait": {}}
+				},
+				"foo#Targets": {
+					"type": "list",
+					"member": {"target": "smithy.api#String", "traits": {"smithy.api#idRef": {}}}
+				},
+				"foo#ByName": {
+					"type": "map",
+					"key": {"target": "foo#Ref"},
+					"value": {"target": "foo#Ref"}
+				},
+				"foo#Ref": {
+					"type": "string",
+					"traits": {"smithy.api#idRef": {"failWhenMissing": true}}
+				},
+				"foo#A": {
+					"type": "string",
+					"traits": {
+						"foo#links": {
+							"targets": ["foo#A", "foo#B"],
+							"byName": {"foo#A": "foo#A"},
+							"other": "foo#A"
+						},
+						"smithy.api#references": [{"resource": "foo#A", "service": "foo#B"}]
+					}
+				},
+				"foo#B": {"type": "string"}
+			}
+		}`)
+
+		m2, err := Rename(m, map[ast.AbsShapeID]ast.AbsShapeID{"foo#A": "foo#C"})
+
+		require.NoError(t, err)
+		c := m2.Shapes["foo#C"]
+		assert.Equal(t, map[string]interface{}{
+			"targets": []interface{}{"foo#C", "foo#B"},
+			"byName":  map[string]interface{}{"foo#C": "foo#C"},
+			"other":   "foo#A",
+		}, c.Traits["foo#links"].(*ast.InterfaceNode).Value)
+		refs := c.Traits[ast.ReferencesTraitID].(*ast.ReferencesTrait)
+		assert.Equal(t, ast.AbsShapeID("foo#C"), refs.Items[0].Resource.Value)
+		assert.Equal(t, ast.AbsShapeID("foo#B"), refs.Items[0].Service.Value)
+		a := m.Shapes["foo#A"]
+		assert.Equal(t, []interface{}{"foo#A", "foo#B"}, a.Traits["foo#links"].(*ast.InterfaceNode).Value.(map[string]interface{})["targets"])
+		assert.Equal(t, ast.AbsShapeID("foo#A"), a.Traits[ast.ReferencesTraitID].(*ast.ReferencesTrait).Items[0].Resource.Value)
+	})
+
+	t.Run("test traits", func(t *testing.T) {
+		m := testmodel.Read(t, `{
+			"version": "1.0",
+			"shapes": {
+				"foo#Op": {
+					"type": "operation",
+					"traits": {
+						"smithy.test#httpRequestTests": [{"id": "A", "protocol": "foo#proto", "method": "GET", "uri": "/", "vendorParamsShape": "foo#Vendor"}],
+						"smithy.test#httpResponseTests": [{"id": "B", "protocol": "foo#proto", "code": 200}],
+						"smithy.test#smokeTests": [{"id": "C", "expect": {"failure": {"errorId": "foo#Err"}}}, {"id": "D", "expect": {"success": {}}}]
+					}
+				},
+				"foo#proto": {"type": "structure", "members": {}, "traits": {"smithy.api#trait": {}}},
+				"foo#Vendor": {"type": "structure", "members": {}},
+				"foo#Err": {"type": "structure", "members": {}, "traits": {"smithy.api#error": "client"}}
+			}
+		}`)
+		before := snapshot(t, m)
+
+		m2, err := Rename(m, map[ast.AbsShapeID]ast.AbsShapeID{"foo#proto": "bar#proto", "foo#Vendor": "bar#Vendor", "foo#Err": "bar#Err"})
+
+		require.NoError(t, err)
+		assert.Equal(t, before, snapshot(t, m))
+		traits := m2.Shapes["foo#Op"].Traits
+		requestTests := traits[ast.HTTPRequestTestsTraitID].(*ast.HTTPRequestTestsTrait)
+		assert.Equal(t, ast.AbsShapeID("bar#proto"), requestTests.Items[0].Protocol.Value)
+		assert.Equal(t, ast.AbsShapeID("bar#Vendor"), requestTests.Items[0].VendorParamsShape.Value)
+		responseTests := traits[ast.HTTPResponseTestsTraitID].(*ast.HTTPResponseTestsTrait)
+		assert.Equal(t, ast.AbsShapeID("bar#proto"), responseTests.Items[0].Protocol.Value)
+		smokeTests := traits[ast.SmokeTestsTraitID].(*ast.SmokeTestsTrait)
+		assert.Equal(t, ast.AbsShapeID("bar#Err"), smokeTests.Items[0].Expect.Failure.ErrorID.Value)
+		assert.NotNil(t, smokeTests.Items[1].Expect.Success)
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2, err := Rename(m, map[ast.AbsShapeID]ast.AbsShapeID{"foo#A": "foo#Err", "foo#Err": "foo#A"})
+
+		require.NoError(t, err)
+		assert.Equal(t, ast.StructureType, m2.Shapes["foo#A"].Type)
+		assert.Contains(t, m2.Shapes["foo#A"].Traits, ast.ErrorTraitID)
+		assert.Len(t, m2.Shapes["foo#Err"].Members, 3)
+		assert.Equal(t, ast.AbsShapeID("foo#A"), m2.Shapes["foo#List"].Value.Target.Value)
+	})
+
+	testCases := []struct {
+		name    string
+		renames map[ast.AbsShapeID]ast.AbsShapeID
+		err     string
+	}{
+		{
+			name:    "not found",
+			renames: map[ast.AbsShapeID]ast.AbsShapeID{"foo#Nope": "foo#Yes"},
+			err:     "transform: cannot rename foo#Nope: shape not found",
+		},
+		{
+			name:    "invalid old ID",
+			renames: map[ast.AbsShapeID]ast.AbsShapeID{"foo#A$id": "foo#A$ID"},
+			err:     "transform: cannot rename foo#A$id: invalid shape ID",
+		},
+		{
+			name:    "invalid new ID",
+			renames: map[ast.AbsShapeID]ast.AbsShapeID{"foo#A": "B"},
+			err:     "transform: cannot rename foo#A to B: invalid shape ID",
+		},
+		{
+			name:    "collision with existing",
+			renames: map[ast.AbsShapeID]ast.AbsShapeID{"foo#A": "foo#Err"},
+			err:     "transform: cannot rename foo#A to foo#Err: shape already exists",
+		},
+		{
+			name:    "collision with renamed",
+			renames: map[ast.AbsShapeID]ast.AbsShapeID{"foo#A": "foo#B", "foo#Id": "foo#B"},
+			err:     "transform: cannot rename foo#Id to foo#B: foo#A is also renamed to foo#B",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := testmodel.Read(t, testModel)
+
+			_, err := Rename(m, testCase.renames)
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
+
+func TestRenameNamespaces(t *testing.T) {
+	const model = `{
+		"version": "1.0",
+		"shapes": {
+			"example.weather#Weather": {
+				"type": "service",
+				"version": "2006-03-01",
+				"operations": ["example.weather#GetCity"],
+				"rename": {"example.weather#City": "Town"}
+			},
+			"example.weather#GetCity": {
+				"type": "operation",
+				"output": "example.weather#City",
+				"errors": ["example.common#NotFound"]
+			},
+			"example.weather#City": {
+				"type": "structure",
+				"members": {
+					"name": {"target": "smithy.api#String"},
+					"forecast": {"target": "example.weather.v2#Forecast"}
+				},
+				"traits": {
+					"smithy.api#references": [{"resource": "example.weather#City"}],
+					"example.common#see": "example.weather#Weather"
+				}
+			},
+			"example.weather.v2#Forecast": {"type": "string"},
+			"example.common#NotFound": {
+				"type": "structure",
+				"members": {},
+				"traits": {"smithy.api#error": "client"}
+			},
+			"example.common#see": {
+				"type": "string",
+				"traits": {"smithy.api#trait": {}, "smithy.api#idRef": {}}
+			},
+			"com.acme.common#NotFound": {"type": "string"}
+		}
+	}`
+
+	t.Run("rename", func(t *testing.T) {
+		m := testmodel.Read(t, model)
+		before := snapshot(t, m)
+
+		m2, err := RenameNamespaces(m, map[string]string{
+			"example.weather": "com.acme.vendored.weather",
+			"example.common":  "com.acme.vendored.common",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, before, snapshot(t, m))
+		assert.ElementsMatch(t, []ast.AbsShapeID{
+			"com.acme.vendored.weather#Weather",
+			"com.acme.vendored.weather#GetCity",
+			"com.acme.vendored.weather#City",
+			"example.weather.v2#Forecast",
+			"com.acme.vendored.common#NotFound",
+			"com.acme.vendored.common#see",
+			"com.acme.common#NotFound",
+		}, shapeIDs(m2))
+		svc := m2.Shapes["com.acme.vendored.weather#Weather"].Service
+		assert.Equal(t, []ast.AbsShapeID{"com.acme.vendored.weather#GetCity"}, refValues(svc.Operations))
+		assert.Equal(t, map[ast.AbsShapeID]ast.StringNode{"com.acme.vendored.weather#City": {Value: "Town"}}, svc.Rename)
+		op := m2.Shapes["com.acme.vendored.weather#GetCity"].Operation
+		assert.Equal(t, ast.AbsShapeID("com.acme.vendored.weather#City"), op.Output.Value)
+		assert.Equal(t, []ast.AbsShapeID{"com.acme.vendored.common#NotFound"}, refValues(op.Errors))
+		city := m2.Shapes["com.acme.vendored.weather#City"]
+		assert.Equal(t, ast.AbsShapeID("smithy.api#String"), city.Members["name"].Target.Value)
+		assert.Equal(t, ast.AbsShapeID("example.weather.v2#Forecast"), city.Members["forecast"].Target.Value)
+		refs := city.Traits[ast.ReferencesTraitID].(*ast.ReferencesTrait)
+		assert.Equal(t, ast.AbsShapeID("com.acme.vendored.weather#City"), refs.Items[0].Resource.Value)
+		assert.Equal(t, "com.acme.vendored.weather#Weather", city.Traits["com.acme.vendored.common#see"].(*ast.InterfaceNode).Value)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		m := testmodel.Read(t, model)
+
+		_, err := RenameNamespaces(m, map[string]string{"example.common": "com.acme.common"})
+
+		assert.EqualError(t, err, "transform: cannot rename example.common#NotFound to com.acme.common#NotFound: shape already exists")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		m := testmodel.Read(t, model)
+
+		_, err := RenameNamespaces(m, map[string]string{"example.weather": "a#b"})
+
+		assert.EqualError(t, err, `transform: cannot rename namespace "example.weather" to "a#b": invalid namespace`)
+	})
+}
@@ -0,0 +1,170 @@
+// Package transform implements transformations of Smithy models, such
+// as removing or renaming shapes, which keep the shape references in
+// the model consistent.
+//
+// Every transformation returns a new ast.Model and leaves its input
+// model unchanged. The returned model shares any shapes and trait
+// values the transformation did not need to change with the input
+// model, so neither model should be modified in place afterwards.
+package transform
+
+import (
+	"fmt"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// Filter returns a copy of m containing only the shapes for which keep
+// returns true. The shapes for which keep returns false are removed as
+// if by Remove.
+func Filter(m ast.Model, keep func(id ast.AbsShapeID, s ast.Shape) bool) ast.Model {
+	var ids []ast.AbsShapeID
+	for id, s := range m.Shapes {
+		if !keep(id, s) {
+			ids = append(ids, id)
+		}
+	}
+	return Remove(m, ids...)
+}
+
+// Remove returns a copy of m without the shapes having the given IDs,
+// and without the references to them that remain in the model. An ID
+// which names a member, such as "foo#Struct$bar", removes only that
+// member from its containing shape. IDs not present in m are ignored.
+//
+// The references removed are structure and union members targeting a
+// removed shape; the operations, resources, errors, lifecycle
+// operations, identifiers, rename entries, inputs and outputs of
+// services, resources and operations which refer to a removed shape;
+// and applications of traits whose trait definition shape was removed.
+//
+// A list, set or map whose member targets a removed shape cannot be
+// left without a target, so it is removed too, and so on recursively.
+// References held in trait values, such as idRef strings, are left as
+// they are.
+func Remove(m ast.Model, ids ...ast.AbsShapeID) ast.Model {
+	m2 := copyModel(m)
+
+	removed := make(map[ast.AbsShapeID]bool)
+	removedTraits := make(map[ast.AbsShapeID]bool)
+	for _, id := range ids {
+		s, ok := m2.Shapes[id]
+		if !ok {
+			if id.Member() != "" {
+				removeMember(&m2, id, removed)
+			}
+			continue
+		}
+		delete(m2.Shapes, id)
+		removed[id] = true
+		if _, ok = s.Traits[ast.TraitTraitID]; ok {
+			removedTraits[id] = true
+		}
+	}
+
+	// Remove references until no more shapes become invalid.
+	for again := len(removed) > 0; again; {
+		again = false
+		for id, s := range m2.Shapes {
+			changed := false
+			s2 := copyShape(s)
+			valid := visitRefs(&s2, func(ref *ast.AbsShapeIDNode) bool {
+				if removed[ref.Value] {
+					changed = true
+					return false
+				}
+				return true
+			})
+			if !valid {
+				delete(m2.Shapes, id)
+				removed[id] = true
+				if _, ok := s.Traits[ast.TraitTraitID]; ok {
+					removedTraits[id] = true
+				}
+				again = true
+			} else if removeTraits(&s2, removedTraits) || changed {
+				m2.Shapes[id] = s2
+			}
+		}
+	}
+
+	return m2
+}
+
+// removeMember removes the member identified by id from its containing
+// shape in m. If the containing shape is a list, set or map, it is
+// removed instead, and recorded in removed.
+func removeMember(m *ast.Model, id ast.AbsShapeID, removed map[ast.AbsShapeID]bool) {
+	container := ast.AbsShapeID(id.Namespace() + "#" + id.Name())
+	s, ok := m.Shapes[container]
+	if !ok {
+		return
+	}
+	name := id.Member()
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		if _, ok = s.Members[name]; ok {
+			s = copyShape(s)
+			delete(s.Members, name)
+			m.Shapes[container] = s
+		}
+	case ast.ListType, ast.SetType, ast.MapType:
+		if name == "member" || name == "key" || name == "value" {
+			delete(m.Shapes, container)
+			removed[container] = true
+		}
+	}
+}
+
+// removeTraits removes applications of the given traits from s and
+// its members, and reports whether any were removed. The shape s must
+// be a copy made with copyShape.
+func removeTraits(s *ast.Shape, traits map[ast.AbsShapeID]bool) bool {
+	if len(traits) == 0 {
+		return false
+	}
+	changed := removeTraitsFrom(s.Traits, traits)
+	if s.Key != nil {
+		changed = removeTraitsFrom(s.Key.Traits, traits) || changed
+	}
+	if s.Value != nil {
+		changed = removeTraitsFrom(s.Value.Traits, traits) || changed
+	}
+	for _, member := range s.Members {
+		changed = removeTraitsFrom(member.Traits, traits) || changed
+	}
+	return changed
+}
+
+func removeTraitsFrom(t ast.Traits, traits map[ast.AbsShapeID]bool) bool {
+	changed := false
+	for id := range t {
+		if traits[id] {
+			delete(t, id)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Replace returns a copy of m in which each of the given shapes is
+// added to the model, replacing any existing shape with the same ID.
+//
+// Replace does not remove references to shapes or members which a
+// replacement shape no longer has. Use Remove for that.
+func Replace(m ast.Model, shapes map[ast.AbsShapeID]ast.Shape) ast.Model {
+	m2 := copyModel(m)
+	if m2.Shapes == nil && len(shapes) > 0 {
+		m2.Shapes = make(map[ast.AbsShapeID]ast.Shape, len(shapes))
+	}
+	for id, s := range shapes {
+		m2.Shapes[id] = s
+	}
+	return m2
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "transform: "
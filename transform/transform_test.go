@@ -0,0 +1,200 @@
+package transform
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "2020-01-01",
+			"operations": ["foo#GetA", "foo#PutA"],
+			"resources": ["foo#Res"],
+			"errors": ["foo#Err"],
+			"rename": {"foo#A": "AA", "foo#Err": "Error"}
+		},
+		"foo#Res": {
+			"type": "resource",
+			"identifiers": {"id": "foo#Id"},
+			"read": "foo#GetA",
+			"operations": ["foo#PutA"]
+		},
+		"foo#GetA": {
+			"type": "operation",
+			"input": "foo#GetAInput",
+			"output": "foo#A",
+			"errors": ["foo#Err"]
+		},
+		"foo#PutA": {
+			"type": "operation",
+			"input": "foo#A"
+		},
+		"foo#GetAInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "foo#Id", "traits": {"smithy.api#required": {}}}
+			}
+		},
+		"foo#A": {
+			"type": "structure",
+			"members": {
+				"list": {"target": "foo#List"},
+				"map": {"target": "foo#Map"},
+				"id": {"target": "foo#Id", "traits": {"foo#ref": "foo#Err"}}
+			},
+			"traits": {"foo#ref": "foo#A$id"}
+		},
+		"foo#List": {
+			"type": "list",
+			"member": {"target": "foo#Err"}
+		},
+		"foo#Map": {
+			"type": "map",
+			"key": {"target": "foo#Id"},
+			"value": {"target": "foo#List"}
+		},
+		"foo#Err": {
+			"type": "structure",
+			"members": {},
+			"traits": {"smithy.api#error": "client"}
+		},
+		"foo#Id": {
+			"type": "string"
+		},
+		"foo#ref": {
+			"type": "string",
+			"traits": {"smithy.api#trait": {}, "smithy.api#idRef": {}}
+		}
+	}
+}`
+
+// snapshot returns the JSON encoding of m, so tests can check that a
+// model was not modified.
+func snapshot(t *testing.T, m ast.Model) string {
+	var buf bytes.Buffer
+	require.NoError(t, ast.WriteModel(m, &buf))
+	return buf.String()
+}
+
+func shapeIDs(m ast.Model) []ast.AbsShapeID {
+	ids := make([]ast.AbsShapeID, 0, len(m.Shapes))
+	for id := range m.Shapes {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func refValues(refs []ast.AbsShapeIDNode) []ast.AbsShapeID {
+	var ids []ast.AbsShapeID
+	for _, ref := range refs {
+		ids = append(ids, ref.Value)
+	}
+	return ids
+}
+
+func TestFilter(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+	before := snapshot(t, m)
+
+	m2 := Filter(m, func(id ast.AbsShapeID, s ast.Shape) bool {
+		return s.Type != ast.OperationType
+	})
+
+	assert.Equal(t, before, snapshot(t, m))
+	assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#GetA"))
+	assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#PutA"))
+	assert.Len(t, m2.Shapes, len(m.Shapes)-2)
+	assert.Empty(t, m2.Shapes["foo#Service"].Service.Operations)
+	res := m2.Shapes["foo#Res"].Resource
+	assert.Nil(t, res.Read)
+	assert.Empty(t, res.Operations)
+	assert.Contains(t, res.Identifiers, "id")
+}
+
+func TestRemove(t *testing.T) {
+	t.Run("references", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+		before := snapshot(t, m)
+
+		m2 := Remove(m, "foo#Err", "foo#Nope")
+
+		assert.Equal(t, before, snapshot(t, m))
+		assert.ElementsMatch(t, []ast.AbsShapeID{
+			"foo#Service", "foo#Res", "foo#GetA", "foo#PutA", "foo#GetAInput", "foo#A", "foo#Id", "foo#ref",
+		}, shapeIDs(m2))
+		svc := m2.Shapes["foo#Service"].Service
+		assert.Empty(t, svc.Errors)
+		assert.Equal(t, map[ast.AbsShapeID]ast.StringNode{"foo#A": {Value: "AA"}}, svc.Rename)
+		assert.Empty(t, m2.Shapes["foo#GetA"].Operation.Errors)
+		assert.Equal(t, ast.AbsShapeID("foo#A"), m2.Shapes["foo#GetA"].Operation.Output.Value)
+		members := m2.Shapes["foo#A"].Members
+		assert.Len(t, members, 1)
+		assert.Contains(t, members, "id")
+		assert.Contains(t, members["id"].Traits, ast.AbsShapeID("foo#ref"))
+	})
+
+	t.Run("member", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2 := Remove(m, "foo#A$list", "foo#Map$value", "foo#A$nope")
+
+		assert.Len(t, m.Shapes["foo#A"].Members, 3)
+		assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#Map"))
+		assert.Len(t, m2.Shapes["foo#A"].Members, 1)
+		assert.Contains(t, m2.Shapes["foo#A"].Members, "id")
+	})
+
+	t.Run("trait definition", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2 := Remove(m, "foo#ref")
+
+		assert.Contains(t, m.Shapes["foo#A"].Traits, ast.AbsShapeID("foo#ref"))
+		assert.Empty(t, m2.Shapes["foo#A"].Traits)
+		assert.Empty(t, m2.Shapes["foo#A"].Members["id"].Traits)
+	})
+
+	t.Run("operation input", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2 := Remove(m, "foo#GetAInput", "foo#Id")
+
+		assert.Nil(t, m2.Shapes["foo#GetA"].Operation.Input)
+		assert.Empty(t, m2.Shapes["foo#Res"].Resource.Identifiers)
+		assert.NotContains(t, m2.Shapes, ast.AbsShapeID("foo#Map"))
+		assert.NotNil(t, m.Shapes["foo#GetA"].Operation.Input)
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		m := testmodel.Read(t, testModel)
+
+		m2 := Remove(m)
+
+		assert.Equal(t, m, m2)
+	})
+}
+
+func TestReplace(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+	before := snapshot(t, m)
+
+	m2 := Replace(m, map[ast.AbsShapeID]ast.Shape{
+		"foo#Id":  {Type: ast.IntegerType},
+		"foo#New": {Type: ast.BlobType},
+	})
+
+	assert.Equal(t, before, snapshot(t, m))
+	assert.Len(t, m2.Shapes, len(m.Shapes)+1)
+	assert.Equal(t, ast.IntegerType, m2.Shapes["foo#Id"].Type)
+	assert.Equal(t, ast.BlobType, m2.Shapes["foo#New"].Type)
+	assert.Equal(t, refValues(m.Shapes["foo#Service"].Service.Operations), refValues(m2.Shapes["foo#Service"].Service.Operations))
+}
@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogama/smithy-ast/ast"
+)
+
+// An ExampleViolation is a violation in an example of the examples trait
+// of an operation.
+type ExampleViolation struct {
+	// Operation is the ID of the operation the example documents.
+	Operation ast.AbsShapeID
+
+	// Title is the title of the example.
+	Title string
+
+	// Violation is the violation. Its path is the JSON Pointer path of
+	// the offending value within the example, such as "/input/name" or
+	// "/error/content/message".
+	Violation
+}
+
+func (v ExampleViolation) String() string {
+	return fmt.Sprintf("operation %s, example %q: %s", v.Operation, v.Title, v.Violation)
+}
+
+// Examples checks the examples traits of the operations in m, and
+// returns the violations it finds, ordered by operation ID and then by
+// example.
+//
+// The input and output of an example must conform to the input and
+// output shapes of its operation, as Value checks them with zero
+// Options. The error of an example must name one of the errors of the
+// operation or of a service in m which binds it, and its content must
+// conform to that error shape. An example may not have both an output
+// and an error.
+//
+// Examples returns an error if a shape an example refers to cannot be
+// found, or if a pattern trait is not a valid regular expression.
+func Examples(m ast.Model) ([]ExampleViolation, error) {
+	c := newChecker(m, Options{})
+	serviceErrors := bindingErrors(c.shapes)
+	ids := make([]ast.AbsShapeID, 0, len(m.Shapes))
+	for id := range m.Shapes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var violations []ExampleViolation
+	for _, id := range ids {
+		op := m.Shapes[id]
+		trait, ok := op.Traits[ast.ExamplesTraitID].(*ast.ExamplesTrait)
+		if op.Type != ast.OperationType || !ok {
+			continue
+		}
+		var operation ast.Operation
+		if op.Operation != nil {
+			operation = *op.Operation
+		}
+		errs := make(map[ast.AbsShapeID]bool)
+		for _, ref := range operation.Errors {
+			errs[ref.Value] = true
+		}
+		for _, errorID := range serviceErrors[id] {
+			errs[errorID] = true
+		}
+
+		for _, item := range trait.Items {
+			c.violations = nil
+			report := func(path, format string, a ...interface{}) {
+				c.violations = append(c.violations, Violation{Path: path, ShapeID: id, Message: fmt.Sprintf(format, a...)})
+			}
+			if err := c.checkExample("/input", operation.Input, item.Input, report); err != nil {
+				return nil, err
+			}
+			if err := c.checkExample("/output", operation.Output, item.Output, report); err != nil {
+				return nil, err
+			}
+			if item.Error != nil {
+				errorID := item.Error.ShapeID.Value
+				if item.Output != nil {
+					report("", "example has both an output and an error")
+				}
+				if !errs[errorID] {
+					report("/error/shapeId", "%s is not an error of the operation or its services", errorID)
+				} else if err := c.check("/error/content", errorID, errorID, nil, item.Error.Content, false); err != nil {
+					return nil, err
+				}
+			}
+			for _, v := range c.violations {
+				violations = append(violations, ExampleViolation{Operation: id, Title: item.Title.Value, Violation: v})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// checkExample checks the input or output of an example against the
+// structure ref refers to, which is nil if the operation has none.
+func (c *checker) checkExample(path string, ref *ast.AbsShapeIDNode, v map[string]ast.InterfaceNode, report func(string, string, ...interface{})) error {
+	switch {
+	case v == nil:
+		return nil
+	case ref == nil:
+		if len(v) > 0 {
+			report(path, "operation has no %s", path[1:])
+		}
+		return nil
+	}
+	return c.check(path, ref.Value, ref.Value, nil, v, false)
+}
+
+// bindingErrors returns the errors of the services which bind each
+// operation, directly or through their resources.
+func bindingErrors(shapes map[ast.AbsShapeID]ast.Shape) map[ast.AbsShapeID][]ast.AbsShapeID {
+	errs := make(map[ast.AbsShapeID][]ast.AbsShapeID)
+	for _, s := range shapes {
+		if s.Type != ast.ServiceType || s.Service == nil {
+			continue
+		}
+		var serviceErrors []ast.AbsShapeID
+		for _, ref := range s.Service.Errors {
+			serviceErrors = append(serviceErrors, ref.Value)
+		}
+		seen := make(map[ast.AbsShapeID]bool)
+		var visit func(refs []ast.AbsShapeIDNode)
+		visit = func(refs []ast.AbsShapeIDNode) {
+			for _, ref := range refs {
+				if seen[ref.Value] {
+					continue
+				}
+				seen[ref.Value] = true
+				child := shapes[ref.Value]
+				switch {
+				case child.Type == ast.OperationType:
+					errs[ref.Value] = append(errs[ref.Value], serviceErrors...)
+				case child.Type == ast.ResourceType && child.Resource != nil:
+					r := child.Resource
+					for _, op := range []*ast.AbsShapeIDNode{r.Create, r.Put, r.Read, r.Update, r.Delete, r.List} {
+						if op != nil {
+							visit([]ast.AbsShapeIDNode{*op})
+						}
+					}
+					visit(r.Operations)
+					visit(r.CollectionOperations)
+					visit(r.Resources)
+				}
+			}
+		}
+		visit(s.Service.Operations)
+		visit(s.Service.Resources)
+	}
+	return errs
+}
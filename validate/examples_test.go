@@ -0,0 +1,124 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const examplesModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Service": {
+			"type": "service",
+			"version": "1",
+			"resources": ["foo#Thing"],
+			"errors": ["foo#Throttled"]
+		},
+		"foo#Thing": {
+			"type": "resource",
+			"identifiers": {"id": "smithy.api#String"},
+			"read": "foo#GetThing"
+		},
+		"foo#GetThing": {
+			"type": "operation",
+			"input": "foo#GetThingInput",
+			"output": "foo#GetThingOutput",
+			"errors": ["foo#NotFound"],
+			"traits": {
+				"smithy.api#readonly": {},
+				"smithy.api#examples": [
+					{"title": "Good", "input": {"id": "a1"}, "output": {"name": "Thing", "size": 3}},
+					{"title": "Bad input", "input": {"id": "A!", "color": "red"}},
+					{"title": "Bad output", "input": {"id": "a1"}, "output": {"size": "big", "tags": ["x", "x"]}},
+					{"title": "Not found", "input": {"id": "a1"}, "error": {"shapeId": "foo#NotFound", "content": {"message": 1}}},
+					{"title": "Throttled", "input": {"id": "a1"}, "error": {"shapeId": "foo#Throttled", "content": {}}},
+					{"title": "Unknown error", "input": {"id": "a1"}, "error": {"shapeId": "foo#Other"}},
+					{"title": "Both", "input": {"id": "a1"}, "output": {}, "error": {"shapeId": "foo#NotFound"}}
+				]
+			}
+		},
+		"foo#GetThingInput": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "foo#Id", "traits": {"smithy.api#required": {}}}
+			}
+		},
+		"foo#GetThingOutput": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "smithy.api#String"},
+				"size": {"target": "smithy.api#Integer"},
+				"tags": {"target": "foo#Tags"}
+			}
+		},
+		"foo#Id": {
+			"type": "string",
+			"traits": {"smithy.api#pattern": "^[a-z0-9]+$"}
+		},
+		"foo#Tags": {
+			"type": "set",
+			"member": {"target": "smithy.api#String"}
+		},
+		"foo#NotFound": {
+			"type": "structure",
+			"members": {"message": {"target": "smithy.api#String"}},
+			"traits": {"smithy.api#error": "client"}
+		},
+		"foo#Throttled": {
+			"type": "structure",
+			"members": {},
+			"traits": {"smithy.api#error": "client"}
+		},
+		"foo#Other": {
+			"type": "structure",
+			"members": {},
+			"traits": {"smithy.api#error": "server"}
+		},
+		"foo#Ping": {
+			"type": "operation",
+			"traits": {
+				"smithy.api#examples": [
+					{"title": "Ping", "input": {"x": 1}, "output": {}}
+				]
+			}
+		}
+	}
+}`
+
+func TestExamples(t *testing.T) {
+	m := testmodel.Read(t, examplesModel)
+
+	violations, err := Examples(m)
+
+	require.NoError(t, err)
+	actual := make([]string, len(violations))
+	for i, v := range violations {
+		actual[i] = v.String()
+	}
+	assert.Equal(t, []string{
+		`operation foo#GetThing, example "Bad input": /input: unknown member "color" (foo#GetThingInput)`,
+		`operation foo#GetThing, example "Bad input": /input/id: value "A!" does not match pattern "^[a-z0-9]+$" (foo#GetThingInput$id)`,
+		`operation foo#GetThing, example "Bad output": /output/size: expected integer, got string (foo#GetThingOutput$size)`,
+		`operation foo#GetThing, example "Bad output": /output/tags: item 1 duplicates item 0 (foo#GetThingOutput$tags)`,
+		`operation foo#GetThing, example "Not found": /error/content/message: expected string, got number (foo#NotFound$message)`,
+		`operation foo#GetThing, example "Unknown error": /error/shapeId: foo#Other is not an error of the operation or its services (foo#GetThing)`,
+		`operation foo#GetThing, example "Both": example has both an output and an error (foo#GetThing)`,
+		`operation foo#Ping, example "Ping": /input: operation has no input (foo#Ping)`,
+	}, actual)
+	assert.Equal(t, "/input/id", violations[1].Path)
+	assert.Equal(t, "Bad input", violations[0].Title)
+}
+
+func TestExamples_Errors(t *testing.T) {
+	m := testmodel.Read(t, `{"version":"1.0","shapes":{
+		"foo#Op":{"type":"operation","input":"foo#Missing","traits":{"smithy.api#examples":[{"title":"X","input":{"a":1}}]}}
+	}}`)
+
+	_, err := Examples(m)
+
+	assert.EqualError(t, err, "validate: shape foo#Missing not found")
+}
@@ -0,0 +1,457 @@
+// Package validate checks values against the shapes of a Smithy model.
+//
+// Value checks a value, such as a decoded JSON payload, against a shape
+// and its constraint traits, and reports every violation it finds along
+// with the JSON Pointer path of the offending value. Examples checks the
+// examples traits of operations against their input, output and error
+// shapes.
+package validate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// A Violation is a value which does not conform to its shape.
+type Violation struct {
+	// Path is the JSON Pointer path of the value within the value
+	// checked, such as "/items/0/name". It is empty for the value
+	// itself.
+	Path string
+
+	// ShapeID is the ID of the member, or of the shape if the value is
+	// not a member value, whose constraint the value violates.
+	ShapeID ast.AbsShapeID
+
+	// Message describes the violation. The values of sensitive shapes
+	// and members are redacted from the message.
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("%s (%s)", v.Message, v.ShapeID)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.Path, v.Message, v.ShapeID)
+}
+
+// Options controls how Value interprets values.
+type Options struct {
+	// JSON indicates the value was decoded from a Smithy JSON protocol
+	// payload. Structure and union members are then keyed by their
+	// jsonName trait, and blobs are base64 strings. Otherwise members
+	// are keyed by member name, and blobs may be strings or []byte.
+	JSON bool
+}
+
+// Redacted replaces the values of sensitive shapes and members in
+// violation messages.
+const Redacted = "[REDACTED]"
+
+// Value checks the value v against the shape with the given ID in m,
+// and returns the violations it finds in the order it finds them. Shapes
+// not in m are looked up in the prelude.
+//
+// Values have the types produced by decoding JSON into an interface{}
+// with encoding/json: structures, unions and maps are
+// map[string]interface{}, lists and sets are []interface{}, and simple
+// values are strings, numbers, booleans or nil. Other maps with string
+// keys, slices, Go integer and floating point types, json.Number,
+// *big.Int, *big.Float, []byte and time.Time values are accepted too,
+// as are ast.InterfaceNode values, such as document nodes, wrapping
+// any of these. A nil structure member is treated as absent.
+//
+// Value checks that values have the right type, that structures have
+// their required members and no unknown ones, that unions have exactly
+// one member, that only sparse lists and maps contain nulls, and that
+// values satisfy the length, range, pattern, enum and uniqueItems
+// traits of their shapes and members. Sets are checked for uniqueness
+// too. Timestamp strings must be in the format named by their
+// timestampFormat trait, or date-time if they have none.
+//
+// Value returns an error if the shape, or a shape it refers to, cannot
+// be found, or if a pattern trait is not a valid regular expression.
+func Value(m ast.Model, id ast.AbsShapeID, v interface{}, opts Options) ([]Violation, error) {
+	c := newChecker(m, opts)
+	if err := c.check("", id, id, nil, v, false); err != nil {
+		return nil, err
+	}
+	return c.violations, nil
+}
+
+type checker struct {
+	opts       Options
+	shapes     map[ast.AbsShapeID]ast.Shape
+	patterns   map[string]*regexp.Regexp
+	violations []Violation
+}
+
+func newChecker(m ast.Model, opts Options) *checker {
+	shapes := prelude.Shapes(m)
+	return &checker{
+		opts:     opts,
+		shapes:   shapes,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+}
+
+// check checks the value v at path against the shape with ID target. If
+// the value is a member value, id is the member ID and memberTraits are
+// the member's traits; otherwise id is the target. If sensitive is true,
+// the value is part of a sensitive value.
+func (c *checker) check(path string, id, target ast.AbsShapeID, memberTraits ast.Traits, v interface{}, sensitive bool) error {
+	s, ok := c.shapes[target]
+	if !ok {
+		if id != target {
+			return newErrorf("target %s of member %s not found", target, id)
+		}
+		return newErrorf("shape %s not found", target)
+	}
+	traits := s.Traits.Merge(memberTraits)
+	sensitive = sensitive || traits.HasTrait(ast.SensitiveTraitID)
+	v = valuetree.Unwrap(v)
+
+	report := func(format string, a ...interface{}) {
+		c.violations = append(c.violations, Violation{Path: path, ShapeID: id, Message: fmt.Sprintf(format, a...)})
+	}
+	show := func(v interface{}) string {
+		if sensitive {
+			return Redacted
+		}
+		if s, ok := v.(string); ok {
+			return strconv.Quote(s)
+		}
+		return fmt.Sprint(v)
+	}
+	showNumber := func(f *big.Float) string {
+		if sensitive {
+			return Redacted
+		}
+		return f.Text('g', -1)
+	}
+	if v == nil {
+		report("expected %s, got null", s.Type)
+		return nil
+	}
+	if (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value == nil ||
+		s.Type == ast.MapType && (s.Key == nil || s.Value == nil) {
+		return newErrorf("%s %s has no members", s.Type, target)
+	}
+
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := valuetree.Object(v)
+		if !ok {
+			report("expected %s, got %s", s.Type, valuetree.TypeName(v))
+			return nil
+		}
+		return c.checkMembers(path, target, s, obj, sensitive, report)
+
+	case ast.ListType, ast.SetType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || valuetree.IsBytes(v) {
+			report("expected %s, got %s", s.Type, valuetree.TypeName(v))
+			return nil
+		}
+		checkLength(rv.Len(), traits, report)
+		seen := make(map[string]int)
+		unique := s.Type == ast.SetType || traits.HasTrait(ast.UniqueItemsTraitID)
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		for i := 0; i < rv.Len(); i++ {
+			item := valuetree.Unwrap(rv.Index(i).Interface())
+			itemPath := path + "/" + strconv.Itoa(i)
+			if item == nil && sparse {
+				continue
+			}
+			if item == nil {
+				c.violations = append(c.violations, Violation{Path: itemPath, ShapeID: target, Message: "null item in list which is not sparse"})
+				continue
+			}
+			if err := c.check(itemPath, target+"$member", s.Value.Target.Value, s.Value.Traits, item, sensitive); err != nil {
+				return err
+			}
+			if unique {
+				key := canonical(item)
+				if j, ok := seen[key]; ok {
+					report("item %d duplicates item %d", i, j)
+				} else {
+					seen[key] = i
+				}
+			}
+		}
+
+	case ast.MapType:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			report("expected map, got %s", valuetree.TypeName(v))
+			return nil
+		}
+		checkLength(rv.Len(), traits, report)
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		sparse := s.Traits.HasTrait(ast.SparseTraitID)
+		for _, key := range keys {
+			valuePath := path + "/" + valuetree.Escape(key.String())
+			if err := c.check(valuePath, target+"$key", s.Key.Target.Value, s.Key.Traits, key.String(), sensitive); err != nil {
+				return err
+			}
+			value := valuetree.Unwrap(rv.MapIndex(key).Interface())
+			if value == nil && !sparse {
+				c.violations = append(c.violations, Violation{Path: valuePath, ShapeID: target, Message: "null value in map which is not sparse"})
+			} else if value != nil {
+				if err := c.check(valuePath, target+"$value", s.Value.Target.Value, s.Value.Traits, value, sensitive); err != nil {
+					return err
+				}
+			}
+		}
+
+	case ast.StringType:
+		str, ok := v.(string)
+		if !ok {
+			report("expected string, got %s", valuetree.TypeName(v))
+			return nil
+		}
+		checkLength(utf8.RuneCountInString(str), traits, report)
+		if pattern, ok := traits[ast.PatternTraitID].(*ast.StringNode); ok {
+			re, err := c.pattern(pattern.Value)
+			if err != nil {
+				return err
+			}
+			if !re.MatchString(str) {
+				report("value %s does not match pattern %q", show(str), pattern.Value)
+			}
+		}
+		if enum, ok := traits[ast.EnumTraitID].(*ast.EnumTrait); ok {
+			values := make([]string, len(enum.Items))
+			found := false
+			for i := range enum.Items {
+				values[i] = enum.Items[i].Value.Value
+				found = found || values[i] == str
+			}
+			if !found {
+				report("value %s is not one of %s", show(str), strings.Join(values, ", "))
+			}
+		}
+
+	case ast.BlobType:
+		var n int
+		switch b := v.(type) {
+		case []byte:
+			n = len(b)
+		case string:
+			n = len(b)
+			if c.opts.JSON {
+				p, err := base64.StdEncoding.DecodeString(b)
+				if err != nil {
+					report("expected base64 blob, got invalid base64")
+					return nil
+				}
+				n = len(p)
+			}
+		default:
+			report("expected blob, got %s", valuetree.TypeName(v))
+			return nil
+		}
+		checkLength(n, traits, report)
+
+	case ast.BooleanType:
+		if _, ok := v.(bool); !ok {
+			report("expected boolean, got %s", valuetree.TypeName(v))
+		}
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType,
+		ast.FloatType, ast.DoubleType, ast.BigDecimalType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			report("expected %s, got %s", s.Type, valuetree.TypeName(v))
+			return nil
+		}
+		if bits, integral := valuetree.IntegerBits[s.Type]; integral {
+			if !f.IsInt() {
+				report("expected %s, got non-integer %s", s.Type, showNumber(f))
+				return nil
+			}
+			if bits > 0 {
+				i, acc := f.Int64()
+				limit := int64(1) << (bits - 1)
+				if acc != big.Exact || bits < 64 && (i < -limit || i >= limit) {
+					report("value %s is out of range for %s", showNumber(f), s.Type)
+					return nil
+				}
+			}
+		}
+		if r, ok := traits[ast.RangeTraitID].(*ast.RangeTrait); ok {
+			if r.Min != nil && f.Cmp(&r.Min.Value) < 0 {
+				report("value %s is less than minimum %s", showNumber(f), r.Min.Value.Text('g', -1))
+			}
+			if r.Max != nil && f.Cmp(&r.Max.Value) > 0 {
+				report("value %s is greater than maximum %s", showNumber(f), r.Max.Value.Text('g', -1))
+			}
+		}
+
+	case ast.TimestampType:
+		switch x := v.(type) {
+		case time.Time:
+		case string:
+			format := traits.StringTrait(ast.TimestampFormatTraitID)
+			if !validTimestamp(x, format) {
+				if format == "" {
+					format = "date-time"
+				}
+				report("expected %s timestamp, got %s", format, show(x))
+			}
+		default:
+			if _, ok := valuetree.Number(v); !ok {
+				report("expected timestamp, got %s", valuetree.TypeName(v))
+			}
+		}
+
+	case ast.DocumentType:
+		// Any value is a document.
+
+	default:
+		return newErrorf("shape %s is a %s, which has no values", target, s.Type)
+	}
+	return nil
+}
+
+// checkMembers checks the members of a structure or union value.
+func (c *checker) checkMembers(path string, id ast.AbsShapeID, s ast.Shape, obj map[string]interface{}, sensitive bool, report func(string, ...interface{})) error {
+	byKey := make(map[string]string, len(s.Members))
+	for name, member := range s.Members {
+		byKey[c.key(name, member)] = name
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var set []string
+	for _, key := range keys {
+		name, ok := byKey[key]
+		if !ok {
+			report("unknown member %q", key)
+			continue
+		}
+		value := valuetree.Unwrap(obj[key])
+		if value == nil {
+			continue
+		}
+		set = append(set, key)
+		member := s.Members[name]
+		if err := c.check(path+"/"+valuetree.Escape(key), id+"$"+ast.AbsShapeID(name), member.Target.Value, member.Traits, value, sensitive); err != nil {
+			return err
+		}
+	}
+
+	if s.Type == ast.UnionType {
+		if len(set) != 1 {
+			report("union must have exactly one member set, got %d", len(set))
+		}
+		return nil
+	}
+	names := make([]string, 0, len(s.Members))
+	for name := range s.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		member := s.Members[name]
+		if !member.Traits.HasTrait(ast.RequiredTraitID) {
+			continue
+		}
+		if v, ok := obj[c.key(name, member)]; !ok || valuetree.Unwrap(v) == nil {
+			c.violations = append(c.violations, Violation{
+				Path:    path,
+				ShapeID: id + "$" + ast.AbsShapeID(name),
+				Message: fmt.Sprintf("missing required member %q", c.key(name, member)),
+			})
+		}
+	}
+	return nil
+}
+
+// key returns the key of a member in a structure or union value.
+func (c *checker) key(name string, member ast.Member) string {
+	if c.opts.JSON {
+		if jsonName, ok := member.Traits[ast.JSONNameTraitID].(*ast.StringNode); ok {
+			return jsonName.Value
+		}
+	}
+	return name
+}
+
+func (c *checker) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := c.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newErrorf("invalid pattern %q: %w", pattern, err)
+	}
+	c.patterns[pattern] = re
+	return re, nil
+}
+
+// validTimestamp reports whether s is a timestamp string in the given
+// timestampFormat, or in the date-time format if there is none. Values in
+// the epoch-seconds format are numbers, not strings.
+func validTimestamp(s, format string) bool {
+	var err error
+	switch format {
+	case "", "date-time":
+		_, err = time.Parse(time.RFC3339Nano, s)
+	case "http-date":
+		_, err = time.Parse(http.TimeFormat, s)
+	default:
+		return false
+	}
+	return err == nil
+}
+
+func checkLength(n int, traits ast.Traits, report func(string, ...interface{})) {
+	length, ok := traits[ast.LengthTraitID].(*ast.LengthTrait)
+	if !ok {
+		return
+	}
+	if length.Min != nil && int64(n) < length.Min.Value {
+		report("length %d is less than minimum %d", n, length.Min.Value)
+	}
+	if length.Max != nil && int64(n) > length.Max.Value {
+		report("length %d is greater than maximum %d", n, length.Max.Value)
+	}
+}
+
+// canonical returns a string which is equal for equal values, for
+// detecting duplicate items.
+func canonical(v interface{}) string {
+	if f, ok := valuetree.Number(v); ok {
+		return "n:" + f.Text('g', -1)
+	}
+	p, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(p)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "validate: "
@@ -0,0 +1,281 @@
+package validate
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#User": {
+			"type": "structure",
+			"members": {
+				"name": {"target": "foo#Name", "traits": {"smithy.api#required": {}, "smithy.api#jsonName": "Name"}},
+				"age": {"target": "smithy.api#Byte", "traits": {"smithy.api#range": {"min": 0, "max": 120}}},
+				"password": {"target": "foo#Name", "traits": {"smithy.api#sensitive": {}}},
+				"role": {"target": "foo#Role"},
+				"tags": {"target": "foo#Tags"},
+				"ids": {"target": "foo#Ids"},
+				"attrs": {"target": "foo#Attrs"},
+				"notes": {"target": "foo#Notes"},
+				"contact": {"target": "foo#Contact"},
+				"avatar": {"target": "foo#Avatar"},
+				"score": {"target": "smithy.api#Double"},
+				"created": {"target": "smithy.api#Timestamp"},
+				"modified": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "http-date"}},
+				"expires": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "epoch-seconds"}},
+				"extra": {"target": "smithy.api#Document"},
+				"secret": {"target": "foo#Secret"}
+			}
+		},
+		"foo#Name": {
+			"type": "string",
+			"traits": {"smithy.api#length": {"min": 2, "max": 8}, "smithy.api#pattern": "^[a-z]+$"}
+		},
+		"foo#Role": {
+			"type": "string",
+			"traits": {"smithy.api#enum": [{"value": "admin"}, {"value": "user"}]}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#length": {"max": 2}, "smithy.api#uniqueItems": {}}
+		},
+		"foo#Ids": {
+			"type": "set",
+			"member": {"target": "smithy.api#Integer"}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "foo#Name"},
+			"value": {"target": "smithy.api#String"}
+		},
+		"foo#Notes": {
+			"type": "list",
+			"member": {"target": "smithy.api#String"},
+			"traits": {"smithy.api#sparse": {}}
+		},
+		"foo#Contact": {
+			"type": "union",
+			"members": {
+				"email": {"target": "smithy.api#String"},
+				"phone": {"target": "smithy.api#String"}
+			}
+		},
+		"foo#Avatar": {
+			"type": "blob",
+			"traits": {"smithy.api#length": {"max": 3}}
+		},
+		"foo#Secret": {
+			"type": "structure",
+			"members": {
+				"pin": {"target": "smithy.api#Short", "traits": {"smithy.api#range": {"max": 9999}}}
+			},
+			"traits": {"smithy.api#sensitive": {}}
+		}
+	}
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValue(t *testing.T) {
+	m := testmodel.Read(t, testModel)
+
+	testCases := []struct {
+		name       string
+		id         ast.AbsShapeID
+		value      interface{}
+		opts       Options
+		violations []string
+	}{
+		{
+			name:  "valid",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","age":30,"role":"admin","tags":["a","b"],"ids":[1,2],"attrs":{"ab":"x"},"notes":["a",null],"contact":{"email":"b@example.com"},"avatar":"abc","score":1.5,"created":"2020-01-01T00:00:00Z","modified":"Wed, 01 Jan 2020 00:00:00 GMT","expires":1577836800,"extra":{"any":[1]},"secret":{"pin":1234}}`),
+		},
+		{
+			name: "valid Go values",
+			id:   "foo#User",
+			value: map[string]interface{}{
+				"name":    "bob",
+				"age":     int8(30),
+				"tags":    []string{"a"},
+				"ids":     []int{1, 2},
+				"attrs":   map[string]string{"ab": "x"},
+				"contact": map[string]interface{}{"phone": "555", "email": nil},
+				"avatar":  []byte{1, 2},
+				"score":   big.NewFloat(2),
+				"extra":   ast.InterfaceNode{Value: "doc"},
+			},
+		},
+		{
+			name:  "JSON names",
+			id:    "foo#User",
+			value: decode(t, `{"Name":"bob","avatar":"AQID"}`),
+			opts:  Options{JSON: true},
+		},
+		{
+			name:  "missing required",
+			id:    "foo#User",
+			value: decode(t, `{"name":null,"age":3}`),
+			violations: []string{
+				`missing required member "name" (foo#User$name)`,
+			},
+		},
+		{
+			name:  "unknown member and wrong types",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","nope":1,"age":"3","tags":"a","contact":[],"created":true}`),
+			violations: []string{
+				`/age: expected byte, got string (foo#User$age)`,
+				`/contact: expected union, got array (foo#User$contact)`,
+				`/created: expected timestamp, got boolean (foo#User$created)`,
+				`unknown member "nope" (foo#User)`,
+				`/tags: expected list, got string (foo#User$tags)`,
+			},
+		},
+		{
+			name:  "timestamps",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","created":"not a date","modified":"2020-01-01T00:00:00Z","expires":"1577836800"}`),
+			violations: []string{
+				`/created: expected date-time timestamp, got "not a date" (foo#User$created)`,
+				`/expires: expected epoch-seconds timestamp, got "1577836800" (foo#User$expires)`,
+				`/modified: expected http-date timestamp, got "2020-01-01T00:00:00Z" (foo#User$modified)`,
+			},
+		},
+		{
+			name:  "constraints",
+			id:    "foo#User",
+			value: decode(t, `{"name":"B","age":121,"role":"guest","tags":["a","b","a"],"ids":[1,1.0,2.5],"attrs":{"A/b":"x"},"avatar":"abcd"}`),
+			violations: []string{
+				`/age: value 121 is greater than maximum 120 (foo#User$age)`,
+				`/attrs/A~1b: value "A/b" does not match pattern "^[a-z]+$" (foo#Attrs$key)`,
+				`/avatar: length 4 is greater than maximum 3 (foo#User$avatar)`,
+				`/ids: item 1 duplicates item 0 (foo#User$ids)`,
+				`/ids/2: expected integer, got non-integer 2.5 (foo#Ids$member)`,
+				`/name: length 1 is less than minimum 2 (foo#User$name)`,
+				`/name: value "B" does not match pattern "^[a-z]+$" (foo#User$name)`,
+				`/role: value "guest" is not one of admin, user (foo#User$role)`,
+				`/tags: length 3 is greater than maximum 2 (foo#User$tags)`,
+				`/tags: item 2 duplicates item 0 (foo#User$tags)`,
+			},
+		},
+		{
+			name:  "integer range",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","age":300}`),
+			violations: []string{
+				`/age: value 300 is out of range for byte (foo#User$age)`,
+			},
+		},
+		{
+			name:  "long minimum",
+			id:    "smithy.api#Long",
+			value: int64(-1 << 63),
+		},
+		{
+			name:  "long out of range",
+			id:    "smithy.api#Long",
+			value: decode(t, `9223372036854775808`),
+			violations: []string{
+				`value 9.223372036854776e+18 is out of range for long (smithy.api#Long)`,
+			},
+		},
+		{
+			name:  "nulls and unions",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","tags":[null],"attrs":{"ab":null},"contact":{"email":"a","phone":"b"}}`),
+			violations: []string{
+				`/attrs/ab: null value in map which is not sparse (foo#Attrs)`,
+				`/contact: union must have exactly one member set, got 2 (foo#User$contact)`,
+				`/tags/0: null item in list which is not sparse (foo#Tags)`,
+			},
+		},
+		{
+			name:  "empty union",
+			id:    "foo#Contact",
+			value: decode(t, `{}`),
+			violations: []string{
+				`union must have exactly one member set, got 0 (foo#Contact)`,
+			},
+		},
+		{
+			name:  "sensitive",
+			id:    "foo#User",
+			value: decode(t, `{"name":"bob","password":"Hunter2","secret":{"pin":12345}}`),
+			violations: []string{
+				`/password: value [REDACTED] does not match pattern "^[a-z]+$" (foo#User$password)`,
+				`/secret/pin: value [REDACTED] is greater than maximum 9999 (foo#Secret$pin)`,
+			},
+		},
+		{
+			name:  "invalid base64",
+			id:    "foo#Avatar",
+			value: "!!",
+			opts:  Options{JSON: true},
+			violations: []string{
+				`expected base64 blob, got invalid base64 (foo#Avatar)`,
+			},
+		},
+		{
+			name:  "document node",
+			id:    "foo#Tags",
+			value: &ast.InterfaceNode{Value: []interface{}{"a", ast.InterfaceNode{Value: "a"}}},
+			violations: []string{
+				`item 1 duplicates item 0 (foo#Tags)`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			violations, err := Value(m, testCase.id, testCase.value, testCase.opts)
+
+			require.NoError(t, err)
+			actual := make([]string, len(violations))
+			for i, v := range violations {
+				actual[i] = v.String()
+			}
+			assert.ElementsMatch(t, testCase.violations, actual)
+		})
+	}
+}
+
+func TestValue_Errors(t *testing.T) {
+	m := testmodel.Read(t, `{"version":"1.0","shapes":{
+		"foo#S":{"type":"structure","members":{"a":{"target":"foo#Missing"}}},
+		"foo#P":{"type":"string","traits":{"smithy.api#pattern":"("}}
+	}}`)
+
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		value interface{}
+		err   string
+	}{
+		{name: "shape not found", id: "foo#Missing", value: 1, err: "validate: shape foo#Missing not found"},
+		{name: "target not found", id: "foo#S", value: map[string]interface{}{"a": 1}, err: "validate: target foo#Missing of member foo#S$a not found"},
+		{name: "invalid pattern", id: "foo#P", value: "x", err: "validate: invalid pattern \"(\": error parsing regexp: missing closing ): `(`"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Value(m, testCase.id, testCase.value, Options{})
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
@@ -0,0 +1,715 @@
+// Package xmlcodec serializes values to and from XML according to the
+// shapes of a Smithy model, following the restXml and awsQuery
+// protocols.
+//
+// A Codec converts between XML bytes and the same generic value tree
+// as package jsoncodec. It applies the xmlName, xmlAttribute,
+// xmlFlattened and xmlNamespace traits: members are serialized as
+// elements, or as attributes of their parent, named by their xmlName
+// trait; lists and maps are wrapped in an element unless flattened;
+// and namespaces, with or without prefixes, are declared on the
+// elements of shapes and members which have them.
+package xmlcodec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/valuetree"
+	"github.com/gogama/smithy-ast/prelude"
+)
+
+// Timestamp formats named by the timestampFormat trait.
+const (
+	DateTime     = "date-time"
+	EpochSeconds = "epoch-seconds"
+	HTTPDate     = "http-date"
+)
+
+// Options controls how a Codec serializes values.
+type Options struct {
+	// TimestampFormat is the format of timestamps which have no
+	// timestampFormat trait: DateTime, EpochSeconds or HTTPDate. The
+	// default is DateTime.
+	TimestampFormat string
+}
+
+// A Codec serializes values of the shapes of a model to and from XML.
+//
+// Values have the types documented by jsoncodec.Codec, except that
+// documents cannot be serialized to XML, and XML has no null, so
+// sparse lists and maps cannot hold nil.
+//
+// Structure members are written in the order they are defined in the
+// model if the model was decoded with a path, and otherwise in order of
+// member name.
+type Codec struct {
+	opts   Options
+	shapes map[ast.AbsShapeID]ast.Shape
+}
+
+// New returns a Codec for the shapes of m. Shapes not in m are looked up
+// in the prelude.
+func New(m ast.Model, opts Options) *Codec {
+	if opts.TimestampFormat == "" {
+		opts.TimestampFormat = DateTime
+	}
+	shapes := prelude.Shapes(m)
+	return &Codec{opts: opts, shapes: shapes}
+}
+
+// Marshal returns the XML encoding of the value v of the shape with the
+// given ID. The root element is named by the shape's xmlName trait, or
+// else after the shape. Marshal returns an error, naming the path of
+// the offending value, if v does not fit the shape.
+func (c *Codec) Marshal(id ast.AbsShapeID, v interface{}) ([]byte, error) {
+	s, ok := c.shapes[id]
+	if !ok {
+		return nil, newErrorf("shape %s not found", id)
+	}
+	var buf bytes.Buffer
+	if err := c.encode(&buf, "", rootName(id, s), id, nil, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the XML data into a value of the shape with the
+// given ID. The name of the root element is not checked. Unmarshal
+// returns an error, naming the path of the offending value, if the
+// data does not fit the shape.
+//
+// Elements and attributes are matched by local name, ignoring their
+// namespaces. Unknown elements and attributes are ignored.
+func (c *Codec) Unmarshal(id ast.AbsShapeID, data []byte) (interface{}, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.decode("", root, id, nil)
+}
+
+// shape returns the shape with ID target, and the traits of the shape
+// merged with memberTraits, which are the traits of the member
+// targeting it, if any.
+func (c *Codec) shape(path string, target ast.AbsShapeID, memberTraits ast.Traits) (ast.Shape, ast.Traits, error) {
+	s, ok := c.shapes[target]
+	if !ok {
+		return s, nil, errorAt(path, "shape %s not found", target)
+	}
+	if (s.Type == ast.ListType || s.Type == ast.SetType) && s.Value == nil ||
+		s.Type == ast.MapType && (s.Key == nil || s.Value == nil) {
+		return s, nil, errorAt(path, "%s %s has no members", s.Type, target)
+	}
+	return s, s.Traits.Merge(memberTraits), nil
+}
+
+// encode writes the value v at path, of the shape with ID target, as an
+// element with the given name.
+func (c *Codec) encode(buf *bytes.Buffer, path, name string, target ast.AbsShapeID, memberTraits ast.Traits, v interface{}) error {
+	s, traits, err := c.shape(path, target, memberTraits)
+	if err != nil {
+		return err
+	}
+	v = valuetree.Unwrap(v)
+	if v == nil {
+		return errorAt(path, "expected %s, got null", s.Type)
+	}
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", s.Type, valuetree.TypeName(v))
+	}
+
+	var content bytes.Buffer
+	attrs := namespace(traits)
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		obj, ok := valuetree.Object(v)
+		if !ok {
+			return mismatch()
+		}
+		memberAttrs, err := c.encodeMembers(&content, path, target, s, obj)
+		if err != nil {
+			return err
+		}
+		attrs = append(attrs, memberAttrs...)
+
+	case ast.ListType, ast.SetType:
+		if err := c.encodeList(&content, path, memberName(*s.Value, "member"), s, v); err != nil {
+			return err
+		}
+
+	case ast.MapType:
+		if err := c.encodeMap(&content, path, "entry", s, v); err != nil {
+			return err
+		}
+
+	default:
+		text, err := c.encodeText(path, s.Type, traits, v)
+		if err != nil {
+			return err
+		}
+		xml.EscapeText(&content, []byte(text))
+	}
+	writeElement(buf, name, attrs, content.Bytes())
+	return nil
+}
+
+// encodeMembers writes the members of a structure or union value as
+// elements, and returns the members serialized as attributes.
+func (c *Codec) encodeMembers(buf *bytes.Buffer, path string, id ast.AbsShapeID, s ast.Shape, obj map[string]interface{}) ([]xml.Attr, error) {
+	for name := range obj {
+		if _, ok := s.Members[name]; !ok {
+			return nil, errorAt(path, "unknown member %q of %s", name, id)
+		}
+	}
+
+	var attrs []xml.Attr
+	n := 0
+	for _, name := range s.MemberOrder() {
+		value := valuetree.Unwrap(obj[name])
+		if value == nil {
+			continue
+		}
+		n++
+		member := s.Members[name]
+		key := memberName(member, name)
+		memberPath := path + "/" + key
+		if member.Traits.HasTrait(ast.XMLAttributeTraitID) {
+			ms, traits, err := c.shape(memberPath, member.Target.Value, member.Traits)
+			if err != nil {
+				return nil, err
+			}
+			text, err := c.encodeText(path+"/@"+key, ms.Type, traits, value)
+			if err != nil {
+				return nil, err
+			}
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: key}, Value: text})
+			continue
+		}
+		if member.Traits.HasTrait(ast.XMLFlattenedTraitID) {
+			ms, _, err := c.shape(memberPath, member.Target.Value, member.Traits)
+			if err != nil {
+				return nil, err
+			}
+			switch ms.Type {
+			case ast.ListType, ast.SetType:
+				if err := c.encodeList(buf, memberPath, key, ms, value); err != nil {
+					return nil, err
+				}
+				continue
+			case ast.MapType:
+				if err := c.encodeMap(buf, memberPath, key, ms, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		if err := c.encode(buf, memberPath, key, member.Target.Value, member.Traits, value); err != nil {
+			return nil, err
+		}
+	}
+	if s.Type == ast.UnionType && n != 1 {
+		return nil, errorAt(path, "union must have exactly one member set, got %d", n)
+	}
+	return attrs, nil
+}
+
+// encodeList writes the items of a list value as elements with the
+// given name.
+func (c *Codec) encodeList(buf *bytes.Buffer, path, itemName string, s ast.Shape, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || valuetree.IsBytes(v) {
+		return errorAt(path, "expected %s, got %s", s.Type, valuetree.TypeName(v))
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item := valuetree.Unwrap(rv.Index(i).Interface())
+		itemPath := path + "/" + strconv.Itoa(i)
+		if item == nil {
+			return errorAt(itemPath, "null item in list, which XML cannot represent")
+		}
+		if err := c.encode(buf, itemPath, itemName, s.Value.Target.Value, s.Value.Traits, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap writes the entries of a map value as elements with the
+// given name, in order of key.
+func (c *Codec) encodeMap(buf *bytes.Buffer, path, entryName string, s ast.Shape, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return errorAt(path, "expected map, got %s", valuetree.TypeName(v))
+	}
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		valuePath := path + "/" + key.String()
+		value := valuetree.Unwrap(rv.MapIndex(key).Interface())
+		if value == nil {
+			return errorAt(valuePath, "null value in map, which XML cannot represent")
+		}
+		var entry bytes.Buffer
+		if err := c.encode(&entry, valuePath, memberName(*s.Key, "key"), s.Key.Target.Value, s.Key.Traits, key.String()); err != nil {
+			return err
+		}
+		if err := c.encode(&entry, valuePath, memberName(*s.Value, "value"), s.Value.Target.Value, s.Value.Traits, value); err != nil {
+			return err
+		}
+		writeElement(buf, entryName, nil, entry.Bytes())
+	}
+	return nil
+}
+
+// encodeText returns the text of a simple value.
+func (c *Codec) encodeText(path string, t ast.ShapeType, traits ast.Traits, v interface{}) (string, error) {
+	mismatch := func() error {
+		return errorAt(path, "expected %s, got %s", t, valuetree.TypeName(v))
+	}
+	switch t {
+	case ast.StringType:
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+
+	case ast.BlobType:
+		if b, ok := v.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+
+	case ast.BooleanType:
+		if b, ok := v.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		f, ok := valuetree.Number(v)
+		if !ok {
+			return "", mismatch()
+		}
+		if !f.IsInt() {
+			return "", errorAt(path, "expected %s, got non-integer %s", t, f.Text('g', -1))
+		}
+		i, _ := f.Int(nil)
+		if bits := valuetree.IntegerBits[t]; bits > 0 && i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits) {
+			return "", errorAt(path, "value %s is out of range for %s", i, t)
+		}
+		return i.String(), nil
+
+	case ast.FloatType, ast.DoubleType:
+		var f float64
+		switch n := v.(type) {
+		case float32:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			g, ok := valuetree.Number(v)
+			if !ok {
+				return "", mismatch()
+			}
+			f, _ = g.Float64()
+		}
+		switch {
+		case math.IsNaN(f):
+			return "NaN", nil
+		case math.IsInf(f, 1):
+			return "Infinity", nil
+		case math.IsInf(f, -1):
+			return "-Infinity", nil
+		}
+		bitSize := 64
+		if t == ast.FloatType {
+			bitSize = 32
+		}
+		return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+
+	case ast.BigDecimalType:
+		if f, ok := valuetree.Number(v); ok {
+			return f.Text('g', -1), nil
+		}
+
+	case ast.TimestampType:
+		if ts, ok := v.(time.Time); ok {
+			return c.encodeTimestamp(ts, traits), nil
+		}
+
+	case ast.DocumentType:
+		return "", errorAt(path, "document values cannot be serialized to XML")
+
+	default:
+		return "", errorAt(path, "%s shapes have no values", t)
+	}
+	return "", mismatch()
+}
+
+func (c *Codec) encodeTimestamp(t time.Time, traits ast.Traits) string {
+	t = t.UTC()
+	switch c.timestampFormat(traits) {
+	case EpochSeconds:
+		ms := t.Unix()*1000 + int64(t.Nanosecond()/int(time.Millisecond))
+		sign := ""
+		if ms < 0 {
+			sign, ms = "-", -ms
+		}
+		if ms%1000 == 0 {
+			return fmt.Sprintf("%s%d", sign, ms/1000)
+		}
+		frac := strings.TrimRight(fmt.Sprintf("%03d", ms%1000), "0")
+		return fmt.Sprintf("%s%d.%s", sign, ms/1000, frac)
+	case HTTPDate:
+		return t.Format(http.TimeFormat)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// decode converts the element el at path to a value of the shape with
+// ID target.
+func (c *Codec) decode(path string, el *element, target ast.AbsShapeID, memberTraits ast.Traits) (interface{}, error) {
+	s, traits, err := c.shape(path, target, memberTraits)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Type {
+	case ast.StructureType, ast.UnionType:
+		return c.decodeMembers(path, el, s)
+	case ast.ListType, ast.SetType:
+		return c.decodeList(path, el.children(memberName(*s.Value, "member")), s)
+	case ast.MapType:
+		return c.decodeMap(path, el.children("entry"), s)
+	}
+	return c.decodeText(path, s.Type, traits, el.text)
+}
+
+// decodeMembers converts an element to a structure or union value.
+func (c *Codec) decodeMembers(path string, el *element, s ast.Shape) (interface{}, error) {
+	values := make(map[string]interface{})
+	for name, member := range s.Members {
+		key := memberName(member, name)
+		local := localName(key)
+		memberPath := path + "/" + key
+		var value interface{}
+		var err error
+		if member.Traits.HasTrait(ast.XMLAttributeTraitID) {
+			text, ok := el.attrs[local]
+			if !ok {
+				continue
+			}
+			ms, traits, err := c.shape(memberPath, member.Target.Value, member.Traits)
+			if err != nil {
+				return nil, err
+			}
+			if values[name], err = c.decodeText(path+"/@"+key, ms.Type, traits, text); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		children := el.children(local)
+		if len(children) == 0 {
+			continue
+		}
+		ms, _, err := c.shape(memberPath, member.Target.Value, member.Traits)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case member.Traits.HasTrait(ast.XMLFlattenedTraitID) && (ms.Type == ast.ListType || ms.Type == ast.SetType):
+			value, err = c.decodeList(memberPath, children, ms)
+		case member.Traits.HasTrait(ast.XMLFlattenedTraitID) && ms.Type == ast.MapType:
+			value, err = c.decodeMap(memberPath, children, ms)
+		default:
+			value, err = c.decode(memberPath, children[len(children)-1], member.Target.Value, member.Traits)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	if s.Type == ast.UnionType && len(values) != 1 {
+		return nil, errorAt(path, "union must have exactly one member set, got %d", len(values))
+	}
+	return values, nil
+}
+
+func (c *Codec) decodeList(path string, items []*element, s ast.Shape) (interface{}, error) {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		var err error
+		if values[i], err = c.decode(path+"/"+strconv.Itoa(i), item, s.Value.Target.Value, s.Value.Traits); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (c *Codec) decodeMap(path string, entries []*element, s ast.Shape) (interface{}, error) {
+	keyName := localName(memberName(*s.Key, "key"))
+	valueName := localName(memberName(*s.Value, "value"))
+	values := make(map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		entryPath := path + "/" + strconv.Itoa(i)
+		keys, vals := entry.children(keyName), entry.children(valueName)
+		if len(keys) != 1 || len(vals) != 1 {
+			return nil, errorAt(entryPath, "map entry must have one %s and one %s element", keyName, valueName)
+		}
+		key, err := c.decode(entryPath+"/"+keyName, keys[0], s.Key.Target.Value, s.Key.Traits)
+		if err != nil {
+			return nil, err
+		}
+		str := key.(string)
+		if values[str], err = c.decode(path+"/"+str, vals[0], s.Value.Target.Value, s.Value.Traits); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// decodeText converts the text of an element or attribute to a simple
+// value.
+func (c *Codec) decodeText(path string, t ast.ShapeType, traits ast.Traits, text string) (interface{}, error) {
+	invalid := func() error {
+		return errorAt(path, "invalid %s %q", t, text)
+	}
+	switch t {
+	case ast.StringType:
+		return text, nil
+
+	case ast.BlobType:
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, errorAt(path, "invalid base64 blob: %w", err)
+		}
+		return b, nil
+
+	case ast.BooleanType:
+		switch strings.TrimSpace(text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, invalid()
+
+	case ast.ByteType, ast.ShortType, ast.IntegerType, ast.LongType, ast.BigIntegerType:
+		i, ok := new(big.Int).SetString(strings.TrimSpace(text), 10)
+		if !ok {
+			return nil, invalid()
+		}
+		if t == ast.BigIntegerType {
+			return i, nil
+		}
+		if bits := valuetree.IntegerBits[t]; i.BitLen() >= int(bits) && !valuetree.IsMinimum(i, bits) {
+			return nil, errorAt(path, "value %s is out of range for %s", i, t)
+		}
+		return i.Int64(), nil
+
+	case ast.FloatType, ast.DoubleType:
+		switch text = strings.TrimSpace(text); text {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		bitSize := 64
+		if t == ast.FloatType {
+			bitSize = 32
+		}
+		f, err := strconv.ParseFloat(text, bitSize)
+		if err != nil {
+			return nil, invalid()
+		}
+		return f, nil
+
+	case ast.BigDecimalType:
+		text = strings.TrimSpace(text)
+		f, _, err := big.ParseFloat(text, 10, uint(4*len(text)+64), big.ToNearestEven)
+		if err != nil {
+			return nil, invalid()
+		}
+		return f, nil
+
+	case ast.TimestampType:
+		return c.decodeTimestamp(path, strings.TrimSpace(text), traits)
+
+	case ast.DocumentType:
+		return nil, errorAt(path, "document values cannot be deserialized from XML")
+	}
+	return nil, errorAt(path, "%s shapes have no values", t)
+}
+
+func (c *Codec) decodeTimestamp(path, text string, traits ast.Traits) (interface{}, error) {
+	format := c.timestampFormat(traits)
+	switch format {
+	case EpochSeconds:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			break
+		}
+		sec := math.Floor(f)
+		ms := math.Round((f - sec) * 1000)
+		return time.Unix(int64(sec), int64(ms)*int64(time.Millisecond)).UTC(), nil
+	default:
+		layout := time.RFC3339Nano
+		if format == HTTPDate {
+			layout = http.TimeFormat
+		}
+		if t, err := time.Parse(layout, text); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return nil, errorAt(path, "invalid %s timestamp %q", format, text)
+}
+
+func (c *Codec) timestampFormat(traits ast.Traits) string {
+	if format, ok := traits[ast.TimestampFormatTraitID].(*ast.StringNode); ok {
+		return format.Value
+	}
+	return c.opts.TimestampFormat
+}
+
+// An element is a parsed XML element.
+type element struct {
+	name     string
+	attrs    map[string]string
+	elements []*element
+	text     string
+}
+
+// children returns the child elements with the given local name.
+func (el *element) children(name string) []*element {
+	var children []*element
+	for _, child := range el.elements {
+		if child.name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// parse parses an XML document into a tree of elements, keeping the
+// local names of elements and attributes and dropping namespace
+// declarations.
+func parse(data []byte) (*element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*element
+	var root *element
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, newErrorf("%w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if root != nil && len(stack) == 0 {
+				return nil, newErrorf("more than one root element")
+			}
+			el := &element{name: tok.Name.Local, attrs: make(map[string]string)}
+			for _, attr := range tok.Attr {
+				if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+					el.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.elements = append(parent.elements, el)
+			} else {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, newErrorf("no root element")
+	}
+	return root, nil
+}
+
+// writeElement writes an element with the given attributes and escaped
+// content.
+func writeElement(buf *bytes.Buffer, name string, attrs []xml.Attr, content []byte) {
+	buf.WriteByte('<')
+	buf.WriteString(name)
+	for _, attr := range attrs {
+		buf.WriteByte(' ')
+		buf.WriteString(attr.Name.Local)
+		buf.WriteString(`="`)
+		xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+	buf.Write(content)
+	buf.WriteString("</")
+	buf.WriteString(name)
+	buf.WriteByte('>')
+}
+
+// namespace returns the namespace declaration of an xmlNamespace trait.
+func namespace(traits ast.Traits) []xml.Attr {
+	ns, ok := traits[ast.XMLNamespaceTraitID].(*ast.XMLNamespaceTrait)
+	if !ok {
+		return nil
+	}
+	name := "xmlns"
+	if ns.Prefix != nil {
+		name += ":" + ns.Prefix.Value
+	}
+	return []xml.Attr{{Name: xml.Name{Local: name}, Value: ns.URI.Value}}
+}
+
+// rootName returns the name of the root element of a value of a shape.
+func rootName(id ast.AbsShapeID, s ast.Shape) string {
+	if xmlName, ok := s.Traits[ast.XMLNameTraitID].(*ast.StringNode); ok {
+		return xmlName.Value
+	}
+	return id.Name()
+}
+
+// memberName returns the element or attribute name of a member, which
+// is its xmlName trait or else def.
+func memberName(member ast.Member, def string) string {
+	if xmlName, ok := member.Traits[ast.XMLNameTraitID].(*ast.StringNode); ok {
+		return xmlName.Value
+	}
+	return def
+}
+
+// localName returns the local part of a possibly prefixed name.
+func localName(name string) string {
+	return name[strings.LastIndexByte(name, ':')+1:]
+}
+
+// errorAt returns an error about the value at path.
+func errorAt(path, format string, a ...interface{}) error {
+	if path == "" {
+		return newErrorf(format, a...)
+	}
+	return newErrorf("%s: "+format, append([]interface{}{path}, a...)...)
+}
+
+func newErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf(prefix+format, a...)
+}
+
+const prefix = "xmlcodec: "
@@ -0,0 +1,231 @@
+package xmlcodec
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gogama/smithy-ast/ast"
+	"github.com/gogama/smithy-ast/internal/testmodel"
+)
+
+const testModel = `{
+	"version": "1.0",
+	"shapes": {
+		"foo#Thing": {
+			"type": "structure",
+			"members": {
+				"id": {"target": "smithy.api#String", "traits": {"smithy.api#xmlAttribute": {}}},
+				"kind": {"target": "smithy.api#String", "traits": {"smithy.api#xmlAttribute": {}, "smithy.api#xmlName": "xsi:type"}},
+				"name": {"target": "smithy.api#String", "traits": {"smithy.api#xmlName": "Name"}},
+				"count": {"target": "smithy.api#Integer"},
+				"ratio": {"target": "smithy.api#Double"},
+				"big": {"target": "smithy.api#BigInteger"},
+				"ok": {"target": "smithy.api#Boolean"},
+				"data": {"target": "smithy.api#Blob"},
+				"created": {"target": "smithy.api#Timestamp"},
+				"expires": {"target": "smithy.api#Timestamp", "traits": {"smithy.api#timestampFormat": "http-date"}},
+				"tags": {"target": "foo#Tags"},
+				"flat": {"target": "foo#Tags", "traits": {"smithy.api#xmlFlattened": {}, "smithy.api#xmlName": "Flat"}},
+				"attrs": {"target": "foo#Attrs"},
+				"flatAttrs": {"target": "foo#Attrs", "traits": {"smithy.api#xmlFlattened": {}}},
+				"nested": {"target": "foo#Nested", "traits": {"smithy.api#xmlNamespace": {"uri": "https://example.com/n", "prefix": "n"}}},
+				"shape": {"target": "foo#Shape"}
+			},
+			"traits": {
+				"smithy.api#xmlName": "Item",
+				"smithy.api#xmlNamespace": {"uri": "https://example.com/"}
+			}
+		},
+		"foo#Tags": {
+			"type": "list",
+			"member": {"target": "smithy.api#String", "traits": {"smithy.api#xmlName": "tag"}}
+		},
+		"foo#Attrs": {
+			"type": "map",
+			"key": {"target": "smithy.api#String", "traits": {"smithy.api#xmlName": "k"}},
+			"value": {"target": "smithy.api#Integer"}
+		},
+		"foo#Nested": {
+			"type": "structure",
+			"members": {
+				"value": {"target": "smithy.api#String"}
+			}
+		},
+		"foo#Shape": {
+			"type": "union",
+			"members": {
+				"circle": {"target": "smithy.api#Float"},
+				"none": {"target": "smithy.api#Unit"}
+			}
+		},
+		"foo#Doc": {
+			"type": "structure",
+			"members": {"doc": {"target": "smithy.api#Document"}}
+		}
+	}
+}`
+
+func testCodec(t *testing.T, opts Options) *Codec {
+	m := testmodel.Read(t, testModel)
+	return New(m, opts)
+}
+
+func TestCodec(t *testing.T) {
+	testCases := []struct {
+		name  string
+		opts  Options
+		id    ast.AbsShapeID
+		value interface{}
+		xml   string
+	}{
+		{
+			name: "structure",
+			id:   "foo#Thing",
+			value: map[string]interface{}{
+				"id":        "a&b",
+				"kind":      "Thing",
+				"name":      "<Bob>",
+				"count":     int64(-2147483648),
+				"ratio":     math.Inf(1),
+				"big":       new(big.Int).Lsh(big.NewInt(1), 70),
+				"ok":        false,
+				"data":      []byte("hi"),
+				"created":   time.Date(1985, 4, 12, 23, 20, 50, 520000000, time.UTC),
+				"expires":   time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+				"tags":      []interface{}{"x", "y"},
+				"flat":      []interface{}{"p", "q"},
+				"attrs":     map[string]interface{}{"b": int64(2), "a": int64(1)},
+				"flatAttrs": map[string]interface{}{"c": int64(3)},
+				"nested":    map[string]interface{}{"value": ""},
+				"shape":     map[string]interface{}{"circle": float64(1.5)},
+			},
+			xml: `<Item xmlns="https://example.com/" id="a&amp;b" xsi:type="Thing">` +
+				`<attrs><entry><k>a</k><value>1</value></entry><entry><k>b</k><value>2</value></entry></attrs>` +
+				`<big>1180591620717411303424</big>` +
+				`<count>-2147483648</count>` +
+				`<created>1985-04-12T23:20:50.52Z</created>` +
+				`<data>aGk=</data>` +
+				`<expires>Sun, 06 Nov 1994 08:49:37 GMT</expires>` +
+				`<Flat>p</Flat><Flat>q</Flat>` +
+				`<flatAttrs><k>c</k><value>3</value></flatAttrs>` +
+				`<Name>&lt;Bob&gt;</Name>` +
+				`<nested xmlns:n="https://example.com/n"><value></value></nested>` +
+				`<ok>false</ok>` +
+				`<ratio>Infinity</ratio>` +
+				`<shape><circle>1.5</circle></shape>` +
+				`<tags><tag>x</tag><tag>y</tag></tags>` +
+				`</Item>`,
+		},
+		{
+			name:  "empty structure",
+			id:    "foo#Nested",
+			value: map[string]interface{}{},
+			xml:   `<Nested></Nested>`,
+		},
+		{
+			name:  "unit union member",
+			id:    "foo#Shape",
+			value: map[string]interface{}{"none": map[string]interface{}{}},
+			xml:   `<Shape><none></none></Shape>`,
+		},
+		{
+			name:  "epoch seconds",
+			opts:  Options{TimestampFormat: EpochSeconds},
+			id:    "smithy.api#Timestamp",
+			value: time.Unix(-2, 500000000),
+			xml:   `<Timestamp>-1.5</Timestamp>`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := testCodec(t, testCase.opts)
+
+			p, err := c.Marshal(testCase.id, testCase.value)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.xml, string(p))
+
+			v, err := c.Unmarshal(testCase.id, p)
+			require.NoError(t, err)
+			q, err := c.Marshal(testCase.id, v)
+			require.NoError(t, err)
+			assert.Equal(t, string(p), string(q))
+		})
+	}
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	c := testCodec(t, Options{})
+
+	v, err := c.Unmarshal("foo#Thing", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<x:Item xmlns:x="https://example.com/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="T" other="1">
+			<x:Name>Bob</x:Name>
+			<unknown><a/></unknown>
+			<count> 3 </count>
+			<ok>true</ok>
+			<Flat>p</Flat>
+			<tags>
+				<tag>x</tag>
+			</tags>
+			<Flat>q</Flat>
+			<attrs/>
+			<shape><none/></shape>
+		</x:Item>`))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"kind":  "T",
+		"name":  "Bob",
+		"count": int64(3),
+		"ok":    true,
+		"flat":  []interface{}{"p", "q"},
+		"tags":  []interface{}{"x"},
+		"attrs": map[string]interface{}{},
+		"shape": map[string]interface{}{"none": map[string]interface{}{}},
+	}, v)
+}
+
+func TestCodec_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    ast.AbsShapeID
+		value interface{}
+		xml   string
+		err   string
+	}{
+		{name: "marshal/not found", id: "foo#Missing", value: 1, err: "xmlcodec: shape foo#Missing not found"},
+		{name: "marshal/type", id: "foo#Thing", value: map[string]interface{}{"name": 1}, err: "xmlcodec: /Name: expected string, got number"},
+		{name: "marshal/attribute type", id: "foo#Thing", value: map[string]interface{}{"id": true}, err: "xmlcodec: /@id: expected string, got boolean"},
+		{name: "marshal/unknown member", id: "foo#Thing", value: map[string]string{"nope": ""}, err: `xmlcodec: unknown member "nope" of foo#Thing`},
+		{name: "marshal/range", id: "smithy.api#Byte", value: -129, err: "xmlcodec: value -129 is out of range for byte"},
+		{name: "marshal/null item", id: "foo#Tags", value: []interface{}{nil}, err: "xmlcodec: /0: null item in list, which XML cannot represent"},
+		{name: "marshal/union", id: "foo#Shape", value: map[string]interface{}{"circle": 1, "none": map[string]interface{}{}}, err: "xmlcodec: union must have exactly one member set, got 2"},
+		{name: "marshal/document", id: "foo#Doc", value: map[string]interface{}{"doc": 1}, err: "xmlcodec: /doc: document values cannot be serialized to XML"},
+		{name: "unmarshal/syntax", id: "foo#Thing", xml: `<Item>`, err: "xmlcodec: XML syntax error on line 1: unexpected EOF"},
+		{name: "unmarshal/empty", id: "foo#Thing", xml: ` `, err: "xmlcodec: no root element"},
+		{name: "unmarshal/integer", id: "foo#Thing", xml: `<Item><count>1.5</count></Item>`, err: `xmlcodec: /count: invalid integer "1.5"`},
+		{name: "unmarshal/range", id: "foo#Thing", xml: `<Item><count>2147483648</count></Item>`, err: "xmlcodec: /count: value 2147483648 is out of range for integer"},
+		{name: "unmarshal/timestamp", id: "foo#Thing", xml: `<Item><expires>soon</expires></Item>`, err: `xmlcodec: /expires: invalid http-date timestamp "soon"`},
+		{name: "unmarshal/map entry", id: "foo#Attrs", xml: `<Attrs><entry><k>a</k></entry></Attrs>`, err: "xmlcodec: /0: map entry must have one k and one value element"},
+		{name: "unmarshal/union", id: "foo#Shape", xml: `<Shape></Shape>`, err: "xmlcodec: union must have exactly one member set, got 0"},
+	}
+
+	c := testCodec(t, Options{})
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var err error
+			if testCase.xml != "" {
+				_, err = c.Unmarshal(testCase.id, []byte(testCase.xml))
+			} else {
+				_, err = c.Marshal(testCase.id, testCase.value)
+			}
+
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}